@@ -3,8 +3,11 @@ module github.com/ericmcbride/go-dfw-testing
 go 1.12
 
 require (
+	github.com/golang/protobuf v1.3.3
 	github.com/gorilla/context v1.1.1 // indirect
 	github.com/gorilla/mux v1.6.2
+	github.com/gorilla/websocket v1.4.2
+	github.com/graphql-go/graphql v0.8.1
 	github.com/konsorten/go-windows-terminal-sequences v1.0.1 // indirect
 	github.com/lib/pq v1.0.0
 	github.com/mitchellh/mapstructure v1.1.2 // indirect
@@ -12,6 +15,5 @@ require (
 	github.com/sirupsen/logrus v1.1.1
 	github.com/spf13/pflag v1.0.3 // indirect
 	github.com/spf13/viper v1.2.1
-	golang.org/x/crypto v0.0.0-20181015023909-0c41d7ab0a0e // indirect
-	golang.org/x/sys v0.0.0-20181011152604-fa43e7bc11ba // indirect
+	google.golang.org/grpc v1.27.1
 )
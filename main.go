@@ -1,16 +1,30 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
 
+	"github.com/ericmcbride/go-dfw-testing/pkg/catalog"
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/commands"
+	"github.com/ericmcbride/go-dfw-testing/pkg/events"
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
 	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/maintenance"
+	"github.com/ericmcbride/go-dfw-testing/pkg/outbox"
+	"github.com/ericmcbride/go-dfw-testing/pkg/reservations"
+	"github.com/ericmcbride/go-dfw-testing/pkg/rpc"
 	server "github.com/ericmcbride/go-dfw-testing/pkg/server"
+	"github.com/ericmcbride/go-dfw-testing/pkg/webhooks"
 	"github.com/spf13/viper"
 )
 
 func main() {
 	viper.SetDefault("logging", "DEBUG")
+	viper.SetDefault("grpc_port", "9090")
+	viper.SetDefault("stats_cache_ttl", "1m")
 	viper.SetEnvPrefix("GO-DFW-TESTING")
 	viper.AutomaticEnv()
 
@@ -18,6 +32,83 @@ func main() {
 		viper.Get("logging").(string),
 	)
 
+	// "id:tenant,id:tenant", defaults to the single built in credential
+	if authIds := viper.GetString("auth_ids"); authIds != "" {
+		if err := handlers.LoadCredentials(authIds); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	handlers.StatsCacheTTL = viper.GetDuration("stats_cache_ttl")
+
+	// Only for webhooks on a trusted internal network
+	webhooks.AllowPrivateTargets = viper.GetBool("webhook_allow_private")
+
+	// Reference makes and models, the bundled ones unless catalog_path is set
+	if err := catalog.Configure(viper.GetString("catalog_path"), viper.GetString("catalog_policy")); err != nil {
+		log.Fatal(err)
+	}
+
+	// Service intervals by make, the bundled ones unless service_rules_path
+	// is set
+	if err := maintenance.Configure(viper.GetString("service_rules_path")); err != nil {
+		log.Fatal(err)
+	}
+
+	// Anything after the binary name is a CLI command, e.g. `service import cars.csv`
+	if len(os.Args) > 1 {
+		if err := commands.Run(os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Feed car changes from Postgres to the event stream subscribers
+	go func() {
+		if err := events.Run(context.Background(), events.DefaultBroker); err != nil {
+			log.Println("Car change listener stopped: ", err)
+		}
+	}()
+
+	// Relay car changes from the outbox to the webhooks, and to stdout when
+	// outbox_stdout is set
+	db, err := clients.NewDbConn()
+	if err != nil {
+		log.Fatal(err)
+	}
+	publisher := outbox.Fanout{outbox.WebhookPublisher{Db: &db}}
+	if viper.GetBool("outbox_stdout") {
+		publisher = append(publisher, outbox.StdoutPublisher{})
+	}
+	go func() {
+		if err := outbox.Run(context.Background(), &db, publisher); err != nil {
+			log.Println("Outbox relay stopped: ", err)
+		}
+	}()
+
+	// Send the webhook deliveries queued by the relay
+	go func() {
+		if err := webhooks.Run(context.Background()); err != nil {
+			log.Println("Webhook worker stopped: ", err)
+		}
+	}()
+
+	// Release reserved cars once their holds run out
+	go func() {
+		if err := reservations.Run(context.Background()); err != nil {
+			log.Println("Reservation sweeper stopped: ", err)
+		}
+	}()
+
+	// CarService for internal callers, on its own port
+	grpcAddr := ":" + viper.GetString("grpc_port")
+	go func() {
+		log.Println("Starting gRPC server on: ", grpcAddr)
+		if err := rpc.Serve(context.Background(), grpcAddr, events.DefaultBroker); err != nil {
+			log.Println("gRPC server stopped: ", err)
+		}
+	}()
+
 	handler := server.New()
 	log.Println("Starting server on: ", ":8080")
 
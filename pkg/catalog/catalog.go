@@ -0,0 +1,274 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// What happens to a car whose make or model isn't in the catalog
+type Policy string
+
+const (
+	// The catalog isn't consulted at all
+	Off Policy = "off"
+	// Known names are made canonical, unknown ones are saved with a warning
+	Flag Policy = "flag"
+	// Unknown names fail validation
+	Reject Policy = "reject"
+)
+
+var (
+	// The catalog cars are checked against, the bundled dataset unless
+	// Configure loads another
+	Default = MustParseJSON(strings.NewReader(dataset))
+	// How unknown makes and models are treated
+	CurrentPolicy = Flag
+)
+
+type Model struct {
+	Name    string   `json:"name" xml:"name"`
+	Aliases []string `json:"aliases" xml:"alias"`
+}
+
+type Make struct {
+	Name    string   `json:"name" xml:"name"`
+	Aliases []string `json:"aliases" xml:"alias"`
+	Models  []Model  `json:"models" xml:"model"`
+}
+
+// A reference list of makes and their models. Names and aliases are
+// matched ignoring case, spaces and punctuation, so "f150", "F-150" and
+// "F 150" are all the same model.
+type Catalog struct {
+	makes  []Make
+	byKey  map[string]int
+	models []map[string]int
+}
+
+// Returned by Resolve for a make, or a model of a known make, that isn't in
+// the catalog
+type UnknownError struct {
+	Make      string
+	Model     string
+	MakeKnown bool
+}
+
+func (e *UnknownError) Error() string {
+	if !e.MakeKnown {
+		return fmt.Sprintf("Unknown make %q", e.Make)
+	}
+	return fmt.Sprintf("Unknown model %q for %s", e.Model, e.Make)
+}
+
+// Replace the bundled dataset with a CSV or JSON file, picked by extension,
+// and set the policy to off, flag or reject. An empty path keeps the
+// bundled dataset and an empty policy keeps flag.
+func Configure(path string, policy string) error {
+	if policy != "" {
+		parsed, err := ParsePolicy(policy)
+		if err != nil {
+			return err
+		}
+		CurrentPolicy = parsed
+	}
+
+	if path == "" {
+		return nil
+	}
+	loaded, err := LoadFile(path)
+	if err != nil {
+		return err
+	}
+	Default = loaded
+	return nil
+}
+
+func ParsePolicy(policy string) (Policy, error) {
+	switch Policy(policy) {
+	case Off, Flag, Reject:
+		return Policy(policy), nil
+	}
+	return "", fmt.Errorf("Unknown catalog policy %q, must be off, flag or reject", policy)
+}
+
+func LoadFile(path string) (*Catalog, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not load catalog %s", err)
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return ParseJSON(file)
+	case ".csv":
+		return ParseCSV(file)
+	}
+	return nil, fmt.Errorf("Could not load catalog %s, must be .csv or .json", path)
+}
+
+// {"makes": [{"name": "Chevrolet", "aliases": ["Chevy"], "models": [{"name": "Silverado 1500", "aliases": ["Silverado"]}]}]}
+func ParseJSON(r io.Reader) (*Catalog, error) {
+	var doc struct {
+		Makes []Make `json:"makes"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("Could not load catalog %s", err)
+	}
+	return New(doc.Makes)
+}
+
+// A header of make, model, make_aliases and model_aliases, then a row per
+// model. Aliases are separated by |, and a make's aliases can be on any of
+// its rows.
+func ParseCSV(r io.Reader) (*Catalog, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("Could not load catalog %s", err)
+	}
+	if strings.Join(header, ",") != "make,model,make_aliases,model_aliases" {
+		return nil, errors.New("Could not load catalog, the header must be make,model,make_aliases,model_aliases")
+	}
+
+	var makes []Make
+	index := make(map[string]int)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Could not load catalog %s", err)
+		}
+
+		i, ok := index[record[0]]
+		if !ok {
+			i = len(makes)
+			index[record[0]] = i
+			makes = append(makes, Make{Name: record[0]})
+		}
+		makes[i].Aliases = append(makes[i].Aliases, splitAliases(record[2])...)
+		if record[1] != "" {
+			makes[i].Models = append(makes[i].Models, Model{Name: record[1], Aliases: splitAliases(record[3])})
+		}
+	}
+	return New(makes)
+}
+
+func MustParseJSON(r io.Reader) *Catalog {
+	c, err := ParseJSON(r)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Build a catalog, rejecting names or aliases that would match more than
+// one make, or more than one model of a make
+func New(makes []Make) (*Catalog, error) {
+	c := &Catalog{byKey: make(map[string]int)}
+
+	makes = append([]Make{}, makes...)
+	sort.Slice(makes, func(i, j int) bool { return strings.ToLower(makes[i].Name) < strings.ToLower(makes[j].Name) })
+
+	for i, m := range makes {
+		if Key(m.Name) == "" {
+			return nil, errors.New("Could not load catalog, a make has no name")
+		}
+		for _, name := range append([]string{m.Name}, m.Aliases...) {
+			if other, ok := c.byKey[Key(name)]; ok && other != i {
+				return nil, fmt.Errorf("Could not load catalog, %q is both %s and %s", name, makes[other].Name, m.Name)
+			}
+			c.byKey[Key(name)] = i
+		}
+
+		models := append([]Model{}, m.Models...)
+		sort.Slice(models, func(i, j int) bool { return strings.ToLower(models[i].Name) < strings.ToLower(models[j].Name) })
+		byKey := make(map[string]int)
+		for j, model := range models {
+			if Key(model.Name) == "" {
+				return nil, fmt.Errorf("Could not load catalog, a %s model has no name", m.Name)
+			}
+			for _, name := range append([]string{model.Name}, model.Aliases...) {
+				if other, ok := byKey[Key(name)]; ok && other != j {
+					return nil, fmt.Errorf("Could not load catalog, %q is both %s %s and %s %s", name, m.Name, models[other].Name, m.Name, model.Name)
+				}
+				byKey[Key(name)] = j
+			}
+			if models[j].Aliases == nil {
+				models[j].Aliases = []string{}
+			}
+		}
+
+		makes[i].Models = models
+		if makes[i].Aliases == nil {
+			makes[i].Aliases = []string{}
+		}
+		c.models = append(c.models, byKey)
+	}
+	c.makes = makes
+	return c, nil
+}
+
+// Every make, alphabetically
+func (c *Catalog) Makes() []Make {
+	return c.makes
+}
+
+// Look a make up by its name or an alias
+func (c *Catalog) FindMake(name string) (Make, bool) {
+	i, ok := c.byKey[Key(name)]
+	if !ok {
+		return Make{}, false
+	}
+	return c.makes[i], true
+}
+
+// The canonical names for a make and model. Names the catalog doesn't have
+// are returned as given along with an *UnknownError.
+func (c *Catalog) Resolve(makeName, modelName string) (string, string, error) {
+	i, ok := c.byKey[Key(makeName)]
+	if !ok {
+		return makeName, modelName, &UnknownError{Make: makeName, Model: modelName}
+	}
+
+	canonical := c.makes[i].Name
+	j, ok := c.models[i][Key(modelName)]
+	if !ok {
+		return canonical, modelName, &UnknownError{Make: canonical, Model: modelName, MakeKnown: true}
+	}
+	return canonical, c.makes[i].Models[j].Name, nil
+}
+
+// What names are matched on: lower case letters and digits
+func Key(name string) string {
+	var key strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			key.WriteRune(r)
+		}
+	}
+	return key.String()
+}
+
+func splitAliases(value string) []string {
+	var aliases []string
+	for _, alias := range strings.Split(value, "|") {
+		if alias = strings.TrimSpace(alias); alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases
+}
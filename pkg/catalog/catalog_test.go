@@ -0,0 +1,134 @@
+package catalog_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/catalog"
+)
+
+const testCSV = `make,model,make_aliases,model_aliases
+Ford,F-150,,F150|F 150 Lightning
+Chevrolet,Silverado 1500,Chevy,Silverado
+Ford,Mustang,,
+DeLorean,,,
+`
+
+func TestResolve(t *testing.T) {
+	c, err := catalog.ParseCSV(strings.NewReader(testCSV))
+	if err != nil {
+		t.Fatalf("Couldn't parse %s", err)
+	}
+
+	cases := []struct{ make, model, wantMake, wantModel string }{
+		{"ford", "f150", "Ford", "F-150"},
+		{" FORD ", "F-150", "Ford", "F-150"},
+		{"Ford", "f150 lightning", "Ford", "F-150"},
+		{"chevy", "SILVERADO", "Chevrolet", "Silverado 1500"},
+	}
+	for _, c2 := range cases {
+		gotMake, gotModel, err := c.Resolve(c2.make, c2.model)
+		if err != nil || gotMake != c2.wantMake || gotModel != c2.wantModel {
+			t.Errorf("%s %s: expected %s %s, got %s %s %v", c2.make, c2.model, c2.wantMake, c2.wantModel, gotMake, gotModel, err)
+		}
+	}
+
+	// An unknown model keeps the canonical make
+	gotMake, gotModel, err := c.Resolve("ford", "Model T")
+	unknown, ok := err.(*catalog.UnknownError)
+	if !ok || !unknown.MakeKnown || gotMake != "Ford" || gotModel != "Model T" {
+		t.Errorf("Expected an unknown Ford model, got %s %s %v", gotMake, gotModel, err)
+	}
+
+	gotMake, _, err = c.Resolve("Yugo", "GV")
+	unknown, ok = err.(*catalog.UnknownError)
+	if !ok || unknown.MakeKnown || gotMake != "Yugo" {
+		t.Errorf("Expected an unknown make, got %s %v", gotMake, err)
+	}
+	if err.Error() != `Unknown make "Yugo"` {
+		t.Errorf("Unexpected message %s", err)
+	}
+}
+
+func TestMakesSorted(t *testing.T) {
+	c, err := catalog.ParseCSV(strings.NewReader(testCSV))
+	if err != nil {
+		t.Fatalf("Couldn't parse %s", err)
+	}
+
+	var names []string
+	for _, m := range c.Makes() {
+		names = append(names, m.Name)
+	}
+	if strings.Join(names, ",") != "Chevrolet,DeLorean,Ford" {
+		t.Errorf("Expected makes in order, got %v", names)
+	}
+
+	ford, ok := c.FindMake("FORD")
+	if !ok || len(ford.Models) != 2 || ford.Models[0].Name != "F-150" || ford.Models[1].Name != "Mustang" {
+		t.Errorf("Expected Ford's models in order, got %+v", ford)
+	}
+	delorean, _ := c.FindMake("delorean")
+	if delorean.Models == nil || delorean.Aliases == nil {
+		t.Errorf("Expected empty lists rather than nil, got %+v", delorean)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	bodies := map[string]string{
+		"bad header":   "make,model\nFord,F-150\n",
+		"short row":    "make,model,make_aliases,model_aliases\nFord,F-150\n",
+		"shared alias": "make,model,make_aliases,model_aliases\nChevrolet,,Chevy,\nChevelle,,Chevy,\n",
+		"same model":   "make,model,make_aliases,model_aliases\nFord,F-150,,\nFord,F150,,\n",
+		"no name":      "make,model,make_aliases,model_aliases\n--,,,\n",
+	}
+	for name, body := range bodies {
+		if _, err := catalog.ParseCSV(strings.NewReader(body)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	if _, err := catalog.ParseJSON(strings.NewReader(`{"makes": [{"name": "Ford", "models": "F-150"}]}`)); err == nil {
+		t.Errorf("Expected an error for a malformed JSON catalog")
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	bundled, policy := catalog.Default, catalog.CurrentPolicy
+	defer func() { catalog.Default, catalog.CurrentPolicy = bundled, policy }()
+
+	dir, err := ioutil.TempDir("", "catalog")
+	if err != nil {
+		t.Fatalf("Couldn't make a temp dir %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "makes.json")
+	ioutil.WriteFile(path, []byte(`{"makes": [{"name": "DeLorean", "models": [{"name": "DMC-12"}]}]}`), 0644)
+
+	if err := catalog.Configure(path, "reject"); err != nil {
+		t.Fatalf("Couldn't configure %s", err)
+	}
+	if catalog.CurrentPolicy != catalog.Reject || len(catalog.Default.Makes()) != 1 {
+		t.Errorf("Expected the DeLorean catalog rejecting unknowns, got %v %v", catalog.CurrentPolicy, catalog.Default.Makes())
+	}
+
+	if err := catalog.Configure("", "sometimes"); err == nil {
+		t.Errorf("Expected an unknown policy to fail")
+	}
+	if err := catalog.Configure(filepath.Join(dir, "makes.yaml"), ""); err == nil {
+		t.Errorf("Expected a missing file to fail")
+	}
+}
+
+func TestBundledDataset(t *testing.T) {
+	if len(catalog.Default.Makes()) < 20 {
+		t.Errorf("Expected the bundled makes, got %d", len(catalog.Default.Makes()))
+	}
+	if _, model, err := catalog.Default.Resolve("vw", "id4"); err != nil || model != "ID.4" {
+		t.Errorf("Expected the ID.4, got %s %v", model, err)
+	}
+}
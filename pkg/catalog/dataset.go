@@ -0,0 +1,155 @@
+package catalog
+
+// The bundled catalog: common makes sold in the US and their current and
+// recent models. Set catalog_path to load a fuller one.
+const dataset = `{
+  "makes": [
+    {
+      "name": "Acura",
+      "models": [{"name": "ILX"}, {"name": "Integra"}, {"name": "MDX"}, {"name": "RDX"}, {"name": "TLX"}]
+    },
+    {
+      "name": "Audi",
+      "models": [{"name": "A3"}, {"name": "A4"}, {"name": "A6"}, {"name": "Q3"}, {"name": "Q5"}, {"name": "Q7"}, {"name": "e-tron"}]
+    },
+    {
+      "name": "BMW",
+      "models": [
+        {"name": "3 Series", "aliases": ["330i"]},
+        {"name": "5 Series", "aliases": ["530i"]},
+        {"name": "M3"},
+        {"name": "X3"},
+        {"name": "X5"},
+        {"name": "i4"}
+      ]
+    },
+    {
+      "name": "Buick",
+      "models": [{"name": "Enclave"}, {"name": "Encore"}, {"name": "Envision"}]
+    },
+    {
+      "name": "Cadillac",
+      "models": [{"name": "CT5"}, {"name": "Escalade"}, {"name": "XT5"}]
+    },
+    {
+      "name": "Chevrolet",
+      "aliases": ["Chevy"],
+      "models": [
+        {"name": "Camaro"},
+        {"name": "Colorado"},
+        {"name": "Corvette", "aliases": ["Vette"]},
+        {"name": "Equinox"},
+        {"name": "Malibu"},
+        {"name": "Silverado 1500", "aliases": ["Silverado"]},
+        {"name": "Suburban"},
+        {"name": "Tahoe"},
+        {"name": "Traverse"}
+      ]
+    },
+    {
+      "name": "Chrysler",
+      "models": [{"name": "300"}, {"name": "Pacifica"}]
+    },
+    {
+      "name": "Dodge",
+      "models": [{"name": "Challenger"}, {"name": "Charger"}, {"name": "Durango"}]
+    },
+    {
+      "name": "Ford",
+      "models": [
+        {"name": "Bronco"},
+        {"name": "Escape"},
+        {"name": "Explorer"},
+        {"name": "F-150", "aliases": ["F150"]},
+        {"name": "F-250", "aliases": ["F250"]},
+        {"name": "Focus"},
+        {"name": "Fusion"},
+        {"name": "Maverick"},
+        {"name": "Mustang"},
+        {"name": "Ranger"}
+      ]
+    },
+    {
+      "name": "GMC",
+      "models": [{"name": "Acadia"}, {"name": "Canyon"}, {"name": "Sierra 1500", "aliases": ["Sierra"]}, {"name": "Yukon"}]
+    },
+    {
+      "name": "Honda",
+      "models": [
+        {"name": "Accord"},
+        {"name": "Civic"},
+        {"name": "CR-V"},
+        {"name": "Fit"},
+        {"name": "HR-V"},
+        {"name": "Odyssey"},
+        {"name": "Pilot"},
+        {"name": "Ridgeline"}
+      ]
+    },
+    {
+      "name": "Hyundai",
+      "models": [{"name": "Elantra"}, {"name": "Ioniq 5"}, {"name": "Kona"}, {"name": "Palisade"}, {"name": "Santa Fe"}, {"name": "Sonata"}, {"name": "Tucson"}]
+    },
+    {
+      "name": "Jeep",
+      "models": [{"name": "Cherokee"}, {"name": "Compass"}, {"name": "Gladiator"}, {"name": "Grand Cherokee"}, {"name": "Wrangler"}]
+    },
+    {
+      "name": "Kia",
+      "models": [{"name": "EV6"}, {"name": "Forte"}, {"name": "Sorento"}, {"name": "Soul"}, {"name": "Sportage"}, {"name": "Telluride"}]
+    },
+    {
+      "name": "Lexus",
+      "models": [{"name": "ES"}, {"name": "GX"}, {"name": "IS"}, {"name": "NX"}, {"name": "RX"}]
+    },
+    {
+      "name": "Mazda",
+      "models": [{"name": "CX-30"}, {"name": "CX-5"}, {"name": "CX-9"}, {"name": "Mazda3", "aliases": ["3"]}, {"name": "MX-5 Miata", "aliases": ["Miata", "MX-5"]}]
+    },
+    {
+      "name": "Mercedes-Benz",
+      "aliases": ["Mercedes", "Benz"],
+      "models": [{"name": "C-Class"}, {"name": "E-Class"}, {"name": "G-Class"}, {"name": "GLC"}, {"name": "GLE"}, {"name": "S-Class"}]
+    },
+    {
+      "name": "Nissan",
+      "models": [{"name": "Altima"}, {"name": "Frontier"}, {"name": "Leaf"}, {"name": "Maxima"}, {"name": "Pathfinder"}, {"name": "Rogue"}, {"name": "Sentra"}]
+    },
+    {
+      "name": "Ram",
+      "models": [{"name": "1500"}, {"name": "2500"}, {"name": "ProMaster"}]
+    },
+    {
+      "name": "Subaru",
+      "models": [{"name": "Ascent"}, {"name": "Crosstrek"}, {"name": "Forester"}, {"name": "Impreza"}, {"name": "Outback"}, {"name": "WRX"}]
+    },
+    {
+      "name": "Tesla",
+      "models": [{"name": "Model 3"}, {"name": "Model S"}, {"name": "Model X"}, {"name": "Model Y"}]
+    },
+    {
+      "name": "Toyota",
+      "models": [
+        {"name": "4Runner"},
+        {"name": "Camry"},
+        {"name": "Corolla"},
+        {"name": "Highlander"},
+        {"name": "Prius"},
+        {"name": "Prius c"},
+        {"name": "RAV4"},
+        {"name": "Sienna"},
+        {"name": "Tacoma"},
+        {"name": "Tundra"}
+      ]
+    },
+    {
+      "name": "Volkswagen",
+      "aliases": ["VW"],
+      "models": [{"name": "Atlas"}, {"name": "Golf"}, {"name": "ID.4"}, {"name": "Jetta"}, {"name": "Passat"}, {"name": "Tiguan"}]
+    },
+    {
+      "name": "Volvo",
+      "models": [{"name": "S60"}, {"name": "XC40"}, {"name": "XC60"}, {"name": "XC90"}]
+    }
+  ]
+}`
@@ -0,0 +1,61 @@
+package clients
+
+import (
+	"context"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/lib/pq"
+)
+
+const (
+	listenerMinReconnect = 10 * time.Second
+	listenerMaxReconnect = time.Minute
+	// Ping an idle connection this often so a dead one gets noticed
+	listenerPingInterval = 90 * time.Second
+)
+
+// Listen on a Postgres NOTIFY channel until ctx is done, calling fn with each
+// payload. pq.Listener reconnects on its own; notifications sent while it was
+// disconnected are lost, so onReconnect is called after each reconnect to let
+// callers resync.
+func Listen(ctx context.Context, channel string, fn func(payload string), onReconnect func()) error {
+	log := logging.GetLog(ctx)
+
+	listener := pq.NewListener(ConnectionString(), listenerMinReconnect, listenerMaxReconnect,
+		func(event pq.ListenerEventType, err error) {
+			switch event {
+			case pq.ListenerEventDisconnected:
+				log.WithError(err).Error("Listener: disconnected from database")
+			case pq.ListenerEventConnectionAttemptFailed:
+				log.WithError(err).Error("Listener: reconnect attempt failed")
+			case pq.ListenerEventReconnected:
+				log.Info("Listener: reconnected to database")
+			}
+		})
+	defer listener.Close()
+
+	err := listener.Listen(channel)
+	if err != nil {
+		return err
+	}
+	log.Infof("Listener: listening on %s", channel)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification := <-listener.Notify:
+			// nil means the connection was re-established
+			if notification == nil {
+				if onReconnect != nil {
+					onReconnect()
+				}
+				continue
+			}
+			fn(notification.Extra)
+		case <-time.After(listenerPingInterval):
+			go listener.Ping()
+		}
+	}
+}
@@ -11,16 +11,21 @@ type DBClient struct {
 	Db *sql.DB
 }
 
-func NewDbConn() (DBClient, error) {
-	var client DBClient
+// Build the connection string from the POSTGRES_* environment variables
+func ConnectionString() string {
 	host := os.Getenv("POSTGRES_HOST")
 	name := os.Getenv("POSTGRES_NAME")
 	user := os.Getenv("POSTGRES_USER")
 	password := os.Getenv("POSTGRES_PASSWORD")
 	sslmode := "disable"
 
-	connection := fmt.Sprintf("user=%s password=%s dbname=%s host=%s sslmode=%s", user, password, name, host, sslmode)
-	db, err := sql.Open("postgres", connection)
+	return fmt.Sprintf("user=%s password=%s dbname=%s host=%s sslmode=%s", user, password, name, host, sslmode)
+}
+
+func NewDbConn() (DBClient, error) {
+	var client DBClient
+
+	db, err := sql.Open("postgres", ConnectionString())
 	if err != nil {
 		panic(err)
 	}
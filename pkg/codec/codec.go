@@ -0,0 +1,217 @@
+package codec
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrNotTable = errors.New("Only collections can be encoded as CSV")
+
+// A wire format responses can be encoded in and, when Decode is set,
+// request bodies read from
+type Codec struct {
+	Name string
+	// Media types this codec answers to, the first one is what we send
+	ContentTypes []string
+	Encode       func(w io.Writer, v interface{}) error
+	Decode       func(r io.Reader, v interface{}) error
+	// Only values implementing Table can be encoded
+	TablesOnly bool
+	// Not text, so responses carry no charset
+	Binary bool
+}
+
+// Collections that can be written as rows, e.g. as CSV
+type Table interface {
+	Header() []string
+	Rows() [][]string
+}
+
+func (c *Codec) ContentType() string {
+	return c.ContentTypes[0]
+}
+
+var (
+	JSON = &Codec{
+		Name:         "json",
+		ContentTypes: []string{"application/json"},
+		Encode: func(w io.Writer, v interface{}) error {
+			data, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(data)
+			return err
+		},
+		Decode: func(r io.Reader, v interface{}) error {
+			return json.NewDecoder(r).Decode(v)
+		},
+	}
+
+	XML = &Codec{
+		Name:         "xml",
+		ContentTypes: []string{"application/xml", "text/xml"},
+		Encode: func(w io.Writer, v interface{}) error {
+			if _, err := io.WriteString(w, xml.Header); err != nil {
+				return err
+			}
+			return xml.NewEncoder(w).Encode(v)
+		},
+		Decode: func(r io.Reader, v interface{}) error {
+			return xml.NewDecoder(r).Decode(v)
+		},
+	}
+
+	MessagePack = &Codec{
+		Name:         "msgpack",
+		ContentTypes: []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"},
+		Encode:       encodeMsgpack,
+		Decode:       decodeMsgpack,
+		Binary:       true,
+	}
+
+	CSV = &Codec{
+		Name:         "csv",
+		ContentTypes: []string{"text/csv"},
+		Encode:       encodeCSV,
+		TablesOnly:   true,
+	}
+)
+
+// Codecs in order of preference, the first is the default and what */*
+// gets
+var codecs = []*Codec{JSON, XML, MessagePack, CSV}
+
+func Register(c *Codec) {
+	codecs = append(codecs, c)
+}
+
+// Pick the codec for a response from an Accept header, honouring q values.
+// An empty header gets JSON. ok is false when nothing acceptable can encode
+// the response; table says whether it's a collection.
+func ForAccept(accept string, table bool) (*Codec, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return codecs[0], true
+	}
+
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, mediaRange{mediaType, q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	for _, r := range ranges {
+		for _, c := range codecs {
+			if c.TablesOnly && !table {
+				continue
+			}
+			if c.accepts(r.mediaType) {
+				return c, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Pick the codec to read a request body with. An empty Content-Type is
+// read as JSON.
+func ForContentType(contentType string) (*Codec, bool) {
+	if strings.TrimSpace(contentType) == "" {
+		return codecs[0], true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	for _, c := range codecs {
+		if c.Decode == nil {
+			continue
+		}
+		for _, t := range c.ContentTypes {
+			if t == mediaType {
+				return c, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Media types responses can be sent as, for error messages
+func Acceptable(table bool) []string {
+	var types []string
+	for _, c := range codecs {
+		if !c.TablesOnly || table {
+			types = append(types, c.ContentType())
+		}
+	}
+	return types
+}
+
+// Media types request bodies can be sent as, for error messages
+func Readable() []string {
+	var types []string
+	for _, c := range codecs {
+		if c.Decode != nil {
+			types = append(types, c.ContentType())
+		}
+	}
+	return types
+}
+
+// Report whether a media range from an Accept header covers this codec
+func (c *Codec) accepts(mediaRange string) bool {
+	if mediaRange == "*/*" {
+		return true
+	}
+	for _, t := range c.ContentTypes {
+		if t == mediaRange {
+			return true
+		}
+		if strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(t, strings.TrimSuffix(mediaRange, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+func encodeCSV(w io.Writer, v interface{}) error {
+	table, ok := v.(Table)
+	if !ok {
+		return ErrNotTable
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(table.Header()); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(table.Rows()); err != nil {
+		return err
+	}
+	return writer.Error()
+}
@@ -0,0 +1,197 @@
+package codec_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/codec"
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+)
+
+func TestForAccept(t *testing.T) {
+	cases := []struct {
+		accept string
+		table  bool
+		want   string
+	}{
+		{"", false, "json"},
+		{"*/*", false, "json"},
+		{"application/json; version=1", false, "json"},
+		{"application/xml", false, "xml"},
+		{"text/xml", false, "xml"},
+		{"application/x-msgpack", false, "msgpack"},
+		{"text/csv", true, "csv"},
+		{"text/csv;q=0.5, application/msgpack", true, "msgpack"},
+		{"application/json;q=0.1, application/xml;q=0.9", false, "xml"},
+		{"image/png, text/*", false, "xml"},
+		{"text/csv, */*;q=0.1", false, "json"},
+	}
+	for _, c := range cases {
+		got, ok := codec.ForAccept(c.accept, c.table)
+		if !ok {
+			t.Errorf("%q: expected %s, got nothing", c.accept, c.want)
+			continue
+		}
+		if got.Name != c.want {
+			t.Errorf("%q: expected %s, got %s", c.accept, c.want, got.Name)
+		}
+	}
+
+	for _, accept := range []string{"image/png", "text/csv", "application/json;q=0"} {
+		if got, ok := codec.ForAccept(accept, false); ok {
+			t.Errorf("%q: expected nothing acceptable, got %s", accept, got.Name)
+		}
+	}
+}
+
+func TestForContentType(t *testing.T) {
+	cases := map[string]string{
+		"":                                "json",
+		"application/json; charset=UTF-8": "json",
+		"text/xml":                        "xml",
+		"application/msgpack":             "msgpack",
+	}
+	for contentType, want := range cases {
+		got, ok := codec.ForContentType(contentType)
+		if !ok || got.Name != want {
+			t.Errorf("%q: expected %s, got %v", contentType, want, got)
+		}
+	}
+
+	// CSV is only ever a response
+	for _, contentType := range []string{"text/csv", "text/plain", "not a type"} {
+		if _, ok := codec.ForContentType(contentType); ok {
+			t.Errorf("%q: expected no decoder", contentType)
+		}
+	}
+}
+
+func TestMessagePackEncoding(t *testing.T) {
+	var buf bytes.Buffer
+	err := codec.MessagePack.Encode(&buf, handlers.CarPostPayload{Make: "Toyota", Model: "Camry", Color: "red", Year: 2018})
+	if err != nil {
+		t.Fatalf("Couldn't encode %s", err)
+	}
+
+	// fixmap of 4, keys sorted, 2018 as a uint16
+	expected := "84" +
+		"a5636f6c6f72" + "a3726564" +
+		"a46d616b65" + "a6546f796f7461" +
+		"a56d6f64656c" + "a543616d7279" +
+		"a479656172" + "cd07e2"
+	if hex.EncodeToString(buf.Bytes()) != expected {
+		t.Errorf("Expected %s, got %x", expected, buf.Bytes())
+	}
+}
+
+func TestMessagePackRoundTrip(t *testing.T) {
+	list := handlers.CarList{
+		Cars: []models.CarModel{
+			{Id: "a", Make: strings.Repeat("m", 40), Model: strings.Repeat("x", 300), Color: "red", Year: -5},
+			{Id: "b", Make: "Honda", Model: "Civic", Color: "blue", Year: 70000},
+		},
+		Limit:  500,
+		Offset: 100000,
+	}
+
+	var buf bytes.Buffer
+	if err := codec.MessagePack.Encode(&buf, list); err != nil {
+		t.Fatalf("Couldn't encode %s", err)
+	}
+
+	var decoded handlers.CarList
+	if err := codec.MessagePack.Decode(&buf, &decoded); err != nil {
+		t.Fatalf("Couldn't decode %s", err)
+	}
+	if len(decoded.Cars) != 2 || decoded.Cars[0] != list.Cars[0] || decoded.Cars[1] != list.Cars[1] {
+		t.Errorf("Expected %v, got %v", list.Cars, decoded.Cars)
+	}
+	if decoded.Limit != 500 || decoded.Offset != 100000 {
+		t.Errorf("Expected limit 500 and offset 100000, got %d and %d", decoded.Limit, decoded.Offset)
+	}
+}
+
+func TestMessagePackInvalid(t *testing.T) {
+	bodies := map[string][]byte{
+		"truncated":  {0x84, 0xa5, 'c', 'o'},
+		"long":       {0xdb, 0xff, 0xff, 0xff, 0xff, 'x'},
+		"int key":    {0x81, 0x01, 0x02},
+		"extension":  {0xd4, 0x01, 0x02},
+		"empty body": {},
+	}
+	for name, body := range bodies {
+		var payload handlers.CarPostPayload
+		if err := codec.MessagePack.Decode(bytes.NewReader(body), &payload); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestMessagePackLimits(t *testing.T) {
+	// A body of 0x91 bytes is an array holding an array holding ...
+	nested := bytes.Repeat([]byte{0x91}, 20<<20)
+	var payload handlers.CarPostPayload
+	err := codec.MessagePack.Decode(bytes.NewReader(nested), &payload)
+	if err == nil || !strings.Contains(err.Error(), "deeper") {
+		t.Errorf("Expected a depth error, got %v", err)
+	}
+
+	// Just inside the limit decodes
+	ok := append(bytes.Repeat([]byte{0x91}, codec.MaxMsgpackDepth), 0x01)
+	var value interface{}
+	if err := codec.MessagePack.Decode(bytes.NewReader(ok), &value); err != nil {
+		t.Errorf("Expected %d levels to decode, got %s", codec.MaxMsgpackDepth, err)
+	}
+
+	// An array 32 claiming more items than allowed
+	huge := []byte{0xdd, 0xff, 0xff, 0xff, 0xff}
+	if err := codec.MessagePack.Decode(bytes.NewReader(huge), &value); err == nil || !strings.Contains(err.Error(), "at most") {
+		t.Errorf("Expected an item count error, got %v", err)
+	}
+}
+
+func TestXML(t *testing.T) {
+	list := handlers.CarList{
+		Cars:  []models.CarModel{{Id: "a", Make: "Toyota", Model: "Camry", Color: "red", Year: 2018, Tenant: "acme"}},
+		Limit: 50,
+	}
+
+	var buf bytes.Buffer
+	if err := codec.XML.Encode(&buf, list); err != nil {
+		t.Fatalf("Couldn't encode %s", err)
+	}
+	expected := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<cars limit="50" offset="0"><car><id>a</id><model>Camry</model><make>Toyota</make><color>red</color><year>2018</year></car></cars>`
+	if buf.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, buf.String())
+	}
+
+	var payload handlers.CarPostPayload
+	body := `<car><make>Honda</make><model>Civic</model><color>blue</color><year>2016</year></car>`
+	if err := codec.XML.Decode(strings.NewReader(body), &payload); err != nil {
+		t.Fatalf("Couldn't decode %s", err)
+	}
+	if payload.Make != "Honda" || payload.Year != 2016 {
+		t.Errorf("Expected the Civic, got %v", payload)
+	}
+}
+
+func TestCSV(t *testing.T) {
+	list := handlers.CarList{Cars: []models.CarModel{{Id: "a", Make: "Toyota", Model: "Camry, LE", Color: "red", Year: 2018}}}
+
+	var buf bytes.Buffer
+	if err := codec.CSV.Encode(&buf, list); err != nil {
+		t.Fatalf("Couldn't encode %s", err)
+	}
+	expected := "id,make,model,color,year\na,Toyota,\"Camry, LE\",red,2018\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+
+	if err := codec.CSV.Encode(&buf, list.Cars[0]); err != codec.ErrNotTable {
+		t.Errorf("Expected ErrNotTable, got %v", err)
+	}
+}
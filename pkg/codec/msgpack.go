@@ -0,0 +1,327 @@
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// MessagePack goes through the value's JSON form, so it has the same field
+// names and omitempty rules as the JSON responses. Maps are written with
+// sorted keys, integers in their smallest encoding and other numbers as
+// float64.
+
+const (
+	// Deepest a body may nest arrays and maps, so a body of nested arrays
+	// can't run the decoder out of stack
+	MaxMsgpackDepth = 100
+	// Most items a body's arrays and maps may each hold
+	MaxMsgpackItems = 100000
+)
+
+func encodeMsgpack(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var generic interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&generic); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := writeMsgpack(&buf, generic); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func decodeMsgpack(r io.Reader, v interface{}) error {
+	generic, err := readMsgpack(bufio.NewReader(r), 0)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func writeMsgpack(buf *bytes.Buffer, v interface{}) error {
+	switch value := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if value {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			writeMsgpackInt(buf, i)
+			return nil
+		}
+		f, err := value.Float64()
+		if err != nil {
+			return err
+		}
+		buf.WriteByte(0xcb)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+	case string:
+		writeMsgpackHeader(buf, len(value), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		buf.WriteString(value)
+	case []interface{}:
+		writeMsgpackHeader(buf, len(value), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, item := range value {
+			if err := writeMsgpack(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		writeMsgpackHeader(buf, len(value), 0x80, 16, 0, 0xde, 0xdf)
+		for _, key := range keys {
+			writeMsgpack(buf, key)
+			if err := writeMsgpack(buf, value[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("Can't encode %T as MessagePack", v)
+	}
+	return nil
+}
+
+// Write a string, array or map header: the fix form when n is under fixMax,
+// otherwise the 8 (when there is one), 16 or 32 bit length form
+func writeMsgpackHeader(buf *bytes.Buffer, n int, fix byte, fixMax int, b8 byte, b16 byte, b32 byte) {
+	switch {
+	case n < fixMax:
+		buf.WriteByte(fix | byte(n))
+	case b8 != 0 && n <= math.MaxUint8:
+		buf.WriteByte(b8)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(b16)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(b32)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+func writeMsgpackInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= math.MaxInt8:
+		buf.WriteByte(byte(i))
+	case i >= -32 && i < 0:
+		buf.WriteByte(byte(int8(i)))
+	case i >= 0 && i <= math.MaxUint8:
+		buf.WriteByte(0xcc)
+		buf.WriteByte(byte(i))
+	case i >= 0 && i <= math.MaxUint16:
+		buf.WriteByte(0xcd)
+		binary.Write(buf, binary.BigEndian, uint16(i))
+	case i >= 0 && i <= math.MaxUint32:
+		buf.WriteByte(0xce)
+		binary.Write(buf, binary.BigEndian, uint32(i))
+	case i >= 0:
+		buf.WriteByte(0xcf)
+		binary.Write(buf, binary.BigEndian, uint64(i))
+	case i >= math.MinInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(int8(i)))
+	case i >= math.MinInt16:
+		buf.WriteByte(0xd1)
+		binary.Write(buf, binary.BigEndian, int16(i))
+	case i >= math.MinInt32:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(i))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, i)
+	}
+}
+
+var errMsgpackTruncated = errors.New("MessagePack body is truncated")
+
+// Read one value into the shapes encoding/json works with: nil, bool,
+// float64, int64, uint64, string, []interface{} and map[string]interface{}.
+// depth is how many arrays and maps the value is inside.
+func readMsgpack(r *bufio.Reader, depth int) (interface{}, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, errMsgpackTruncated
+	}
+
+	switch {
+	case b <= 0x7f:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b&0xe0 == 0xa0:
+		return readMsgpackString(r, int(b&0x1f))
+	case b&0xf0 == 0x90:
+		return readMsgpackArray(r, uint64(b&0x0f), depth)
+	case b&0xf0 == 0x80:
+		return readMsgpackMap(r, uint64(b&0x0f), depth)
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xca:
+		var bits uint32
+		err = binary.Read(r, binary.BigEndian, &bits)
+		return float64(math.Float32frombits(bits)), truncated(err)
+	case 0xcb:
+		var bits uint64
+		err = binary.Read(r, binary.BigEndian, &bits)
+		return math.Float64frombits(bits), truncated(err)
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := readMsgpackLength(r, b-0xcc)
+		return n, err
+	case 0xd0:
+		var i int8
+		err = binary.Read(r, binary.BigEndian, &i)
+		return int64(i), truncated(err)
+	case 0xd1:
+		var i int16
+		err = binary.Read(r, binary.BigEndian, &i)
+		return int64(i), truncated(err)
+	case 0xd2:
+		var i int32
+		err = binary.Read(r, binary.BigEndian, &i)
+		return int64(i), truncated(err)
+	case 0xd3:
+		var i int64
+		err = binary.Read(r, binary.BigEndian, &i)
+		return i, truncated(err)
+	case 0xd9, 0xda, 0xdb, 0xc4, 0xc5, 0xc6:
+		// Binary is read as a string, JSON has nothing closer
+		first := byte(0xd9)
+		if b <= 0xc6 {
+			first = 0xc4
+		}
+		n, err := readMsgpackLength(r, b-first)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackString(r, int(n))
+	case 0xdc, 0xdd:
+		n, err := readMsgpackLength(r, b-0xdc+1)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackArray(r, n, depth)
+	case 0xde, 0xdf:
+		n, err := readMsgpackLength(r, b-0xde+1)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackMap(r, n, depth)
+	}
+	return nil, fmt.Errorf("Unsupported MessagePack type 0x%x", b)
+}
+
+// Read a big endian unsigned integer of 1, 2, 4 or 8 bytes, picked by size
+// 0 to 3
+func readMsgpackLength(r *bufio.Reader, size byte) (uint64, error) {
+	var n uint64
+	for i := 0; i < 1<<size; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, errMsgpackTruncated
+		}
+		n = n<<8 | uint64(b)
+	}
+	return n, nil
+}
+
+// Copied rather than allocated up front so a bogus length can't claim more
+// memory than the body holds
+func readMsgpackString(r *bufio.Reader, n int) (interface{}, error) {
+	var data bytes.Buffer
+	if _, err := io.CopyN(&data, r, int64(n)); err != nil {
+		return nil, errMsgpackTruncated
+	}
+	return data.String(), nil
+}
+
+// Check an array or map of n items can be read at depth
+func checkMsgpackContainer(n uint64, depth int) error {
+	if depth >= MaxMsgpackDepth {
+		return fmt.Errorf("MessagePack body nests deeper than %d", MaxMsgpackDepth)
+	}
+	if n > MaxMsgpackItems {
+		return fmt.Errorf("MessagePack arrays and maps can hold at most %d items", MaxMsgpackItems)
+	}
+	return nil
+}
+
+func readMsgpackArray(r *bufio.Reader, n uint64, depth int) (interface{}, error) {
+	if err := checkMsgpackContainer(n, depth); err != nil {
+		return nil, err
+	}
+
+	items := []interface{}{}
+	for i := uint64(0); i < n; i++ {
+		item, err := readMsgpack(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func readMsgpackMap(r *bufio.Reader, n uint64, depth int) (interface{}, error) {
+	if err := checkMsgpackContainer(n, depth); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]interface{})
+	for i := uint64(0); i < n; i++ {
+		key, err := readMsgpack(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(string)
+		if !ok {
+			return nil, errors.New("MessagePack map keys must be strings")
+		}
+		if values[name], err = readMsgpack(r, depth+1); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+func truncated(err error) error {
+	if err != nil {
+		return errMsgpackTruncated
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package commands
+
+import (
+	"fmt"
+)
+
+// Run dispatches a CLI subcommand, e.g. `service import cars.csv`
+func Run(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("No command given")
+	}
+
+	switch args[0] {
+	case "import":
+		return Import(args[1:])
+	case "export":
+		return Export(args[1:])
+	}
+	return fmt.Errorf("Unknown command %s", args[0])
+}
@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/exporter"
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+)
+
+// The listing filters export takes, as flags. Their values go through
+// handlers.ParseCarFilter so the CLI filters exactly like the API.
+var exportFilterFlags = []struct {
+	flag, param, usage string
+}{
+	{"make", "make", "only cars of this make"},
+	{"model", "model", "only cars of this model"},
+	{"color", "color", "only cars of this color"},
+	{"year", "year", "only cars from this year"},
+	{"year-min", "year_min", "only cars from this year or later"},
+	{"year-max", "year_max", "only cars from this year or earlier"},
+	{"location-id", "location_id", "only cars at this location"},
+	{"status", "status", "only cars in this lifecycle status"},
+	{"currency", "currency", "only cars priced in this currency"},
+	{"price-min", "price_min", "only cars priced at least this, in minor units of -currency"},
+	{"price-max", "price_max", "only cars priced at most this, in minor units of -currency"},
+	{"mileage-min", "mileage_min", "only cars with at least this many kilometers"},
+	{"mileage-max", "mileage_max", "only cars with at most this many kilometers"},
+}
+
+// Export streams one tenant's cars matching the filters to stdout or a file.
+//
+//	service export [-tenant default] [-format ndjson|csv|parquet] [-make Toyota] [-year-min 2015] [-o cars.csv]
+func Export(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", exporter.FormatNDJSON, "csv, ndjson or parquet")
+	output := flags.String("o", "", "file to write, defaults to stdout")
+	tenant := flags.String("tenant", models.DefaultTenant, "tenant whose cars are exported")
+	params := make(map[string]*string, len(exportFilterFlags))
+	for _, filterFlag := range exportFilterFlags {
+		params[filterFlag.param] = flags.String(filterFlag.flag, "", filterFlag.usage)
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return fmt.Errorf("Usage: service export [flags]")
+	}
+	if *tenant == "" {
+		return fmt.Errorf("Need a -tenant to export")
+	}
+
+	query := url.Values{}
+	for param, value := range params {
+		if *value != "" {
+			query.Set(param, *value)
+		}
+	}
+	filter, err := handlers.ParseCarFilter(query)
+	if err != nil {
+		return err
+	}
+	filter.Tenant = *tenant
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	writer, err := exporter.NewWriter(*format, out)
+	if err != nil {
+		return err
+	}
+
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return err
+	}
+	defer clients.Close(&db)
+
+	return exporter.Export(&db, filter, writer)
+}
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/importer"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+)
+
+// Import loads cars from a CSV or NDJSON file into a tenant and prints the
+// import report, even when the file can't be read to the end.
+//
+//	service import [-tenant default] [-dry-run] [-batch-size 1000] [-format csv|ndjson] file.csv
+func Import(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "validate rows without saving them")
+	batchSize := flags.Int("batch-size", importer.DefaultBatchSize, "rows saved per COPY")
+	format := flags.String("format", "", "csv or ndjson, defaults to the file extension")
+	tenant := flags.String("tenant", models.DefaultTenant, "tenant the cars are imported into")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *tenant == "" {
+		return fmt.Errorf("Need a -tenant to import into")
+	}
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("Usage: service import [flags] file")
+	}
+	path := flags.Arg(0)
+
+	if *format == "" {
+		var ok bool
+		*format, ok = importer.FormatFromFilename(path)
+		if !ok {
+			return fmt.Errorf("Can't tell the format of %s, pass -format", path)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	rows, err := importer.NewReader(*format, file)
+	if err != nil {
+		return err
+	}
+
+	var db clients.DBClient
+	if !*dryRun {
+		db, err = clients.NewDbConn()
+		if err != nil {
+			return err
+		}
+		defer clients.Close(&db)
+	}
+
+	report, importErr := importer.Import(&db, rows, importer.Options{DryRun: *dryRun, BatchSize: *batchSize, Tenant: *tenant})
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+
+	if importErr != nil {
+		return importErr
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d rows failed to import", report.Failed, report.Total)
+	}
+	return nil
+}
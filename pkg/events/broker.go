@@ -0,0 +1,176 @@
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+)
+
+const (
+	Created = "created"
+	Updated = "updated"
+	Deleted = "deleted"
+	// A reservation ran out and the sweeper released the car
+	ReservationExpired = "reservation_expired"
+	// Changes may have been missed, so clients should refetch whatever they
+	// show. Sent to every tenant, with no car.
+	Reset = "reset"
+
+	// Events kept around for Last-Event-ID resume
+	DefaultReplaySize = 1000
+	// Events buffered per subscriber before it's considered too slow
+	SubscriberBuffer = 64
+)
+
+// A change to a car, as published by the cars_notify trigger. Expiry events
+// carry the reservation that ran out. Id is the broker's resume cursor,
+// assigned in delivery order; the trigger's sequence id can't be one since
+// NOTIFY delivers in commit order, not the order ids were taken.
+type Event struct {
+	Id          int64               `json:"id"`
+	Type        string              `json:"type"`
+	Tenant      string              `json:"tenant"`
+	Car         models.CarModel     `json:"car"`
+	Reservation *models.Reservation `json:"reservation,omitempty"`
+}
+
+// A reset only has its id and type
+func (e Event) MarshalJSON() ([]byte, error) {
+	if e.Type == Reset {
+		return json.Marshal(struct {
+			Id   int64  `json:"id"`
+			Type string `json:"type"`
+		}{e.Id, e.Type})
+	}
+	type event Event
+	return json.Marshal(event(e))
+}
+
+// Broker fans events out to subscribers and keeps a bounded replay buffer
+// so clients can resume from the last event they saw.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+	replay      []Event
+	next        int
+	full        bool
+	lastId      int64
+}
+
+// Broker fed by the database listener and used by the HTTP handlers. Its ids
+// start at the time it was created in microseconds, so a cursor saved
+// before a restart is behind every id handed out after it.
+var DefaultBroker = func() *Broker {
+	broker := NewBroker(DefaultReplaySize)
+	broker.lastId = time.Now().UnixNano() / int64(time.Microsecond)
+	return broker
+}()
+
+func NewBroker(replaySize int) *Broker {
+	return &Broker{
+		subscribers: make(map[*Subscription]struct{}),
+		replay:      make([]Event, replaySize),
+	}
+}
+
+// Subscription receives events for one tenant. C is closed when the
+// subscription ends, either through Close or because the subscriber fell too
+// far behind; a closed channel with Dropped() true means the latter.
+type Subscription struct {
+	C       <-chan Event
+	c       chan Event
+	tenant  string
+	broker  *Broker
+	dropped bool
+	closed  bool
+}
+
+// Subscribe to a tenant's events. Buffered events newer than lastId are
+// returned so the caller can send them before anything read from C; pass 0
+// to skip the replay.
+func (b *Broker) Subscribe(tenant string, lastId int64) (*Subscription, []Event) {
+	c := make(chan Event, SubscriberBuffer)
+	sub := &Subscription{C: c, c: c, tenant: tenant, broker: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var missed []Event
+	if lastId > 0 {
+		for _, event := range b.buffered() {
+			if event.Id > lastId && sub.matches(event) {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	b.subscribers[sub] = struct{}{}
+	return sub, missed
+}
+
+// Publish an event to every matching subscriber, giving it the next id.
+// Subscribers whose buffer is full are dropped rather than holding up
+// everyone else.
+func (b *Broker) Publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastId++
+	event.Id = b.lastId
+
+	if len(b.replay) > 0 {
+		b.replay[b.next] = event
+		b.next = (b.next + 1) % len(b.replay)
+		if b.next == 0 {
+			b.full = true
+		}
+	}
+
+	for sub := range b.subscribers {
+		if !sub.matches(event) {
+			continue
+		}
+		select {
+		case sub.c <- event:
+		default:
+			sub.dropped = true
+			b.remove(sub)
+		}
+	}
+}
+
+// Events in the replay buffer, oldest first. Callers hold the lock.
+func (b *Broker) buffered() []Event {
+	if !b.full {
+		return b.replay[:b.next]
+	}
+	return append(append([]Event{}, b.replay[b.next:]...), b.replay[:b.next]...)
+}
+
+func (b *Broker) remove(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	delete(b.subscribers, sub)
+	close(sub.c)
+}
+
+func (s *Subscription) matches(event Event) bool {
+	return event.Type == Reset || event.Tenant == s.tenant
+}
+
+// Report whether the subscription was ended for falling behind
+func (s *Subscription) Dropped() bool {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	return s.dropped
+}
+
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
@@ -0,0 +1,226 @@
+package events_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/events"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
+)
+
+func TestMain(m *testing.M) {
+	logging.ConfigureLogger("ERROR")
+	os.Exit(m.Run())
+}
+
+func event(id int64, tenant string) events.Event {
+	return events.Event{
+		Id:     id,
+		Type:   events.Created,
+		Tenant: tenant,
+		Car:    models.CarModel{Id: "9b2e3f4a-1c2d-4e5f-8a9b-0c1d2e3f4a5b", Make: "Toyota", Model: "Camry", Color: "red", Year: 2015},
+	}
+}
+
+func TestBrokerFiltersByTenant(t *testing.T) {
+	broker := events.NewBroker(10)
+	sub, _ := broker.Subscribe("acme", 0)
+	defer sub.Close()
+
+	broker.Publish(event(1, "default"))
+	broker.Publish(event(2, "acme"))
+
+	select {
+	case got := <-sub.C:
+		if got.Id != 2 {
+			t.Errorf("Expected: event 2, got: %d", got.Id)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected an event")
+	}
+
+	select {
+	case got := <-sub.C:
+		t.Errorf("Expected no more events, got: %+v", got)
+	default:
+	}
+}
+
+func TestBrokerReplay(t *testing.T) {
+	broker := events.NewBroker(3)
+	for id := int64(1); id <= 5; id++ {
+		broker.Publish(event(id, "default"))
+	}
+
+	sub, missed := broker.Subscribe("default", 3)
+	defer sub.Close()
+	if len(missed) != 2 || missed[0].Id != 4 || missed[1].Id != 5 {
+		t.Errorf("Expected events 4 and 5, got: %+v", missed)
+	}
+
+	// Older than the buffer: replay what's left
+	sub2, missed := broker.Subscribe("default", 1)
+	defer sub2.Close()
+	if len(missed) != 3 || missed[0].Id != 3 {
+		t.Errorf("Expected events 3 to 5, got: %+v", missed)
+	}
+
+	sub3, missed := broker.Subscribe("default", 0)
+	defer sub3.Close()
+	if len(missed) != 0 {
+		t.Errorf("Expected no replay without a Last-Event-ID, got: %+v", missed)
+	}
+}
+
+func TestBrokerNumbersInDeliveryOrder(t *testing.T) {
+	broker := events.NewBroker(10)
+	// A slower transaction took sequence id 7 but committed after 8
+	sub, _ := broker.Subscribe("default", 0)
+	broker.Publish(event(8, "default"))
+	lastId := (<-sub.C).Id
+	sub.Close()
+
+	broker.Publish(event(7, "default"))
+	resumed, missed := broker.Subscribe("default", lastId)
+	defer resumed.Close()
+	if len(missed) != 1 || missed[0].Id <= lastId {
+		t.Errorf("Expected the late event after %d on resume, got: %+v", lastId, missed)
+	}
+}
+
+func TestBrokerDropsSlowSubscriber(t *testing.T) {
+	broker := events.NewBroker(0)
+	slow, _ := broker.Subscribe("default", 0)
+
+	for id := int64(1); id <= events.SubscriberBuffer+1; id++ {
+		broker.Publish(event(id, "default"))
+	}
+
+	if !slow.Dropped() {
+		t.Errorf("Expected the slow subscriber to be dropped")
+	}
+
+	count := 0
+	for range slow.C {
+		count++
+	}
+	if count != events.SubscriberBuffer {
+		t.Errorf("Expected %d buffered events before the close, got %d", events.SubscriberBuffer, count)
+	}
+	slow.Close()
+}
+
+func TestBrokerReset(t *testing.T) {
+	broker := events.NewBroker(10)
+	broker.Publish(event(1, "default"))
+	acme, _ := broker.Subscribe("acme", 0)
+	defer acme.Close()
+
+	broker.Publish(events.Event{Type: events.Reset})
+
+	select {
+	case got := <-acme.C:
+		if got.Type != events.Reset {
+			t.Errorf("Expected a reset, got: %+v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected every tenant to get the reset")
+	}
+
+	// Resuming from before the reset replays it, so the gap isn't hidden
+	resumed, missed := broker.Subscribe("default", 1)
+	defer resumed.Close()
+	if len(missed) != 1 || missed[0].Type != events.Reset {
+		t.Fatalf("Expected the reset on resume, got: %+v", missed)
+	}
+
+	data, _ := json.Marshal(missed[0])
+	if string(data) != fmt.Sprintf(`{"id":%d,"type":"reset"}`, missed[0].Id) {
+		t.Errorf("Expected a reset without a car, got %s", data)
+	}
+	if err := openapi.ValidateSchema("CarEvent", data); err != nil {
+		t.Errorf("Reset doesn't match CarEvent schema: %s", err)
+	}
+	if data, _ = json.Marshal(event(2, "default")); !strings.Contains(string(data), `"car":{`) {
+		t.Errorf("Expected other events to keep their car, got %s", data)
+	}
+}
+
+func TestStreamHandler(t *testing.T) {
+	broker := events.NewBroker(10)
+	broker.Publish(event(1, "default"))
+	broker.Publish(event(2, "default"))
+
+	srv := httptest.NewServer(events.StreamHandler(broker))
+	defer srv.Close()
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	if err != nil {
+		t.Fatalf("Error while building request: %s", err)
+	}
+	req.Header.Set("X-CARS-ID", "1234")
+	req.Header.Set("Last-Event-ID", "1")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Error while connecting: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got: %s", ct)
+	}
+
+	// Publish once the subscription is live
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		broker.Publish(event(3, "acme"))
+		broker.Publish(event(4, "default"))
+	}()
+
+	var ids, data []string
+	reader := bufio.NewReader(resp.Body)
+	for len(ids) < 2 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Error while reading stream: %s", err)
+		}
+		if strings.HasPrefix(line, "id: ") {
+			ids = append(ids, strings.TrimSpace(strings.TrimPrefix(line, "id: ")))
+		}
+		if strings.HasPrefix(line, "data: ") {
+			data = append(data, strings.TrimPrefix(line, "data: "))
+		}
+	}
+
+	if ids[0] != "2" || ids[1] != "4" {
+		t.Errorf("Expected events 2 and 4, got: %v", ids)
+	}
+	if err := openapi.ValidateSchema("CarEvent", []byte(data[0])); err != nil {
+		t.Errorf("Event doesn't match CarEvent schema: %s", err)
+	}
+}
+
+func TestStreamHandlerUnauthorized(t *testing.T) {
+	req, err := http.NewRequest("GET", "/v1/cars/events", nil)
+	if err != nil {
+		t.Fatalf("Error while building request: %s", err)
+	}
+	req.Header.Set("X-CARS-ID", "nope")
+
+	rr := httptest.NewRecorder()
+	events.StreamHandler(events.NewBroker(1)).ServeHTTP(rr, req)
+
+	if rr.Code != 401 {
+		t.Errorf("Expected: %d, but got: %d", 401, rr.Code)
+	}
+}
@@ -0,0 +1,33 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+)
+
+// Channel the cars_notify trigger publishes on
+const Channel = "car_changes"
+
+// Run feeds car change notifications from Postgres into the broker until ctx
+// is done.
+func Run(ctx context.Context, broker *Broker) error {
+	log := logging.GetLog(ctx)
+
+	return clients.Listen(ctx, Channel, func(payload string) {
+		var event Event
+		if err := json.Unmarshal([]byte(payload), &event); err != nil {
+			log.WithError(err).Error("Events: could not decode notification")
+			return
+		}
+		event.Car.Tenant = event.Tenant
+		broker.Publish(event)
+	}, func() {
+		// Notifications sent while disconnected are gone, so subscribers
+		// can't know what they missed: tell them to refetch
+		log.Warn("Events: reconnected, changes made while disconnected were missed, resetting subscribers")
+		broker.Publish(Event{Type: Reset})
+	})
+}
@@ -0,0 +1,361 @@
+package events
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/gorilla/websocket"
+)
+
+// What to do with a client that can't keep up
+const (
+	// Discard events and tell the client how many it missed
+	SlowDrop = "drop"
+	// Close the connection
+	SlowDisconnect = "disconnect"
+)
+
+// Message types. Clients send auth, subscribe, unsubscribe and ping; the
+// server sends ack, error, event, heartbeat, pong and dropped.
+const (
+	MessageAuth        = "auth"
+	MessageSubscribe   = "subscribe"
+	MessageUnsubscribe = "unsubscribe"
+	MessagePing        = "ping"
+	MessageAck         = "ack"
+	MessageError       = "error"
+	MessageEvent       = "event"
+	MessageHeartbeat   = "heartbeat"
+	MessagePong        = "pong"
+	MessageDropped     = "dropped"
+)
+
+var (
+	SocketHeartbeatInterval = 30 * time.Second
+	// Messages queued per connection before it counts as slow
+	SocketSendBuffer = 64
+	// Subscriptions a single connection can hold
+	MaxSocketSubscriptions = 16
+
+	socketAuthTimeout  = 10 * time.Second
+	socketWriteTimeout = 10 * time.Second
+	socketReadLimit    = int64(4096)
+)
+
+var upgrader = websocket.Upgrader{
+	// Browser clients are served from other origins; access is controlled by
+	// the X-CARS-ID credential instead
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// A message in either direction. Id is the client chosen subscription id.
+type SocketMessage struct {
+	Type    string            `json:"type"`
+	Id      string            `json:"id,omitempty"`
+	Ack     string            `json:"ack,omitempty"`
+	CarsId  string            `json:"cars_id,omitempty"`
+	Filter  *models.CarFilter `json:"filter,omitempty"`
+	Events  []string          `json:"events,omitempty"`
+	Event   *Event            `json:"event,omitempty"`
+	Dropped int               `json:"dropped,omitempty"`
+	Message string            `json:"message,omitempty"`
+}
+
+type socketSubscription struct {
+	filter models.CarFilter
+	events map[string]bool
+}
+
+func (s socketSubscription) matches(event Event) bool {
+	// Every subscription may have missed something
+	if event.Type == Reset {
+		return true
+	}
+	if len(s.events) > 0 && !s.events[event.Type] {
+		return false
+	}
+	return s.filter.Matches(event.Car)
+}
+
+type socket struct {
+	conn   *websocket.Conn
+	send   chan SocketMessage
+	policy string
+	done   chan struct{}
+	once   sync.Once
+
+	mu      sync.Mutex
+	subs    map[string]socketSubscription
+	dropped int
+}
+
+// SocketHandler serves /cars/ws: one connection carrying any number of
+// filtered subscriptions to the tenant's car changes. Clients authenticate
+// with the X-CARS-ID header, or when they can't set headers (browsers) with
+// an auth message first: {"type": "auth", "cars_id": "..."}.
+func SocketHandler(broker *Broker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logging.GetLog(r.Context())
+
+		var tenant string
+		if auth := r.Header.Get("X-CARS-ID"); auth != "" {
+			var err error
+			tenant, err = handlers.TenantForAuthId(auth)
+			if err != nil {
+				log.Error("Unauthorized Auth Id: ", err)
+				http.Error(w, "Unauthorized", 401)
+				return
+			}
+		}
+
+		policy := r.URL.Query().Get("slow")
+		if policy == "" {
+			policy = SlowDisconnect
+		}
+		if policy != SlowDrop && policy != SlowDisconnect {
+			logging.FormatError(r.Context(), w, 400, logging.JsonError{
+				Code:    "400",
+				Message: fmt.Sprintf("slow must be %s or %s", SlowDrop, SlowDisconnect),
+			})
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// Upgrade already answered the request
+			log.WithError(err).Error("Socket: upgrade failed")
+			return
+		}
+
+		s := &socket{
+			conn:   conn,
+			send:   make(chan SocketMessage, SocketSendBuffer),
+			policy: policy,
+			done:   make(chan struct{}),
+			subs:   make(map[string]socketSubscription),
+		}
+		s.serve(r, broker, tenant)
+	}
+}
+
+func (s *socket) serve(r *http.Request, broker *Broker, tenant string) {
+	log := logging.GetLog(r.Context())
+	defer s.close(websocket.CloseNormalClosure, "")
+
+	s.conn.SetReadLimit(socketReadLimit)
+
+	if tenant == "" {
+		var err error
+		tenant, err = s.authenticate()
+		if err != nil {
+			log.Error("Socket: ", err)
+			s.writeNow(SocketMessage{Type: MessageError, Message: err.Error()})
+			s.close(websocket.ClosePolicyViolation, "unauthorized")
+			return
+		}
+	}
+	s.writeNow(SocketMessage{Type: MessageAck, Ack: MessageAuth})
+
+	sub, _ := broker.Subscribe(tenant, 0)
+	defer sub.Close()
+
+	go s.writer()
+	go s.reader()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				log.Warn("Socket: subscriber fell behind, closing connection")
+				s.close(websocket.CloseTryAgainLater, "slow consumer")
+				return
+			}
+
+			s.mu.Lock()
+			var matched []string
+			for id, subscription := range s.subs {
+				if subscription.matches(event) {
+					matched = append(matched, id)
+				}
+			}
+			s.mu.Unlock()
+
+			for _, id := range matched {
+				e := event
+				s.enqueue(SocketMessage{Type: MessageEvent, Id: id, Event: &e})
+			}
+		}
+	}
+}
+
+// Wait for the first message to be an auth message with a valid credential
+func (s *socket) authenticate() (string, error) {
+	s.conn.SetReadDeadline(time.Now().Add(socketAuthTimeout))
+	defer s.conn.SetReadDeadline(time.Time{})
+
+	var msg SocketMessage
+	if err := s.conn.ReadJSON(&msg); err != nil {
+		return "", fmt.Errorf("Expected an auth message: %s", err)
+	}
+	if msg.Type != MessageAuth {
+		return "", fmt.Errorf("Expected an auth message, got %q", msg.Type)
+	}
+	return handlers.TenantForAuthId(msg.CarsId)
+}
+
+func (s *socket) reader() {
+	defer s.close(websocket.CloseNormalClosure, "")
+
+	extend := func() {
+		s.conn.SetReadDeadline(time.Now().Add(2 * SocketHeartbeatInterval))
+	}
+	extend()
+	s.conn.SetPongHandler(func(string) error {
+		extend()
+		return nil
+	})
+
+	for {
+		var msg SocketMessage
+		if err := s.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		extend()
+		s.handle(msg)
+	}
+}
+
+func (s *socket) handle(msg SocketMessage) {
+	switch msg.Type {
+	case MessageSubscribe:
+		if err := s.subscribe(msg); err != nil {
+			s.enqueue(SocketMessage{Type: MessageError, Id: msg.Id, Message: err.Error()})
+			return
+		}
+		s.enqueue(SocketMessage{Type: MessageAck, Ack: MessageSubscribe, Id: msg.Id})
+	case MessageUnsubscribe:
+		s.mu.Lock()
+		_, ok := s.subs[msg.Id]
+		delete(s.subs, msg.Id)
+		s.mu.Unlock()
+
+		if !ok {
+			s.enqueue(SocketMessage{Type: MessageError, Id: msg.Id, Message: "No such subscription"})
+			return
+		}
+		s.enqueue(SocketMessage{Type: MessageAck, Ack: MessageUnsubscribe, Id: msg.Id})
+	case MessagePing:
+		s.enqueue(SocketMessage{Type: MessagePong})
+	default:
+		s.enqueue(SocketMessage{Type: MessageError, Message: fmt.Sprintf("Unknown message type %q", msg.Type)})
+	}
+}
+
+func (s *socket) subscribe(msg SocketMessage) error {
+	if msg.Id == "" {
+		return fmt.Errorf("subscribe needs an id")
+	}
+
+	subscription := socketSubscription{events: make(map[string]bool)}
+	if msg.Filter != nil {
+		subscription.filter = *msg.Filter
+	}
+	for _, eventType := range msg.Events {
+		if eventType != Created && eventType != Updated && eventType != Deleted && eventType != ReservationExpired {
+			return fmt.Errorf("Unknown event type %q", eventType)
+		}
+		subscription.events[eventType] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subs[msg.Id]; ok {
+		return fmt.Errorf("Subscription %s already exists", msg.Id)
+	}
+	if len(s.subs) >= MaxSocketSubscriptions {
+		return fmt.Errorf("A connection can have at most %d subscriptions", MaxSocketSubscriptions)
+	}
+	s.subs[msg.Id] = subscription
+	return nil
+}
+
+// Queue a message for the writer without ever blocking. A full queue means
+// the client isn't keeping up, so the slow policy kicks in.
+func (s *socket) enqueue(msg SocketMessage) {
+	select {
+	case s.send <- msg:
+		return
+	case <-s.done:
+		return
+	default:
+	}
+
+	if s.policy == SlowDrop {
+		s.mu.Lock()
+		s.dropped++
+		s.mu.Unlock()
+		return
+	}
+	s.close(websocket.CloseTryAgainLater, "slow consumer")
+}
+
+// The only goroutine writing data frames once the connection is set up
+func (s *socket) writer() {
+	heartbeat := time.NewTicker(SocketHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-heartbeat.C:
+			deadline := time.Now().Add(socketWriteTimeout)
+			if err := s.conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+				s.close(websocket.CloseGoingAway, "")
+				return
+			}
+			if err := s.writeNow(SocketMessage{Type: MessageHeartbeat}); err != nil {
+				return
+			}
+		case msg := <-s.send:
+			s.mu.Lock()
+			dropped := s.dropped
+			s.dropped = 0
+			s.mu.Unlock()
+
+			if dropped > 0 {
+				if err := s.writeNow(SocketMessage{Type: MessageDropped, Dropped: dropped}); err != nil {
+					return
+				}
+			}
+			if err := s.writeNow(msg); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *socket) writeNow(msg SocketMessage) error {
+	s.conn.SetWriteDeadline(time.Now().Add(socketWriteTimeout))
+	err := s.conn.WriteJSON(msg)
+	if err != nil {
+		s.close(websocket.CloseGoingAway, "")
+	}
+	return err
+}
+
+func (s *socket) close(code int, reason string) {
+	s.once.Do(func() {
+		close(s.done)
+		deadline := time.Now().Add(time.Second)
+		s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+		s.conn.Close()
+	})
+}
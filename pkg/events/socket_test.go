@@ -0,0 +1,157 @@
+package events_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/events"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
+	"github.com/gorilla/websocket"
+)
+
+// Dial a socket served by a test server; call the returned func to close both
+func dialSocket(t *testing.T, broker *events.Broker, header http.Header) (*websocket.Conn, func()) {
+	srv := httptest.NewServer(events.SocketHandler(broker))
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		srv.Close()
+		t.Fatalf("Error while dialing: %s", err)
+	}
+	return conn, func() {
+		conn.Close()
+		srv.Close()
+	}
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) events.SocketMessage {
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Error while reading message: %s", err)
+	}
+	if err := openapi.ValidateSchema("SocketMessage", data); err != nil {
+		t.Errorf("Message doesn't match SocketMessage schema: %s", err)
+	}
+
+	var msg events.SocketMessage
+	json.Unmarshal(data, &msg)
+	return msg
+}
+
+func carEvent(id int64, tenant string, make string, year int) events.Event {
+	return events.Event{
+		Id:     id,
+		Type:   events.Created,
+		Tenant: tenant,
+		Car:    models.CarModel{Id: "9b2e3f4a-1c2d-4e5f-8a9b-0c1d2e3f4a5b", Make: make, Model: "Camry", Color: "red", Year: year},
+	}
+}
+
+func TestSocketSubscribeWithFilter(t *testing.T) {
+	broker := events.NewBroker(10)
+	header := http.Header{}
+	header.Set("X-CARS-ID", "1234")
+	conn, stop := dialSocket(t, broker, header)
+	defer stop()
+
+	if msg := readMessage(t, conn); msg.Type != events.MessageAck || msg.Ack != events.MessageAuth {
+		t.Fatalf("Expected an auth ack, got: %+v", msg)
+	}
+
+	conn.WriteJSON(events.SocketMessage{
+		Type:   events.MessageSubscribe,
+		Id:     "toyotas",
+		Filter: &models.CarFilter{Make: "Toyota", YearMin: 2015},
+	})
+	if msg := readMessage(t, conn); msg.Type != events.MessageAck || msg.Id != "toyotas" {
+		t.Fatalf("Expected a subscribe ack, got: %+v", msg)
+	}
+
+	broker.Publish(carEvent(1, "default", "Honda", 2018))
+	broker.Publish(carEvent(2, "default", "Toyota", 2010))
+	broker.Publish(carEvent(3, "acme", "Toyota", 2018))
+	broker.Publish(carEvent(4, "default", "Toyota", 2018))
+
+	msg := readMessage(t, conn)
+	if msg.Type != events.MessageEvent || msg.Id != "toyotas" || msg.Event == nil || msg.Event.Id != 4 {
+		t.Fatalf("Expected event 4 for toyotas, got: %+v", msg)
+	}
+
+	// A reset gets past any filter
+	broker.Publish(events.Event{Type: events.Reset})
+	msg = readMessage(t, conn)
+	if msg.Type != events.MessageEvent || msg.Id != "toyotas" || msg.Event == nil || msg.Event.Type != events.Reset {
+		t.Fatalf("Expected a reset for toyotas, got: %+v", msg)
+	}
+
+	conn.WriteJSON(events.SocketMessage{Type: events.MessageUnsubscribe, Id: "toyotas"})
+	if msg := readMessage(t, conn); msg.Type != events.MessageAck || msg.Ack != events.MessageUnsubscribe {
+		t.Fatalf("Expected an unsubscribe ack, got: %+v", msg)
+	}
+
+	broker.Publish(carEvent(5, "default", "Toyota", 2018))
+	conn.WriteJSON(events.SocketMessage{Type: events.MessagePing})
+	if msg := readMessage(t, conn); msg.Type != events.MessagePong {
+		t.Fatalf("Expected a pong and no more events, got: %+v", msg)
+	}
+}
+
+func TestSocketAuthMessage(t *testing.T) {
+	conn, stop := dialSocket(t, events.NewBroker(1), nil)
+	defer stop()
+
+	conn.WriteJSON(events.SocketMessage{Type: events.MessageAuth, CarsId: "1234"})
+	if msg := readMessage(t, conn); msg.Type != events.MessageAck || msg.Ack != events.MessageAuth {
+		t.Fatalf("Expected an auth ack, got: %+v", msg)
+	}
+}
+
+func TestSocketAuthRejected(t *testing.T) {
+	conn, stop := dialSocket(t, events.NewBroker(1), nil)
+	defer stop()
+
+	conn.WriteJSON(events.SocketMessage{Type: events.MessageAuth, CarsId: "nope"})
+	if msg := readMessage(t, conn); msg.Type != events.MessageError {
+		t.Fatalf("Expected an error, got: %+v", msg)
+	}
+
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+		t.Errorf("Expected a policy violation close, got: %v", err)
+	}
+}
+
+func TestSocketInvalidSubscribe(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-CARS-ID", "1234")
+	conn, stop := dialSocket(t, events.NewBroker(1), header)
+	defer stop()
+	readMessage(t, conn)
+
+	conn.WriteJSON(events.SocketMessage{Type: events.MessageSubscribe, Id: "bad", Events: []string{"exploded"}})
+	if msg := readMessage(t, conn); msg.Type != events.MessageError || msg.Id != "bad" {
+		t.Fatalf("Expected an error for bad, got: %+v", msg)
+	}
+}
+
+func TestSocketUnauthorizedHeader(t *testing.T) {
+	req, err := http.NewRequest("GET", "/v1/cars/ws", nil)
+	if err != nil {
+		t.Fatalf("Error while building request: %s", err)
+	}
+	req.Header.Set("X-CARS-ID", "nope")
+
+	rr := httptest.NewRecorder()
+	events.SocketHandler(events.NewBroker(1)).ServeHTTP(rr, req)
+
+	if rr.Code != 401 {
+		t.Errorf("Expected: %d, but got: %d", 401, rr.Code)
+	}
+}
@@ -0,0 +1,122 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+)
+
+// How often an idle stream gets a comment so proxies don't time it out
+var HeartbeatInterval = 15 * time.Second
+
+// StreamHandler serves the caller's tenant's car changes as Server-Sent
+// Events. Clients resume with the Last-Event-ID header (or last_event_id
+// query param) as long as the event is still in the broker's replay buffer.
+func StreamHandler(broker *Broker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logging.GetLog(r.Context())
+
+		tenant, err := handlers.TenantForAuthId(r.Header.Get("X-CARS-ID"))
+		if err != nil {
+			log.Error("Unauthorized Auth Id: ", err)
+			http.Error(w, "Unauthorized", 401)
+			return
+		}
+
+		if r.Method != "GET" {
+			http.Error(w, "Invalid Request Method.", 405)
+			return
+		}
+
+		statusCode, err := StreamEvents(w, r, broker, tenant)
+		if err != nil {
+			log.Error(err)
+			jsonErr := &logging.JsonError{
+				Status:  http.StatusText(statusCode),
+				Code:    strconv.Itoa(statusCode),
+				Message: err.Error(),
+			}
+			logging.FormatError(r.Context(), w, statusCode, *jsonErr)
+		}
+	}
+}
+
+func StreamEvents(w http.ResponseWriter, r *http.Request, broker *Broker, tenant string) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("StreamEvents: Processing car events stream...")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return 500, errors.New("Streaming is not supported")
+	}
+
+	lastId := r.Header.Get("Last-Event-ID")
+	if lastId == "" {
+		lastId = r.URL.Query().Get("last_event_id")
+	}
+
+	var resumeFrom int64
+	if lastId != "" {
+		parsed, err := strconv.ParseInt(lastId, 10, 64)
+		if err != nil {
+			return 400, errors.New("Last-Event-ID must be an event id")
+		}
+		resumeFrom = parsed
+	}
+
+	sub, missed := broker.Subscribe(tenant, resumeFrom)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: 3000\n\n")
+	for _, event := range missed {
+		if err := writeEvent(w, event); err != nil {
+			return 200, nil
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(HeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return 200, nil
+		case <-heartbeat.C:
+			fmt.Fprintf(w, ": heartbeat\n\n")
+		case event, ok := <-sub.C:
+			if !ok {
+				// Dropped for falling behind; the client reconnects with
+				// Last-Event-ID and picks up from the replay buffer
+				log.Warn("StreamEvents: subscriber fell behind, closing stream")
+				return 200, nil
+			}
+			if err := writeEvent(w, event); err != nil {
+				return 200, nil
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Id, event.Type, data)
+	return err
+}
@@ -0,0 +1,127 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+
+	// Rows pulled from the server-side cursor per FETCH
+	DefaultFetchSize = 1000
+)
+
+// Content types for each format, the first one is what we answer with
+var contentTypes = map[string][]string{
+	FormatCSV:     {"text/csv"},
+	FormatNDJSON:  {"application/x-ndjson", "application/ndjson"},
+	FormatParquet: {"application/vnd.apache.parquet", "application/x-parquet"},
+}
+
+// Writer encodes cars one at a time. Close writes any trailer the format
+// needs; it doesn't close the underlying io.Writer.
+type Writer interface {
+	Write(models.CarModel) error
+	Close() error
+}
+
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatNDJSON:
+		return &ndjsonWriter{encoder: json.NewEncoder(w)}, nil
+	case FormatParquet:
+		return newParquetWriter(w), nil
+	}
+	return nil, fmt.Errorf("Unsupported export format %s", format)
+}
+
+func ContentType(format string) string {
+	return contentTypes[format][0]
+}
+
+// Pick an export format from an explicit format name, falling back to the
+// Accept header and then NDJSON. ok is false when the client only accepts
+// formats we can't produce.
+func Negotiate(format string, accept string) (string, bool) {
+	if format != "" {
+		_, ok := contentTypes[format]
+		return format, ok
+	}
+
+	if strings.TrimSpace(accept) == "" {
+		return FormatNDJSON, true
+	}
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if mediaType == "*/*" || mediaType == "application/*" {
+			return FormatNDJSON, true
+		}
+		for name, types := range contentTypes {
+			for _, t := range types {
+				if t == mediaType {
+					return name, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// Export streams every car matching the filter into w
+func Export(db *clients.DBClient, filter models.CarFilter, w Writer) error {
+	err := models.StreamCars(db, filter, DefaultFetchSize, w.Write)
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"id", "make", "model", "color", "year"}); err != nil {
+		return nil, err
+	}
+	return &csvWriter{writer: writer}, nil
+}
+
+func (c *csvWriter) Write(car models.CarModel) error {
+	return c.writer.Write([]string{car.Id, car.Make, car.Model, car.Color, strconv.Itoa(car.Year)})
+}
+
+func (c *csvWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (n *ndjsonWriter) Write(car models.CarModel) error {
+	return n.encoder.Encode(car)
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,118 @@
+package exporter_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/exporter"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/server"
+)
+
+func TestMain(m *testing.M) {
+	logging.ConfigureLogger("ERROR")
+	os.Exit(m.Run())
+}
+
+var cars = []models.CarModel{
+	{Id: "9b2e3f4a-1c2d-4e5f-8a9b-0c1d2e3f4a5b", Make: "Toyota", Model: "Camry", Color: "red", Year: 2015},
+	{Id: "0c1d2e3f-4a5b-4e5f-8a9b-9b2e3f4a1c2d", Make: "Honda", Model: "Civic, Si", Color: "blue", Year: 2016},
+}
+
+func write(t *testing.T, format string) []byte {
+	var buf bytes.Buffer
+	w, err := exporter.NewWriter(format, &buf)
+	if err != nil {
+		t.Fatalf("Couldn't create writer: %s", err)
+	}
+	for _, car := range cars {
+		if err := w.Write(car); err != nil {
+			t.Fatalf("Couldn't write car: %s", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Couldn't close writer: %s", err)
+	}
+	return buf.Bytes()
+}
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		format, accept, expected string
+		ok                       bool
+	}{
+		{"", "", exporter.FormatNDJSON, true},
+		{"csv", "application/x-ndjson", exporter.FormatCSV, true},
+		{"", "text/csv", exporter.FormatCSV, true},
+		{"", "application/vnd.apache.parquet", exporter.FormatParquet, true},
+		{"", "text/html, */*;q=0.8", exporter.FormatNDJSON, true},
+		{"", "text/html", "", false},
+		{"xml", "", "xml", false},
+	}
+
+	for _, c := range cases {
+		got, ok := exporter.Negotiate(c.format, c.accept)
+		if got != c.expected || ok != c.ok {
+			t.Errorf("Negotiate(%q, %q): expected %q %v, got %q %v", c.format, c.accept, c.expected, c.ok, got, ok)
+		}
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	expected := "id,make,model,color,year\n" +
+		"9b2e3f4a-1c2d-4e5f-8a9b-0c1d2e3f4a5b,Toyota,Camry,red,2015\n" +
+		"0c1d2e3f-4a5b-4e5f-8a9b-9b2e3f4a1c2d,Honda,\"Civic, Si\",blue,2016\n"
+
+	if got := string(write(t, exporter.FormatCSV)); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(string(write(t, exporter.FormatNDJSON))), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected: 2 lines, got: %d", len(lines))
+	}
+	if !strings.Contains(lines[1], `"model":"Civic, Si"`) {
+		t.Errorf("Unexpected second line: %s", lines[1])
+	}
+}
+
+func TestParquetWriter(t *testing.T) {
+	got := write(t, exporter.FormatParquet)
+
+	if !bytes.HasPrefix(got, []byte("PAR1")) || !bytes.HasSuffix(got, []byte("PAR1")) {
+		t.Fatalf("Expected PAR1 magic at both ends")
+	}
+
+	footer := binary.LittleEndian.Uint32(got[len(got)-8 : len(got)-4])
+	if int(footer) >= len(got)-12 {
+		t.Errorf("Footer length %d doesn't fit in a %d byte file", footer, len(got))
+	}
+	if !bytes.Contains(got, []byte("Civic, Si")) {
+		t.Errorf("Expected values to be PLAIN encoded in the file")
+	}
+}
+
+func TestExportHandlerNotAcceptable(t *testing.T) {
+	req, err := http.NewRequest("GET", "/v1/cars:export", nil)
+	if err != nil {
+		t.Errorf("Error while building request: %s", err)
+	}
+	req.Header.Set("X-CARS-ID", "1234")
+	req.Header.Set("Accept", "application/xml")
+
+	rr := httptest.NewRecorder()
+	handler := server.New()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != 406 {
+		t.Errorf("Expected: %d, but got: %d", 406, rr.Code)
+	}
+}
@@ -0,0 +1,85 @@
+package exporter
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+)
+
+func ExportHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.GetLog(r.Context())
+
+	err := handlers.ValidateAuthId(r.Header.Get("X-CARS-ID"))
+	if err != nil {
+		log.Error("Unauthorized Auth Id: ", err)
+		http.Error(w, "Unauthorized", 401)
+		return
+	}
+
+	if r.Method != "GET" {
+		http.Error(w, "Invalid Request Method.", 405)
+		return
+	}
+
+	statusCode, err := ExportCars(w, r)
+	if err != nil {
+		log.Error(err)
+		// Once the body has started the status is gone, all we can do is log
+		if statusCode == 200 {
+			return
+		}
+		jsonErr := &logging.JsonError{
+			Status:  http.StatusText(statusCode),
+			Code:    strconv.Itoa(statusCode),
+			Message: err.Error(),
+		}
+		logging.FormatError(r.Context(), w, statusCode, *jsonErr)
+	}
+}
+
+func ExportCars(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("ExportCars: Processing Export Cars endpoint...")
+
+	query := r.URL.Query()
+	format, ok := Negotiate(query.Get("format"), r.Header.Get("Accept"))
+	if !ok {
+		return 406, errors.New("Export format must be csv, ndjson or parquet")
+	}
+
+	log.Debug("ExportCars: Parsing filters...")
+	filter, err := handlers.ParseCarFilter(query)
+	if err != nil {
+		return 400, err
+	}
+	filter.Tenant, _ = handlers.TenantForAuthId(r.Header.Get("X-CARS-ID"))
+
+	// get db conn
+	log.Debug("ExportCars: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	w.Header().Set("Content-Type", ContentType(format))
+	w.Header().Set("Content-Disposition", `attachment; filename="cars.`+format+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	writer, err := NewWriter(format, w)
+	if err != nil {
+		return 200, err
+	}
+
+	log.Debug("ExportCars: Streaming cars...")
+	err = Export(&db, filter, writer)
+	if err != nil {
+		return 200, err
+	}
+	return 200, nil
+}
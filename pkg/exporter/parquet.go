@@ -0,0 +1,321 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+)
+
+// Rows buffered per row group. Memory use is bounded by this, not by the
+// number of rows exported.
+const ParquetRowGroupSize = 10000
+
+// Parquet physical types, encodings and friends from parquet.thrift
+const (
+	parquetInt32     = 1
+	parquetByteArray = 6
+
+	parquetRequired = 0
+	parquetUTF8     = 0
+
+	parquetPlain = 0
+	parquetRLE   = 3
+
+	parquetDataPage     = 0
+	parquetUncompressed = 0
+)
+
+var parquetMagic = []byte("PAR1")
+
+type parquetColumn struct {
+	name      string
+	kind      int32
+	values    bytes.Buffer
+	rowGroups []parquetChunk
+}
+
+type parquetChunk struct {
+	offset int64
+	size   int64
+	rows   int64
+}
+
+// parquetWriter writes an uncompressed, PLAIN encoded Parquet file with one
+// required column per CarModel field and one data page per column chunk.
+type parquetWriter struct {
+	out     *countingWriter
+	columns []*parquetColumn
+	rows    int64
+	pending int64
+	groups  []int64
+}
+
+func newParquetWriter(w io.Writer) *parquetWriter {
+	return &parquetWriter{
+		out: &countingWriter{w: w},
+		columns: []*parquetColumn{
+			{name: "id", kind: parquetByteArray},
+			{name: "make", kind: parquetByteArray},
+			{name: "model", kind: parquetByteArray},
+			{name: "color", kind: parquetByteArray},
+			{name: "year", kind: parquetInt32},
+		},
+	}
+}
+
+func (p *parquetWriter) Write(car models.CarModel) error {
+	if p.out.n == 0 {
+		if _, err := p.out.Write(parquetMagic); err != nil {
+			return err
+		}
+	}
+
+	for _, column := range p.columns {
+		switch column.name {
+		case "id":
+			plainByteArray(&column.values, car.Id)
+		case "make":
+			plainByteArray(&column.values, car.Make)
+		case "model":
+			plainByteArray(&column.values, car.Model)
+		case "color":
+			plainByteArray(&column.values, car.Color)
+		case "year":
+			binary.Write(&column.values, binary.LittleEndian, int32(car.Year))
+		}
+	}
+
+	p.pending++
+	if p.pending >= ParquetRowGroupSize {
+		return p.flushRowGroup()
+	}
+	return nil
+}
+
+func plainByteArray(buf *bytes.Buffer, s string) {
+	binary.Write(buf, binary.LittleEndian, uint32(len(s)))
+	buf.WriteString(s)
+}
+
+func (p *parquetWriter) flushRowGroup() error {
+	if p.pending == 0 {
+		return nil
+	}
+
+	for _, column := range p.columns {
+		header := &thriftCompact{}
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(column.values.Len()))
+		header.i32(3, int32(column.values.Len()))
+		header.beginStruct(5)
+		header.i32(1, int32(p.pending))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.endStruct()
+		header.stop()
+
+		offset := p.out.n
+		if _, err := p.out.Write(header.buf.Bytes()); err != nil {
+			return err
+		}
+		if _, err := column.values.WriteTo(p.out); err != nil {
+			return err
+		}
+
+		column.rowGroups = append(column.rowGroups, parquetChunk{
+			offset: offset,
+			size:   p.out.n - offset,
+			rows:   p.pending,
+		})
+		column.values.Reset()
+	}
+
+	p.groups = append(p.groups, p.pending)
+	p.rows += p.pending
+	p.pending = 0
+	return nil
+}
+
+// Close flushes the last row group and writes the footer. It doesn't close
+// the underlying writer.
+func (p *parquetWriter) Close() error {
+	if p.out.n == 0 {
+		if _, err := p.out.Write(parquetMagic); err != nil {
+			return err
+		}
+	}
+	if err := p.flushRowGroup(); err != nil {
+		return err
+	}
+
+	meta := &thriftCompact{}
+	meta.i32(1, 1)
+
+	meta.beginList(2, thriftStruct, len(p.columns)+1)
+	meta.beginElement()
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(p.columns)))
+	meta.endStruct()
+	for _, column := range p.columns {
+		meta.beginElement()
+		meta.i32(1, column.kind)
+		meta.i32(3, parquetRequired)
+		meta.binary(4, column.name)
+		if column.kind == parquetByteArray {
+			meta.i32(6, parquetUTF8)
+		}
+		meta.endStruct()
+	}
+
+	meta.i64(3, p.rows)
+
+	meta.beginList(4, thriftStruct, len(p.groups))
+	for g, rows := range p.groups {
+		var total int64
+		for _, column := range p.columns {
+			total += column.rowGroups[g].size
+		}
+
+		meta.beginElement()
+		meta.beginList(1, thriftStruct, len(p.columns))
+		for _, column := range p.columns {
+			chunk := column.rowGroups[g]
+			meta.beginElement()
+			meta.i64(2, chunk.offset)
+			meta.beginStruct(3)
+			meta.i32(1, column.kind)
+			meta.beginList(2, thriftI32, 2)
+			meta.listI32(parquetPlain)
+			meta.listI32(parquetRLE)
+			meta.beginList(3, thriftBinary, 1)
+			meta.listBinary(column.name)
+			meta.i32(4, parquetUncompressed)
+			meta.i64(5, chunk.rows)
+			meta.i64(6, chunk.size)
+			meta.i64(7, chunk.size)
+			meta.i64(9, chunk.offset)
+			meta.endStruct()
+			meta.endStruct()
+		}
+		meta.i64(2, total)
+		meta.i64(3, rows)
+		meta.endStruct()
+	}
+
+	meta.binary(6, "go-dfw-testing")
+	meta.stop()
+
+	if _, err := p.out.Write(meta.buf.Bytes()); err != nil {
+		return err
+	}
+	if err := binary.Write(p.out, binary.LittleEndian, uint32(meta.buf.Len())); err != nil {
+		return err
+	}
+	_, err := p.out.Write(parquetMagic)
+	return err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// Thrift compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// Just enough of the Thrift compact protocol to write Parquet metadata
+type thriftCompact struct {
+	buf   bytes.Buffer
+	last  int16
+	stack []int16
+}
+
+func (t *thriftCompact) varint(v uint64) {
+	var scratch [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(scratch[:], v)
+	t.buf.Write(scratch[:n])
+}
+
+func (t *thriftCompact) zigzag(v int64) {
+	t.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *thriftCompact) field(id int16, kind byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | kind)
+	} else {
+		t.buf.WriteByte(kind)
+		t.zigzag(int64(id))
+	}
+	t.last = id
+}
+
+func (t *thriftCompact) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.zigzag(int64(v))
+}
+
+func (t *thriftCompact) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.zigzag(v)
+}
+
+func (t *thriftCompact) binary(id int16, v string) {
+	t.field(id, thriftBinary)
+	t.listBinary(v)
+}
+
+func (t *thriftCompact) beginStruct(id int16) {
+	t.field(id, thriftStruct)
+	t.beginElement()
+}
+
+// Start a struct that is a list element, so has no field header
+func (t *thriftCompact) beginElement() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+func (t *thriftCompact) endStruct() {
+	t.stop()
+	t.last = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
+
+func (t *thriftCompact) stop() {
+	t.buf.WriteByte(0)
+}
+
+func (t *thriftCompact) beginList(id int16, kind byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | kind)
+		return
+	}
+	t.buf.WriteByte(0xf0 | kind)
+	t.varint(uint64(size))
+}
+
+func (t *thriftCompact) listI32(v int32) {
+	t.zigzag(int64(v))
+}
+
+func (t *thriftCompact) listBinary(v string) {
+	t.varint(uint64(len(v)))
+	t.buf.WriteString(v)
+}
@@ -0,0 +1,237 @@
+package exporter_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/exporter"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+)
+
+// Just enough of a Thrift compact protocol reader to decode what the writer
+// puts in a Parquet file. Structs come back as field id to value, lists as
+// slices, integers as int64 and binary as strings.
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) next() byte {
+	if r.pos >= len(r.data) {
+		panic(fmt.Sprintf("read past the end at %d", r.pos))
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		panic(fmt.Sprintf("bad varint at %d", r.pos))
+	}
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) bytes(n int) []byte {
+	if n < 0 || r.pos+n > len(r.data) {
+		panic(fmt.Sprintf("%d bytes at %d run past the end", n, r.pos))
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *thriftReader) value(kind byte) interface{} {
+	switch kind {
+	case 1, 2:
+		return kind == 1
+	case 3:
+		return int64(int8(r.next()))
+	case 4, 5, 6:
+		return r.zigzag()
+	case 7:
+		return math.Float64frombits(binary.LittleEndian.Uint64(r.bytes(8)))
+	case 8:
+		return string(r.bytes(int(r.uvarint())))
+	case 9, 10:
+		header := r.next()
+		size, elem := int(header>>4), header&0x0f
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+		list := make([]interface{}, 0, size)
+		for i := 0; i < size; i++ {
+			list = append(list, r.value(elem))
+		}
+		return list
+	case 12:
+		return r.readStruct()
+	}
+	panic(fmt.Sprintf("unexpected thrift type %d at %d", kind, r.pos))
+}
+
+func (r *thriftReader) readStruct() map[int16]interface{} {
+	fields := map[int16]interface{}{}
+	var last int16
+	for {
+		header := r.next()
+		if header == 0 {
+			return fields
+		}
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			id = int16(r.zigzag())
+		}
+		fields[id] = r.value(header & 0x0f)
+		last = id
+	}
+}
+
+type parquetFile struct {
+	meta      map[int16]interface{}
+	rowGroups []int64
+	columns   map[string][]interface{}
+}
+
+// Read back the footer and every column chunk of a Parquet file the way a
+// reader would: from the offsets in the metadata, not from the writer
+func readParquet(t *testing.T, data []byte) (file parquetFile) {
+	defer func() {
+		if err := recover(); err != nil {
+			t.Fatalf("Couldn't read the parquet file: %v", err)
+		}
+	}()
+
+	if !bytes.HasPrefix(data, []byte("PAR1")) || !bytes.HasSuffix(data, []byte("PAR1")) {
+		t.Fatalf("Expected PAR1 magic at both ends")
+	}
+	footer := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	meta := &thriftReader{data: data[:len(data)-8], pos: len(data) - 8 - footer}
+	file.meta = meta.readStruct()
+	if meta.pos != len(data)-8 {
+		t.Fatalf("Footer is %d bytes but the metadata was %d", footer, meta.pos-(len(data)-8-footer))
+	}
+
+	file.columns = map[string][]interface{}{}
+	for _, group := range file.meta[4].([]interface{}) {
+		group := group.(map[int16]interface{})
+		file.rowGroups = append(file.rowGroups, group[3].(int64))
+
+		for _, chunk := range group[1].([]interface{}) {
+			column := chunk.(map[int16]interface{})[3].(map[int16]interface{})
+			name := column[3].([]interface{})[0].(string)
+
+			page := &thriftReader{data: data, pos: int(column[9].(int64))}
+			header := page.readStruct()
+			values := &thriftReader{data: page.bytes(int(header[3].(int64)))}
+			count := header[5].(map[int16]interface{})[1].(int64)
+			if count != column[5].(int64) || count != group[3].(int64) {
+				t.Errorf("Column %s has %d values in its page, %d in its chunk and %d rows in its group", name, count, column[5], group[3])
+			}
+
+			for i := int64(0); i < count; i++ {
+				switch column[1].(int64) {
+				case 1:
+					file.columns[name] = append(file.columns[name], int64(int32(binary.LittleEndian.Uint32(values.bytes(4)))))
+				case 6:
+					file.columns[name] = append(file.columns[name], string(values.bytes(int(binary.LittleEndian.Uint32(values.bytes(4))))))
+				default:
+					t.Fatalf("Unexpected type %d for column %s", column[1], name)
+				}
+			}
+			if values.pos != len(values.data) {
+				t.Errorf("Column %s has %d bytes left after its values", name, len(values.data)-values.pos)
+			}
+		}
+	}
+	return file
+}
+
+func writeParquet(t *testing.T, cars []models.CarModel) []byte {
+	var buf bytes.Buffer
+	w, err := exporter.NewWriter(exporter.FormatParquet, &buf)
+	if err != nil {
+		t.Fatalf("Couldn't create writer: %s", err)
+	}
+	for _, car := range cars {
+		if err := w.Write(car); err != nil {
+			t.Fatalf("Couldn't write car: %s", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Couldn't close writer: %s", err)
+	}
+	return buf.Bytes()
+}
+
+func TestParquetRoundTrip(t *testing.T) {
+	// One full row group and one more row
+	var written []models.CarModel
+	for i := 0; i <= exporter.ParquetRowGroupSize; i++ {
+		written = append(written, models.CarModel{
+			Id:    fmt.Sprintf("car-%05d", i),
+			Make:  []string{"Toyota", "Honda", "Ford"}[i%3],
+			Model: fmt.Sprintf("Model %d, é", i%7),
+			Color: "",
+			Year:  1990 + i%35,
+		})
+	}
+	file := readParquet(t, writeParquet(t, written))
+
+	if file.meta[1] != int64(1) || file.meta[3] != int64(len(written)) || file.meta[6] != "go-dfw-testing" {
+		t.Errorf("Expected version 1 with %d rows, got %v", len(written), file.meta)
+	}
+	if len(file.rowGroups) != 2 || file.rowGroups[0] != exporter.ParquetRowGroupSize || file.rowGroups[1] != 1 {
+		t.Errorf("Expected a full row group then one row, got %v", file.rowGroups)
+	}
+
+	schema := file.meta[2].([]interface{})
+	expected := []struct {
+		name string
+		kind int64
+	}{{"id", 6}, {"make", 6}, {"model", 6}, {"color", 6}, {"year", 1}}
+	root := schema[0].(map[int16]interface{})
+	if len(schema) != len(expected)+1 || root[4] != "schema" || root[5] != int64(len(expected)) {
+		t.Fatalf("Expected a root with %d children, got %v", len(expected), schema)
+	}
+	for i, column := range expected {
+		element := schema[i+1].(map[int16]interface{})
+		if element[4] != column.name || element[1] != column.kind || element[3] != int64(0) {
+			t.Errorf("Expected required column %s of type %d, got %v", column.name, column.kind, element)
+		}
+		// Strings are annotated UTF8, ints aren't annotated
+		if _, annotated := element[6]; annotated != (column.kind == 6) {
+			t.Errorf("Expected only strings annotated, got %v", element)
+		}
+	}
+
+	for i, car := range written {
+		got := models.CarModel{
+			Id:    file.columns["id"][i].(string),
+			Make:  file.columns["make"][i].(string),
+			Model: file.columns["model"][i].(string),
+			Color: file.columns["color"][i].(string),
+			Year:  int(file.columns["year"][i].(int64)),
+		}
+		if got != car {
+			t.Fatalf("Row %d: expected %+v, got %+v", i, car, got)
+		}
+	}
+}
+
+func TestParquetEmpty(t *testing.T) {
+	file := readParquet(t, writeParquet(t, nil))
+	if file.meta[3] != int64(0) || len(file.rowGroups) != 0 {
+		t.Errorf("Expected no rows or row groups, got %v", file.meta)
+	}
+}
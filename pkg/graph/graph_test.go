@@ -0,0 +1,241 @@
+package graph_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/graph"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+const (
+	toyotaId = "9b2e3f4a-1c2d-4e5f-8a9b-0c1d2e3f4a5b"
+	hondaId  = "1f0c9d8e-7b6a-4c5d-9e8f-7a6b5c4d3e2f"
+	noCarId  = "00000000-0000-4000-8000-000000000000"
+)
+
+func init() {
+	logging.ConfigureLogger("ERROR")
+}
+
+// A loader over two cars that records every batch it's asked for
+func fakeLoader(batches *[][]string) *graph.CarLoader {
+	cars := map[string]models.CarModel{
+		toyotaId: {Id: toyotaId, Make: "Toyota", Model: "Camry", Color: "red", Year: 2018},
+		hondaId:  {Id: hondaId, Make: "Honda", Model: "Civic", Color: "blue", Year: 2016},
+	}
+	return graph.NewCarLoader(func(ids []string) ([]models.CarModel, error) {
+		*batches = append(*batches, ids)
+		found := []models.CarModel{}
+		for _, id := range ids {
+			if car, ok := cars[id]; ok {
+				found = append(found, car)
+			}
+		}
+		return found, nil
+	})
+}
+
+func post(t *testing.T, query string) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(graph.Request{Query: query})
+	req, err := http.NewRequest("POST", "/v1/graphql", bytes.NewBuffer(payload))
+	if err != nil {
+		t.Fatalf("Error while building request: %s", err)
+	}
+	req.Header.Set("X-CARS-ID", "1234")
+
+	rr := httptest.NewRecorder()
+	graph.Handler(rr, req)
+	return rr
+}
+
+func TestLoaderBatchesSameLevelLookups(t *testing.T) {
+	var batches [][]string
+	result := graphql.Do(graphql.Params{
+		Schema: graph.Schema,
+		RequestString: `{
+			a: car(id: "` + toyotaId + `") { make }
+			b: car(id: "` + hondaId + `") { make }
+			c: car(id: "` + toyotaId + `") { id }
+			d: car(id: "` + noCarId + `") { id }
+		}`,
+		Context: graph.NewContext(context.Background(), nil, "default", fakeLoader(&batches)),
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("Unexpected errors: %v", result.Errors)
+	}
+
+	if len(batches) != 1 {
+		t.Fatalf("Expected 1 batch, got %d: %v", len(batches), batches)
+	}
+	ids := batches[0]
+	sort.Strings(ids)
+	if strings.Join(ids, ",") != strings.Join([]string{noCarId, hondaId, toyotaId}, ",") {
+		t.Errorf("Expected each id once, got %v", ids)
+	}
+
+	data, _ := json.Marshal(result.Data)
+	expected := `{"a":{"make":"Toyota"},"b":{"make":"Honda"},"c":{"id":"` + toyotaId + `"},"d":null}`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
+
+func TestLoaderError(t *testing.T) {
+	loader := graph.NewCarLoader(func(ids []string) ([]models.CarModel, error) {
+		return nil, errors.New("database down")
+	})
+
+	if _, err := loader.Load(toyotaId)(); err == nil || err.Error() != "database down" {
+		t.Errorf("Expected the fetch error, got %v", err)
+	}
+	if loader.Batches() != 1 {
+		t.Errorf("Expected 1 batch, got %d", loader.Batches())
+	}
+}
+
+func TestDepthLimit(t *testing.T) {
+	maxDepth := graph.MaxDepth
+	graph.MaxDepth = 2
+	defer func() { graph.MaxDepth = maxDepth }()
+
+	rr := post(t, `{ cars { cars { id } } }`)
+	if rr.Code != 400 {
+		t.Fatalf("Expected: %d, but got: %d", 400, rr.Code)
+	}
+	if err := openapi.ValidateSchema("GraphQLResponse", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match GraphQLResponse schema: %s", err)
+	}
+	if !strings.Contains(rr.Body.String(), "Query depth 3 exceeds the limit of 2") {
+		t.Errorf("Expected a depth error, got %s", rr.Body.String())
+	}
+}
+
+func TestComplexityLimit(t *testing.T) {
+	// Each page is cars (1 + 5 fields), limit and offset, so 1 + 500 * 8 twice
+	payload, _ := json.Marshal(graph.Request{
+		Query: `query Big($limit: Int) {
+			a: cars(limit: 500) { ...page }
+			b: cars(limit: $limit) { ...page }
+		}
+		fragment page on CarList { cars { id make model color year } limit offset }`,
+		Variables: map[string]interface{}{"limit": 500},
+	})
+	req, _ := http.NewRequest("POST", "/v1/graphql", bytes.NewBuffer(payload))
+	req.Header.Set("X-CARS-ID", "1234")
+	rr := httptest.NewRecorder()
+	graph.Handler(rr, req)
+
+	if rr.Code != 400 {
+		t.Fatalf("Expected: %d, but got: %d", 400, rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "Query complexity 8002 exceeds the limit of 5000") {
+		t.Errorf("Expected a complexity error, got %s", rr.Body.String())
+	}
+}
+
+func checkLimits(t *testing.T, query string, variables map[string]interface{}) error {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		t.Fatalf("Couldn't parse %s", err)
+	}
+	operation, err := graph.Operation(doc, "")
+	if err != nil {
+		t.Fatalf("Couldn't find the operation %s", err)
+	}
+	return graph.CheckLimits(doc, operation, variables)
+}
+
+func TestComplexityUnsetLimit(t *testing.T) {
+	maxComplexity := graph.MaxComplexity
+	graph.MaxComplexity = 8 * graph.DefaultLimit
+	defer func() { graph.MaxComplexity = maxComplexity }()
+
+	// An unset limit is the default page, 1 + 50 * 8
+	query := `query Page($limit: Int) { cars(limit: $limit) { cars { id make model color year } limit offset } }`
+	err := checkLimits(t, query, nil)
+	if err == nil || !strings.Contains(err.Error(), "Query complexity 401") {
+		t.Errorf("Expected the default page to be counted, got %v", err)
+	}
+	if err = checkLimits(t, query, map[string]interface{}{"limit": float64(10)}); err != nil {
+		t.Errorf("Expected a page of 10 to pass, got %s", err)
+	}
+
+	// Or the operation's own default
+	query = `query Page($limit: Int = 2) { cars(limit: $limit) { cars { id make model color year } limit offset } }`
+	if err = checkLimits(t, query, nil); err != nil {
+		t.Errorf("Expected the declared default of 2 to pass, got %s", err)
+	}
+}
+
+func TestIntrospectionLimits(t *testing.T) {
+	typeRef := "kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } } } } }"
+	standard := `{ __schema { types { fields { args { type { ` + typeRef + ` } } } } } }`
+	if err := checkLimits(t, standard, nil); err != nil {
+		t.Errorf("Expected the usual introspection depth to pass, got %s", err)
+	}
+
+	nested := "name"
+	for i := 0; i < graph.MaxIntrospectionDepth; i++ {
+		nested = "fields { type { " + nested + " } }"
+	}
+	rr := post(t, `{ __schema { types { `+nested+` } } }`)
+	if rr.Code != 400 || !strings.Contains(rr.Body.String(), "Introspection depth") {
+		t.Errorf("Expected nested introspection to be rejected, got %d %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestInvalidQueries(t *testing.T) {
+	queries := map[string]string{
+		"parse":      `{ cars {`,
+		"validation": `{ cars { cars { vin } } }`,
+		"operation":  `query A { cars { limit } } query B { cars { offset } }`,
+	}
+	for name, query := range queries {
+		rr := post(t, query)
+		if rr.Code != 400 {
+			t.Errorf("%s: expected: %d, but got: %d", name, 400, rr.Code)
+		}
+		if err := openapi.ValidateSchema("GraphQLResponse", rr.Body.Bytes()); err != nil {
+			t.Errorf("%s: response doesn't match GraphQLResponse schema: %s", name, err)
+		}
+	}
+
+	if rr := post(t, ``); rr.Code != 400 {
+		t.Errorf("Expected a missing query to be a 400, got %d", rr.Code)
+	}
+}
+
+func TestMutationOverGet(t *testing.T) {
+	query := url.Values{"query": {`mutation { deleteCar(id: "` + toyotaId + `") }`}}
+	req, _ := http.NewRequest("GET", "/v1/graphql?"+query.Encode(), nil)
+	req.Header.Set("X-CARS-ID", "1234")
+
+	rr := httptest.NewRecorder()
+	graph.Handler(rr, req)
+	if rr.Code != 405 {
+		t.Errorf("Expected: %d, but got: %d", 405, rr.Code)
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/v1/graphql", strings.NewReader(`{"query": "{ cars { limit } }"}`))
+
+	rr := httptest.NewRecorder()
+	graph.Handler(rr, req)
+	if rr.Code != 401 {
+		t.Errorf("Expected: %d, but got: %d", 401, rr.Code)
+	}
+}
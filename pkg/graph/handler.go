@@ -0,0 +1,150 @@
+package graph
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+type Request struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+func Handler(w http.ResponseWriter, r *http.Request) {
+	log := logging.GetLog(r.Context())
+
+	err := handlers.ValidateAuthId(r.Header.Get("X-CARS-ID"))
+	if err != nil {
+		log.Error("Unauthorized Auth Id: ", err)
+		http.Error(w, "Unauthorized", 401)
+		return
+	}
+
+	if r.Method != "GET" && r.Method != "POST" {
+		http.Error(w, "Invalid Request Method.", 405)
+		return
+	}
+
+	statusCode, err := Serve(w, r)
+	if err != nil {
+		log.Error(err)
+		jsonErr := &logging.JsonError{
+			Status:  http.StatusText(statusCode),
+			Code:    strconv.Itoa(statusCode),
+			Message: err.Error(),
+		}
+		logging.FormatError(r.Context(), w, statusCode, *jsonErr)
+	}
+}
+
+// Serve runs a GraphQL request, from the query string for GET or the JSON
+// body for POST. Queries that don't parse, validate or fit the limits are
+// answered with a 400 and GraphQL errors; anything that gets to run is a 200
+// whether or not its fields resolved.
+func Serve(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("GraphQL: Processing GraphQL endpoint...")
+
+	request, err := readRequest(r)
+	if err != nil {
+		return 400, err
+	}
+
+	log.Debug("GraphQL: Parsing query...")
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(request.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		return writeResult(w, 400, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+	}
+
+	validation := graphql.ValidateDocument(&Schema, doc, nil)
+	if !validation.IsValid {
+		return writeResult(w, 400, &graphql.Result{Errors: validation.Errors})
+	}
+
+	operation, err := Operation(doc, request.OperationName)
+	if err == nil {
+		err = CheckLimits(doc, operation, request.Variables)
+	}
+	if err != nil {
+		return writeResult(w, 400, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+	}
+
+	// GETs can be cached and prefetched, so they only read
+	if r.Method == "GET" && operation.Operation != ast.OperationTypeQuery {
+		return 405, errors.New("Mutations must be sent with POST")
+	}
+
+	log.Debug("GraphQL: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	// Loads, lists and mutations all act for the caller's tenant
+	tenant, _ := handlers.TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	cars := NewCarLoader(func(ids []string) ([]models.CarModel, error) {
+		return models.GetCars(&db, tenant, ids)
+	})
+
+	log.Debug("GraphQL: Executing query...")
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        Schema,
+		AST:           doc,
+		OperationName: request.OperationName,
+		Args:          request.Variables,
+		Context:       NewContext(ctx, &db, tenant, cars),
+	})
+	return writeResult(w, 200, result)
+}
+
+func readRequest(r *http.Request) (Request, error) {
+	var request Request
+
+	if r.Method == "GET" {
+		query := r.URL.Query()
+		request.Query = query.Get("query")
+		request.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				return request, errors.New("variables must be a JSON object")
+			}
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return request, err
+	}
+
+	if request.Query == "" {
+		return request, errors.New("A query is required")
+	}
+	return request, nil
+}
+
+func writeResult(w http.ResponseWriter, statusCode int, result *graphql.Result) (int, error) {
+	resultJson, err := json.Marshal(result)
+	if err != nil {
+		return 500, err
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(statusCode)
+	w.Write(resultJson)
+	return statusCode, nil
+}
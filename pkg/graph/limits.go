@@ -0,0 +1,174 @@
+package graph
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+var (
+	// Deepest selection allowed, counting the top level fields as 1
+	MaxDepth = 8
+	// Deepest selection allowed under __schema or __type. The usual
+	// introspection query nests type references 13 deep.
+	MaxIntrospectionDepth = 15
+	// Most fields a query may resolve. Each field counts 1, and the fields
+	// under one that takes a limit count once per item it can return.
+	MaxComplexity = 5000
+)
+
+// Top level fields returning a page of results, with the page size used
+// when the query doesn't give a limit
+var listFields = map[string]int{
+	"cars": DefaultLimit,
+}
+
+// Find the operation a request runs: the one named, or the only one
+func Operation(doc *ast.Document, name string) (*ast.OperationDefinition, error) {
+	var found *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			if found != nil {
+				return nil, fmt.Errorf("operationName is needed when a document has more than one operation")
+			}
+			found = operation
+		} else if operation.Name != nil && operation.Name.Value == name {
+			found = operation
+		}
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("Unknown operation %q", name)
+	}
+	return found, nil
+}
+
+// Reject an operation that's deeper than MaxDepth or costs more than
+// MaxComplexity, before anything runs. Introspection fields count towards
+// the complexity like any other, but have their own depth limit.
+func CheckLimits(doc *ast.Document, operation *ast.OperationDefinition, variables map[string]interface{}) error {
+	m := &measurer{
+		fragments: make(map[string]*ast.FragmentDefinition),
+		variables: make(map[string]interface{}),
+		visiting:  make(map[string]bool),
+	}
+	for _, definition := range doc.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			m.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	// Variables the request leaves out take the operation's defaults
+	for _, definition := range operation.VariableDefinitions {
+		if value, ok := definition.DefaultValue.(*ast.IntValue); ok {
+			if parsed, err := strconv.Atoi(value.Value); err == nil {
+				m.variables[definition.Variable.Name.Value] = parsed
+			}
+		}
+	}
+	for name, value := range variables {
+		m.variables[name] = value
+	}
+
+	depth, complexity := m.measure(operation.SelectionSet, true)
+	if depth > MaxDepth {
+		return fmt.Errorf("Query depth %d exceeds the limit of %d", depth, MaxDepth)
+	}
+	if m.introspectionDepth > MaxIntrospectionDepth {
+		return fmt.Errorf("Introspection depth %d exceeds the limit of %d", m.introspectionDepth, MaxIntrospectionDepth)
+	}
+	if complexity > MaxComplexity {
+		return fmt.Errorf("Query complexity %d exceeds the limit of %d", complexity, MaxComplexity)
+	}
+	return nil
+}
+
+type measurer struct {
+	fragments          map[string]*ast.FragmentDefinition
+	variables          map[string]interface{}
+	visiting           map[string]bool
+	introspectionDepth int
+}
+
+// Depth and complexity of a selection set, following fragments. The depth
+// of __schema and __type selections is kept apart in introspectionDepth.
+func (m *measurer) measure(set *ast.SelectionSet, root bool) (int, int) {
+	if set == nil {
+		return 0, 0
+	}
+
+	depth, complexity := 0, 0
+	add := func(d int, c int) {
+		if d > depth {
+			depth = d
+		}
+		complexity += c
+	}
+
+	for _, selection := range set.Selections {
+		switch s := selection.(type) {
+		case *ast.Field:
+			childDepth, childComplexity := m.measure(s.SelectionSet, false)
+			if strings.HasPrefix(s.Name.Value, "__") && s.Name.Value != "__typename" {
+				if childDepth+1 > m.introspectionDepth {
+					m.introspectionDepth = childDepth + 1
+				}
+				complexity += 1 + childComplexity
+				continue
+			}
+			add(childDepth+1, 1+m.listSize(s, root)*childComplexity)
+		case *ast.InlineFragment:
+			add(m.measure(s.SelectionSet, root))
+		case *ast.FragmentSpread:
+			name := s.Name.Value
+			fragment, ok := m.fragments[name]
+			if !ok || m.visiting[name] {
+				continue
+			}
+			m.visiting[name] = true
+			add(m.measure(fragment.SelectionSet, root))
+			delete(m.visiting, name)
+		}
+	}
+	return depth, complexity
+}
+
+// How many items a field can return, from its limit argument. A limit from a
+// variable the request doesn't set leaves the field's default, as it does
+// for the resolver.
+func (m *measurer) listSize(field *ast.Field, root bool) int {
+	size, ok := 0, false
+	if root {
+		size, ok = listFields[field.Name.Value]
+	}
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "limit" {
+			continue
+		}
+		ok = true
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			if parsed, err := strconv.Atoi(value.Value); err == nil {
+				size = parsed
+			}
+		case *ast.Variable:
+			switch v := m.variables[value.Name.Value].(type) {
+			case float64:
+				size = int(v)
+			case int:
+				size = v
+			}
+		}
+	}
+
+	if !ok || size < 1 {
+		return 1
+	}
+	return size
+}
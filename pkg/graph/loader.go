@@ -0,0 +1,90 @@
+package graph
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+)
+
+// CarLoader batches the car lookups made while resolving one level of a query
+// into a single query, DataLoader style. Load only queues the id and returns
+// a thunk; graphql-go runs thunks after every field at the same depth has
+// been resolved, so the first thunk to run fetches everything queued so far.
+// Loaded cars are cached for the rest of the request.
+type CarLoader struct {
+	fetch func(ids []string) ([]models.CarModel, error)
+
+	mu      sync.Mutex
+	pending []string
+	results map[string]*carResult
+	batches int
+}
+
+type carResult struct {
+	car *models.CarModel
+	err error
+}
+
+func NewCarLoader(fetch func(ids []string) ([]models.CarModel, error)) *CarLoader {
+	return &CarLoader{fetch: fetch, results: make(map[string]*carResult)}
+}
+
+// Queue a car for the next batch. The thunk resolves to the car, or nil when
+// it doesn't exist.
+func (l *CarLoader) Load(id string) func() (interface{}, error) {
+	id = strings.ToLower(id)
+
+	l.mu.Lock()
+	if _, ok := l.results[id]; !ok {
+		l.results[id] = &carResult{}
+		l.pending = append(l.pending, id)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		l.dispatch()
+		result := l.results[id]
+		if result.err != nil {
+			return nil, result.err
+		}
+		if result.car == nil {
+			return nil, nil
+		}
+		return *result.car, nil
+	}
+}
+
+// How many queries the loader has made
+func (l *CarLoader) Batches() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.batches
+}
+
+// Fetch everything pending. Callers hold the lock.
+func (l *CarLoader) dispatch() {
+	if len(l.pending) == 0 {
+		return
+	}
+	ids := l.pending
+	l.pending = nil
+	l.batches++
+
+	cars, err := l.fetch(ids)
+	if err != nil {
+		for _, id := range ids {
+			l.results[id].err = err
+		}
+		return
+	}
+
+	for i := range cars {
+		if result, ok := l.results[strings.ToLower(cars[i].Id)]; ok {
+			result.car = &cars[i]
+		}
+	}
+}
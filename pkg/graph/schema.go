@@ -0,0 +1,261 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/graphql-go/graphql"
+	"github.com/satori/go.uuid"
+)
+
+const DefaultLimit = handlers.DefaultListLimit
+
+// Per request state the resolvers need
+type session struct {
+	db     *clients.DBClient
+	tenant string
+	cars   *CarLoader
+}
+
+type sessionKey struct{}
+
+// Context the resolvers run in. Car lookups go through the loader, anything
+// else through db, and both only see tenant's cars. The loader has to be
+// scoped to the same tenant.
+func NewContext(ctx context.Context, db *clients.DBClient, tenant string, cars *CarLoader) context.Context {
+	return context.WithValue(ctx, sessionKey{}, &session{db: db, tenant: tenant, cars: cars})
+}
+
+func sessionFrom(ctx context.Context) *session {
+	return ctx.Value(sessionKey{}).(*session)
+}
+
+var carType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "Car",
+	Description: "A car, as stored by the cars endpoints",
+	Fields: graphql.Fields{
+		"id":    &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"make":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"model": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"color": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"year":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+	},
+})
+
+var carListType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "CarList",
+	Fields: graphql.Fields{
+		"cars":   &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(carType)))},
+		"limit":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"offset": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+	},
+})
+
+var carInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "CarInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"make":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"model": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"color": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"year":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+	},
+})
+
+var queryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Query",
+	Fields: graphql.Fields{
+		"car": &graphql.Field{
+			Type:        carType,
+			Description: "One of the tenant's cars by id, or null when it has none with that id",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+			},
+			Resolve: resolveCar,
+		},
+		"cars": &graphql.Field{
+			Type:        graphql.NewNonNull(carListType),
+			Description: "A page of the tenant's cars, with the same filters as GET /v1/cars",
+			Args: graphql.FieldConfigArgument{
+				"make":    &graphql.ArgumentConfig{Type: graphql.String},
+				"model":   &graphql.ArgumentConfig{Type: graphql.String},
+				"color":   &graphql.ArgumentConfig{Type: graphql.String},
+				"year":    &graphql.ArgumentConfig{Type: graphql.Int},
+				"yearMin": &graphql.ArgumentConfig{Type: graphql.Int},
+				"yearMax": &graphql.ArgumentConfig{Type: graphql.Int},
+				"limit":   &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: DefaultLimit},
+				"offset":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+			},
+			Resolve: resolveCars,
+		},
+	},
+})
+
+var mutationType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Mutation",
+	Fields: graphql.Fields{
+		"createCar": &graphql.Field{
+			Type: graphql.NewNonNull(carType),
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(carInputType)},
+			},
+			Resolve: resolveCreateCar,
+		},
+		"updateCar": &graphql.Field{
+			Type:        graphql.NewNonNull(carType),
+			Description: "Replace one of the tenant's cars",
+			Args: graphql.FieldConfigArgument{
+				"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(carInputType)},
+			},
+			Resolve: resolveUpdateCar,
+		},
+		"deleteCar": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.ID),
+			Description: "Delete one of the tenant's cars, returning its id",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+			},
+			Resolve: resolveDeleteCar,
+		},
+	},
+})
+
+var Schema = mustSchema()
+
+func mustSchema() graphql.Schema {
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query:    queryType,
+		Mutation: mutationType,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+func carId(args map[string]interface{}) (string, error) {
+	id, _ := args["id"].(string)
+	if _, err := uuid.FromString(id); err != nil {
+		return "", errors.New("id must be a valid car Id")
+	}
+	return id, nil
+}
+
+func carPayload(args map[string]interface{}) (handlers.CarPostPayload, error) {
+	input, _ := args["input"].(map[string]interface{})
+	payload := handlers.CarPostPayload{}
+	payload.Make, _ = input["make"].(string)
+	payload.Model, _ = input["model"].(string)
+	payload.Color, _ = input["color"].(string)
+	payload.Year, _ = input["year"].(int)
+	return payload, handlers.ValidateCarPayload(&payload)
+}
+
+func resolveCar(p graphql.ResolveParams) (interface{}, error) {
+	id, err := carId(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	return sessionFrom(p.Context).cars.Load(id), nil
+}
+
+func resolveCars(p graphql.ResolveParams) (interface{}, error) {
+	s := sessionFrom(p.Context)
+
+	filter := models.CarFilter{Tenant: s.tenant}
+	filter.Make, _ = p.Args["make"].(string)
+	filter.Model, _ = p.Args["model"].(string)
+	filter.Color, _ = p.Args["color"].(string)
+	filter.Year, _ = p.Args["year"].(int)
+	filter.YearMin, _ = p.Args["yearMin"].(int)
+	filter.YearMax, _ = p.Args["yearMax"].(int)
+
+	limit, _ := p.Args["limit"].(int)
+	offset, _ := p.Args["offset"].(int)
+	if limit < 1 || limit > handlers.MaxListLimit {
+		return nil, fmt.Errorf("limit must be between 1 and %d", handlers.MaxListLimit)
+	}
+	if offset < 0 {
+		return nil, errors.New("offset must not be negative")
+	}
+
+	cars, err := models.ListCars(s.db, filter, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return handlers.CarList{Cars: cars, Limit: limit, Offset: offset}, nil
+}
+
+func resolveCreateCar(p graphql.ResolveParams) (interface{}, error) {
+	s := sessionFrom(p.Context)
+
+	payload, err := carPayload(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	car := models.CarModel{
+		Id:     uuid.NewV4().String(),
+		Make:   payload.Make,
+		Model:  payload.Model,
+		Color:  payload.Color,
+		Year:   payload.Year,
+		Tenant: s.tenant,
+	}
+	if _, err := models.SaveCar(s.db, &car); err != nil {
+		return nil, err
+	}
+	return car, nil
+}
+
+func resolveUpdateCar(p graphql.ResolveParams) (interface{}, error) {
+	s := sessionFrom(p.Context)
+
+	id, err := carId(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := carPayload(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	car := models.CarModel{
+		Id:     id,
+		Make:   payload.Make,
+		Model:  payload.Model,
+		Color:  payload.Color,
+		Year:   payload.Year,
+		Tenant: s.tenant,
+	}
+	if err := models.UpdateCar(s.db, s.tenant, &car); err != nil {
+		return nil, err
+	}
+	return car, nil
+}
+
+func resolveDeleteCar(p graphql.ResolveParams) (interface{}, error) {
+	s := sessionFrom(p.Context)
+
+	id, err := carId(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	txn, err := s.db.Db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	if err := models.DeleteCarTx(txn, s.tenant, id); err != nil {
+		txn.Rollback()
+		return nil, err
+	}
+	if err := txn.Commit(); err != nil {
+		return nil, err
+	}
+	return id, nil
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+)
+
+// Credentials map each accepted X-CARS-ID to the tenant it acts for
+var Credentials = map[string]string{
+	"1234": models.DefaultTenant,
+}
+
+// Replace the accepted credentials with a "id:tenant,id:tenant" list, e.g.
+// from config. An id without a tenant belongs to the default tenant.
+func LoadCredentials(spec string) error {
+	credentials := make(map[string]string)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 2)
+		tenant := models.DefaultTenant
+		if len(parts) == 2 && parts[1] != "" {
+			tenant = parts[1]
+		}
+		if parts[0] == "" {
+			return fmt.Errorf("Invalid credential %q", entry)
+		}
+		credentials[parts[0]] = tenant
+	}
+
+	if len(credentials) == 0 {
+		return errors.New("No credentials given")
+	}
+	Credentials = credentials
+	return nil
+}
+
+// A name for the credential an X-CARS-ID is that's safe to store and show:
+// the start of its SHA-256, never the credential itself
+func CredentialFingerprint(auth string) string {
+	sum := sha256.Sum256([]byte(auth))
+	return "sha256:" + hex.EncodeToString(sum[:6])
+}
+
+// Look up the tenant an X-CARS-ID belongs to
+func TenantForAuthId(auth string) (string, error) {
+	tenant, ok := Credentials[auth]
+	if !ok {
+		return "", errors.New("Invalid Authorization Header ID")
+	}
+	return tenant, nil
+}
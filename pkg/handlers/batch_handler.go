@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/satori/go.uuid"
+)
+
+const (
+	// Every operation runs in one transaction that is rolled back on the
+	// first failure
+	BatchAtomic = "atomic"
+	// Each operation succeeds or fails on its own
+	BatchBestEffort = "best_effort"
+
+	MaxBatchOperations = 1000
+)
+
+// Per-operation outcomes
+const (
+	BatchOk         = "ok"
+	BatchFailed     = "failed"
+	BatchRolledBack = "rolled_back"
+	BatchSkipped    = "skipped"
+)
+
+type BatchOperation struct {
+	Op  string          `json:"op"`
+	Id  string          `json:"id,omitempty"`
+	Car *CarPostPayload `json:"car,omitempty"`
+}
+
+type BatchRequest struct {
+	Mode       string           `json:"mode"`
+	Operations []BatchOperation `json:"operations"`
+	// Tenant every operation acts for, from the caller's credentials
+	Tenant string `json:"-"`
+}
+
+type BatchResult struct {
+	Index  int              `json:"index"`
+	Op     string           `json:"op"`
+	Status string           `json:"status"`
+	Code   int              `json:"code,omitempty"`
+	Car    *models.CarModel `json:"car,omitempty"`
+	Error  string           `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	Mode      string        `json:"mode"`
+	Committed bool          `json:"committed"`
+	Results   []BatchResult `json:"results"`
+}
+
+func BatchHandler(w http.ResponseWriter, r *http.Request) {
+	serveMethod(w, r, "POST", BatchCars)
+}
+
+func BatchCars(w http.ResponseWriter, r *http.Request) (int, error) {
+	var batch BatchRequest
+
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("BatchCars: Processing Batch Cars endpoint...")
+
+	log.Debug("BatchCars: Decoding request body...")
+	statusCode, err := DecodeBody(r, &batch)
+	if err != nil {
+		return statusCode, err
+	}
+
+	batch.Tenant, _ = TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	if batch.Mode == "" {
+		batch.Mode = BatchAtomic
+	}
+	if batch.Mode != BatchAtomic && batch.Mode != BatchBestEffort {
+		return 400, fmt.Errorf("mode must be %s or %s", BatchAtomic, BatchBestEffort)
+	}
+	if len(batch.Operations) == 0 {
+		return 400, errors.New("Need at least one operation")
+	}
+	if len(batch.Operations) > MaxBatchOperations {
+		return 400, fmt.Errorf("A batch can have at most %d operations", MaxBatchOperations)
+	}
+
+	// get db conn
+	log.Debug("BatchCars: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	log.Debugf("BatchCars: Running %d operations...", len(batch.Operations))
+	response, err := RunBatch(&db, batch)
+	if err != nil {
+		return 500, err
+	}
+
+	responseJson, err := json.Marshal(response)
+	if err != nil {
+		return 500, err
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+	return 200, nil
+}
+
+// Run a batch inside one transaction. Best effort batches wrap each
+// operation in a savepoint so a failure only undoes that operation.
+func RunBatch(db *clients.DBClient, batch BatchRequest) (BatchResponse, error) {
+	response := BatchResponse{
+		Mode:    batch.Mode,
+		Results: make([]BatchResult, len(batch.Operations)),
+	}
+
+	txn, err := db.Db.Begin()
+	if err != nil {
+		return response, err
+	}
+
+	failed := false
+	for i, op := range batch.Operations {
+		result := &response.Results[i]
+		result.Index = i
+		result.Op = op.Op
+
+		if failed {
+			result.Status = BatchSkipped
+			continue
+		}
+
+		if batch.Mode == BatchBestEffort {
+			if _, err := txn.Exec("SAVEPOINT batch_operation"); err != nil {
+				txn.Rollback()
+				return response, err
+			}
+		}
+
+		code, car, err := runBatchOperation(txn, batch.Tenant, op)
+		result.Code = code
+		result.Car = car
+		if err == nil {
+			result.Status = BatchOk
+			if batch.Mode == BatchBestEffort {
+				if _, err := txn.Exec("RELEASE SAVEPOINT batch_operation"); err != nil {
+					txn.Rollback()
+					return response, err
+				}
+			}
+			continue
+		}
+
+		result.Status = BatchFailed
+		result.Error = err.Error()
+
+		if batch.Mode == BatchBestEffort {
+			if _, err := txn.Exec("ROLLBACK TO SAVEPOINT batch_operation"); err != nil {
+				txn.Rollback()
+				return response, err
+			}
+			continue
+		}
+
+		// Atomic: undo everything that already ran
+		failed = true
+		for j := 0; j < i; j++ {
+			response.Results[j].Status = BatchRolledBack
+		}
+	}
+
+	if failed {
+		return response, txn.Rollback()
+	}
+
+	if err := txn.Commit(); err != nil {
+		return response, err
+	}
+	response.Committed = true
+	return response, nil
+}
+
+func runBatchOperation(txn *sql.Tx, tenant string, op BatchOperation) (int, *models.CarModel, error) {
+	switch op.Op {
+	case "create":
+		if op.Car == nil {
+			return 400, nil, errors.New("create needs a car")
+		}
+		if err := ValidateCarPayload(op.Car); err != nil {
+			return 422, nil, err
+		}
+
+		carModel := &models.CarModel{
+			Id:     uuid.NewV4().String(),
+			Model:  op.Car.Model,
+			Make:   op.Car.Make,
+			Color:  op.Car.Color,
+			Year:   op.Car.Year,
+			Tenant: tenant,
+		}
+		if _, err := models.SaveCarTx(txn, carModel); err != nil {
+			return 500, nil, err
+		}
+		return 200, carModel, nil
+
+	case "update":
+		if _, err := uuid.FromString(op.Id); err != nil {
+			return 400, nil, errors.New("update needs a valid car id")
+		}
+		if op.Car == nil {
+			return 400, nil, errors.New("update needs a car")
+		}
+		if err := ValidateCarPayload(op.Car); err != nil {
+			return 422, nil, err
+		}
+
+		carModel := &models.CarModel{
+			Id:    op.Id,
+			Model: op.Car.Model,
+			Make:  op.Car.Make,
+			Color: op.Car.Color,
+			Year:  op.Car.Year,
+		}
+		if err := models.UpdateCarTx(txn, tenant, carModel); err != nil {
+			if err == models.ErrCarNotFound {
+				return 404, nil, err
+			}
+			return 500, nil, err
+		}
+		return 200, carModel, nil
+
+	case "delete":
+		if _, err := uuid.FromString(op.Id); err != nil {
+			return 400, nil, errors.New("delete needs a valid car id")
+		}
+		if err := models.DeleteCarTx(txn, tenant, op.Id); err != nil {
+			if err == models.ErrCarNotFound {
+				return 404, nil, err
+			}
+			return 500, nil, err
+		}
+		return 200, nil, nil
+	}
+
+	return 400, nil, fmt.Errorf("Unknown operation %q, must be create, update or delete", op.Op)
+}
@@ -0,0 +1,195 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/harness"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
+	"github.com/ericmcbride/go-dfw-testing/pkg/server"
+	"github.com/satori/go.uuid"
+)
+
+func postBatch(t *testing.T, payload []byte) (*httptest.ResponseRecorder, handlers.BatchResponse) {
+	req, err := http.NewRequest("POST", "/v1/cars:batch", bytes.NewBuffer(payload))
+	if err != nil {
+		t.Errorf("Error while reading request payload: %s", err)
+	}
+	req.Header.Set("X-CARS-ID", "1234")
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler := server.New()
+	handler.ServeHTTP(rr, req)
+
+	var got handlers.BatchResponse
+	if rr.Code == 200 {
+		if err := openapi.ValidateSchema("BatchResponse", rr.Body.Bytes()); err != nil {
+			t.Errorf("Response doesn't match BatchResponse schema: %s", err)
+		}
+		json.Unmarshal(rr.Body.Bytes(), &got)
+	}
+	return rr, got
+}
+
+func saveTestCar(t *testing.T, db *clients.DBClient) string {
+	carModel := &models.CarModel{
+		Id:    uuid.NewV4().String(),
+		Model: "Corolla",
+		Make:  "Toyota",
+		Color: "White",
+		Year:  2018,
+	}
+	id, err := models.SaveCar(db, carModel)
+	if err != nil {
+		t.Fatalf("Couldn't save model")
+	}
+	return id
+}
+
+func countCars(t *testing.T, db *clients.DBClient) int {
+	var count int
+	err := db.Db.QueryRow(`SELECT count(*) FROM "cars"`).Scan(&count)
+	if err != nil {
+		t.Fatalf("Failed to count cars %v", err)
+	}
+	return count
+}
+
+func TestBatchAtomicRollsBack(t *testing.T) {
+	db, err := clients.NewDbConn()
+	if err != nil {
+		t.Fatalf("Couldnt connect to db")
+	}
+	defer clients.Close(&db)
+
+	existing := saveTestCar(t, &db)
+	payload := []byte(fmt.Sprintf(`{"mode": "atomic", "operations": [
+		{"op": "create", "car": {"make": "Honda", "model": "Civic", "color": "blue", "year": 2016}},
+		{"op": "delete", "id": "%s"},
+		{"op": "delete", "id": "%s"},
+		{"op": "create", "car": {"make": "Ford", "model": "F150", "color": "white", "year": 2018}}
+	]}`, existing, uuid.NewV4().String()))
+
+	rr, got := postBatch(t, payload)
+	if rr.Code != 200 {
+		t.Fatalf("Expected: 200, but got: %d", rr.Code)
+	}
+	if got.Committed {
+		t.Errorf("Expected the batch not to commit")
+	}
+
+	expected := []string{handlers.BatchRolledBack, handlers.BatchRolledBack, handlers.BatchFailed, handlers.BatchSkipped}
+	for i, status := range expected {
+		if got.Results[i].Status != status {
+			t.Errorf("Operation %d: expected %s, got %s", i, status, got.Results[i].Status)
+		}
+	}
+	if got.Results[2].Code != 404 {
+		t.Errorf("Expected: 404, got: %d", got.Results[2].Code)
+	}
+
+	// The delete and create were undone
+	if count := countCars(t, &db); count != 1 {
+		t.Errorf("Expected: 1 car, got: %d", count)
+	}
+	harness.Truncate()
+}
+
+func TestBatchBestEffort(t *testing.T) {
+	db, err := clients.NewDbConn()
+	if err != nil {
+		t.Fatalf("Couldnt connect to db")
+	}
+	defer clients.Close(&db)
+
+	existing := saveTestCar(t, &db)
+	payload := []byte(fmt.Sprintf(`{"mode": "best_effort", "operations": [
+		{"op": "create", "car": {"make": "Honda", "model": "Civic", "color": "blue", "year": 2016}},
+		{"op": "update", "id": "%s", "car": {"make": "Toyota", "model": "Corolla", "color": "red", "year": 2019}},
+		{"op": "create", "car": {"make": "Ford"}},
+		{"op": "explode"}
+	]}`, existing))
+
+	rr, got := postBatch(t, payload)
+	if rr.Code != 200 {
+		t.Fatalf("Expected: 200, but got: %d", rr.Code)
+	}
+	if !got.Committed {
+		t.Errorf("Expected the batch to commit")
+	}
+
+	expected := []int{200, 200, 422, 400}
+	for i, code := range expected {
+		if got.Results[i].Code != code {
+			t.Errorf("Operation %d: expected %d, got %d", i, code, got.Results[i].Code)
+		}
+	}
+
+	car, err := models.GetCar(&db, models.DefaultTenant, existing)
+	if err != nil {
+		t.Fatalf("Failed to lookup car %v", err)
+	}
+	if car.Year != 2019 {
+		t.Errorf("Expected the update to stick, got year %d", car.Year)
+	}
+	if count := countCars(t, &db); count != 2 {
+		t.Errorf("Expected: 2 cars, got: %d", count)
+	}
+	harness.Truncate()
+}
+
+func TestBatchOtherTenant(t *testing.T) {
+	db, err := clients.NewDbConn()
+	if err != nil {
+		t.Fatalf("Couldnt connect to db")
+	}
+	defer clients.Close(&db)
+	defer harness.Truncate()
+
+	other := otherTenantCar(t)
+	payload := []byte(fmt.Sprintf(`{"mode": "best_effort", "operations": [
+		{"op": "update", "id": "%[1]s", "car": {"make": "Toyota", "model": "Corolla", "color": "red", "year": 2019}},
+		{"op": "delete", "id": "%[1]s"}
+	]}`, other.Id))
+
+	rr, got := postBatch(t, payload)
+	if rr.Code != 200 {
+		t.Fatalf("Expected: 200, but got: %d", rr.Code)
+	}
+	for i, result := range got.Results {
+		if result.Code != 404 {
+			t.Errorf("Operation %d: expected 404 for another tenant's car, got %d", i, result.Code)
+		}
+	}
+
+	car, err := models.GetCar(&db, "acme", other.Id)
+	if err != nil {
+		t.Fatalf("Expected another tenant's car to survive the batch, got %v", err)
+	}
+	if car.Year != 2018 || car.Color != "White" {
+		t.Errorf("Expected another tenant's car unchanged, got %+v", car)
+	}
+}
+
+func TestBatchTooLarge(t *testing.T) {
+	large := `{"operations": [{"op": "create", "car": {"make": "` + strings.Repeat("a", int(handlers.MaxBodyBytes)) + `"}}]}`
+	if rr, _ := postBatch(t, []byte(large)); rr.Code != 413 {
+		t.Errorf("Expected: %d, but got: %d %s", 413, rr.Code, rr.Body.String())
+	}
+}
+
+func TestBatchInvalidMode(t *testing.T) {
+	rr, _ := postBatch(t, []byte(`{"mode": "yolo", "operations": [{"op": "delete"}]}`))
+	if rr.Code != 400 {
+		t.Errorf("Expected: %d, but got: %d", 400, rr.Code)
+	}
+}
@@ -1,21 +1,36 @@
 package handlers
 
 import (
-	"encoding/json"
+	"encoding/xml"
 	"errors"
+	"fmt"
+	"github.com/ericmcbride/go-dfw-testing/pkg/catalog"
 	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/lifecycle"
 	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
 	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/money"
 	"github.com/satori/go.uuid"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 500
 )
 
 type CarPostPayload struct {
-	Make  string `json:"make"`
-	Model string `json:"model"`
-	Color string `json:"color"`
-	Year  int    `json:"year"`
+	Make  string `json:"make" xml:"make"`
+	Model string `json:"model" xml:"model"`
+	Color string `json:"color" xml:"color"`
+	Year  int    `json:"year" xml:"year"`
+	// Fills in whichever of make, model and year are left out
+	Vin string `json:"vin,omitempty" xml:"vin,omitempty"`
 }
 
 func CarsHandler(w http.ResponseWriter, r *http.Request) {
@@ -38,7 +53,12 @@ func CarsHandler(w http.ResponseWriter, r *http.Request) {
 	case "DELETE":
 		statusCode, err = DeleteCar(w, r)
 	case "GET":
-		statusCode, err = GetCar(w, r)
+		// Without a car_id at all the request is a listing
+		if _, ok := r.URL.Query()["car_id"]; ok {
+			statusCode, err = GetCar(w, r)
+		} else {
+			statusCode, err = ListCars(w, r)
+		}
 	default:
 		http.Error(w, "Invalid Request Method.", 405)
 		return
@@ -62,6 +82,11 @@ func PostCar(w http.ResponseWriter, r *http.Request) (int, error) {
 	log := logging.GetLog(ctx)
 	log.Info("PostCar: Processing Add Car endpoint...")
 
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
 	// get db conn
 	log.Debug("PostCar: Getting Database Connection...")
 	db, err := clients.NewDbConn()
@@ -71,27 +96,32 @@ func PostCar(w http.ResponseWriter, r *http.Request) (int, error) {
 	defer clients.Close(&db)
 
 	log.Debug("PostCar: Decoding request body...")
-	decoder := json.NewDecoder(r.Body)
-	err = decoder.Decode(&postPayload)
+	statusCode, err = DecodeBody(r, &postPayload)
 	if err != nil {
 		log.WithError(err)
-		return 400, err
+		return statusCode, err
 	}
 
 	log.Debug("PostCar: validating payload...")
-	err = ValidateCarPayload(&postPayload)
+	warnings, err := CheckCarPayload(&postPayload)
 	if err != nil {
 		return 422, err
 	}
+	for _, warning := range warnings {
+		log.Warn("PostCar: ", warning)
+		w.Header().Add("Warning", fmt.Sprintf("199 - %q", warning))
+	}
 
 	log.Debug("PostCar: building model from payload...")
 	carId := uuid.NewV4().String()
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
 	carModel := &models.CarModel{
-		Id:    carId,
-		Model: postPayload.Model,
-		Make:  postPayload.Make,
-		Color: postPayload.Color,
-		Year:  postPayload.Year,
+		Id:     carId,
+		Model:  postPayload.Model,
+		Make:   postPayload.Make,
+		Color:  postPayload.Color,
+		Year:   postPayload.Year,
+		Tenant: tenant,
 	}
 
 	log.Debug("PostCar: Saving Car Model")
@@ -100,18 +130,8 @@ func PostCar(w http.ResponseWriter, r *http.Request) (int, error) {
 		return 500, err
 	}
 
-	// Return valid response if No err comes back from db
-	saveCarJson, err := json.Marshal(carModel)
-	if err != nil {
-		return 400, err
-	}
-
 	// Send back response
-	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.WriteHeader(http.StatusOK)
-	w.Write(saveCarJson)
-
-	return 200, nil
+	return WriteEncoded(w, responseCodec, carModel)
 }
 
 func DeleteCar(w http.ResponseWriter, r *http.Request) (int, error) {
@@ -134,7 +154,8 @@ func DeleteCar(w http.ResponseWriter, r *http.Request) (int, error) {
 	}
 
 	log.Debug("DeleteCar: Deleting car from databse...")
-	err = models.DeleteCar(&db, carId)
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	err = models.DeleteCar(&db, tenant, carId)
 	if err != nil {
 		return 500, err
 	}
@@ -150,6 +171,11 @@ func GetCar(w http.ResponseWriter, r *http.Request) (int, error) {
 	log := logging.GetLog(ctx)
 	log.Info("GetCar: Processing Get Cars endpoint...")
 
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
 	// get db conn
 	log.Debug("GetCar: Getting Database Connection...")
 	db, err := clients.NewDbConn()
@@ -164,44 +190,293 @@ func GetCar(w http.ResponseWriter, r *http.Request) (int, error) {
 		return 400, errors.New("Need a car Id to GET...")
 	}
 
+	sparse, err := ParseSparse(r.URL.Query())
+	if err != nil {
+		return 400, err
+	}
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	if !sparse.Empty() {
+		log.Debug("GetCar: Getting sparse car from databse...")
+		car, err := models.GetCarColumns(&db, tenant, carId, sparse.Columns())
+		if err == models.ErrCarNotFound {
+			return 404, err
+		}
+		if err != nil {
+			return 500, err
+		}
+
+		resources, err := LoadCarResources(&db, []models.CarModel{car}, sparse)
+		if err != nil {
+			return 500, err
+		}
+		return WriteEncoded(w, responseCodec, resources[0])
+	}
+
 	log.Debug("GetCar: Getting car from databse...")
-	car, err := models.GetCar(&db, carId)
+	car, err := models.GetCar(&db, tenant, carId)
+	if err == models.ErrCarNotFound {
+		return 404, err
+	}
 	if err != nil {
 		return 500, err
 	}
 
-	// Return valid response if No err comes back from db
-	carJson, err := json.Marshal(car)
+	cars := []models.CarModel{car}
+	if err := attachOwners(&db, cars); err != nil {
+		return 500, err
+	}
+
+	return WriteEncoded(w, responseCodec, cars[0])
+}
+
+type CarList struct {
+	XMLName xml.Name          `json:"-" xml:"cars"`
+	Cars    []models.CarModel `json:"cars" xml:"car"`
+	Limit   int               `json:"limit" xml:"limit,attr"`
+	Offset  int               `json:"offset" xml:"offset,attr"`
+}
+
+// CarList is a codec.Table so listings can be sent as CSV
+func (l CarList) Header() []string {
+	return []string{"id", "make", "model", "color", "year"}
+}
+
+func (l CarList) Rows() [][]string {
+	rows := make([][]string, 0, len(l.Cars))
+	for _, car := range l.Cars {
+		rows = append(rows, []string{car.Id, car.Make, car.Model, car.Color, strconv.Itoa(car.Year)})
+	}
+	return rows
+}
+
+func ListCars(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("ListCars: Processing List Cars endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, true)
+	if err != nil {
+		return statusCode, err
+	}
+
+	log.Debug("ListCars: Parsing filters...")
+	query := r.URL.Query()
+	filter, err := ParseCarFilter(query)
 	if err != nil {
 		return 400, err
 	}
+	filter.Tenant, _ = TenantForAuthId(r.Header.Get("X-CARS-ID"))
 
-	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.WriteHeader(http.StatusOK)
-	w.Write(carJson)
-	return 200, nil
+	limit, err := queryInt(query, "limit", DefaultListLimit)
+	if err != nil {
+		return 400, err
+	}
+	if limit < 1 || limit > MaxListLimit {
+		return 400, fmt.Errorf("limit must be between 1 and %d", MaxListLimit)
+	}
+
+	offset, err := queryInt(query, "offset", 0)
+	if err != nil {
+		return 400, err
+	}
+	if offset < 0 {
+		return 400, errors.New("offset must not be negative")
+	}
+
+	order, err := ParseCarOrder(query)
+	if err != nil {
+		return 400, err
+	}
+
+	sparse, err := ParseSparse(query)
+	if err != nil {
+		return 400, err
+	}
+
+	// get db conn
+	log.Debug("ListCars: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	if !sparse.Empty() {
+		log.Debug("ListCars: Listing sparse cars from databse...")
+		cars, err := models.ListCarColumns(&db, filter, order, sparse.Columns(), limit, offset)
+		if err != nil {
+			return 500, err
+		}
+
+		resources, err := LoadCarResources(&db, cars, sparse)
+		if err != nil {
+			return 500, err
+		}
+		return WriteEncoded(w, responseCodec, CarResourceList{Cars: resources, Limit: limit, Offset: offset, Fields: sparse.Fields})
+	}
+
+	log.Debug("ListCars: Listing cars from databse...")
+	cars, err := models.ListSortedCars(&db, filter, order, limit, offset)
+	if err != nil {
+		return 500, err
+	}
+
+	if err := attachOwners(&db, cars); err != nil {
+		return 500, err
+	}
+
+	return WriteEncoded(w, responseCodec, CarList{Cars: cars, Limit: limit, Offset: offset})
+}
+
+// Read the listing filters out of the query string: make, model, color, year,
+// year_min, year_max, location_id, status, price_min, price_max, currency,
+// mileage_min and mileage_max.
+func ParseCarFilter(query url.Values) (models.CarFilter, error) {
+	filter := models.CarFilter{
+		Make:       query.Get("make"),
+		Model:      query.Get("model"),
+		Color:      query.Get("color"),
+		LocationId: query.Get("location_id"),
+		Status:     query.Get("status"),
+		Currency:   strings.ToUpper(query.Get("currency")),
+	}
+
+	if filter.Status != "" && !lifecycle.ValidState(filter.Status) {
+		return filter, fmt.Errorf("Unknown status %q", filter.Status)
+	}
+
+	if filter.LocationId != "" {
+		if _, err := uuid.FromString(filter.LocationId); err != nil {
+			return filter, errors.New("location_id must be a valid location Id")
+		}
+	}
+
+	var err error
+	if filter.Year, err = queryInt(query, "year", 0); err != nil {
+		return filter, err
+	}
+	if filter.YearMin, err = queryInt(query, "year_min", 0); err != nil {
+		return filter, err
+	}
+	if filter.YearMax, err = queryInt(query, "year_max", 0); err != nil {
+		return filter, err
+	}
+
+	if filter.Currency != "" && !money.ValidCurrency(filter.Currency) {
+		return filter, fmt.Errorf("Unknown currency %q, currencies must be some of %s", filter.Currency, strings.Join(money.Codes(), ", "))
+	}
+	// Minor units and kilometers, so whole numbers
+	for _, bound := range []struct {
+		name  string
+		unit  string
+		value *int64
+	}{
+		{"price_min", "minor units, like cents", &filter.PriceMin},
+		{"price_max", "minor units, like cents", &filter.PriceMax},
+		{"mileage_min", "kilometers", &filter.MileageMin},
+		{"mileage_max", "kilometers", &filter.MileageMax},
+	} {
+		value := query.Get(bound.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 0 {
+			return filter, fmt.Errorf("%s must be a whole number of %s", bound.name, bound.unit)
+		}
+		*bound.value = parsed
+	}
+	if (filter.PriceMin != 0 || filter.PriceMax != 0) && filter.Currency == "" {
+		return filter, errors.New("price_min and price_max need a currency, prices in different currencies don't compare")
+	}
+
+	return filter, nil
+}
+
+// Read ?sort=, a CarSortColumns name with a leading - for descending
+func ParseCarOrder(query url.Values) (models.CarOrder, error) {
+	value := query.Get("sort")
+	if value == "" {
+		return models.CarOrder{}, nil
+	}
+
+	order := models.CarOrder{Sort: strings.TrimPrefix(value, "-"), Desc: strings.HasPrefix(value, "-")}
+	if _, ok := models.CarSortColumns[order.Sort]; !ok {
+		sorts := make([]string, 0, len(models.CarSortColumns))
+		for name := range models.CarSortColumns {
+			sorts = append(sorts, name)
+		}
+		sort.Strings(sorts)
+		return order, fmt.Errorf("Unknown sort %q, sort must be one of %s, with a leading - for descending", value, strings.Join(sorts, ", "))
+	}
+	return order, nil
+}
+
+func queryInt(query url.Values, name string, fallback int) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a whole number", name)
+	}
+	return parsed, nil
 }
 
 func ValidateCarPayload(payload *CarPostPayload) error {
-	if payload.Make == "" {
-		return errors.New("Make must be included in the payload")
+	_, err := CheckCarPayload(payload)
+	return err
+}
+
+// Validate the payload and fill it in from its VIN and the catalog. Problems
+// that don't stop the car being saved, a VIN that disagrees with the payload
+// or a name the catalog doesn't know, come back as warnings.
+func CheckCarPayload(payload *CarPostPayload) ([]string, error) {
+	warnings, err := ApplyVin(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(payload.Make) == "" {
+		return nil, errors.New("Make must be included in the payload")
 	}
-	if payload.Model == "" {
-		return errors.New("Model must be included in the payload")
+	if strings.TrimSpace(payload.Model) == "" {
+		return nil, errors.New("Model must be included in the payload")
 	}
-	if payload.Color == "" {
-		return errors.New("Color must be included in the payload")
+	// A VIN doesn't say what color the car is
+	if strings.TrimSpace(payload.Color) == "" && strings.TrimSpace(payload.Vin) == "" {
+		return nil, errors.New("Color must be included in the payload")
 	}
 	if payload.Year == 0 {
-		return errors.New("Year must be included in the payload")
+		return nil, errors.New("Year must be included in the payload")
+	}
+
+	// Measured the way they'll be stored, after normalizing whitespace
+	for _, field := range []struct{ name, value string }{
+		{"Make", payload.Make},
+		{"Model", payload.Model},
+		{"Color", payload.Color},
+	} {
+		if utf8.RuneCountInString(models.NormalizeText(field.value)) > models.MaxCarTextLength {
+			return nil, fmt.Errorf("%s must be at most %d characters", field.name, models.MaxCarTextLength)
+		}
 	}
 
-	return nil
+	// Known makes and models are saved by their catalog names
+	if err := ResolveCatalog(payload); err != nil {
+		if catalog.CurrentPolicy == catalog.Reject {
+			return nil, err
+		}
+		warnings = append(warnings, err.Error())
+	}
+
+	return warnings, nil
 }
 
 func ValidateAuthId(auth string) error {
-	if auth != "1234" {
-		return errors.New("Invalid Authorization Header ID")
-	}
-	return nil
+	_, err := TenantForAuthId(auth)
+	return err
 }
@@ -8,12 +8,12 @@ import (
 	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
 	"github.com/ericmcbride/go-dfw-testing/pkg/harness"
 	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
 	"github.com/ericmcbride/go-dfw-testing/pkg/server"
 	"github.com/satori/go.uuid"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
-	"strings"
 	"testing"
 )
 
@@ -135,6 +135,10 @@ func TestCarPostHandler(t *testing.T) {
 		t.Errorf("Error while converting body to string")
 	}
 
+	if err := openapi.ValidateSchema("CarModel", body); err != nil {
+		t.Errorf("Response doesn't match CarModel schema: %s", err)
+	}
+
 	json.Unmarshal([]byte(body), &got)
 	if got.Make != "Toyota" {
 		t.Errorf("Expected: Toyota, got: %s", got.Make)
@@ -186,6 +190,9 @@ func TestCarPostHandlerMissingModelError(t *testing.T) {
 	if rr.Code != 422 {
 		t.Errorf("Expected: %d, but got: %d", 422, rr.Code)
 	}
+	if err := openapi.ValidateSchema("JsonError", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match JsonError schema: %s", err)
+	}
 }
 
 func TestCarPostHandlerMissingMakeError(t *testing.T) {
@@ -367,21 +374,71 @@ func TestGetHandler(t *testing.T) {
 	}
 	var got models.CarModel
 
+	if err := openapi.ValidateSchema("CarModel", body); err != nil {
+		t.Errorf("Response doesn't match CarModel schema: %s", err)
+	}
+
 	json.Unmarshal([]byte(body), &got)
 
-	if strings.EqualFold(got.Model, "Corolla") {
+	if got.Model != "Corolla" {
 		t.Fatalf("Expected: Corolla, got: %s", got.Model)
 	}
-	if strings.EqualFold(got.Make, "Toyota") {
+	if got.Make != "Toyota" {
 		t.Fatalf("Expected: Toyota, got: %s", got.Make)
 	}
-	if strings.EqualFold(got.Color, "White") {
+	if got.Color != "White" {
 		t.Fatalf("Expected: White, got %s", got.Color)
 	}
 
 	harness.Truncate()
 }
 
+// A car saved for another tenant than the one the test credential acts for
+func otherTenantCar(t *testing.T) models.CarModel {
+	db, err := clients.NewDbConn()
+	if err != nil {
+		t.Fatalf("Couldnt connect to db")
+	}
+	defer clients.Close(&db)
+
+	car := models.CarModel{Id: uuid.NewV4().String(), Model: "Corolla", Make: "Toyota", Color: "White", Year: 2018, Tenant: "acme"}
+	if _, err := models.SaveCar(&db, &car); err != nil {
+		t.Fatalf("Couldn't save model")
+	}
+	return car
+}
+
+func TestCarsOtherTenant(t *testing.T) {
+	car := otherTenantCar(t)
+	defer harness.Truncate()
+
+	for _, path := range []string{"/v1/cars?car_id=" + car.Id, "/v1/cars?car_id=" + car.Id + "&fields=make"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		if rr := serveCars(req); rr.Code != 404 {
+			t.Errorf("Expected: 404 for %s, but got: %d %s", path, rr.Code, rr.Body.String())
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/v1/cars", nil)
+	var list handlers.CarList
+	json.Unmarshal(serveCars(req).Body.Bytes(), &list)
+	if len(list.Cars) != 0 {
+		t.Errorf("Expected another tenant's car left out of the listing, got %+v", list.Cars)
+	}
+
+	req, _ = http.NewRequest("DELETE", "/v1/cars?car_id="+car.Id, nil)
+	serveCars(req)
+
+	db, err := clients.NewDbConn()
+	if err != nil {
+		t.Fatalf("Couldnt connect to db")
+	}
+	defer clients.Close(&db)
+	if _, err := models.GetCar(&db, "acme", car.Id); err != nil {
+		t.Errorf("Expected another tenant's car to survive the delete, got %v", err)
+	}
+}
+
 func TestGetCarMissingCarId(t *testing.T) {
 	carIdStr := fmt.Sprintf("/cars?car_id=")
 	req, err := http.NewRequest("GET", carIdStr, nil)
@@ -400,3 +457,126 @@ func TestGetCarMissingCarId(t *testing.T) {
 		t.Errorf("Expected: %d, but got: %d", 400, rr.Code)
 	}
 }
+
+func TestListHandler(t *testing.T) {
+	db, err := clients.NewDbConn()
+	if err != nil {
+		t.Fatalf("Couldnt connect to db")
+	}
+	defer clients.Close(&db)
+
+	for _, year := range []int{2014, 2018} {
+		carModel := &models.CarModel{
+			Id:    uuid.NewV4().String(),
+			Model: "Corolla",
+			Make:  "Toyota",
+			Color: "White",
+			Year:  year,
+		}
+		_, err = models.SaveCar(&db, carModel)
+		if err != nil {
+			t.Fatalf("Couldn't save model")
+		}
+	}
+
+	req, err := http.NewRequest("GET", "/cars?make=Toyota&year_min=2015", nil)
+	if err != nil {
+		t.Errorf("Error while reading request payload: %s", err)
+	}
+	req.Header.Set("X-CARS-ID", "1234")
+
+	rr := httptest.NewRecorder()
+	handler := server.New()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != 200 {
+		t.Errorf("Expected: %d, but got: %d", 200, rr.Code)
+	}
+	if err := openapi.ValidateSchema("CarList", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match CarList schema: %s", err)
+	}
+
+	var got handlers.CarList
+	json.Unmarshal(rr.Body.Bytes(), &got)
+	if len(got.Cars) != 1 || got.Cars[0].Year != 2018 {
+		t.Errorf("Expected only the 2018 car, got: %+v", got.Cars)
+	}
+
+	harness.Truncate()
+}
+
+func TestListHandlerInvalidFilter(t *testing.T) {
+	req, err := http.NewRequest("GET", "/cars?year_min=new", nil)
+	if err != nil {
+		t.Errorf("Error while reading request payload: %s", err)
+	}
+	req.Header.Set("X-CARS-ID", "1234")
+
+	rr := httptest.NewRecorder()
+	handler := server.New()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != 400 {
+		t.Errorf("Expected: %d, but got: %d", 400, rr.Code)
+	}
+}
+
+func TestCarPostHandlerNormalizes(t *testing.T) {
+	var got models.CarModel
+	payload := []byte(`{"make": "  mercedes-BENZ ", "model": "C   300\t4matic", "color": " silver", "year": 2019}`)
+
+	req, err := http.NewRequest("POST", "/cars", bytes.NewBuffer(payload))
+	if err != nil {
+		t.Errorf("Error while reading request payload: %s", err)
+	}
+	req.Header.Set("X-CARS-ID", "1234")
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler := server.New()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != 200 {
+		t.Fatalf("Expected: 200, but got: %d ", rr.Code)
+	}
+	json.Unmarshal(rr.Body.Bytes(), &got)
+	if got.Make != "Mercedes-Benz" || got.Model != "C 300 4matic" || got.Color != "silver" {
+		t.Errorf("Expected a normalized car, got: %+v", got)
+	}
+
+	// Filters compare without case
+	req, _ = http.NewRequest("GET", "/cars?make=MERCEDES-BENZ&color=Silver", nil)
+	req.Header.Set("X-CARS-ID", "1234")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	var list handlers.CarList
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	if len(list.Cars) != 1 || list.Cars[0].Id != got.Id {
+		t.Errorf("Expected the saved car, got: %s", rr.Body.String())
+	}
+
+	harness.Truncate()
+}
+
+func TestCarPostHandlerTooLongError(t *testing.T) {
+	payload, _ := json.Marshal(handlers.CarPostPayload{Make: "Toyota", Model: string(bytes.Repeat([]byte("x"), 129)), Color: "red", Year: 2018})
+
+	req, err := http.NewRequest("POST", "/cars", bytes.NewBuffer(payload))
+	if err != nil {
+		t.Errorf("Error while reading request payload: %s", err)
+	}
+	req.Header.Set("X-CARS-ID", "1234")
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler := server.New()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != 422 {
+		t.Errorf("Expected: %d, but got: %d", 422, rr.Code)
+	}
+	if err := openapi.ValidateSchema("JsonError", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match JsonError schema: %s", err)
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/catalog"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/gorilla/mux"
+)
+
+// A make without its models, for dropdowns
+type CatalogMake struct {
+	Name    string   `json:"name" xml:"name"`
+	Aliases []string `json:"aliases" xml:"alias"`
+}
+
+type CatalogMakeList struct {
+	XMLName xml.Name      `json:"-" xml:"makes"`
+	Makes   []CatalogMake `json:"makes" xml:"make"`
+}
+
+type CatalogModelList struct {
+	XMLName xml.Name        `json:"-" xml:"models"`
+	Make    string          `json:"make" xml:"make,attr"`
+	Models  []catalog.Model `json:"models" xml:"model"`
+}
+
+// Swap the payload's make and model for their catalog names. A make or
+// model the catalog doesn't have is left as is and reported with a
+// *catalog.UnknownError.
+func ResolveCatalog(payload *CarPostPayload) error {
+	if catalog.CurrentPolicy == catalog.Off {
+		return nil
+	}
+
+	var err error
+	payload.Make, payload.Model, err = catalog.Default.Resolve(payload.Make, payload.Model)
+	return err
+}
+
+func CatalogMakesHandler(w http.ResponseWriter, r *http.Request) {
+	serveMethod(w, r, "GET", ListCatalogMakes)
+}
+
+func CatalogModelsHandler(w http.ResponseWriter, r *http.Request) {
+	serveMethod(w, r, "GET", ListCatalogModels)
+}
+
+func ListCatalogMakes(w http.ResponseWriter, r *http.Request) (int, error) {
+	log := logging.GetLog(r.Context())
+	log.Info("ListCatalogMakes: Processing List Catalog Makes endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	makes := catalog.Default.Makes()
+	list := CatalogMakeList{Makes: make([]CatalogMake, 0, len(makes))}
+	for _, m := range makes {
+		list.Makes = append(list.Makes, CatalogMake{Name: m.Name, Aliases: m.Aliases})
+	}
+	return WriteEncoded(w, responseCodec, list)
+}
+
+// The models of the make in the path, which can be an alias
+func ListCatalogModels(w http.ResponseWriter, r *http.Request) (int, error) {
+	log := logging.GetLog(r.Context())
+	log.Info("ListCatalogModels: Processing List Catalog Models endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	name := mux.Vars(r)["make"]
+	m, ok := catalog.Default.FindMake(name)
+	if !ok {
+		return 404, fmt.Errorf("Unknown make %q", name)
+	}
+	return WriteEncoded(w, responseCodec, CatalogModelList{Make: m.Name, Models: m.Models})
+}
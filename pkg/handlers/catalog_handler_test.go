@@ -0,0 +1,118 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/catalog"
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/harness"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
+)
+
+func TestCatalogMakes(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/v1/catalog/makes", nil)
+	rr := serveCars(req)
+
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+	if err := openapi.ValidateSchema("CatalogMakeList", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match CatalogMakeList schema: %s", err)
+	}
+
+	var list handlers.CatalogMakeList
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	if len(list.Makes) != len(catalog.Default.Makes()) || list.Makes[0].Name != "Acura" {
+		t.Errorf("Expected every make alphabetically, got %s", rr.Body.String())
+	}
+}
+
+func TestCatalogModelsByAlias(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/v1/catalog/makes/chevy/models", nil)
+	rr := serveCars(req)
+
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+	if err := openapi.ValidateSchema("CatalogModelList", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match CatalogModelList schema: %s", err)
+	}
+
+	var list handlers.CatalogModelList
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	if list.Make != "Chevrolet" || len(list.Models) == 0 {
+		t.Errorf("Expected Chevrolet's models, got %s", rr.Body.String())
+	}
+}
+
+func TestCatalogModelsUnknownMake(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/v1/catalog/makes/yugo/models", nil)
+	rr := serveCars(req)
+
+	if rr.Code != 404 {
+		t.Errorf("Expected: %d, but got: %d", 404, rr.Code)
+	}
+	if err := openapi.ValidateSchema("JsonError", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match JsonError schema: %s", err)
+	}
+}
+
+func TestCarPostHandlerCatalogAliases(t *testing.T) {
+	defer harness.Truncate()
+
+	payload := []byte(`{"make": "chevy", "model": "silverado", "color": "Black", "year": 2020}`)
+	req, _ := http.NewRequest("POST", "/v1/cars", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	rr := serveCars(req)
+
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get("Warning") != "" {
+		t.Errorf("Expected no warning, got %s", rr.Header().Get("Warning"))
+	}
+
+	var got models.CarModel
+	json.Unmarshal(rr.Body.Bytes(), &got)
+	if got.Make != "Chevrolet" || got.Model != "Silverado 1500" {
+		t.Errorf("Expected the catalog's names, got %+v", got)
+	}
+}
+
+func TestCarPostHandlerCatalogFlag(t *testing.T) {
+	defer harness.Truncate()
+
+	payload := []byte(`{"make": "Ford", "model": "Model T", "color": "Black", "year": 1925}`)
+	req, _ := http.NewRequest("POST", "/v1/cars", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	rr := serveCars(req)
+
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+	expected := `199 - "Unknown model \"Model T\" for Ford"`
+	if rr.Header().Get("Warning") != expected {
+		t.Errorf("Expected warning %s, got %s", expected, rr.Header().Get("Warning"))
+	}
+}
+
+func TestCarPostHandlerCatalogReject(t *testing.T) {
+	catalog.CurrentPolicy = catalog.Reject
+	defer func() { catalog.CurrentPolicy = catalog.Flag }()
+
+	payload := []byte(`{"make": "Yugo", "model": "GV", "color": "Red", "year": 1987}`)
+	req, _ := http.NewRequest("POST", "/v1/cars", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	rr := serveCars(req)
+
+	if rr.Code != 422 {
+		t.Errorf("Expected: %d, but got: %d", 422, rr.Code)
+	}
+	if err := openapi.ValidateSchema("JsonError", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match JsonError schema: %s", err)
+	}
+}
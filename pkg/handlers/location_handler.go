@@ -0,0 +1,770 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+)
+
+const (
+	MaxDealershipNameLength  = 128
+	MaxLocationNameLength    = 128
+	MaxLocationAddressLength = 256
+	MaxMoveNoteLength        = 512
+)
+
+type DealershipPostPayload struct {
+	Name string `json:"name" xml:"name"`
+}
+
+type DealershipList struct {
+	XMLName     xml.Name            `json:"-" xml:"dealerships"`
+	Dealerships []models.Dealership `json:"dealerships" xml:"dealership"`
+	Limit       int                 `json:"limit" xml:"limit,attr"`
+	Offset      int                 `json:"offset" xml:"offset,attr"`
+}
+
+type LocationPostPayload struct {
+	// Only read when the location is added, locations stay with their
+	// dealership
+	DealershipId string `json:"dealership_id" xml:"dealership_id"`
+	Name         string `json:"name" xml:"name"`
+	Address      string `json:"address" xml:"address"`
+}
+
+type LocationList struct {
+	XMLName   xml.Name          `json:"-" xml:"locations"`
+	Locations []models.Location `json:"locations" xml:"location"`
+	Limit     int               `json:"limit" xml:"limit,attr"`
+	Offset    int               `json:"offset" xml:"offset,attr"`
+}
+
+type MovePayload struct {
+	LocationId string `json:"location_id" xml:"location_id"`
+	Note       string `json:"note" xml:"note"`
+}
+
+type MoveList struct {
+	XMLName xml.Name      `json:"-" xml:"moves"`
+	CarId   string        `json:"car_id" xml:"car_id,attr"`
+	Moves   []models.Move `json:"moves" xml:"move"`
+}
+
+// Cars per location. Unassigned counts the cars that aren't at any location
+// and is only filled in when every dealership is counted.
+type Inventory struct {
+	XMLName    xml.Name               `json:"-" xml:"inventory"`
+	Locations  []models.LocationCount `json:"locations" xml:"location"`
+	Unassigned int                    `json:"unassigned" xml:"unassigned,attr"`
+	Total      int                    `json:"total" xml:"total,attr"`
+}
+
+func init() {
+	RegisterInclude("location", func(db *clients.DBClient, carIds []string) (map[string]interface{}, error) {
+		locations, err := models.CarLocations(db, carIds)
+		if err != nil {
+			return nil, err
+		}
+		included := make(map[string]interface{}, len(locations))
+		for carId, location := range locations {
+			included[carId] = location
+		}
+		return included, nil
+	})
+}
+
+func DealershipsHandler(w http.ResponseWriter, r *http.Request) {
+	if !authorize(w, r) {
+		return
+	}
+
+	var (
+		statusCode int
+		err        error
+	)
+
+	switch r.Method {
+	case "POST":
+		statusCode, err = PostDealership(w, r)
+	case "PUT":
+		statusCode, err = PutDealership(w, r)
+	case "DELETE":
+		statusCode, err = DeleteDealership(w, r)
+	case "GET":
+		if _, ok := r.URL.Query()["dealership_id"]; ok {
+			statusCode, err = GetDealership(w, r)
+		} else {
+			statusCode, err = ListDealerships(w, r)
+		}
+	default:
+		http.Error(w, "Invalid Request Method.", 405)
+		return
+	}
+
+	writeHandlerError(w, r, statusCode, err)
+}
+
+func LocationsHandler(w http.ResponseWriter, r *http.Request) {
+	if !authorize(w, r) {
+		return
+	}
+
+	var (
+		statusCode int
+		err        error
+	)
+
+	switch r.Method {
+	case "POST":
+		statusCode, err = PostLocation(w, r)
+	case "PUT":
+		statusCode, err = PutLocation(w, r)
+	case "DELETE":
+		statusCode, err = DeleteLocation(w, r)
+	case "GET":
+		if _, ok := r.URL.Query()["location_id"]; ok {
+			statusCode, err = GetLocation(w, r)
+		} else {
+			statusCode, err = ListLocations(w, r)
+		}
+	default:
+		http.Error(w, "Invalid Request Method.", 405)
+		return
+	}
+
+	writeHandlerError(w, r, statusCode, err)
+}
+
+func InventoryHandler(w http.ResponseWriter, r *http.Request) {
+	serveMethod(w, r, "GET", GetInventory)
+}
+
+func CarMoveHandler(w http.ResponseWriter, r *http.Request) {
+	serveMethod(w, r, "POST", MoveCar)
+}
+
+func CarMovesHandler(w http.ResponseWriter, r *http.Request) {
+	serveMethod(w, r, "GET", ListMoves)
+}
+
+func PostDealership(w http.ResponseWriter, r *http.Request) (int, error) {
+	var postPayload DealershipPostPayload
+
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("PostDealership: Processing Add Dealership endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	log.Debug("PostDealership: Decoding request body...")
+	statusCode, err = DecodeBody(r, &postPayload)
+	if err != nil {
+		return statusCode, err
+	}
+
+	log.Debug("PostDealership: validating payload...")
+	if err = ValidateDealershipPayload(&postPayload); err != nil {
+		return 422, err
+	}
+
+	// get db conn
+	log.Debug("PostDealership: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	dealership := &models.Dealership{
+		Id:     uuid.NewV4().String(),
+		Name:   postPayload.Name,
+		Tenant: tenant,
+	}
+
+	log.Debug("PostDealership: Saving Dealership")
+	if err = models.SaveDealership(&db, dealership); err != nil {
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, dealership)
+}
+
+// Rename the dealership named by dealership_id
+func PutDealership(w http.ResponseWriter, r *http.Request) (int, error) {
+	var postPayload DealershipPostPayload
+
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("PutDealership: Processing Update Dealership endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	dealershipId := r.URL.Query().Get("dealership_id")
+	if _, err := uuid.FromString(dealershipId); err != nil {
+		return 400, errors.New("dealership_id must be a valid dealership Id")
+	}
+
+	log.Debug("PutDealership: Decoding request body...")
+	statusCode, err = DecodeBody(r, &postPayload)
+	if err != nil {
+		return statusCode, err
+	}
+
+	log.Debug("PutDealership: validating payload...")
+	if err = ValidateDealershipPayload(&postPayload); err != nil {
+		return 422, err
+	}
+
+	// get db conn
+	log.Debug("PutDealership: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	dealership := &models.Dealership{
+		Id:     dealershipId,
+		Name:   postPayload.Name,
+		Tenant: tenant,
+	}
+
+	log.Debug("PutDealership: Updating Dealership")
+	err = models.UpdateDealership(&db, dealership)
+	if err == models.ErrDealershipNotFound {
+		return 404, err
+	}
+	if err != nil {
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, dealership)
+}
+
+func GetDealership(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("GetDealership: Processing Get Dealership endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	dealershipId := r.URL.Query().Get("dealership_id")
+	if _, err := uuid.FromString(dealershipId); err != nil {
+		return 400, errors.New("dealership_id must be a valid dealership Id")
+	}
+
+	// get db conn
+	log.Debug("GetDealership: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	dealership, err := models.GetDealership(&db, tenant, dealershipId)
+	if err == models.ErrDealershipNotFound {
+		return 404, err
+	}
+	if err != nil {
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, dealership)
+}
+
+func ListDealerships(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("ListDealerships: Processing List Dealerships endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	limit, offset, err := queryPage(r.URL.Query())
+	if err != nil {
+		return 400, err
+	}
+
+	// get db conn
+	log.Debug("ListDealerships: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	dealerships, err := models.ListDealerships(&db, tenant, limit, offset)
+	if err != nil {
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, DealershipList{Dealerships: dealerships, Limit: limit, Offset: offset})
+}
+
+func DeleteDealership(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("DeleteDealership: Processing Delete Dealership endpoint...")
+
+	dealershipId := r.URL.Query().Get("dealership_id")
+	if _, err := uuid.FromString(dealershipId); err != nil {
+		return 400, errors.New("Need a valid dealership Id to delete...")
+	}
+
+	// get db conn
+	log.Debug("DeleteDealership: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	err = models.DeleteDealership(&db, tenant, dealershipId)
+	switch {
+	case err == models.ErrDealershipNotFound:
+		return 404, err
+	case err == models.ErrDealershipHasLocations:
+		return 409, err
+	case err != nil:
+		return 500, err
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	return 200, nil
+}
+
+func PostLocation(w http.ResponseWriter, r *http.Request) (int, error) {
+	var postPayload LocationPostPayload
+
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("PostLocation: Processing Add Location endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	log.Debug("PostLocation: Decoding request body...")
+	statusCode, err = DecodeBody(r, &postPayload)
+	if err != nil {
+		return statusCode, err
+	}
+
+	log.Debug("PostLocation: validating payload...")
+	if err = ValidateLocationPayload(&postPayload); err != nil {
+		return 422, err
+	}
+	if _, err := uuid.FromString(postPayload.DealershipId); err != nil {
+		return 422, errors.New("DealershipId must be a valid dealership Id")
+	}
+
+	// get db conn
+	log.Debug("PostLocation: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	location := &models.Location{
+		Id:           uuid.NewV4().String(),
+		DealershipId: postPayload.DealershipId,
+		Name:         postPayload.Name,
+		Address:      postPayload.Address,
+		Tenant:       tenant,
+	}
+
+	log.Debug("PostLocation: Saving Location")
+	err = models.SaveLocation(&db, location)
+	if err == models.ErrDealershipNotFound {
+		return 422, err
+	}
+	if err != nil {
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, location)
+}
+
+// Replace the name and address of the location named by location_id
+func PutLocation(w http.ResponseWriter, r *http.Request) (int, error) {
+	var postPayload LocationPostPayload
+
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("PutLocation: Processing Update Location endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	locationId := r.URL.Query().Get("location_id")
+	if _, err := uuid.FromString(locationId); err != nil {
+		return 400, errors.New("location_id must be a valid location Id")
+	}
+
+	log.Debug("PutLocation: Decoding request body...")
+	statusCode, err = DecodeBody(r, &postPayload)
+	if err != nil {
+		return statusCode, err
+	}
+
+	log.Debug("PutLocation: validating payload...")
+	if err = ValidateLocationPayload(&postPayload); err != nil {
+		return 422, err
+	}
+
+	// get db conn
+	log.Debug("PutLocation: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	location := &models.Location{
+		Id:      locationId,
+		Name:    postPayload.Name,
+		Address: postPayload.Address,
+		Tenant:  tenant,
+	}
+
+	log.Debug("PutLocation: Updating Location")
+	err = models.UpdateLocation(&db, location)
+	if err == models.ErrLocationNotFound {
+		return 404, err
+	}
+	if err != nil {
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, location)
+}
+
+func GetLocation(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("GetLocation: Processing Get Location endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	locationId := r.URL.Query().Get("location_id")
+	if _, err := uuid.FromString(locationId); err != nil {
+		return 400, errors.New("location_id must be a valid location Id")
+	}
+
+	// get db conn
+	log.Debug("GetLocation: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	location, err := models.GetLocation(&db, tenant, locationId)
+	if err == models.ErrLocationNotFound {
+		return 404, err
+	}
+	if err != nil {
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, location)
+}
+
+// List the tenant's locations, only one dealership's with ?dealership_id=
+func ListLocations(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("ListLocations: Processing List Locations endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	query := r.URL.Query()
+	limit, offset, err := queryPage(query)
+	if err != nil {
+		return 400, err
+	}
+	dealershipId, err := queryDealershipId(query)
+	if err != nil {
+		return 400, err
+	}
+
+	// get db conn
+	log.Debug("ListLocations: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	locations, err := models.ListLocations(&db, tenant, dealershipId, limit, offset)
+	if err != nil {
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, LocationList{Locations: locations, Limit: limit, Offset: offset})
+}
+
+func DeleteLocation(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("DeleteLocation: Processing Delete Location endpoint...")
+
+	locationId := r.URL.Query().Get("location_id")
+	if _, err := uuid.FromString(locationId); err != nil {
+		return 400, errors.New("Need a valid location Id to delete...")
+	}
+
+	// get db conn
+	log.Debug("DeleteLocation: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	err = models.DeleteLocation(&db, tenant, locationId)
+	switch {
+	case err == models.ErrLocationNotFound:
+		return 404, err
+	case err == models.ErrLocationHasCars:
+		return 409, err
+	case err != nil:
+		return 500, err
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	return 200, nil
+}
+
+// Cars per location, only one dealership's with ?dealership_id=
+func GetInventory(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("GetInventory: Processing Get Inventory endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	dealershipId, err := queryDealershipId(r.URL.Query())
+	if err != nil {
+		return 400, err
+	}
+
+	// get db conn
+	log.Debug("GetInventory: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	counts, unassigned, err := models.CountInventory(&db, tenant, dealershipId)
+	if err != nil {
+		return 500, err
+	}
+
+	inventory := Inventory{Locations: counts, Unassigned: unassigned, Total: unassigned}
+	for _, count := range counts {
+		inventory.Total += count.Cars
+	}
+	return WriteEncoded(w, responseCodec, inventory)
+}
+
+// Move the car in the path to another location, recording the move
+func MoveCar(w http.ResponseWriter, r *http.Request) (int, error) {
+	var payload MovePayload
+
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("MoveCar: Processing Move Car endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	carId := mux.Vars(r)["id"]
+	if _, err := uuid.FromString(carId); err != nil {
+		return 400, errors.New("Need a valid car Id to move...")
+	}
+
+	log.Debug("MoveCar: Decoding request body...")
+	statusCode, err = DecodeBody(r, &payload)
+	if err != nil {
+		return statusCode, err
+	}
+
+	log.Debug("MoveCar: validating payload...")
+	if err = ValidateMovePayload(&payload); err != nil {
+		return 422, err
+	}
+
+	// get db conn
+	log.Debug("MoveCar: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	move, err := models.MoveCar(&db, tenant, carId, payload.LocationId, payload.Note)
+	switch {
+	case err == models.ErrCarNotFound:
+		return 404, err
+	case err == models.ErrLocationNotFound:
+		return 422, err
+	case err == models.ErrAlreadyAtLocation:
+		return 409, err
+	case err != nil:
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, move)
+}
+
+// A car's moves between locations, oldest first
+func ListMoves(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("ListMoves: Processing List Moves endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	carId := mux.Vars(r)["id"]
+	if _, err := uuid.FromString(carId); err != nil {
+		return 400, errors.New("Need a valid car Id to list moves...")
+	}
+
+	// get db conn
+	log.Debug("ListMoves: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	if _, err := models.GetCarColumns(&db, tenant, carId, []string{"id"}); err == models.ErrCarNotFound {
+		return 404, err
+	} else if err != nil {
+		return 500, err
+	}
+
+	moves, err := models.ListMoves(&db, tenant, carId)
+	if err != nil {
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, MoveList{CarId: carId, Moves: moves})
+}
+
+// Read ?limit= and ?offset= for a listing
+func queryPage(query url.Values) (int, int, error) {
+	limit, err := queryInt(query, "limit", DefaultListLimit)
+	if err != nil {
+		return 0, 0, err
+	}
+	if limit < 1 || limit > MaxListLimit {
+		return 0, 0, fmt.Errorf("limit must be between 1 and %d", MaxListLimit)
+	}
+
+	offset, err := queryInt(query, "offset", 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	if offset < 0 {
+		return 0, 0, errors.New("offset must not be negative")
+	}
+	return limit, offset, nil
+}
+
+func queryDealershipId(query url.Values) (string, error) {
+	dealershipId := query.Get("dealership_id")
+	if dealershipId == "" {
+		return "", nil
+	}
+	if _, err := uuid.FromString(dealershipId); err != nil {
+		return "", errors.New("dealership_id must be a valid dealership Id")
+	}
+	return dealershipId, nil
+}
+
+func ValidateDealershipPayload(payload *DealershipPostPayload) error {
+	payload.Name = strings.Join(strings.Fields(payload.Name), " ")
+
+	if payload.Name == "" {
+		return errors.New("Name must be included in the payload")
+	}
+	if utf8.RuneCountInString(payload.Name) > MaxDealershipNameLength {
+		return fmt.Errorf("Name must be at most %d characters", MaxDealershipNameLength)
+	}
+	return nil
+}
+
+func ValidateLocationPayload(payload *LocationPostPayload) error {
+	payload.Name = strings.Join(strings.Fields(payload.Name), " ")
+	payload.Address = strings.TrimSpace(payload.Address)
+
+	if payload.Name == "" {
+		return errors.New("Name must be included in the payload")
+	}
+	if utf8.RuneCountInString(payload.Name) > MaxLocationNameLength {
+		return fmt.Errorf("Name must be at most %d characters", MaxLocationNameLength)
+	}
+	if utf8.RuneCountInString(payload.Address) > MaxLocationAddressLength {
+		return fmt.Errorf("Address must be at most %d characters", MaxLocationAddressLength)
+	}
+	return nil
+}
+
+func ValidateMovePayload(payload *MovePayload) error {
+	payload.Note = strings.TrimSpace(payload.Note)
+
+	if _, err := uuid.FromString(payload.LocationId); err != nil {
+		return errors.New("LocationId must be a valid location Id")
+	}
+	if utf8.RuneCountInString(payload.Note) > MaxMoveNoteLength {
+		return fmt.Errorf("Note must be at most %d characters", MaxMoveNoteLength)
+	}
+	return nil
+}
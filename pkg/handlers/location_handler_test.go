@@ -0,0 +1,226 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/harness"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
+)
+
+func postDealership(t *testing.T, name string) models.Dealership {
+	req, _ := http.NewRequest("POST", "/v1/dealerships", strings.NewReader(`{"name": "`+name+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := serveCars(req)
+	if rr.Code != 200 {
+		t.Fatalf("Couldn't save dealership: %d %s", rr.Code, rr.Body.String())
+	}
+
+	var dealership models.Dealership
+	json.Unmarshal(rr.Body.Bytes(), &dealership)
+	return dealership
+}
+
+func postLocation(t *testing.T, dealershipId string, name string) models.Location {
+	body, _ := json.Marshal(handlers.LocationPostPayload{DealershipId: dealershipId, Name: name, Address: "1 Main St"})
+	req, _ := http.NewRequest("POST", "/v1/locations", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := serveCars(req)
+	if rr.Code != 200 {
+		t.Fatalf("Couldn't save location: %d %s", rr.Code, rr.Body.String())
+	}
+
+	var location models.Location
+	json.Unmarshal(rr.Body.Bytes(), &location)
+	return location
+}
+
+func move(carId string, locationId string) *bytes.Buffer {
+	body, _ := json.Marshal(handlers.MovePayload{LocationId: locationId, Note: "Moved for the test"})
+	req, _ := http.NewRequest("POST", "/v1/cars/"+carId+"/move", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := serveCars(req)
+	if rr.Code != 200 {
+		return bytes.NewBufferString(rr.Result().Status + " " + rr.Body.String())
+	}
+	return rr.Body
+}
+
+func TestDealershipAndLocationCRUD(t *testing.T) {
+	defer harness.Truncate()
+
+	dealership := postDealership(t, "  Downtown   Motors ")
+	if dealership.Name != "Downtown Motors" {
+		t.Errorf("Expected a normalized name, got %q", dealership.Name)
+	}
+	location := postLocation(t, dealership.Id, "North Lot")
+
+	req, _ := http.NewRequest("PUT", "/v1/locations?location_id="+location.Id, strings.NewReader(`{"name": "South Lot", "address": "2 Main St"}`))
+	req.Header.Set("Content-Type", "application/json")
+	if rr := serveCars(req); rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/locations?location_id="+location.Id, nil)
+	rr := serveCars(req)
+	if err := openapi.ValidateSchema("Location", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match Location schema: %s", err)
+	}
+	var got models.Location
+	json.Unmarshal(rr.Body.Bytes(), &got)
+	if got.Name != "South Lot" || got.Address != "2 Main St" || got.DealershipId != dealership.Id {
+		t.Errorf("Expected the update to replace the location, got %+v", got)
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/locations?dealership_id="+dealership.Id, nil)
+	rr = serveCars(req)
+	if err := openapi.ValidateSchema("LocationList", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match LocationList schema: %s", err)
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/dealerships", nil)
+	rr = serveCars(req)
+	if err := openapi.ValidateSchema("DealershipList", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match DealershipList schema: %s", err)
+	}
+
+	// A dealership goes after its locations
+	req, _ = http.NewRequest("DELETE", "/v1/dealerships?dealership_id="+dealership.Id, nil)
+	if rr = serveCars(req); rr.Code != 409 {
+		t.Errorf("Expected: %d, but got: %d", 409, rr.Code)
+	}
+	req, _ = http.NewRequest("DELETE", "/v1/locations?location_id="+location.Id, nil)
+	if rr = serveCars(req); rr.Code != 200 {
+		t.Errorf("Expected: %d, but got: %d", 200, rr.Code)
+	}
+	req, _ = http.NewRequest("DELETE", "/v1/dealerships?dealership_id="+dealership.Id, nil)
+	if rr = serveCars(req); rr.Code != 200 {
+		t.Errorf("Expected: %d, but got: %d", 200, rr.Code)
+	}
+}
+
+func TestLocationPostUnknownDealership(t *testing.T) {
+	body := `{"dealership_id": "00000000-0000-4000-8000-000000000000", "name": "Nowhere"}`
+	req, _ := http.NewRequest("POST", "/v1/locations", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := serveCars(req)
+
+	if rr.Code != 422 {
+		t.Errorf("Expected: %d, but got: %d", 422, rr.Code)
+	}
+	if err := openapi.ValidateSchema("JsonError", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match JsonError schema: %s", err)
+	}
+}
+
+func TestMoveCar(t *testing.T) {
+	car := saveNegotiationCar(t)
+	defer harness.Truncate()
+	dealership := postDealership(t, "Downtown Motors")
+	north, south := postLocation(t, dealership.Id, "North Lot"), postLocation(t, dealership.Id, "South Lot")
+
+	var first models.Move
+	json.Unmarshal(move(car.Id, north.Id).Bytes(), &first)
+	if first.FromLocationId != nil || first.ToLocationId == nil || *first.ToLocationId != north.Id {
+		t.Fatalf("Expected a first placement at the north lot, got %+v", first)
+	}
+
+	body := move(car.Id, south.Id)
+	if err := openapi.ValidateSchema("Move", body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match Move schema: %s %s", err, body)
+	}
+
+	if body := move(car.Id, south.Id).String(); !strings.HasPrefix(body, "409") {
+		t.Errorf("Expected a move to the same location to be a 409, got %s", body)
+	}
+	if body := move(car.Id, "00000000-0000-4000-8000-000000000000").String(); !strings.HasPrefix(body, "422") {
+		t.Errorf("Expected a move to an unknown location to be a 422, got %s", body)
+	}
+	if body := move("00000000-0000-4000-8000-000000000000", north.Id).String(); !strings.HasPrefix(body, "404") {
+		t.Errorf("Expected moving an unknown car to be a 404, got %s", body)
+	}
+
+	// The car carries its location and listings filter on it
+	req, _ := http.NewRequest("GET", "/v1/cars?car_id="+car.Id, nil)
+	rr := serveCars(req)
+	if err := openapi.ValidateSchema("CarModel", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match CarModel schema: %s", err)
+	}
+	var got models.CarModel
+	json.Unmarshal(rr.Body.Bytes(), &got)
+	if got.LocationId == nil || *got.LocationId != south.Id {
+		t.Errorf("Expected the car at the south lot, got %s", rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/cars?location_id="+north.Id, nil)
+	rr = serveCars(req)
+	var list handlers.CarList
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	if len(list.Cars) != 0 {
+		t.Errorf("Expected no cars at the north lot, got %s", rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/cars?fields=id&include=location", nil)
+	rr = serveCars(req)
+	if !strings.Contains(rr.Body.String(), `"name":"South Lot"`) {
+		t.Errorf("Expected the location include, got %s", rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/cars/"+car.Id+"/moves", nil)
+	rr = serveCars(req)
+	if err := openapi.ValidateSchema("MoveList", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match MoveList schema: %s", err)
+	}
+	var history handlers.MoveList
+	json.Unmarshal(rr.Body.Bytes(), &history)
+	if len(history.Moves) != 2 || history.Moves[1].FromLocationId == nil || *history.Moves[1].FromLocationId != north.Id {
+		t.Errorf("Expected both moves oldest first, got %s", rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/locations/inventory", nil)
+	rr = serveCars(req)
+	if err := openapi.ValidateSchema("Inventory", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match Inventory schema: %s", err)
+	}
+	var inventory handlers.Inventory
+	json.Unmarshal(rr.Body.Bytes(), &inventory)
+	counts := map[string]int{}
+	for _, count := range inventory.Locations {
+		counts[count.LocationId] = count.Cars
+	}
+	if counts[north.Id] != 0 || counts[south.Id] != 1 || len(counts) != 2 || inventory.Total != 1 {
+		t.Errorf("Expected one car at the south lot, got %s", rr.Body.String())
+	}
+
+	// A location holding cars can't be deleted
+	req, _ = http.NewRequest("DELETE", "/v1/locations?location_id="+south.Id, nil)
+	if rr = serveCars(req); rr.Code != 409 {
+		t.Errorf("Expected: %d, but got: %d", 409, rr.Code)
+	}
+	req, _ = http.NewRequest("DELETE", "/v1/locations?location_id="+north.Id, nil)
+	if rr = serveCars(req); rr.Code != 200 {
+		t.Errorf("Expected: %d, but got: %d", 200, rr.Code)
+	}
+}
+
+func TestValidateMovePayload(t *testing.T) {
+	payload := handlers.MovePayload{LocationId: "00000000-0000-4000-8000-000000000000", Note: "  to the front  "}
+	if err := handlers.ValidateMovePayload(&payload); err != nil || payload.Note != "to the front" {
+		t.Errorf("Expected a trimmed note, got %q %v", payload.Note, err)
+	}
+
+	for _, payload := range []handlers.MovePayload{
+		{},
+		{LocationId: "north lot"},
+		{LocationId: "00000000-0000-4000-8000-000000000000", Note: strings.Repeat("n", handlers.MaxMoveNoteLength+1)},
+	} {
+		if err := handlers.ValidateMovePayload(&payload); err == nil {
+			t.Errorf("%+v: expected a validation error", payload)
+		}
+	}
+}
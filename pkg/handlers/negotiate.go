@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/codec"
+)
+
+// Pick how to encode the response from the Accept header. table says
+// whether the response is a collection, which CSV needs.
+func ResponseCodec(r *http.Request, table bool) (*codec.Codec, int, error) {
+	c, ok := codec.ForAccept(r.Header.Get("Accept"), table)
+	if !ok {
+		return nil, 406, fmt.Errorf("Accept must allow one of %s", strings.Join(codec.Acceptable(table), ", "))
+	}
+	return c, 0, nil
+}
+
+// Largest request body DecodeBody reads
+var MaxBodyBytes int64 = 1 << 20
+
+// Decode the request body with the codec its Content-Type names. Bodies over
+// MaxBodyBytes are refused with a 413.
+func DecodeBody(r *http.Request, v interface{}) (int, error) {
+	c, ok := codec.ForContentType(r.Header.Get("Content-Type"))
+	if !ok {
+		return 415, fmt.Errorf("Content-Type must be one of %s", strings.Join(codec.Readable(), ", "))
+	}
+
+	body := &countedBody{ReadCloser: http.MaxBytesReader(nil, r.Body, MaxBodyBytes)}
+	r.Body = body
+	if err := c.Decode(body, v); err != nil {
+		if body.read >= MaxBodyBytes && body.err != nil && body.err != io.EOF {
+			return 413, fmt.Errorf("Request body must be at most %d bytes", MaxBodyBytes)
+		}
+		return 400, err
+	}
+	return 0, nil
+}
+
+// Counts what's read through it, so DecodeBody can tell a body cut off at
+// MaxBodyBytes from one that's malformed
+type countedBody struct {
+	io.ReadCloser
+	read int64
+	err  error
+}
+
+func (b *countedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if err != nil {
+		b.err = err
+	}
+	return n, err
+}
+
+// Encode body with c and send it as a 200
+func WriteEncoded(w http.ResponseWriter, c *codec.Codec, body interface{}) (int, error) {
+	var buf bytes.Buffer
+	if err := c.Encode(&buf, body); err != nil {
+		return 500, err
+	}
+
+	contentType := c.ContentType()
+	if !c.Binary {
+		contentType += "; charset=UTF-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+	return 200, nil
+}
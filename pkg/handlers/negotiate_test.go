@@ -0,0 +1,167 @@
+package handlers_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/codec"
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/harness"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
+	"github.com/ericmcbride/go-dfw-testing/pkg/server"
+	"github.com/satori/go.uuid"
+)
+
+func saveNegotiationCar(t *testing.T) models.CarModel {
+	db, err := clients.NewDbConn()
+	if err != nil {
+		t.Fatalf("Couldnt connect to db")
+	}
+	defer clients.Close(&db)
+
+	car := models.CarModel{Id: uuid.NewV4().String(), Model: "Corolla", Make: "Toyota", Color: "White", Year: 2018}
+	if _, err := models.SaveCar(&db, &car); err != nil {
+		t.Fatalf("Couldn't save model")
+	}
+	return car
+}
+
+func serveCars(req *http.Request) *httptest.ResponseRecorder {
+	req.Header.Set("X-CARS-ID", "1234")
+	rr := httptest.NewRecorder()
+	server.New().ServeHTTP(rr, req)
+	return rr
+}
+
+func TestGetCarAsXML(t *testing.T) {
+	car := saveNegotiationCar(t)
+	defer harness.Truncate()
+
+	req, _ := http.NewRequest("GET", "/v1/cars?car_id="+car.Id, nil)
+	req.Header.Set("Accept", "application/xml")
+	rr := serveCars(req)
+
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d", 200, rr.Code)
+	}
+	if contentType := rr.Header().Get("Content-Type"); contentType != "application/xml; charset=UTF-8" {
+		t.Errorf("Expected an XML Content-Type, got %s", contentType)
+	}
+
+	var got models.CarModel
+	if err := codec.XML.Decode(rr.Body, &got); err != nil {
+		t.Fatalf("Couldn't decode XML %s", err)
+	}
+	if got.Id != car.Id || got.Year != 2018 {
+		t.Errorf("Expected %v, got %v", car, got)
+	}
+}
+
+func TestPostCarAsMessagePack(t *testing.T) {
+	defer harness.Truncate()
+
+	var body bytes.Buffer
+	payload := handlers.CarPostPayload{Make: "Honda", Model: "Civic", Color: "blue", Year: 2016}
+	if err := codec.MessagePack.Encode(&body, payload); err != nil {
+		t.Fatalf("Couldn't encode payload %s", err)
+	}
+
+	req, _ := http.NewRequest("POST", "/v1/cars", &body)
+	req.Header.Set("Content-Type", "application/msgpack")
+	req.Header.Set("Accept", "application/msgpack")
+	rr := serveCars(req)
+
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+	if contentType := rr.Header().Get("Content-Type"); contentType != "application/msgpack" {
+		t.Errorf("Expected a MessagePack Content-Type, got %s", contentType)
+	}
+
+	var got models.CarModel
+	if err := codec.MessagePack.Decode(rr.Body, &got); err != nil {
+		t.Fatalf("Couldn't decode MessagePack %s", err)
+	}
+	if got.Id == "" || got.Make != "Honda" || got.Year != 2016 {
+		t.Errorf("Expected the saved Civic, got %v", got)
+	}
+}
+
+func TestListCarsAsCSV(t *testing.T) {
+	car := saveNegotiationCar(t)
+	defer harness.Truncate()
+
+	req, _ := http.NewRequest("GET", "/v1/cars?make=Toyota", nil)
+	req.Header.Set("Accept", "text/csv")
+	rr := serveCars(req)
+
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d", 200, rr.Code)
+	}
+	lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+	if len(lines) != 2 || lines[0] != "id,make,model,color,year" || !strings.HasPrefix(lines[1], car.Id+",") {
+		t.Errorf("Expected a header and the Corolla, got %q", rr.Body.String())
+	}
+}
+
+func TestNotAcceptable(t *testing.T) {
+	car := saveNegotiationCar(t)
+	defer harness.Truncate()
+
+	// CSV is only for listings
+	for _, accept := range []string{"image/png", "text/csv"} {
+		req, _ := http.NewRequest("GET", "/v1/cars?car_id="+car.Id, nil)
+		req.Header.Set("Accept", accept)
+		rr := serveCars(req)
+
+		if rr.Code != 406 {
+			t.Errorf("%s: expected: %d, but got: %d", accept, 406, rr.Code)
+		}
+		if err := openapi.ValidateSchema("JsonError", rr.Body.Bytes()); err != nil {
+			t.Errorf("%s: response doesn't match JsonError schema: %s", accept, err)
+		}
+	}
+}
+
+func TestUnsupportedMediaType(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/v1/cars", strings.NewReader("make=Toyota"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := serveCars(req)
+
+	if rr.Code != 415 {
+		t.Errorf("Expected: %d, but got: %d", 415, rr.Code)
+	}
+	if err := openapi.ValidateSchema("JsonError", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match JsonError schema: %s", err)
+	}
+}
+
+func TestDecodeBodyLimits(t *testing.T) {
+	var payload handlers.CarPostPayload
+
+	large := `{"make": "` + strings.Repeat("a", int(handlers.MaxBodyBytes)) + `"}`
+	req, _ := http.NewRequest("POST", "/v1/cars", strings.NewReader(large))
+	req.Header.Set("Content-Type", "application/json")
+	if statusCode, err := handlers.DecodeBody(req, &payload); statusCode != 413 {
+		t.Errorf("Expected: %d, but got: %d %v", 413, statusCode, err)
+	}
+
+	// Arrays nested deeper than the decoder allows
+	nested := bytes.Repeat([]byte{0x91}, 20<<20)
+	req, _ = http.NewRequest("POST", "/v1/cars", bytes.NewReader(nested))
+	req.Header.Set("Content-Type", "application/msgpack")
+	if statusCode, err := handlers.DecodeBody(req, &payload); statusCode != 400 {
+		t.Errorf("Expected: %d, but got: %d %v", 400, statusCode, err)
+	}
+
+	req, _ = http.NewRequest("POST", "/v1/cars", strings.NewReader(`{"make": "Honda"`))
+	req.Header.Set("Content-Type", "application/json")
+	if statusCode, err := handlers.DecodeBody(req, &payload); statusCode != 400 {
+		t.Errorf("Expected a truncated body to be a %d, but got: %d %v", 400, statusCode, err)
+	}
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/odometer"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+)
+
+const MaxOdometerSourceLength = 128
+
+// An odometer reading as it was read. ReadOn defaults to today.
+type OdometerPayload struct {
+	Value  *int64 `json:"value" xml:"value"`
+	Unit   string `json:"unit" xml:"unit"`
+	ReadOn string `json:"read_on" xml:"read_on"`
+	// Where the reading came from, like "service visit" or "title"
+	Source string `json:"source" xml:"source"`
+}
+
+// A car's latest mileage and every reading it's had, oldest first. The
+// mileage is left out until the car has a reading.
+type OdometerHistory struct {
+	XMLName   xml.Name                 `json:"-" xml:"odometer"`
+	CarId     string                   `json:"car_id" xml:"car_id,attr"`
+	MileageKm *int64                   `json:"mileage_km,omitempty" xml:"mileage_km,attr,omitempty"`
+	Readings  []models.OdometerReading `json:"readings" xml:"reading"`
+}
+
+func CarOdometerHandler(w http.ResponseWriter, r *http.Request) {
+	if !authorize(w, r) {
+		return
+	}
+
+	var (
+		statusCode int
+		err        error
+	)
+
+	switch r.Method {
+	case "POST":
+		statusCode, err = RecordOdometer(w, r)
+	case "GET":
+		statusCode, err = ListOdometer(w, r)
+	default:
+		http.Error(w, "Invalid Request Method.", 405)
+		return
+	}
+
+	writeHandlerError(w, r, statusCode, err)
+}
+
+// Record an odometer reading for the car in the path. Readings lower than
+// an earlier one are kept but flagged as a suspected rollback.
+func RecordOdometer(w http.ResponseWriter, r *http.Request) (int, error) {
+	var payload OdometerPayload
+
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("RecordOdometer: Processing Record Odometer endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	carId := mux.Vars(r)["id"]
+	if _, err := uuid.FromString(carId); err != nil {
+		return 400, errors.New("Need a valid car Id to record a reading...")
+	}
+
+	log.Debug("RecordOdometer: Decoding request body...")
+	statusCode, err = DecodeBody(r, &payload)
+	if err != nil {
+		return statusCode, err
+	}
+
+	log.Debug("RecordOdometer: validating payload...")
+	if err = ValidateOdometerPayload(&payload, time.Now()); err != nil {
+		return 422, err
+	}
+
+	// get db conn
+	log.Debug("RecordOdometer: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	reading := models.OdometerReading{
+		Value:  *payload.Value,
+		Unit:   payload.Unit,
+		ReadOn: payload.ReadOn,
+		Source: payload.Source,
+	}
+	err = models.RecordOdometer(&db, tenant, carId, &reading)
+	if err == models.ErrCarNotFound {
+		return 404, err
+	}
+	if err != nil {
+		return 500, err
+	}
+	if reading.SuspectedRollback {
+		log.Warn("RecordOdometer: suspected rollback on car ", carId)
+	}
+	return WriteEncoded(w, responseCodec, reading)
+}
+
+// A car's latest mileage and its odometer readings
+func ListOdometer(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("ListOdometer: Processing List Odometer endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	carId := mux.Vars(r)["id"]
+	if _, err := uuid.FromString(carId); err != nil {
+		return 400, errors.New("Need a valid car Id to list readings...")
+	}
+
+	// get db conn
+	log.Debug("ListOdometer: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	car, err := models.GetCarColumns(&db, tenant, carId, []string{"mileage_km"})
+	if err == models.ErrCarNotFound {
+		return 404, err
+	}
+	if err != nil {
+		return 500, err
+	}
+
+	readings, err := models.ListOdometer(&db, tenant, carId)
+	if err != nil {
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, OdometerHistory{
+		CarId:     carId,
+		MileageKm: car.MileageKm,
+		Readings:  readings,
+	})
+}
+
+// Check the payload, which can't be dated after today
+func ValidateOdometerPayload(payload *OdometerPayload, now time.Time) error {
+	payload.Unit = strings.ToLower(strings.TrimSpace(payload.Unit))
+	payload.Source = strings.TrimSpace(payload.Source)
+
+	if payload.Value == nil {
+		return errors.New("Value must be included in the payload")
+	}
+	if *payload.Value < 0 || *payload.Value > odometer.MaxValue {
+		return fmt.Errorf("Value must be between 0 and %d", odometer.MaxValue)
+	}
+	if !odometer.ValidUnit(payload.Unit) {
+		return fmt.Errorf("Unit must be one of %s", strings.Join(odometer.UnitNames(), ", "))
+	}
+	if payload.Source == "" {
+		return errors.New("Source must be included in the payload")
+	}
+	if utf8.RuneCountInString(payload.Source) > MaxOdometerSourceLength {
+		return fmt.Errorf("Source must be at most %d characters", MaxOdometerSourceLength)
+	}
+
+	today := now.UTC().Format(models.DateLayout)
+	if payload.ReadOn == "" {
+		payload.ReadOn = today
+	}
+	if _, err := time.Parse(models.DateLayout, payload.ReadOn); err != nil {
+		return fmt.Errorf("ReadOn must be a date like %s", models.DateLayout)
+	}
+	if payload.ReadOn > today {
+		return errors.New("ReadOn can't be in the future")
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/harness"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
+)
+
+func readOdometer(carId string, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/v1/cars/"+carId+"/odometer", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return serveCars(req)
+}
+
+func TestRecordOdometer(t *testing.T) {
+	car := saveNegotiationCar(t)
+	defer harness.Truncate()
+
+	rr := readOdometer(car.Id, `{"value": 10000, "unit": "miles", "read_on": "2024-03-01", "source": "title"}`)
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+	if err := openapi.ValidateSchema("OdometerReading", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match OdometerReading schema: %s %s", err, rr.Body.String())
+	}
+	var reading models.OdometerReading
+	json.Unmarshal(rr.Body.Bytes(), &reading)
+	if reading.Km != 16093 || reading.SuspectedRollback {
+		t.Errorf("Expected 16093 km and no rollback, got %+v", reading)
+	}
+
+	// The same distance read in kilometers isn't a rollback
+	rr = readOdometer(car.Id, `{"value": 16093, "unit": "km", "read_on": "2024-06-01", "source": "service visit"}`)
+	json.Unmarshal(rr.Body.Bytes(), &reading)
+	if rr.Code != 200 || reading.SuspectedRollback {
+		t.Errorf("Expected an honest reading, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = readOdometer(car.Id, `{"value": 8000, "unit": "miles", "read_on": "2025-01-15", "source": "auction"}`)
+	json.Unmarshal(rr.Body.Bytes(), &reading)
+	if rr.Code != 200 || !reading.SuspectedRollback {
+		t.Errorf("Expected a suspected rollback, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	// Backfilled readings don't move the car's mileage
+	rr = readOdometer(car.Id, `{"value": 5000, "unit": "miles", "read_on": "2023-01-01", "source": "owner"}`)
+	json.Unmarshal(rr.Body.Bytes(), &reading)
+	if rr.Code != 200 || reading.SuspectedRollback {
+		t.Errorf("Expected a backfilled reading without a rollback, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	// but one higher than a reading taken after it is flagged
+	rr = readOdometer(car.Id, `{"value": 14000, "unit": "km", "read_on": "2023-06-01", "source": "owner"}`)
+	json.Unmarshal(rr.Body.Bytes(), &reading)
+	if rr.Code != 200 || !reading.SuspectedRollback {
+		t.Errorf("Expected a backfilled reading above a later one to be flagged, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	req, _ := http.NewRequest("GET", "/v1/cars/"+car.Id+"/odometer", nil)
+	rr = serveCars(req)
+	if err := openapi.ValidateSchema("OdometerHistory", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match OdometerHistory schema: %s %s", err, rr.Body.String())
+	}
+	var history handlers.OdometerHistory
+	json.Unmarshal(rr.Body.Bytes(), &history)
+	if history.MileageKm == nil || *history.MileageKm != 12875 || len(history.Readings) != 5 || history.Readings[0].ReadOn != "2023-01-01" {
+		t.Errorf("Expected the latest mileage and every reading by date, got %s", rr.Body.String())
+	}
+
+	if rr = readOdometer("00000000-0000-4000-8000-000000000000", `{"value": 1, "unit": "km", "source": "title"}`); rr.Code != 404 {
+		t.Errorf("Expected: %d, but got: %d", 404, rr.Code)
+	}
+}
+
+func TestOdometerOtherTenant(t *testing.T) {
+	car := otherTenantCar(t)
+	defer harness.Truncate()
+
+	req, _ := http.NewRequest("GET", "/v1/cars/"+car.Id+"/odometer", nil)
+	if rr := serveCars(req); rr.Code != 404 {
+		t.Errorf("Expected another tenant's readings to be a %d, got %d %s", 404, rr.Code, rr.Body.String())
+	}
+	if rr := readOdometer(car.Id, `{"value": 10000, "unit": "miles", "source": "dealer"}`); rr.Code != 404 {
+		t.Errorf("Expected reading another tenant's car to be a %d, got %d", 404, rr.Code)
+	}
+}
+
+func TestListCarsByMileage(t *testing.T) {
+	defer harness.Truncate()
+
+	low, high, unread := saveNegotiationCar(t), saveNegotiationCar(t), saveNegotiationCar(t)
+	readOdometer(low.Id, `{"value": 12000, "unit": "km", "source": "dealer"}`)
+	readOdometer(high.Id, `{"value": 90000, "unit": "miles", "source": "dealer"}`)
+
+	list := func(query string) []string {
+		req, _ := http.NewRequest("GET", "/v1/cars?"+query, nil)
+		rr := serveCars(req)
+		if rr.Code != 200 {
+			t.Fatalf("%s: expected: %d, but got: %d %s", query, 200, rr.Code, rr.Body.String())
+		}
+		var cars handlers.CarList
+		json.Unmarshal(rr.Body.Bytes(), &cars)
+		ids := []string{}
+		for _, car := range cars.Cars {
+			ids = append(ids, car.Id)
+		}
+		return ids
+	}
+
+	if ids := list("sort=-mileage"); strings.Join(ids, ",") != strings.Join([]string{high.Id, low.Id, unread.Id}, ",") {
+		t.Errorf("Expected highest mileage first and unread last, got %v", ids)
+	}
+	if ids := list("mileage_max=50000"); len(ids) != 1 || ids[0] != low.Id {
+		t.Errorf("Expected only the low mileage car, got %v", ids)
+	}
+	if ids := list("mileage_min=100000"); len(ids) != 1 || ids[0] != high.Id {
+		t.Errorf("Expected only the high mileage car, got %v", ids)
+	}
+}
+
+func TestValidateOdometerPayload(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	value := int64(42000)
+
+	payload := handlers.OdometerPayload{Value: &value, Unit: " Miles ", Source: "title"}
+	if err := handlers.ValidateOdometerPayload(&payload, now); err != nil || payload.Unit != "miles" || payload.ReadOn != "2025-06-01" {
+		t.Errorf("Expected a reading in miles dated today, got %+v %v", payload, err)
+	}
+
+	negative, huge := int64(-1), int64(10000000)
+	cases := []handlers.OdometerPayload{
+		{Unit: "km", Source: "title"},
+		{Value: &negative, Unit: "km", Source: "title"},
+		{Value: &huge, Unit: "km", Source: "title"},
+		{Value: &value, Unit: "furlongs", Source: "title"},
+		{Value: &value, Unit: "km"},
+		{Value: &value, Unit: "km", Source: strings.Repeat("a", handlers.MaxOdometerSourceLength+1)},
+		{Value: &value, Unit: "km", Source: "title", ReadOn: "06/01/2025"},
+		{Value: &value, Unit: "km", Source: "title", ReadOn: "2025-06-02"},
+	}
+	for _, c := range cases {
+		if err := handlers.ValidateOdometerPayload(&c, now); err == nil {
+			t.Errorf("Expected %+v to be rejected", c)
+		}
+	}
+}
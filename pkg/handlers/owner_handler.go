@@ -0,0 +1,470 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+)
+
+const (
+	MaxOwnerNameLength  = 128
+	MaxOwnerEmailLength = 254
+	MaxOwnerPhoneLength = 32
+)
+
+type OwnerPostPayload struct {
+	Name  string `json:"name" xml:"name"`
+	Email string `json:"email" xml:"email"`
+	Phone string `json:"phone" xml:"phone"`
+}
+
+type OwnerList struct {
+	XMLName xml.Name       `json:"-" xml:"owners"`
+	Owners  []models.Owner `json:"owners" xml:"owner"`
+	Limit   int            `json:"limit" xml:"limit,attr"`
+	Offset  int            `json:"offset" xml:"offset,attr"`
+}
+
+type TransferPayload struct {
+	OwnerId string `json:"owner_id" xml:"owner_id"`
+	// YYYY-MM-DD, today when left out
+	EffectiveDate string `json:"effective_date" xml:"effective_date"`
+}
+
+type OwnershipList struct {
+	XMLName    xml.Name           `json:"-" xml:"ownerships"`
+	CarId      string             `json:"car_id" xml:"car_id,attr"`
+	Ownerships []models.Ownership `json:"ownerships" xml:"ownership"`
+}
+
+func init() {
+	RegisterInclude("owner", func(db *clients.DBClient, carIds []string) (map[string]interface{}, error) {
+		owners, err := models.CurrentOwners(db, carIds)
+		if err != nil {
+			return nil, err
+		}
+		included := make(map[string]interface{}, len(owners))
+		for carId, owner := range owners {
+			included[carId] = owner
+		}
+		return included, nil
+	})
+}
+
+func OwnersHandler(w http.ResponseWriter, r *http.Request) {
+	if !authorize(w, r) {
+		return
+	}
+
+	var (
+		statusCode int
+		err        error
+	)
+
+	switch r.Method {
+	case "POST":
+		statusCode, err = PostOwner(w, r)
+	case "PUT":
+		statusCode, err = PutOwner(w, r)
+	case "DELETE":
+		statusCode, err = DeleteOwner(w, r)
+	case "GET":
+		if _, ok := r.URL.Query()["owner_id"]; ok {
+			statusCode, err = GetOwner(w, r)
+		} else {
+			statusCode, err = ListOwners(w, r)
+		}
+	default:
+		http.Error(w, "Invalid Request Method.", 405)
+		return
+	}
+
+	writeHandlerError(w, r, statusCode, err)
+}
+
+func CarTransferHandler(w http.ResponseWriter, r *http.Request) {
+	serveMethod(w, r, "POST", TransferCar)
+}
+
+func CarOwnershipsHandler(w http.ResponseWriter, r *http.Request) {
+	serveMethod(w, r, "GET", ListOwnerships)
+}
+
+func PostOwner(w http.ResponseWriter, r *http.Request) (int, error) {
+	var postPayload OwnerPostPayload
+
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("PostOwner: Processing Add Owner endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	log.Debug("PostOwner: Decoding request body...")
+	statusCode, err = DecodeBody(r, &postPayload)
+	if err != nil {
+		return statusCode, err
+	}
+
+	log.Debug("PostOwner: validating payload...")
+	if err = ValidateOwnerPayload(&postPayload); err != nil {
+		return 422, err
+	}
+
+	// get db conn
+	log.Debug("PostOwner: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	owner := &models.Owner{
+		Id:     uuid.NewV4().String(),
+		Name:   postPayload.Name,
+		Email:  postPayload.Email,
+		Phone:  postPayload.Phone,
+		Tenant: tenant,
+	}
+
+	log.Debug("PostOwner: Saving Owner")
+	if err = models.SaveOwner(&db, owner); err != nil {
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, owner)
+}
+
+// Replace the owner named by owner_id with the payload
+func PutOwner(w http.ResponseWriter, r *http.Request) (int, error) {
+	var postPayload OwnerPostPayload
+
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("PutOwner: Processing Update Owner endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	ownerId := r.URL.Query().Get("owner_id")
+	if _, err := uuid.FromString(ownerId); err != nil {
+		return 400, errors.New("owner_id must be a valid owner Id")
+	}
+
+	log.Debug("PutOwner: Decoding request body...")
+	statusCode, err = DecodeBody(r, &postPayload)
+	if err != nil {
+		return statusCode, err
+	}
+
+	log.Debug("PutOwner: validating payload...")
+	if err = ValidateOwnerPayload(&postPayload); err != nil {
+		return 422, err
+	}
+
+	// get db conn
+	log.Debug("PutOwner: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	owner := &models.Owner{
+		Id:     ownerId,
+		Name:   postPayload.Name,
+		Email:  postPayload.Email,
+		Phone:  postPayload.Phone,
+		Tenant: tenant,
+	}
+
+	log.Debug("PutOwner: Updating Owner")
+	err = models.UpdateOwner(&db, owner)
+	if err == models.ErrOwnerNotFound {
+		return 404, err
+	}
+	if err != nil {
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, owner)
+}
+
+func GetOwner(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("GetOwner: Processing Get Owner endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	ownerId := r.URL.Query().Get("owner_id")
+	if _, err := uuid.FromString(ownerId); err != nil {
+		return 400, errors.New("owner_id must be a valid owner Id")
+	}
+
+	// get db conn
+	log.Debug("GetOwner: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	owner, err := models.GetOwner(&db, tenant, ownerId)
+	if err == models.ErrOwnerNotFound {
+		return 404, err
+	}
+	if err != nil {
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, owner)
+}
+
+func ListOwners(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("ListOwners: Processing List Owners endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	limit, offset, err := queryPage(r.URL.Query())
+	if err != nil {
+		return 400, err
+	}
+
+	// get db conn
+	log.Debug("ListOwners: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	owners, err := models.ListOwners(&db, tenant, limit, offset)
+	if err != nil {
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, OwnerList{Owners: owners, Limit: limit, Offset: offset})
+}
+
+func DeleteOwner(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("DeleteOwner: Processing Delete Owner endpoint...")
+
+	ownerId := r.URL.Query().Get("owner_id")
+	if _, err := uuid.FromString(ownerId); err != nil {
+		return 400, errors.New("Need a valid owner Id to delete...")
+	}
+
+	// get db conn
+	log.Debug("DeleteOwner: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	err = models.DeleteOwner(&db, tenant, ownerId)
+	switch {
+	case err == models.ErrOwnerNotFound:
+		return 404, err
+	case err == models.ErrOwnerHasCars:
+		return 409, err
+	case err != nil:
+		return 500, err
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	return 200, nil
+}
+
+// Hand the car in the path to another owner, closing the current ownership
+func TransferCar(w http.ResponseWriter, r *http.Request) (int, error) {
+	var payload TransferPayload
+
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("TransferCar: Processing Transfer Car endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	carId := mux.Vars(r)["id"]
+	if _, err := uuid.FromString(carId); err != nil {
+		return 400, errors.New("Need a valid car Id to transfer...")
+	}
+
+	log.Debug("TransferCar: Decoding request body...")
+	statusCode, err = DecodeBody(r, &payload)
+	if err != nil {
+		return statusCode, err
+	}
+
+	log.Debug("TransferCar: validating payload...")
+	effective, err := ValidateTransferPayload(&payload, time.Now())
+	if err != nil {
+		return 422, err
+	}
+
+	// get db conn
+	log.Debug("TransferCar: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	transfer, err := models.TransferCar(&db, tenant, carId, payload.OwnerId, effective)
+	switch {
+	case err == models.ErrCarNotFound:
+		return 404, err
+	case err == models.ErrOwnerNotFound:
+		return 422, err
+	case err == models.ErrOwnershipOverlap, err == models.ErrAlreadyOwner:
+		return 409, err
+	case err != nil:
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, transfer)
+}
+
+// A car's ownership history, oldest first
+func ListOwnerships(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("ListOwnerships: Processing List Ownerships endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	carId := mux.Vars(r)["id"]
+	if _, err := uuid.FromString(carId); err != nil {
+		return 400, errors.New("Need a valid car Id to list ownerships...")
+	}
+
+	// get db conn
+	log.Debug("ListOwnerships: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	if _, err := models.GetCarColumns(&db, tenant, carId, []string{"id"}); err == models.ErrCarNotFound {
+		return 404, err
+	} else if err != nil {
+		return 500, err
+	}
+
+	ownerships, err := models.ListOwnerships(&db, tenant, carId)
+	if err != nil {
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, OwnershipList{CarId: carId, Ownerships: ownerships})
+}
+
+// Load each car's current owner onto it with one query
+func attachOwners(db *clients.DBClient, cars []models.CarModel) error {
+	ids := make([]string, 0, len(cars))
+	for _, car := range cars {
+		ids = append(ids, car.Id)
+	}
+
+	owners, err := models.CurrentOwners(db, ids)
+	if err != nil {
+		return err
+	}
+	for i := range cars {
+		if owner, ok := owners[cars[i].Id]; ok {
+			cars[i].Owner = &owner
+		}
+	}
+	return nil
+}
+
+func ValidateOwnerPayload(payload *OwnerPostPayload) error {
+	payload.Name = strings.Join(strings.Fields(payload.Name), " ")
+	payload.Email = strings.TrimSpace(payload.Email)
+	payload.Phone = strings.TrimSpace(payload.Phone)
+
+	if payload.Name == "" {
+		return errors.New("Name must be included in the payload")
+	}
+	if utf8.RuneCountInString(payload.Name) > MaxOwnerNameLength {
+		return fmt.Errorf("Name must be at most %d characters", MaxOwnerNameLength)
+	}
+
+	if payload.Email != "" {
+		address, err := mail.ParseAddress(payload.Email)
+		if err != nil || address.Address != payload.Email {
+			return errors.New("Email must be a plain email address")
+		}
+		if len(payload.Email) > MaxOwnerEmailLength {
+			return fmt.Errorf("Email must be at most %d characters", MaxOwnerEmailLength)
+		}
+	}
+
+	if len(payload.Phone) > MaxOwnerPhoneLength {
+		return fmt.Errorf("Phone must be at most %d characters", MaxOwnerPhoneLength)
+	}
+	for _, r := range payload.Phone {
+		if !strings.ContainsRune("0123456789+-(). ", r) {
+			return errors.New("Phone can only have digits, spaces and + - ( ) .")
+		}
+	}
+	return nil
+}
+
+// Check the payload and work out the effective date, which can't be after
+// today
+func ValidateTransferPayload(payload *TransferPayload, now time.Time) (time.Time, error) {
+	if _, err := uuid.FromString(payload.OwnerId); err != nil {
+		return time.Time{}, errors.New("OwnerId must be a valid owner Id")
+	}
+
+	today := now.UTC().Format(models.DateLayout)
+	if payload.EffectiveDate == "" {
+		payload.EffectiveDate = today
+	}
+
+	effective, err := time.Parse(models.DateLayout, payload.EffectiveDate)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("EffectiveDate must be a date like %s", models.DateLayout)
+	}
+	if payload.EffectiveDate > today {
+		return time.Time{}, errors.New("EffectiveDate can't be in the future")
+	}
+	return effective, nil
+}
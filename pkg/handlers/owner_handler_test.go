@@ -0,0 +1,207 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/harness"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
+)
+
+func postOwner(t *testing.T, name string) models.Owner {
+	req, _ := http.NewRequest("POST", "/v1/owners", strings.NewReader(`{"name": "`+name+`", "email": "owner@example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := serveCars(req)
+	if rr.Code != 200 {
+		t.Fatalf("Couldn't save owner: %d %s", rr.Code, rr.Body.String())
+	}
+
+	var owner models.Owner
+	json.Unmarshal(rr.Body.Bytes(), &owner)
+	return owner
+}
+
+func transfer(carId string, ownerId string, effective string) *bytes.Buffer {
+	body, _ := json.Marshal(handlers.TransferPayload{OwnerId: ownerId, EffectiveDate: effective})
+	req, _ := http.NewRequest("POST", "/v1/cars/"+carId+"/transfer", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := serveCars(req)
+	if rr.Code != 200 {
+		return bytes.NewBufferString(rr.Result().Status + " " + rr.Body.String())
+	}
+	return rr.Body
+}
+
+func TestOwnerCRUD(t *testing.T) {
+	defer harness.Truncate()
+
+	owner := postOwner(t, "  Ada   Lovelace ")
+	if owner.Name != "Ada Lovelace" || owner.Email != "owner@example.com" {
+		t.Errorf("Expected a normalized owner, got %+v", owner)
+	}
+
+	req, _ := http.NewRequest("PUT", "/v1/owners?owner_id="+owner.Id, strings.NewReader(`{"name": "Ada King", "phone": "+44 20 7946 0000"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := serveCars(req)
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/owners?owner_id="+owner.Id, nil)
+	rr = serveCars(req)
+	if err := openapi.ValidateSchema("Owner", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match Owner schema: %s", err)
+	}
+	var got models.Owner
+	json.Unmarshal(rr.Body.Bytes(), &got)
+	if got.Name != "Ada King" || got.Email != "" || got.Phone != "+44 20 7946 0000" {
+		t.Errorf("Expected the update to replace the owner, got %+v", got)
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/owners", nil)
+	rr = serveCars(req)
+	if err := openapi.ValidateSchema("OwnerList", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match OwnerList schema: %s", err)
+	}
+
+	req, _ = http.NewRequest("DELETE", "/v1/owners?owner_id="+owner.Id, nil)
+	if rr = serveCars(req); rr.Code != 200 {
+		t.Errorf("Expected: %d, but got: %d", 200, rr.Code)
+	}
+	req, _ = http.NewRequest("GET", "/v1/owners?owner_id="+owner.Id, nil)
+	if rr = serveCars(req); rr.Code != 404 {
+		t.Errorf("Expected: %d, but got: %d", 404, rr.Code)
+	}
+}
+
+func TestOwnerPostInvalid(t *testing.T) {
+	for _, body := range []string{`{"name": " "}`, `{"name": "Ada", "email": "Ada <ada@example.com>"}`, `{"name": "Ada", "phone": "call me"}`} {
+		req, _ := http.NewRequest("POST", "/v1/owners", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := serveCars(req)
+
+		if rr.Code != 422 {
+			t.Errorf("%s: expected: %d, but got: %d", body, 422, rr.Code)
+		}
+		if err := openapi.ValidateSchema("JsonError", rr.Body.Bytes()); err != nil {
+			t.Errorf("Response doesn't match JsonError schema: %s", err)
+		}
+	}
+}
+
+func TestTransferCar(t *testing.T) {
+	car := saveNegotiationCar(t)
+	defer harness.Truncate()
+	first, second := postOwner(t, "First Owner"), postOwner(t, "Second Owner")
+
+	var opened models.Transfer
+	json.Unmarshal(transfer(car.Id, first.Id, "2019-03-01").Bytes(), &opened)
+	if opened.Previous != nil || opened.Current.Owner.Id != first.Id || opened.Current.StartedOn != "2019-03-01" {
+		t.Fatalf("Expected a first ownership, got %+v", opened)
+	}
+
+	body := transfer(car.Id, second.Id, "2021-07-15")
+	if err := openapi.ValidateSchema("Transfer", body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match Transfer schema: %s %s", err, body)
+	}
+	var passed models.Transfer
+	json.Unmarshal(body.Bytes(), &passed)
+	if passed.Previous == nil || *passed.Previous.EndedOn != "2021-07-15" || passed.Current.Owner.Id != second.Id {
+		t.Errorf("Expected the first ownership closed the day the second opened, got %s", body)
+	}
+
+	// The car and its listing carry the current owner
+	req, _ := http.NewRequest("GET", "/v1/cars?car_id="+car.Id, nil)
+	rr := serveCars(req)
+	if err := openapi.ValidateSchema("CarModel", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match CarModel schema: %s", err)
+	}
+	var got models.CarModel
+	json.Unmarshal(rr.Body.Bytes(), &got)
+	if got.Owner == nil || got.Owner.Id != second.Id {
+		t.Errorf("Expected the second owner on the car, got %s", rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/cars?fields=id&include=owner", nil)
+	rr = serveCars(req)
+	if !strings.Contains(rr.Body.String(), `"name":"Second Owner"`) {
+		t.Errorf("Expected the owner include, got %s", rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/cars/"+car.Id+"/ownerships", nil)
+	rr = serveCars(req)
+	if err := openapi.ValidateSchema("OwnershipList", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match OwnershipList schema: %s", err)
+	}
+	var history handlers.OwnershipList
+	json.Unmarshal(rr.Body.Bytes(), &history)
+	if len(history.Ownerships) != 2 || history.Ownerships[0].Owner.Id != first.Id || history.Ownerships[1].EndedOn != nil {
+		t.Errorf("Expected both ownerships oldest first, got %s", rr.Body.String())
+	}
+
+	// Owners with history can't be deleted
+	req, _ = http.NewRequest("DELETE", "/v1/owners?owner_id="+first.Id, nil)
+	if rr = serveCars(req); rr.Code != 409 {
+		t.Errorf("Expected: %d, but got: %d", 409, rr.Code)
+	}
+}
+
+func TestTransferCarRejected(t *testing.T) {
+	car := saveNegotiationCar(t)
+	defer harness.Truncate()
+	first, second := postOwner(t, "First Owner"), postOwner(t, "Second Owner")
+	transfer(car.Id, first.Id, "2020-01-01")
+
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format(models.DateLayout)
+	cases := []struct {
+		carId, ownerId, effective, status string
+	}{
+		{car.Id, second.Id, "2020-01-01", "409"},
+		{car.Id, second.Id, "2019-12-31", "409"},
+		{car.Id, first.Id, "2021-01-01", "409"},
+		{car.Id, second.Id, tomorrow, "422"},
+		{car.Id, second.Id, "01/02/2021", "422"},
+		{car.Id, "00000000-0000-4000-8000-000000000000", "2021-01-01", "422"},
+		{"00000000-0000-4000-8000-000000000000", second.Id, "2021-01-01", "404"},
+	}
+	for _, c := range cases {
+		if body := transfer(c.carId, c.ownerId, c.effective).String(); !strings.HasPrefix(body, c.status) {
+			t.Errorf("%+v: expected %s, got %s", c, c.status, body)
+		}
+	}
+
+	// Nothing changed
+	req, _ := http.NewRequest("GET", "/v1/cars/"+car.Id+"/ownerships", nil)
+	rr := serveCars(req)
+	var history handlers.OwnershipList
+	json.Unmarshal(rr.Body.Bytes(), &history)
+	if len(history.Ownerships) != 1 || history.Ownerships[0].EndedOn != nil {
+		t.Errorf("Expected only the first ownership, got %s", rr.Body.String())
+	}
+}
+
+func TestValidateTransferPayload(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	payload := handlers.TransferPayload{OwnerId: "00000000-0000-4000-8000-000000000000"}
+	effective, err := handlers.ValidateTransferPayload(&payload, now)
+	if err != nil || effective.Format(models.DateLayout) != "2021-06-01" {
+		t.Errorf("Expected today by default, got %s %v", effective, err)
+	}
+
+	for _, payload := range []handlers.TransferPayload{
+		{OwnerId: "someone", EffectiveDate: "2021-01-01"},
+		{OwnerId: "00000000-0000-4000-8000-000000000000", EffectiveDate: "2021-06-02"},
+		{OwnerId: "00000000-0000-4000-8000-000000000000", EffectiveDate: "2021-6-1"},
+	} {
+		if _, err := handlers.ValidateTransferPayload(&payload, now); err == nil {
+			t.Errorf("%+v: expected a validation error", payload)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/money"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+)
+
+const MaxPriceReasonLength = 512
+
+// A new list price, as minor units or a decimal string but never a float.
+// Exactly one of PriceMinor and Amount is sent.
+type PricePayload struct {
+	PriceMinor *int64 `json:"price_minor" xml:"price_minor"`
+	// A decimal like "18995.00", parsed in the currency's minor units
+	Amount   string `json:"amount" xml:"amount"`
+	Currency string `json:"currency" xml:"currency"`
+	Reason   string `json:"reason" xml:"reason"`
+}
+
+// A car's current list price and every price it's had, oldest first. The
+// current price is left out until the car is priced.
+type PriceHistory struct {
+	XMLName    xml.Name             `json:"-" xml:"prices"`
+	CarId      string               `json:"car_id" xml:"car_id,attr"`
+	PriceMinor *int64               `json:"price_minor,omitempty" xml:"price_minor,attr,omitempty"`
+	Currency   *string              `json:"currency,omitempty" xml:"currency,attr,omitempty"`
+	Prices     []models.PriceChange `json:"prices" xml:"price"`
+}
+
+func CarPricesHandler(w http.ResponseWriter, r *http.Request) {
+	if !authorize(w, r) {
+		return
+	}
+
+	var (
+		statusCode int
+		err        error
+	)
+
+	switch r.Method {
+	case "POST":
+		statusCode, err = SetCarPrice(w, r)
+	case "GET":
+		statusCode, err = ListPrices(w, r)
+	default:
+		http.Error(w, "Invalid Request Method.", 405)
+		return
+	}
+
+	writeHandlerError(w, r, statusCode, err)
+}
+
+// Give the car in the path a new list price, recorded with the actor and
+// reason
+func SetCarPrice(w http.ResponseWriter, r *http.Request) (int, error) {
+	var payload PricePayload
+
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("SetCarPrice: Processing Set Car Price endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	carId := mux.Vars(r)["id"]
+	if _, err := uuid.FromString(carId); err != nil {
+		return 400, errors.New("Need a valid car Id to price...")
+	}
+
+	actor, err := ParseActor(r.Header.Get("X-CARS-ACTOR"))
+	if err != nil {
+		return 400, err
+	}
+
+	log.Debug("SetCarPrice: Decoding request body...")
+	statusCode, err = DecodeBody(r, &payload)
+	if err != nil {
+		return statusCode, err
+	}
+
+	log.Debug("SetCarPrice: validating payload...")
+	priceMinor, err := ValidatePricePayload(&payload)
+	if err != nil {
+		return 422, err
+	}
+
+	// get db conn
+	log.Debug("SetCarPrice: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	price, err := models.SetCarPrice(&db, tenant, carId, priceMinor, payload.Currency, actor, payload.Reason)
+	switch {
+	case err == models.ErrCarNotFound:
+		return 404, err
+	case err == models.ErrSamePrice:
+		return 409, err
+	case err != nil:
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, price)
+}
+
+// A car's current list price and its price history
+func ListPrices(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("ListPrices: Processing List Prices endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	carId := mux.Vars(r)["id"]
+	if _, err := uuid.FromString(carId); err != nil {
+		return 400, errors.New("Need a valid car Id to list prices...")
+	}
+
+	// get db conn
+	log.Debug("ListPrices: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	car, err := models.GetCarColumns(&db, tenant, carId, []string{"price_minor", "currency"})
+	if err == models.ErrCarNotFound {
+		return 404, err
+	}
+	if err != nil {
+		return 500, err
+	}
+
+	prices, err := models.ListPrices(&db, tenant, carId)
+	if err != nil {
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, PriceHistory{
+		CarId:      carId,
+		PriceMinor: car.PriceMinor,
+		Currency:   car.Currency,
+		Prices:     prices,
+	})
+}
+
+// Check the payload and work out the price in minor units of its currency
+func ValidatePricePayload(payload *PricePayload) (int64, error) {
+	payload.Currency = strings.ToUpper(strings.TrimSpace(payload.Currency))
+	payload.Amount = strings.TrimSpace(payload.Amount)
+	payload.Reason = strings.TrimSpace(payload.Reason)
+
+	if !money.ValidCurrency(payload.Currency) {
+		return 0, fmt.Errorf("Currency must be one of %s", strings.Join(money.Codes(), ", "))
+	}
+	if utf8.RuneCountInString(payload.Reason) > MaxPriceReasonLength {
+		return 0, fmt.Errorf("Reason must be at most %d characters", MaxPriceReasonLength)
+	}
+
+	switch {
+	case payload.PriceMinor != nil && payload.Amount != "":
+		return 0, errors.New("Send either PriceMinor or Amount, not both")
+	case payload.PriceMinor != nil:
+		if *payload.PriceMinor < 0 {
+			return 0, errors.New("PriceMinor must not be negative")
+		}
+		return *payload.PriceMinor, nil
+	case payload.Amount != "":
+		return money.Parse(payload.Amount, payload.Currency)
+	}
+	return 0, errors.New("PriceMinor or Amount must be included in the payload")
+}
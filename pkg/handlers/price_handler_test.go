@@ -0,0 +1,177 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/harness"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
+)
+
+func price(carId string, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/v1/cars/"+carId+"/prices", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-CARS-ACTOR", "pricing@example.com")
+	return serveCars(req)
+}
+
+func TestSetCarPrice(t *testing.T) {
+	car := saveNegotiationCar(t)
+	defer harness.Truncate()
+
+	rr := price(car.Id, `{"amount": "18995.50", "currency": "usd", "reason": "Initial listing"}`)
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+	if err := openapi.ValidateSchema("PriceChange", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match PriceChange schema: %s %s", err, rr.Body.String())
+	}
+	var change models.PriceChange
+	json.Unmarshal(rr.Body.Bytes(), &change)
+	if change.PriceMinor != 1899550 || change.Currency != "USD" || change.Amount != "18995.50" || change.Actor != "pricing@example.com" {
+		t.Errorf("Expected 18995.50 USD by the actor, got %+v", change)
+	}
+
+	// Floats never make it in
+	if rr = price(car.Id, `{"amount": 18995.5, "currency": "USD"}`); rr.Code < 400 || rr.Code > 499 {
+		t.Errorf("Expected a JSON number amount to be rejected, got %d", rr.Code)
+	}
+	if rr = price(car.Id, `{"price_minor": 1899550, "currency": "USD"}`); rr.Code != 409 {
+		t.Errorf("Expected: %d, but got: %d", 409, rr.Code)
+	}
+	if rr = price(car.Id, `{"price_minor": 1750000, "currency": "USD", "reason": "Price drop"}`); rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+
+	req, _ := http.NewRequest("GET", "/v1/cars/"+car.Id+"/prices", nil)
+	rr = serveCars(req)
+	if err := openapi.ValidateSchema("PriceHistory", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match PriceHistory schema: %s %s", err, rr.Body.String())
+	}
+	var history handlers.PriceHistory
+	json.Unmarshal(rr.Body.Bytes(), &history)
+	if history.PriceMinor == nil || *history.PriceMinor != 1750000 || len(history.Prices) != 2 || history.Prices[1].Reason != "Price drop" {
+		t.Errorf("Expected the current price and both changes, got %s", rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/cars?car_id="+car.Id, nil)
+	var got models.CarModel
+	json.Unmarshal(serveCars(req).Body.Bytes(), &got)
+	if got.PriceMinor == nil || *got.PriceMinor != 1750000 || got.Currency == nil || *got.Currency != "USD" {
+		t.Errorf("Expected the car to carry its price, got %+v", got)
+	}
+
+	if rr = price("00000000-0000-4000-8000-000000000000", `{"amount": "1.00", "currency": "USD"}`); rr.Code != 404 {
+		t.Errorf("Expected: %d, but got: %d", 404, rr.Code)
+	}
+}
+
+func TestPricesOtherTenant(t *testing.T) {
+	car := otherTenantCar(t)
+	defer harness.Truncate()
+
+	req, _ := http.NewRequest("GET", "/v1/cars/"+car.Id+"/prices", nil)
+	if rr := serveCars(req); rr.Code != 404 {
+		t.Errorf("Expected another tenant's prices to be a %d, got %d %s", 404, rr.Code, rr.Body.String())
+	}
+	if rr := price(car.Id, `{"amount": "1.00", "currency": "USD"}`); rr.Code != 404 {
+		t.Errorf("Expected pricing another tenant's car to be a %d, got %d", 404, rr.Code)
+	}
+}
+
+func TestListCarsByPrice(t *testing.T) {
+	defer harness.Truncate()
+
+	cheap, dear, unpriced, yen := saveNegotiationCar(t), saveNegotiationCar(t), saveNegotiationCar(t), saveNegotiationCar(t)
+	price(cheap.Id, `{"amount": "9500", "currency": "USD"}`)
+	price(dear.Id, `{"amount": "32000", "currency": "USD"}`)
+	price(yen.Id, `{"amount": "2000000", "currency": "JPY"}`)
+
+	list := func(query string) []string {
+		req, _ := http.NewRequest("GET", "/v1/cars?"+query, nil)
+		rr := serveCars(req)
+		if rr.Code != 200 {
+			t.Fatalf("%s: expected: %d, but got: %d %s", query, 200, rr.Code, rr.Body.String())
+		}
+		var cars handlers.CarList
+		json.Unmarshal(rr.Body.Bytes(), &cars)
+		ids := []string{}
+		for _, car := range cars.Cars {
+			ids = append(ids, car.Id)
+		}
+		return ids
+	}
+
+	// Yen before dollars, then by price within each currency
+	if ids := list("sort=price"); strings.Join(ids, ",") != strings.Join([]string{yen.Id, cheap.Id, dear.Id, unpriced.Id}, ",") {
+		t.Errorf("Expected cheapest first and unpriced last, got %v", ids)
+	}
+	if ids := list("sort=-price"); strings.Join(ids, ",") != strings.Join([]string{yen.Id, dear.Id, cheap.Id, unpriced.Id}, ",") {
+		t.Errorf("Expected dearest first and unpriced last, got %v", ids)
+	}
+	if ids := list("price_min=1000000&currency=USD"); len(ids) != 1 || ids[0] != dear.Id {
+		t.Errorf("Expected only the dear car, got %v", ids)
+	}
+	if ids := list("price_max=1000000&currency=usd"); len(ids) != 1 || ids[0] != cheap.Id {
+		t.Errorf("Expected only the cheap car, got %v", ids)
+	}
+	if ids := list("currency=EUR"); len(ids) != 0 {
+		t.Errorf("Expected no cars priced in euros, got %v", ids)
+	}
+}
+
+func TestValidatePricePayload(t *testing.T) {
+	minor := int64(250000)
+	payload := handlers.PricePayload{PriceMinor: &minor, Currency: " jpy "}
+	if got, err := handlers.ValidatePricePayload(&payload); err != nil || got != 250000 || payload.Currency != "JPY" {
+		t.Errorf("Expected 250000 JPY, got %d %s %v", got, payload.Currency, err)
+	}
+
+	negative := int64(-1)
+	cases := []handlers.PricePayload{
+		{Amount: "10.00"},
+		{Amount: "10.00", Currency: "XYZ"},
+		{Currency: "USD"},
+		{PriceMinor: &minor, Amount: "2500.00", Currency: "USD"},
+		{PriceMinor: &negative, Currency: "USD"},
+		{Amount: "10.005", Currency: "USD"},
+		{Amount: "10", Currency: "USD", Reason: strings.Repeat("a", handlers.MaxPriceReasonLength+1)},
+	}
+	for _, c := range cases {
+		if _, err := handlers.ValidatePricePayload(&c); err == nil {
+			t.Errorf("Expected %+v to be rejected", c)
+		}
+	}
+}
+
+func TestParseCarOrder(t *testing.T) {
+	order, err := handlers.ParseCarOrder(url.Values{"sort": {"-price"}})
+	if err != nil || order.Sort != "price" || !order.Desc {
+		t.Errorf("Expected descending price, got %+v %v", order, err)
+	}
+	if order.OrderBy() != "currency NULLS LAST, price_minor DESC NULLS LAST, id" {
+		t.Errorf("Expected unpriced cars last, got %q", order.OrderBy())
+	}
+	if order, _ := handlers.ParseCarOrder(url.Values{}); order.OrderBy() != "id ASC" {
+		t.Errorf("Expected listings by id by default, got %q", order.OrderBy())
+	}
+	if _, err := handlers.ParseCarOrder(url.Values{"sort": {"color"}}); err == nil {
+		t.Errorf("Expected an unknown sort to be rejected")
+	}
+
+	if _, err := handlers.ParseCarFilter(url.Values{"price_min": {"99.5"}}); err == nil {
+		t.Errorf("Expected a fractional price_min to be rejected")
+	}
+	if _, err := handlers.ParseCarFilter(url.Values{"price_max": {"1000000"}}); err == nil {
+		t.Errorf("Expected a price bound without a currency to be rejected")
+	}
+	if _, err := handlers.ParseCarFilter(url.Values{"currency": {"XYZ"}}); err == nil {
+		t.Errorf("Expected an unknown currency to be rejected")
+	}
+}
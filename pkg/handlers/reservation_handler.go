@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/lifecycle"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+)
+
+const (
+	// How long a reservation holds a car when expires_at is left out
+	DefaultReservationHold = 48 * time.Hour
+	// The longest a car can be held
+	MaxReservationHold = 14 * 24 * time.Hour
+)
+
+type ReservationPayload struct {
+	HolderName   string `json:"holder_name" xml:"holder_name"`
+	HolderEmail  string `json:"holder_email" xml:"holder_email"`
+	HolderPhone  string `json:"holder_phone" xml:"holder_phone"`
+	DepositCents int64  `json:"deposit_cents" xml:"deposit_cents"`
+	// RFC 3339, DefaultReservationHold from now when left out
+	ExpiresAt string `json:"expires_at" xml:"expires_at"`
+}
+
+type ReservationList struct {
+	XMLName      xml.Name             `json:"-" xml:"reservations"`
+	CarId        string               `json:"car_id" xml:"car_id,attr"`
+	Reservations []models.Reservation `json:"reservations" xml:"reservation"`
+}
+
+func CarReservationsHandler(w http.ResponseWriter, r *http.Request) {
+	if !authorize(w, r) {
+		return
+	}
+
+	var (
+		statusCode int
+		err        error
+	)
+
+	switch r.Method {
+	case "POST":
+		statusCode, err = ReserveCar(w, r)
+	case "DELETE":
+		statusCode, err = ReleaseReservation(w, r)
+	case "GET":
+		statusCode, err = ListReservations(w, r)
+	default:
+		http.Error(w, "Invalid Request Method.", 405)
+		return
+	}
+
+	writeHandlerError(w, r, statusCode, err)
+}
+
+// Hold the car in the path for a customer. The car has to be listed and not
+// held already.
+func ReserveCar(w http.ResponseWriter, r *http.Request) (int, error) {
+	var payload ReservationPayload
+
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("ReserveCar: Processing Reserve Car endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	carId := mux.Vars(r)["id"]
+	if _, err := uuid.FromString(carId); err != nil {
+		return 400, errors.New("Need a valid car Id to reserve...")
+	}
+
+	actor, err := ParseActor(r.Header.Get("X-CARS-ACTOR"))
+	if err != nil {
+		return 400, err
+	}
+
+	log.Debug("ReserveCar: Decoding request body...")
+	statusCode, err = DecodeBody(r, &payload)
+	if err != nil {
+		return statusCode, err
+	}
+
+	log.Debug("ReserveCar: validating payload...")
+	expiresAt, err := ValidateReservationPayload(&payload, time.Now())
+	if err != nil {
+		return 422, err
+	}
+
+	// get db conn
+	log.Debug("ReserveCar: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	reservation := &models.Reservation{
+		Id:           uuid.NewV4().String(),
+		CarId:        carId,
+		HolderName:   payload.HolderName,
+		HolderEmail:  payload.HolderEmail,
+		HolderPhone:  payload.HolderPhone,
+		DepositCents: payload.DepositCents,
+		ExpiresAt:    expiresAt,
+		Tenant:       tenant,
+	}
+
+	log.Debug("ReserveCar: Saving Reservation")
+	err = models.ReserveCar(&db, reservation, actor, CredentialFingerprint(r.Header.Get("X-CARS-ID")))
+	if _, ok := err.(*lifecycle.TransitionError); ok {
+		return 409, err
+	}
+	switch {
+	case err == models.ErrCarNotFound:
+		return 404, err
+	case err == models.ErrCarReserved:
+		return 409, err
+	case err != nil:
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, reservation)
+}
+
+// End the car's active reservation early and list the car again
+func ReleaseReservation(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("ReleaseReservation: Processing Release Reservation endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	carId := mux.Vars(r)["id"]
+	if _, err := uuid.FromString(carId); err != nil {
+		return 400, errors.New("Need a valid car Id to release...")
+	}
+
+	actor, err := ParseActor(r.Header.Get("X-CARS-ACTOR"))
+	if err != nil {
+		return 400, err
+	}
+
+	// get db conn
+	log.Debug("ReleaseReservation: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	reservation, err := models.ReleaseReservation(&db, tenant, carId, actor, CredentialFingerprint(r.Header.Get("X-CARS-ID")))
+	if _, ok := err.(*lifecycle.TransitionError); ok {
+		return 409, err
+	}
+	switch {
+	case err == models.ErrCarNotFound, err == models.ErrNoReservation:
+		return 404, err
+	case err != nil:
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, reservation)
+}
+
+// A car's reservations, oldest first
+func ListReservations(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("ListReservations: Processing List Reservations endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	carId := mux.Vars(r)["id"]
+	if _, err := uuid.FromString(carId); err != nil {
+		return 400, errors.New("Need a valid car Id to list reservations...")
+	}
+
+	// get db conn
+	log.Debug("ListReservations: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	if _, err := models.GetCarColumns(&db, tenant, carId, []string{"id"}); err == models.ErrCarNotFound {
+		return 404, err
+	} else if err != nil {
+		return 500, err
+	}
+
+	reservations, err := models.ListReservations(&db, tenant, carId)
+	if err != nil {
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, ReservationList{CarId: carId, Reservations: reservations})
+}
+
+// Check the holder and deposit and work out when the hold expires, which
+// has to be after now and within MaxReservationHold of it
+func ValidateReservationPayload(payload *ReservationPayload, now time.Time) (time.Time, error) {
+	holder := OwnerPostPayload{Name: payload.HolderName, Email: payload.HolderEmail, Phone: payload.HolderPhone}
+	if err := ValidateOwnerPayload(&holder); err != nil {
+		return time.Time{}, fmt.Errorf("Holder %s", err)
+	}
+	payload.HolderName, payload.HolderEmail, payload.HolderPhone = holder.Name, holder.Email, holder.Phone
+
+	if payload.DepositCents < 0 {
+		return time.Time{}, errors.New("DepositCents must not be negative")
+	}
+
+	if payload.ExpiresAt == "" {
+		return now.Add(DefaultReservationHold), nil
+	}
+	expiresAt, err := time.Parse(time.RFC3339, payload.ExpiresAt)
+	if err != nil {
+		return time.Time{}, errors.New("ExpiresAt must be an RFC 3339 time")
+	}
+	if !expiresAt.After(now) {
+		return time.Time{}, errors.New("ExpiresAt must be in the future")
+	}
+	if expiresAt.Sub(now) > MaxReservationHold {
+		return time.Time{}, fmt.Errorf("ExpiresAt must be within %s", MaxReservationHold)
+	}
+	return expiresAt, nil
+}
@@ -0,0 +1,188 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/harness"
+	"github.com/ericmcbride/go-dfw-testing/pkg/lifecycle"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
+)
+
+// A car at a location, listed for sale
+func listedCar(t *testing.T) models.CarModel {
+	car := saveNegotiationCar(t)
+	dealership := postDealership(t, "Downtown Motors")
+	move(car.Id, postLocation(t, dealership.Id, "North Lot").Id)
+	if rr := fire(car.Id, "list"); rr.Code != 200 {
+		t.Fatalf("Couldn't list car: %d %s", rr.Code, rr.Body.String())
+	}
+	return car
+}
+
+func reserve(carId string, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/v1/cars/"+carId+"/reservations", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return serveCars(req)
+}
+
+func carStatus(t *testing.T, carId string) string {
+	req, _ := http.NewRequest("GET", "/v1/cars/"+carId+"/transitions", nil)
+	var history handlers.TransitionList
+	json.Unmarshal(serveCars(req).Body.Bytes(), &history)
+	return history.Status
+}
+
+func TestReserveCar(t *testing.T) {
+	car := saveNegotiationCar(t)
+	defer harness.Truncate()
+
+	// Only listed cars can be held
+	if rr := reserve(car.Id, `{"holder_name": "Jo Buyer"}`); rr.Code != 409 {
+		t.Errorf("Expected: %d, but got: %d %s", 409, rr.Code, rr.Body.String())
+	}
+
+	car = listedCar(t)
+	rr := reserve(car.Id, `{"holder_name": " Jo  Buyer ", "holder_email": "jo@example.com", "deposit_cents": 50000}`)
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+	if err := openapi.ValidateSchema("Reservation", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match Reservation schema: %s %s", err, rr.Body.String())
+	}
+	var reservation models.Reservation
+	json.Unmarshal(rr.Body.Bytes(), &reservation)
+	if reservation.Status != models.ReservationActive || reservation.HolderName != "Jo Buyer" || reservation.DepositCents != 50000 {
+		t.Errorf("Expected an active hold for Jo Buyer, got %+v", reservation)
+	}
+	if hold := reservation.ExpiresAt.Sub(reservation.CreatedAt); hold < handlers.DefaultReservationHold-time.Minute || hold > handlers.DefaultReservationHold+time.Minute {
+		t.Errorf("Expected the default hold, got %s", hold)
+	}
+	if status := carStatus(t, car.Id); status != string(lifecycle.Reserved) {
+		t.Errorf("Expected the car to be reserved, got %q", status)
+	}
+
+	if rr = reserve(car.Id, `{"holder_name": "Someone Else"}`); rr.Code != 409 {
+		t.Errorf("Expected: %d, but got: %d", 409, rr.Code)
+	}
+	// Holds only come from the reservations endpoint
+	if rr = fire(car.Id, "reserve"); rr.Code != 422 {
+		t.Errorf("Expected: %d, but got: %d", 422, rr.Code)
+	}
+
+	req, _ := http.NewRequest("DELETE", "/v1/cars/"+car.Id+"/reservations", nil)
+	rr = serveCars(req)
+	json.Unmarshal(rr.Body.Bytes(), &reservation)
+	if rr.Code != 200 || reservation.Status != models.ReservationReleased || reservation.EndedAt == nil {
+		t.Errorf("Expected the hold to be released, got %d %s", rr.Code, rr.Body.String())
+	}
+	if status := carStatus(t, car.Id); status != string(lifecycle.Listed) {
+		t.Errorf("Expected the car to be listed again, got %q", status)
+	}
+	if rr = serveCars(req); rr.Code != 404 {
+		t.Errorf("Expected: %d, but got: %d", 404, rr.Code)
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/cars/"+car.Id+"/reservations", nil)
+	rr = serveCars(req)
+	if err := openapi.ValidateSchema("ReservationList", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match ReservationList schema: %s", err)
+	}
+	var list handlers.ReservationList
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	if len(list.Reservations) != 1 || list.Reservations[0].Id != reservation.Id {
+		t.Errorf("Expected the released hold, got %s", rr.Body.String())
+	}
+}
+
+func TestReserveCarConcurrently(t *testing.T) {
+	defer harness.Truncate()
+	car := listedCar(t)
+
+	const attempts = 8
+	codes := make(chan int, attempts)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			codes <- reserve(car.Id, `{"holder_name": "Jo Buyer"}`).Code
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(codes)
+
+	held := 0
+	for code := range codes {
+		switch code {
+		case 200:
+			held++
+		case 409:
+		default:
+			t.Errorf("Expected a hold or a conflict, got %d", code)
+		}
+	}
+	if held != 1 {
+		t.Errorf("Expected exactly one reservation to succeed, got %d", held)
+	}
+
+	req, _ := http.NewRequest("GET", "/v1/cars/"+car.Id+"/reservations", nil)
+	var list handlers.ReservationList
+	json.Unmarshal(serveCars(req).Body.Bytes(), &list)
+	if len(list.Reservations) != 1 {
+		t.Errorf("Expected one reservation stored, got %d", len(list.Reservations))
+	}
+}
+
+func TestSellReservedCar(t *testing.T) {
+	defer harness.Truncate()
+	car := listedCar(t)
+
+	if rr := reserve(car.Id, `{"holder_name": "Jo Buyer"}`); rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+	transfer(car.Id, postOwner(t, "Jo Buyer").Id, "")
+	if rr := fire(car.Id, "sell"); rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+
+	req, _ := http.NewRequest("GET", "/v1/cars/"+car.Id+"/reservations", nil)
+	var list handlers.ReservationList
+	json.Unmarshal(serveCars(req).Body.Bytes(), &list)
+	if len(list.Reservations) != 1 || list.Reservations[0].Status != models.ReservationFulfilled {
+		t.Errorf("Expected selling to fulfil the hold, got %+v", list.Reservations)
+	}
+}
+
+func TestValidateReservationPayload(t *testing.T) {
+	now := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	payload := handlers.ReservationPayload{HolderName: "Jo", ExpiresAt: "2019-06-03T12:00:00Z"}
+	if expiresAt, err := handlers.ValidateReservationPayload(&payload, now); err != nil || !expiresAt.Equal(now.Add(48*time.Hour)) {
+		t.Errorf("Expected the requested expiry, got %s %v", expiresAt, err)
+	}
+
+	cases := []handlers.ReservationPayload{
+		{},
+		{HolderName: "Jo", HolderEmail: "not an email"},
+		{HolderName: "Jo", DepositCents: -1},
+		{HolderName: "Jo", ExpiresAt: "tomorrow"},
+		{HolderName: "Jo", ExpiresAt: "2019-06-01T11:00:00Z"},
+		{HolderName: "Jo", ExpiresAt: "2019-07-01T12:00:00Z"},
+	}
+	for _, c := range cases {
+		if _, err := handlers.ValidateReservationPayload(&c, now); err == nil {
+			t.Errorf("Expected %+v to be rejected", c)
+		}
+	}
+}
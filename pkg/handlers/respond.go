@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/lifecycle"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+)
+
+// Check the X-CARS-ID header, answering 401 when it isn't valid
+func authorize(w http.ResponseWriter, r *http.Request) bool {
+	err := ValidateAuthId(r.Header.Get("X-CARS-ID"))
+	if err != nil {
+		logging.GetLog(r.Context()).Error("Unauthorized Auth Id: ", err)
+		http.Error(w, "Unauthorized", 401)
+		return false
+	}
+	return true
+}
+
+// Serve a route with a single method
+func serveMethod(w http.ResponseWriter, r *http.Request, method string, handle func(http.ResponseWriter, *http.Request) (int, error)) {
+	if !authorize(w, r) {
+		return
+	}
+
+	if r.Method != method {
+		http.Error(w, "Invalid Request Method.", 405)
+		return
+	}
+
+	statusCode, err := handle(w, r)
+	writeHandlerError(w, r, statusCode, err)
+}
+
+func writeHandlerError(w http.ResponseWriter, r *http.Request, statusCode int, err error) {
+	if err == nil {
+		return
+	}
+
+	logging.GetLog(r.Context()).Error(err)
+	if transitionErr, ok := err.(*lifecycle.TransitionError); ok {
+		writeTransitionConflict(w, r, statusCode, transitionErr)
+		return
+	}
+	jsonErr := &logging.JsonError{
+		Status:  http.StatusText(statusCode),
+		Code:    strconv.Itoa(statusCode),
+		Message: err.Error(),
+	}
+	logging.FormatError(r.Context(), w, statusCode, *jsonErr)
+}
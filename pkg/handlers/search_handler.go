@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+)
+
+const (
+	MaxSearchTerms = 10
+	// Words at least this similar to a term are highlighted. It's pg_trgm's
+	// default word_similarity_threshold, the one the search query matches
+	// with.
+	HighlightSimilarity = 0.6
+)
+
+// Fields of a result that matched the search, with the matching words
+// wrapped in <mark>. The text is HTML escaped.
+type CarHighlights struct {
+	Make  string `json:"make,omitempty" xml:"make,omitempty"`
+	Model string `json:"model,omitempty" xml:"model,omitempty"`
+	Color string `json:"color,omitempty" xml:"color,omitempty"`
+	Year  string `json:"year,omitempty" xml:"year,omitempty"`
+}
+
+type CarSearchResult struct {
+	Car        models.CarModel `json:"car" xml:"car"`
+	Rank       float64         `json:"rank" xml:"rank,attr"`
+	Highlights CarHighlights   `json:"highlights" xml:"highlights"`
+}
+
+type CarSearchList struct {
+	XMLName xml.Name          `json:"-" xml:"search"`
+	Query   string            `json:"query" xml:"query,attr"`
+	Results []CarSearchResult `json:"results" xml:"result"`
+	Limit   int               `json:"limit" xml:"limit,attr"`
+	Offset  int               `json:"offset" xml:"offset,attr"`
+}
+
+func SearchHandler(w http.ResponseWriter, r *http.Request) {
+	serveMethod(w, r, "GET", SearchCars)
+}
+
+func SearchCars(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("SearchCars: Processing Search Cars endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	log.Debug("SearchCars: Parsing search...")
+	query := r.URL.Query()
+	q := query.Get("q")
+	search := models.ParseCarSearch(q)
+	if search.Empty() {
+		return 400, errors.New("q must have something to search for")
+	}
+	if len(search.Terms) > MaxSearchTerms {
+		return 400, fmt.Errorf("q can have at most %d words", MaxSearchTerms)
+	}
+
+	limit, err := queryInt(query, "limit", DefaultListLimit)
+	if err != nil {
+		return 400, err
+	}
+	if limit < 1 || limit > MaxListLimit {
+		return 400, fmt.Errorf("limit must be between 1 and %d", MaxListLimit)
+	}
+
+	offset, err := queryInt(query, "offset", 0)
+	if err != nil {
+		return 400, err
+	}
+	if offset < 0 {
+		return 400, errors.New("offset must not be negative")
+	}
+
+	// get db conn
+	log.Debug("SearchCars: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	log.Debug("SearchCars: Searching cars in databse...")
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	ranked, err := models.SearchCars(&db, tenant, search, limit, offset)
+	if err != nil {
+		return 500, err
+	}
+
+	cars := make([]models.CarModel, 0, len(ranked))
+	for _, hit := range ranked {
+		cars = append(cars, hit.Car)
+	}
+	if err := attachOwners(&db, cars); err != nil {
+		return 500, err
+	}
+
+	results := make([]CarSearchResult, 0, len(ranked))
+	for i, hit := range ranked {
+		results = append(results, CarSearchResult{
+			Car:        cars[i],
+			Rank:       hit.Rank,
+			Highlights: HighlightCar(hit.Car, search),
+		})
+	}
+
+	return WriteEncoded(w, responseCodec, CarSearchList{Query: q, Results: results, Limit: limit, Offset: offset})
+}
+
+// Mark the words of the car that a search term matches, the same way the
+// database does: a whole word, or a close enough spelling by word similarity
+func HighlightCar(car models.CarModel, search models.CarSearch) CarHighlights {
+	highlights := CarHighlights{
+		Make:  highlightField(car.Make, search.Terms),
+		Model: highlightField(car.Model, search.Terms),
+		Color: highlightField(car.Color, search.Terms),
+	}
+	for _, year := range search.Years {
+		if car.Year == year {
+			highlights.Year = "<mark>" + strconv.Itoa(year) + "</mark>"
+		}
+	}
+	return highlights
+}
+
+// The escaped field with matching words marked, or "" when nothing matched
+func highlightField(value string, terms []string) string {
+	var (
+		out     strings.Builder
+		word    []rune
+		matched bool
+	)
+
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		text := html.EscapeString(string(word))
+		if termMatches(strings.ToLower(string(word)), terms) {
+			out.WriteString("<mark>" + text + "</mark>")
+			matched = true
+		} else {
+			out.WriteString(text)
+		}
+		word = word[:0]
+	}
+
+	for _, r := range value {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word = append(word, r)
+			continue
+		}
+		flush()
+		out.WriteString(html.EscapeString(string(r)))
+	}
+	flush()
+
+	if !matched {
+		return ""
+	}
+	return out.String()
+}
+
+func termMatches(word string, terms []string) bool {
+	for _, term := range terms {
+		if word == term || wordSimilarity(term, word) >= HighlightSimilarity {
+			return true
+		}
+	}
+	return false
+}
+
+// pg_trgm's word_similarity of a term to a single word: the fraction of the
+// term's padded three character sequences the word has too
+func wordSimilarity(term, word string) float64 {
+	left, right := trigrams(term), trigrams(word)
+	if len(left) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for trigram := range left {
+		if right[trigram] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(left))
+}
+
+func trigrams(word string) map[string]bool {
+	padded := []rune("  " + word + " ")
+	set := make(map[string]bool, len(padded))
+	for i := 0; i+3 <= len(padded); i++ {
+		set[string(padded[i:i+3])] = true
+	}
+	return set
+}
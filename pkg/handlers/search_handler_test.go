@@ -0,0 +1,81 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/harness"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
+)
+
+func TestHighlightCar(t *testing.T) {
+	car := models.CarModel{Make: "Toyota", Model: "Camry <LE>", Color: "Dark Red", Year: 2015}
+	got := handlers.HighlightCar(car, models.ParseCarSearch("red toyta cam 2015"))
+
+	expected := handlers.CarHighlights{
+		Make:  "<mark>Toyota</mark>",
+		Model: "<mark>Camry</mark> &lt;LE&gt;",
+		Color: "Dark <mark>Red</mark>",
+		Year:  "<mark>2015</mark>",
+	}
+	if got != expected {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+
+	got = handlers.HighlightCar(car, models.ParseCarSearch("honda 2016"))
+	if got != (handlers.CarHighlights{}) {
+		t.Errorf("Expected nothing highlighted, got %+v", got)
+	}
+
+	// Shares a prefix with Camry but isn't close enough for the query to match
+	got = handlers.HighlightCar(car, models.ParseCarSearch("camero"))
+	if got != (handlers.CarHighlights{}) {
+		t.Errorf("Expected nothing highlighted, got %+v", got)
+	}
+}
+
+func TestSearchCarsEndpoint(t *testing.T) {
+	car := saveNegotiationCar(t)
+	saveNegotiationCar(t)
+	defer harness.Truncate()
+
+	req, _ := http.NewRequest("GET", "/v1/cars/search?limit=1&q="+url.QueryEscape("white toyta 2018"), nil)
+	rr := serveCars(req)
+
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+	if err := openapi.ValidateSchema("CarSearchList", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match CarSearchList schema: %s", err)
+	}
+
+	var got handlers.CarSearchList
+	json.Unmarshal(rr.Body.Bytes(), &got)
+	if len(got.Results) != 1 || got.Limit != 1 || got.Query != "white toyta 2018" {
+		t.Fatalf("Expected one result on the page, got %s", rr.Body.String())
+	}
+	if got.Results[0].Highlights.Make != "<mark>Toyota</mark>" || got.Results[0].Highlights.Model != "" {
+		t.Errorf("Expected the make highlighted, got %+v", got.Results[0].Highlights)
+	}
+	if got.Results[0].Car.Id == "" || got.Results[0].Rank <= 0 {
+		t.Errorf("Expected a ranked car like %s, got %+v", car.Id, got.Results[0])
+	}
+}
+
+func TestSearchCarsValidation(t *testing.T) {
+	for _, query := range []string{"", "q=", "q=..", "q=camry&limit=0", "q=camry&offset=-1", "q=a+b+c+d+e+f+g+h+i+j+k"} {
+		req, _ := http.NewRequest("GET", "/v1/cars/search?"+query, nil)
+		rr := serveCars(req)
+
+		if rr.Code != 400 {
+			t.Errorf("%s: expected: %d, but got: %d", query, 400, rr.Code)
+		}
+		if err := openapi.ValidateSchema("JsonError", rr.Body.Bytes()); err != nil {
+			t.Errorf("%s: response doesn't match JsonError schema: %s", query, err)
+		}
+	}
+}
@@ -0,0 +1,369 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/maintenance"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/money"
+	"github.com/ericmcbride/go-dfw-testing/pkg/odometer"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+)
+
+const (
+	MaxServiceVendorLength = 128
+	MaxServiceNotesLength  = 2000
+	// What the odometer reading logged with a service record is sourced as
+	ServiceOdometerSource = "service"
+)
+
+// Work done on a car. ServicedOn defaults to today. A Mileage is also
+// logged as an odometer reading, and a Cost is a decimal in Currency.
+type ServicePayload struct {
+	Type       string `json:"type" xml:"type"`
+	ServicedOn string `json:"serviced_on" xml:"serviced_on"`
+	Mileage    *int64 `json:"mileage" xml:"mileage"`
+	Unit       string `json:"unit" xml:"unit"`
+	Cost       string `json:"cost" xml:"cost"`
+	Currency   string `json:"currency" xml:"currency"`
+	Vendor     string `json:"vendor" xml:"vendor"`
+	Notes      string `json:"notes" xml:"notes"`
+}
+
+type ServiceRecordList struct {
+	XMLName xml.Name               `json:"-" xml:"service_records"`
+	CarId   string                 `json:"car_id" xml:"car_id,attr"`
+	Records []models.ServiceRecord `json:"service_records" xml:"service_record"`
+}
+
+// When each service on the car's make is next due
+type ServiceDue struct {
+	XMLName   xml.Name          `json:"-" xml:"service_due"`
+	CarId     string            `json:"car_id" xml:"car_id,attr"`
+	Make      string            `json:"make" xml:"make,attr"`
+	MileageKm *int64            `json:"mileage_km,omitempty" xml:"mileage_km,attr,omitempty"`
+	Services  []maintenance.Due `json:"services" xml:"service"`
+}
+
+type OverdueReport struct {
+	XMLName xml.Name            `json:"-" xml:"overdue"`
+	Cars    []models.OverdueCar `json:"cars" xml:"car"`
+	Total   int                 `json:"total" xml:"total,attr"`
+	Limit   int                 `json:"limit" xml:"limit,attr"`
+	Offset  int                 `json:"offset" xml:"offset,attr"`
+}
+
+type ServiceRules struct {
+	XMLName xml.Name           `json:"-" xml:"service_rules"`
+	Rules   []maintenance.Rule `json:"rules" xml:"rule"`
+}
+
+func CarServiceHandler(w http.ResponseWriter, r *http.Request) {
+	if !authorize(w, r) {
+		return
+	}
+
+	var (
+		statusCode int
+		err        error
+	)
+
+	switch r.Method {
+	case "POST":
+		statusCode, err = AddServiceRecord(w, r)
+	case "GET":
+		statusCode, err = ListServiceRecords(w, r)
+	default:
+		http.Error(w, "Invalid Request Method.", 405)
+		return
+	}
+
+	writeHandlerError(w, r, statusCode, err)
+}
+
+func CarServiceDueHandler(w http.ResponseWriter, r *http.Request) {
+	serveMethod(w, r, "GET", GetServiceDue)
+}
+
+func ServiceOverdueHandler(w http.ResponseWriter, r *http.Request) {
+	serveMethod(w, r, "GET", ListOverdueService)
+}
+
+func ServiceRulesHandler(w http.ResponseWriter, r *http.Request) {
+	serveMethod(w, r, "GET", ListServiceRules)
+}
+
+// Log work done on the car in the path
+func AddServiceRecord(w http.ResponseWriter, r *http.Request) (int, error) {
+	var payload ServicePayload
+
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("AddServiceRecord: Processing Add Service Record endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	carId := mux.Vars(r)["id"]
+	if _, err := uuid.FromString(carId); err != nil {
+		return 400, errors.New("Need a valid car Id to add a service record...")
+	}
+
+	log.Debug("AddServiceRecord: Decoding request body...")
+	statusCode, err = DecodeBody(r, &payload)
+	if err != nil {
+		return statusCode, err
+	}
+
+	log.Debug("AddServiceRecord: validating payload...")
+	costMinor, err := ValidateServicePayload(&payload, time.Now())
+	if err != nil {
+		return 422, err
+	}
+
+	// get db conn
+	log.Debug("AddServiceRecord: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	record := models.ServiceRecord{
+		Type:       payload.Type,
+		ServicedOn: payload.ServicedOn,
+		CostMinor:  costMinor,
+		Vendor:     payload.Vendor,
+		Notes:      payload.Notes,
+	}
+	if costMinor != nil {
+		record.Currency = &payload.Currency
+	}
+	var reading *models.OdometerReading
+	if payload.Mileage != nil {
+		reading = &models.OdometerReading{Value: *payload.Mileage, Unit: payload.Unit, Source: ServiceOdometerSource}
+	}
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	err = models.AddServiceRecord(&db, tenant, carId, &record, reading)
+	if err == models.ErrCarNotFound {
+		return 404, err
+	}
+	if err != nil {
+		return 500, err
+	}
+	if reading != nil && reading.SuspectedRollback {
+		log.Warn("AddServiceRecord: suspected rollback on car ", carId)
+	}
+	return WriteEncoded(w, responseCodec, record)
+}
+
+// A car's service records
+func ListServiceRecords(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("ListServiceRecords: Processing List Service Records endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	carId := mux.Vars(r)["id"]
+	if _, err := uuid.FromString(carId); err != nil {
+		return 400, errors.New("Need a valid car Id to list service records...")
+	}
+
+	// get db conn
+	log.Debug("ListServiceRecords: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	if _, err = models.GetCarColumns(&db, tenant, carId, []string{"id"}); err == models.ErrCarNotFound {
+		return 404, err
+	}
+	if err != nil {
+		return 500, err
+	}
+
+	records, err := models.ListServiceRecords(&db, tenant, carId)
+	if err != nil {
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, ServiceRecordList{CarId: carId, Records: records})
+}
+
+// When each service is next due on the car in the path, under the rules for
+// its make
+func GetServiceDue(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("GetServiceDue: Processing Get Service Due endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	carId := mux.Vars(r)["id"]
+	if _, err := uuid.FromString(carId); err != nil {
+		return 400, errors.New("Need a valid car Id to check service...")
+	}
+
+	// get db conn
+	log.Debug("GetServiceDue: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	car, err := models.GetCarColumns(&db, tenant, carId, []string{"make", "mileage_km"})
+	if err == models.ErrCarNotFound {
+		return 404, err
+	}
+	if err != nil {
+		return 500, err
+	}
+
+	last, err := models.LastServices(&db, tenant, carId)
+	if err != nil {
+		return 500, err
+	}
+	since, err := models.FirstReadingOn(&db, tenant, carId)
+	if err != nil {
+		return 500, err
+	}
+
+	return WriteEncoded(w, responseCodec, ServiceDue{
+		CarId:     carId,
+		Make:      car.Make,
+		MileageKm: car.MileageKm,
+		Services:  maintenance.Check(maintenance.Default.For(car.Make), last, car.MileageKm, since, time.Now()),
+	})
+}
+
+// The tenant's cars with a service overdue, filtered like listings
+func ListOverdueService(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("ListOverdueService: Processing Overdue Service Report endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	query := r.URL.Query()
+	filter, err := ParseCarFilter(query)
+	if err != nil {
+		return 400, err
+	}
+	limit, offset, err := queryPage(query)
+	if err != nil {
+		return 400, err
+	}
+
+	// get db conn
+	log.Debug("ListOverdueService: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	cars, total, err := models.OverdueCars(&db, tenant, filter, maintenance.Default, time.Now(), limit, offset)
+	if err != nil {
+		return 500, err
+	}
+
+	return WriteEncoded(w, responseCodec, OverdueReport{Cars: cars, Total: total, Limit: limit, Offset: offset})
+}
+
+// The service interval rules in use
+func ListServiceRules(w http.ResponseWriter, r *http.Request) (int, error) {
+	log := logging.GetLog(r.Context())
+	log.Info("ListServiceRules: Processing List Service Rules endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+	return WriteEncoded(w, responseCodec, ServiceRules{Rules: maintenance.Default.Rules()})
+}
+
+// Check the payload and work out the cost in minor units of its currency,
+// nil when there's no cost. The service can't be dated after today.
+func ValidateServicePayload(payload *ServicePayload, now time.Time) (*int64, error) {
+	payload.Type = strings.ToLower(strings.TrimSpace(payload.Type))
+	payload.Unit = strings.ToLower(strings.TrimSpace(payload.Unit))
+	payload.Cost = strings.TrimSpace(payload.Cost)
+	payload.Currency = strings.ToUpper(strings.TrimSpace(payload.Currency))
+	payload.Vendor = strings.TrimSpace(payload.Vendor)
+	payload.Notes = strings.TrimSpace(payload.Notes)
+
+	if !maintenance.ValidType(payload.Type) {
+		return nil, fmt.Errorf("Type must be one of %s", strings.Join(maintenance.Types, ", "))
+	}
+
+	today := now.UTC().Format(models.DateLayout)
+	if payload.ServicedOn == "" {
+		payload.ServicedOn = today
+	}
+	if _, err := time.Parse(models.DateLayout, payload.ServicedOn); err != nil {
+		return nil, fmt.Errorf("ServicedOn must be a date like %s", models.DateLayout)
+	}
+	if payload.ServicedOn > today {
+		return nil, errors.New("ServicedOn can't be in the future")
+	}
+
+	if payload.Mileage != nil {
+		if *payload.Mileage < 0 || *payload.Mileage > odometer.MaxValue {
+			return nil, fmt.Errorf("Mileage must be between 0 and %d", odometer.MaxValue)
+		}
+		if !odometer.ValidUnit(payload.Unit) {
+			return nil, fmt.Errorf("Unit must be one of %s", strings.Join(odometer.UnitNames(), ", "))
+		}
+	} else if payload.Unit != "" {
+		return nil, errors.New("Unit needs a Mileage")
+	}
+
+	if utf8.RuneCountInString(payload.Vendor) > MaxServiceVendorLength {
+		return nil, fmt.Errorf("Vendor must be at most %d characters", MaxServiceVendorLength)
+	}
+	if utf8.RuneCountInString(payload.Notes) > MaxServiceNotesLength {
+		return nil, fmt.Errorf("Notes must be at most %d characters", MaxServiceNotesLength)
+	}
+
+	if payload.Cost == "" {
+		if payload.Currency != "" {
+			return nil, errors.New("Currency needs a Cost")
+		}
+		return nil, nil
+	}
+	if !money.ValidCurrency(payload.Currency) {
+		return nil, fmt.Errorf("Currency must be one of %s", strings.Join(money.Codes(), ", "))
+	}
+	costMinor, err := money.Parse(payload.Cost, payload.Currency)
+	if err != nil {
+		return nil, err
+	}
+	return &costMinor, nil
+}
@@ -0,0 +1,210 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/harness"
+	"github.com/ericmcbride/go-dfw-testing/pkg/maintenance"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
+)
+
+func service(carId string, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/v1/cars/"+carId+"/service", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return serveCars(req)
+}
+
+func TestAddServiceRecord(t *testing.T) {
+	car := saveNegotiationCar(t)
+	defer harness.Truncate()
+
+	rr := service(car.Id, `{"type": "oil_change", "serviced_on": "2025-03-01", "mileage": 10000, "unit": "miles", "cost": "89.95", "currency": "usd", "vendor": "Quick Lube", "notes": "5W-30"}`)
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+	if err := openapi.ValidateSchema("ServiceRecord", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match ServiceRecord schema: %s %s", err, rr.Body.String())
+	}
+	var record models.ServiceRecord
+	json.Unmarshal(rr.Body.Bytes(), &record)
+	if record.MileageKm == nil || *record.MileageKm != 16093 || record.Cost != "89.95" || *record.CostMinor != 8995 {
+		t.Errorf("Expected 16093 km and 89.95 USD, got %s", rr.Body.String())
+	}
+	if record.Odometer == nil || record.Odometer.Source != handlers.ServiceOdometerSource || record.Odometer.ReadOn != "2025-03-01" {
+		t.Errorf("Expected the mileage logged as a reading on the service date, got %+v", record.Odometer)
+	}
+
+	// Free work without a mileage
+	if rr = service(car.Id, `{"type": "inspection", "serviced_on": "2025-04-01", "vendor": "State"}`); rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+	if rr = service(car.Id, `{"type": "detailing"}`); rr.Code != 422 {
+		t.Errorf("Expected: %d, but got: %d", 422, rr.Code)
+	}
+
+	req, _ := http.NewRequest("GET", "/v1/cars/"+car.Id+"/service", nil)
+	rr = serveCars(req)
+	if err := openapi.ValidateSchema("ServiceRecordList", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match ServiceRecordList schema: %s %s", err, rr.Body.String())
+	}
+	var list handlers.ServiceRecordList
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	if len(list.Records) != 2 || list.Records[1].Type != maintenance.Inspection || list.Records[1].Odometer != nil {
+		t.Errorf("Expected both records oldest first, got %s", rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/cars?car_id="+car.Id, nil)
+	var got models.CarModel
+	json.Unmarshal(serveCars(req).Body.Bytes(), &got)
+	if got.MileageKm == nil || *got.MileageKm != 16093 {
+		t.Errorf("Expected the car's mileage from the service, got %+v", got)
+	}
+
+	if rr = service("00000000-0000-4000-8000-000000000000", `{"type": "repair"}`); rr.Code != 404 {
+		t.Errorf("Expected: %d, but got: %d", 404, rr.Code)
+	}
+}
+
+func TestServiceDue(t *testing.T) {
+	defer harness.Truncate()
+
+	today := time.Now().UTC()
+	day := func(t time.Time) string { return t.Format(models.DateLayout) }
+
+	// A Toyota: oil every 16000 km, tires every 8000 km, inspection yearly
+	due, fresh := saveNegotiationCar(t), saveNegotiationCar(t)
+	readOdometer(due.Id, `{"value": 30000, "unit": "km", "source": "dealer"}`)
+	service(due.Id, `{"type": "oil_change", "mileage": 20000, "unit": "km", "serviced_on": "`+day(today.AddDate(0, -1, 0))+`"}`)
+	service(due.Id, `{"type": "inspection", "serviced_on": "`+day(today.AddDate(-2, 0, 0))+`"}`)
+
+	req, _ := http.NewRequest("GET", "/v1/cars/"+due.Id+"/service/due", nil)
+	rr := serveCars(req)
+	if err := openapi.ValidateSchema("ServiceDue", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match ServiceDue schema: %s %s", err, rr.Body.String())
+	}
+	var check handlers.ServiceDue
+	json.Unmarshal(rr.Body.Bytes(), &check)
+	overdue := map[string]bool{}
+	for _, next := range check.Services {
+		overdue[next.Type] = next.Overdue
+	}
+	// The later reading at 30000 km is the car's mileage
+	if *check.MileageKm != 30000 || overdue[maintenance.OilChange] || !overdue[maintenance.TireRotation] || overdue[maintenance.Brakes] || !overdue[maintenance.Inspection] {
+		t.Errorf("Expected tires and inspection overdue, got %s", rr.Body.String())
+	}
+
+	// Never serviced, so the dated services count from its first reading
+	stale := saveNegotiationCar(t)
+	readOdometer(stale.Id, `{"value": 1000, "unit": "km", "read_on": "`+day(today.AddDate(0, -13, 0))+`", "source": "title"}`)
+	req, _ = http.NewRequest("GET", "/v1/cars/"+stale.Id+"/service/due", nil)
+	json.Unmarshal(serveCars(req).Body.Bytes(), &check)
+	overdue = map[string]bool{}
+	for _, next := range check.Services {
+		overdue[next.Type] = next.Overdue
+	}
+	if !overdue[maintenance.OilChange] || !overdue[maintenance.TireRotation] || overdue[maintenance.Brakes] || !overdue[maintenance.Inspection] {
+		t.Errorf("Expected oil, tires and inspection overdue a year after the first reading, got %+v", check.Services)
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/cars/service/overdue?make=Toyota", nil)
+	rr = serveCars(req)
+	if err := openapi.ValidateSchema("OverdueReport", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match OverdueReport schema: %s %s", err, rr.Body.String())
+	}
+	var report handlers.OverdueReport
+	json.Unmarshal(rr.Body.Bytes(), &report)
+	services := map[string]int{}
+	for _, car := range report.Cars {
+		services[car.CarId] = len(car.Overdue)
+	}
+	if report.Total != 2 || len(report.Cars) != 2 || services[due.Id] != 2 || services[stale.Id] != 3 {
+		t.Errorf("Expected %s and %s overdue, not %s: %s", due.Id, stale.Id, fresh.Id, rr.Body.String())
+	}
+
+	// Pages run by car id
+	second := due.Id
+	if stale.Id > second {
+		second = stale.Id
+	}
+	req, _ = http.NewRequest("GET", "/v1/cars/service/overdue?limit=1&offset=1", nil)
+	json.Unmarshal(serveCars(req).Body.Bytes(), &report)
+	if report.Total != 2 || len(report.Cars) != 1 || report.Cars[0].CarId != second {
+		t.Errorf("Expected the second overdue car, got %+v", report)
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/cars/service/overdue?offset=2", nil)
+	json.Unmarshal(serveCars(req).Body.Bytes(), &report)
+	if report.Total != 2 || len(report.Cars) != 0 {
+		t.Errorf("Expected an empty page past the end, got %+v", report)
+	}
+}
+
+func TestServiceOtherTenant(t *testing.T) {
+	car := otherTenantCar(t)
+	defer harness.Truncate()
+
+	for _, path := range []string{"/v1/cars/" + car.Id + "/service/due", "/v1/cars/" + car.Id + "/service"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		if rr := serveCars(req); rr.Code != 404 {
+			t.Errorf("Expected %s of another tenant's car to be a %d, got %d %s", path, 404, rr.Code, rr.Body.String())
+		}
+	}
+	if rr := service(car.Id, `{"type": "inspection"}`); rr.Code != 404 {
+		t.Errorf("Expected servicing another tenant's car to be a %d, got %d", 404, rr.Code)
+	}
+}
+
+func TestListServiceRules(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/v1/service/rules", nil)
+	rr := serveCars(req)
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+	if err := openapi.ValidateSchema("ServiceRules", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match ServiceRules schema: %s %s", err, rr.Body.String())
+	}
+}
+
+func TestValidateServicePayload(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	mileage := int64(42000)
+
+	payload := handlers.ServicePayload{Type: " Oil_Change ", Mileage: &mileage, Unit: "KM", Cost: "1500", Currency: "jpy"}
+	costMinor, err := handlers.ValidateServicePayload(&payload, now)
+	if err != nil || costMinor == nil || *costMinor != 1500 || payload.Type != maintenance.OilChange || payload.ServicedOn != "2025-06-01" {
+		t.Errorf("Expected an oil change costing 1500 JPY today, got %+v %v", payload, err)
+	}
+
+	payload = handlers.ServicePayload{Type: "repair"}
+	if costMinor, err := handlers.ValidateServicePayload(&payload, now); err != nil || costMinor != nil {
+		t.Errorf("Expected free work to have no cost, got %v %v", costMinor, err)
+	}
+
+	negative := int64(-1)
+	cases := []handlers.ServicePayload{
+		{},
+		{Type: "wash"},
+		{Type: "repair", ServicedOn: "2025-06-02"},
+		{Type: "repair", ServicedOn: "June 1st"},
+		{Type: "repair", Mileage: &mileage},
+		{Type: "repair", Mileage: &negative, Unit: "km"},
+		{Type: "repair", Unit: "km"},
+		{Type: "repair", Cost: "10.00"},
+		{Type: "repair", Currency: "USD"},
+		{Type: "repair", Cost: "10.005", Currency: "USD"},
+		{Type: "repair", Vendor: strings.Repeat("a", handlers.MaxServiceVendorLength+1)},
+		{Type: "repair", Notes: strings.Repeat("a", handlers.MaxServiceNotesLength+1)},
+	}
+	for _, c := range cases {
+		if _, err := handlers.ValidateServicePayload(&c, now); err == nil {
+			t.Errorf("Expected %+v to be rejected", c)
+		}
+	}
+}
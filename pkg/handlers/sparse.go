@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+)
+
+// Loads a related resource for a page of cars in one query, keyed by car
+// id. Cars without one are left out of the map.
+type IncludeLoader func(db *clients.DBClient, carIds []string) (map[string]interface{}, error)
+
+// Related resources ?include= can embed, by name
+var Includes = map[string]IncludeLoader{}
+
+func RegisterInclude(name string, load IncludeLoader) {
+	Includes[name] = load
+}
+
+// What ?fields= and ?include= asked for. Zero means the full car.
+type Sparse struct {
+	Fields   []string
+	Includes []string
+}
+
+func (s Sparse) Empty() bool {
+	return len(s.Fields) == 0 && len(s.Includes) == 0
+}
+
+// Columns to select: the fields, plus the id when includes need it
+func (s Sparse) Columns() []string {
+	columns := append([]string{}, s.Fields...)
+	if len(s.Includes) > 0 && !contains(columns, "id") {
+		columns = append(columns, "id")
+	}
+	return columns
+}
+
+// Read ?fields=id,make and ?include=owner, rejecting anything unknown.
+// Includes without fields get every field.
+func ParseSparse(query url.Values) (Sparse, error) {
+	var sparse Sparse
+
+	if _, ok := query["fields"]; ok {
+		for _, field := range splitList(query.Get("fields")) {
+			if !models.ValidCarColumn(field) {
+				return sparse, fmt.Errorf("Unknown field %q, fields must be some of %s", field, strings.Join(models.CarColumns, ", "))
+			}
+			if !contains(sparse.Fields, field) {
+				sparse.Fields = append(sparse.Fields, field)
+			}
+		}
+		if len(sparse.Fields) == 0 {
+			return sparse, fmt.Errorf("fields must name some of %s", strings.Join(models.CarColumns, ", "))
+		}
+	}
+
+	for _, include := range splitList(query.Get("include")) {
+		if _, ok := Includes[include]; !ok {
+			if len(Includes) == 0 {
+				return sparse, fmt.Errorf("Unknown include %q, nothing can be included yet", include)
+			}
+			return sparse, fmt.Errorf("Unknown include %q, includes must be some of %s", include, strings.Join(includeNames(), ", "))
+		}
+		if !contains(sparse.Includes, include) {
+			sparse.Includes = append(sparse.Includes, include)
+		}
+	}
+
+	if len(sparse.Fields) == 0 && len(sparse.Includes) > 0 {
+		sparse.Fields = models.CarColumns
+	}
+	return sparse, nil
+}
+
+// A car trimmed to the requested fields, with its includes embedded
+type CarResource struct {
+	Car      models.CarModel
+	Fields   []string
+	Includes []string
+	Included map[string]interface{}
+}
+
+// Build the resources for cars read with sparse.Columns(), loading each
+// include once for the whole page
+func LoadCarResources(db *clients.DBClient, cars []models.CarModel, sparse Sparse) ([]CarResource, error) {
+	resources := make([]CarResource, 0, len(cars))
+	ids := make([]string, 0, len(cars))
+	for _, car := range cars {
+		resources = append(resources, CarResource{
+			Car:      car,
+			Fields:   sparse.Fields,
+			Includes: sparse.Includes,
+			Included: make(map[string]interface{}),
+		})
+		ids = append(ids, car.Id)
+	}
+
+	for _, include := range sparse.Includes {
+		loaded, err := Includes[include](db, ids)
+		if err != nil {
+			return nil, err
+		}
+		for i := range resources {
+			if value, ok := loaded[resources[i].Car.Id]; ok {
+				resources[i].Included[include] = value
+			}
+		}
+	}
+	return resources, nil
+}
+
+// Fields in the order asked for, then includes, which are null when the car
+// has none
+func (c CarResource) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	write := func(name string, value interface{}) error {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(strconv.Quote(name))
+		buf.WriteByte(':')
+		buf.Write(data)
+		return nil
+	}
+
+	for _, field := range c.Fields {
+		if err := write(field, carField(c.Car, field)); err != nil {
+			return nil, err
+		}
+	}
+	for _, include := range c.Includes {
+		if err := write(include, c.Included[include]); err != nil {
+			return nil, err
+		}
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Same shape as a CarModel element, missing includes are left out
+func (c CarResource) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Local: "car"}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	for _, field := range c.Fields {
+		if err := e.EncodeElement(carField(c.Car, field), xml.StartElement{Name: xml.Name{Local: field}}); err != nil {
+			return err
+		}
+	}
+	for _, include := range c.Includes {
+		value, ok := c.Included[include]
+		if !ok {
+			continue
+		}
+		if err := e.EncodeElement(value, xml.StartElement{Name: xml.Name{Local: include}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// A page of CarResources, the sparse CarList
+type CarResourceList struct {
+	XMLName xml.Name      `json:"-" xml:"cars"`
+	Cars    []CarResource `json:"cars" xml:"car"`
+	Limit   int           `json:"limit" xml:"limit,attr"`
+	Offset  int           `json:"offset" xml:"offset,attr"`
+	Fields  []string      `json:"-" xml:"-"`
+}
+
+// As CSV only the fields are written, includes don't flatten into columns
+func (l CarResourceList) Header() []string {
+	return l.Fields
+}
+
+func (l CarResourceList) Rows() [][]string {
+	rows := make([][]string, 0, len(l.Cars))
+	for _, resource := range l.Cars {
+		row := make([]string, 0, len(l.Fields))
+		for _, field := range l.Fields {
+			// Unpriced cars and cars without a reading get empty cells
+			value := carField(resource.Car, field)
+			if value == nil {
+				row = append(row, "")
+				continue
+			}
+			row = append(row, fmt.Sprint(value))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func carField(car models.CarModel, field string) interface{} {
+	switch field {
+	case "id":
+		return car.Id
+	case "make":
+		return car.Make
+	case "model":
+		return car.Model
+	case "color":
+		return car.Color
+	case "year":
+		return car.Year
+	case "status":
+		return car.Status
+	case "price_minor":
+		if car.PriceMinor != nil {
+			return *car.PriceMinor
+		}
+	case "currency":
+		if car.Currency != nil {
+			return *car.Currency
+		}
+	case "mileage_km":
+		if car.MileageKm != nil {
+			return *car.MileageKm
+		}
+	}
+	return nil
+}
+
+func includeNames() []string {
+	names := make([]string, 0, len(Includes))
+	for name := range Includes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
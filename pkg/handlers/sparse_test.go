@@ -0,0 +1,156 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/harness"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
+)
+
+// An include that tags every car, recording the ids it was asked for
+func registerBadgeInclude(t *testing.T, calls *[][]string) {
+	handlers.RegisterInclude("badge", func(db *clients.DBClient, carIds []string) (map[string]interface{}, error) {
+		*calls = append(*calls, carIds)
+		badges := make(map[string]interface{})
+		for _, id := range carIds {
+			badges[id] = map[string]string{"label": "car " + id[:4]}
+		}
+		return badges, nil
+	})
+}
+
+func TestParseSparse(t *testing.T) {
+	sparse, err := handlers.ParseSparse(url.Values{"fields": {"year, id,year"}})
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if strings.Join(sparse.Fields, ",") != "year,id" || strings.Join(sparse.Columns(), ",") != "year,id" {
+		t.Errorf("Expected year and id once each, got %v", sparse.Fields)
+	}
+
+	sparse, err = handlers.ParseSparse(url.Values{})
+	if err != nil || !sparse.Empty() {
+		t.Errorf("Expected no fieldset, got %v %v", sparse, err)
+	}
+
+	for _, query := range []url.Values{
+		{"fields": {"id,tenant"}},
+		{"fields": {""}},
+		{"include": {"warranty"}},
+	} {
+		if _, err := handlers.ParseSparse(query); err == nil {
+			t.Errorf("%v: expected a validation error", query)
+		}
+	}
+}
+
+func TestGetCarFields(t *testing.T) {
+	car := saveNegotiationCar(t)
+	defer harness.Truncate()
+
+	req, _ := http.NewRequest("GET", "/v1/cars?fields=year,make&car_id="+car.Id, nil)
+	rr := serveCars(req)
+
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d", 200, rr.Code)
+	}
+	if err := openapi.ValidateSchema("SparseCar", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match SparseCar schema: %s", err)
+	}
+	if rr.Body.String() != `{"year":2018,"make":"Toyota"}` {
+		t.Errorf("Expected only year and make, got %s", rr.Body.String())
+	}
+}
+
+func TestListCarsFieldsAndIncludes(t *testing.T) {
+	var calls [][]string
+	registerBadgeInclude(t, &calls)
+	defer delete(handlers.Includes, "badge")
+
+	first := saveNegotiationCar(t)
+	saveNegotiationCar(t)
+	defer harness.Truncate()
+
+	req, _ := http.NewRequest("GET", "/v1/cars?make=Toyota&fields=model&include=badge", nil)
+	rr := serveCars(req)
+
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+	if err := openapi.ValidateSchema("SparseCarList", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match SparseCarList schema: %s", err)
+	}
+
+	var got struct {
+		Cars []map[string]interface{} `json:"cars"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &got)
+	if len(got.Cars) != 2 {
+		t.Fatalf("Expected 2 cars, got %s", rr.Body.String())
+	}
+	for _, car := range got.Cars {
+		model, _ := car["model"].(string)
+		if _, ok := car["id"]; ok || model != "Corolla" || car["badge"] == nil {
+			t.Errorf("Expected model and badge only, got %v", car)
+		}
+	}
+
+	// The id is read for the include even though it isn't returned, and the
+	// include loads once for the page
+	if len(calls) != 1 || len(calls[0]) != 2 {
+		t.Errorf("Expected one include load for both cars, got %v", calls)
+	}
+	if !strings.Contains(rr.Body.String(), `"label":"car `+first.Id[:4]+`"`) {
+		t.Errorf("Expected a badge for %s, got %s", first.Id, rr.Body.String())
+	}
+}
+
+func TestSparseValidation(t *testing.T) {
+	car := saveNegotiationCar(t)
+	defer harness.Truncate()
+
+	for _, query := range []string{"fields=id,vin", "include=images", "fields=", "car_id=" + car.Id + "&include=warranty"} {
+		req, _ := http.NewRequest("GET", "/v1/cars?"+query, nil)
+		rr := serveCars(req)
+
+		if rr.Code != 400 {
+			t.Errorf("%s: expected: %d, but got: %d", query, 400, rr.Code)
+		}
+		if err := openapi.ValidateSchema("JsonError", rr.Body.Bytes()); err != nil {
+			t.Errorf("%s: response doesn't match JsonError schema: %s", query, err)
+		}
+	}
+}
+
+func TestGetCarFieldsNotFound(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/v1/cars?fields=id&car_id=00000000-0000-4000-8000-000000000000", nil)
+	rr := serveCars(req)
+
+	if rr.Code != 404 {
+		t.Errorf("Expected: %d, but got: %d", 404, rr.Code)
+	}
+}
+
+func TestCarResourceEncoding(t *testing.T) {
+	resource := handlers.CarResource{
+		Car:      models.CarModel{Id: "a", Make: "Toyota", Year: 2018},
+		Fields:   []string{"year", "id"},
+		Includes: []string{"owner"},
+		Included: map[string]interface{}{},
+	}
+
+	data, err := json.Marshal(resource)
+	if err != nil {
+		t.Fatalf("Couldn't encode %s", err)
+	}
+	if string(data) != `{"year":2018,"id":"a","owner":null}` {
+		t.Errorf("Expected fields in order and a null owner, got %s", data)
+	}
+}
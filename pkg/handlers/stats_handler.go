@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+)
+
+// How long stats are served from memory, and how long clients are told
+// they can keep them. Zero turns caching off.
+var StatsCacheTTL = time.Minute
+
+// Past this many cached queries the expired ones are dropped, and if that's
+// not enough the whole cache is
+const MaxStatsCacheEntries = 1000
+
+type statsCacheEntry struct {
+	rows    [][]interface{}
+	expires time.Time
+}
+
+var statsCache = struct {
+	sync.Mutex
+	entries map[string]statsCacheEntry
+}{entries: make(map[string]statsCacheEntry)}
+
+func ClearStatsCache() {
+	statsCache.Lock()
+	defer statsCache.Unlock()
+	statsCache.entries = make(map[string]statsCacheEntry)
+}
+
+// One row of stats: the group values then the metrics, named by Columns
+type CarStatsGroup struct {
+	Columns []string
+	Values  []interface{}
+}
+
+type CarStatsResponse struct {
+	XMLName xml.Name        `json:"-" xml:"stats"`
+	GroupBy []string        `json:"group_by" xml:"-"`
+	Metrics []string        `json:"metrics" xml:"-"`
+	Groups  []CarStatsGroup `json:"groups" xml:"group"`
+}
+
+// CarStatsResponse is a codec.Table so stats can be sent as CSV
+func (s CarStatsResponse) Header() []string {
+	return append(append([]string{}, s.GroupBy...), s.Metrics...)
+}
+
+func (s CarStatsResponse) Rows() [][]string {
+	rows := make([][]string, 0, len(s.Groups))
+	for _, group := range s.Groups {
+		row := make([]string, 0, len(group.Values))
+		for _, value := range group.Values {
+			if value == nil {
+				row = append(row, "")
+			} else {
+				row = append(row, fmt.Sprint(value))
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// Columns in order, a NULL group (cars without a year) is null
+func (g CarStatsGroup) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, column := range g.Columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		data, err := json.Marshal(g.Values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(strconv.Quote(column))
+		buf.WriteByte(':')
+		buf.Write(data)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// One element per column, NULLs are left out
+func (g CarStatsGroup) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Local: "group"}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for i, column := range g.Columns {
+		if g.Values[i] == nil {
+			continue
+		}
+		if err := e.EncodeElement(g.Values[i], xml.StartElement{Name: xml.Name{Local: column}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func StatsHandler(w http.ResponseWriter, r *http.Request) {
+	serveMethod(w, r, "GET", CarStats)
+}
+
+func CarStats(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("CarStats: Processing Car Stats endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, true)
+	if err != nil {
+		return statusCode, err
+	}
+
+	log.Debug("CarStats: Parsing filters and groups...")
+	query := r.URL.Query()
+	filter, err := ParseCarFilter(query)
+	if err != nil {
+		return 400, err
+	}
+	filter.Tenant, _ = TenantForAuthId(r.Header.Get("X-CARS-ID"))
+
+	groups, err := parseStatsColumns(query, "group_by", models.CarStatsGroups, nil)
+	if err != nil {
+		return 400, err
+	}
+	metrics, err := parseStatsColumns(query, "metrics", models.CarStatsMetrics, []string{"count"})
+	if err != nil {
+		return 400, err
+	}
+
+	// The filter doesn't marshal its tenant, so it's part of the key on its
+	// own: one tenant's cached stats are never served to another
+	key, err := json.Marshal(struct {
+		Tenant  string
+		Filter  models.CarFilter
+		Groups  []string
+		Metrics []string
+	}{filter.Tenant, filter, models.StatsColumnNames(groups), models.StatsColumnNames(metrics)})
+	if err != nil {
+		return 500, err
+	}
+
+	rows, expires, hit := cachedStats(string(key))
+	if !hit {
+		log.Debug("CarStats: Getting Database Connection...")
+		db, err := clients.NewDbConn()
+		if err != nil {
+			return 500, err
+		}
+		defer clients.Close(&db)
+
+		log.Debug("CarStats: Aggregating cars in databse...")
+		rows, err = models.CarStats(&db, filter, groups, metrics)
+		if err != nil {
+			return 500, err
+		}
+		expires = cacheStats(string(key), rows)
+	}
+
+	setStatsCacheHeaders(w, hit, expires)
+
+	response := CarStatsResponse{
+		GroupBy: models.StatsColumnNames(groups),
+		Metrics: models.StatsColumnNames(metrics),
+		Groups:  make([]CarStatsGroup, 0, len(rows)),
+	}
+	columns := response.Header()
+	for _, row := range rows {
+		response.Groups = append(response.Groups, CarStatsGroup{Columns: columns, Values: row})
+	}
+	return WriteEncoded(w, responseCodec, response)
+}
+
+// Read a comma separated list of stats columns, rejecting unknown names
+func parseStatsColumns(query url.Values, name string, known []models.StatsColumn, fallback []string) ([]models.StatsColumn, error) {
+	names := splitList(query.Get(name))
+	if len(names) == 0 {
+		names = fallback
+	}
+
+	var columns []models.StatsColumn
+	for _, columnName := range names {
+		column, ok := models.FindStatsColumn(known, columnName)
+		if !ok {
+			return nil, fmt.Errorf("Unknown %s %q, must be some of %s", name, columnName, strings.Join(models.StatsColumnNames(known), ", "))
+		}
+		if !contains(models.StatsColumnNames(columns), columnName) {
+			columns = append(columns, column)
+		}
+	}
+	return columns, nil
+}
+
+func cachedStats(key string) ([][]interface{}, time.Time, bool) {
+	statsCache.Lock()
+	defer statsCache.Unlock()
+
+	entry, ok := statsCache.entries[key]
+	if !ok || !time.Now().Before(entry.expires) {
+		return nil, time.Time{}, false
+	}
+	return entry.rows, entry.expires, true
+}
+
+// Keep the rows for StatsCacheTTL, returning when they expire
+func cacheStats(key string, rows [][]interface{}) time.Time {
+	now := time.Now()
+	expires := now.Add(StatsCacheTTL)
+	if StatsCacheTTL <= 0 {
+		return expires
+	}
+
+	statsCache.Lock()
+	defer statsCache.Unlock()
+
+	if len(statsCache.entries) >= MaxStatsCacheEntries {
+		for cachedKey, entry := range statsCache.entries {
+			if !now.Before(entry.expires) {
+				delete(statsCache.entries, cachedKey)
+			}
+		}
+	}
+	if len(statsCache.entries) >= MaxStatsCacheEntries {
+		statsCache.entries = make(map[string]statsCacheEntry)
+	}
+
+	statsCache.entries[key] = statsCacheEntry{rows: rows, expires: expires}
+	return expires
+}
+
+// Tell clients how much longer the stats are good for, the same as the
+// server will keep serving them. The credential makes them private.
+func setStatsCacheHeaders(w http.ResponseWriter, hit bool, expires time.Time) {
+	if StatsCacheTTL <= 0 {
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		maxAge := int(math.Ceil(time.Until(expires).Seconds()))
+		w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", maxAge))
+	}
+
+	if hit {
+		w.Header().Set("X-Cache", "HIT")
+	} else {
+		w.Header().Set("X-Cache", "MISS")
+	}
+}
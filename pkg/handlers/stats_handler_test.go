@@ -0,0 +1,196 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/codec"
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/harness"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
+	"github.com/ericmcbride/go-dfw-testing/pkg/server"
+	"github.com/satori/go.uuid"
+)
+
+func saveStatsCars(t *testing.T) {
+	db, err := clients.NewDbConn()
+	if err != nil {
+		t.Fatalf("Couldnt connect to db")
+	}
+	defer clients.Close(&db)
+
+	for _, car := range []models.CarModel{
+		{Make: "Toyota", Model: "Camry", Color: "Red", Year: 2015},
+		{Make: "Toyota", Model: "Corolla", Color: "White", Year: 2009},
+		{Make: "Honda", Model: "Civic", Color: "Red", Year: 2018},
+	} {
+		car.Id = uuid.NewV4().String()
+		if _, err := models.SaveCar(&db, &car); err != nil {
+			t.Fatalf("Couldn't save model")
+		}
+	}
+}
+
+func TestCarStatsGrouped(t *testing.T) {
+	handlers.ClearStatsCache()
+	saveStatsCars(t)
+	defer harness.Truncate()
+
+	req, _ := http.NewRequest("GET", "/v1/cars/stats?group_by=make&metrics=count,min_year,avg_year", nil)
+	rr := serveCars(req)
+
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+	if err := openapi.ValidateSchema("CarStats", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match CarStats schema: %s", err)
+	}
+
+	expected := `{"group_by":["make"],"metrics":["count","min_year","avg_year"],"groups":[` +
+		`{"make":"Honda","count":1,"min_year":2018,"avg_year":2018},` +
+		`{"make":"Toyota","count":2,"min_year":2009,"avg_year":2012}]}`
+	if rr.Body.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, rr.Body.String())
+	}
+}
+
+func TestCarStatsFilteredByDecade(t *testing.T) {
+	handlers.ClearStatsCache()
+	saveStatsCars(t)
+	defer harness.Truncate()
+
+	req, _ := http.NewRequest("GET", "/v1/cars/stats?group_by=decade&make=Toyota", nil)
+	req.Header.Set("Accept", "text/csv")
+	rr := serveCars(req)
+
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+	if rr.Body.String() != "decade,count\n2000,1\n2010,1\n" {
+		t.Errorf("Expected a Toyota in each decade, got %q", rr.Body.String())
+	}
+}
+
+func TestCarStatsCached(t *testing.T) {
+	handlers.ClearStatsCache()
+	defer handlers.ClearStatsCache()
+	saveStatsCars(t)
+	defer harness.Truncate()
+
+	get := func() (string, http.Header) {
+		req, _ := http.NewRequest("GET", "/v1/cars/stats?group_by=color", nil)
+		rr := serveCars(req)
+		if rr.Code != 200 {
+			t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+		}
+		return rr.Body.String(), rr.Header()
+	}
+
+	first, header := get()
+	if header.Get("X-Cache") != "MISS" || header.Get("Cache-Control") != "private, max-age=60" {
+		t.Errorf("Expected a fresh result good for a minute, got %v", header)
+	}
+
+	// New cars don't show up until the cached result expires
+	saveStatsCars(t)
+	second, header := get()
+	if header.Get("X-Cache") != "HIT" || second != first {
+		t.Errorf("Expected the cached %s, got %s %v", first, second, header)
+	}
+
+	handlers.StatsCacheTTL = 0
+	defer func() { handlers.StatsCacheTTL = time.Minute }()
+	third, header := get()
+	if header.Get("X-Cache") != "MISS" || header.Get("Cache-Control") != "no-cache" || third == first {
+		t.Errorf("Expected fresh stats with caching off, got %s %v", third, header)
+	}
+}
+
+func TestCarStatsOtherTenant(t *testing.T) {
+	handlers.ClearStatsCache()
+	defer handlers.ClearStatsCache()
+	saveStatsCars(t)
+	otherTenantCar(t)
+	defer harness.Truncate()
+
+	handlers.Credentials["5678"] = "acme"
+	defer delete(handlers.Credentials, "5678")
+
+	get := func(auth string) (string, http.Header) {
+		req, _ := http.NewRequest("GET", "/v1/cars/stats?group_by=make", nil)
+		req.Header.Set("X-CARS-ID", auth)
+		rr := httptest.NewRecorder()
+		server.New().ServeHTTP(rr, req)
+		if rr.Code != 200 {
+			t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+		}
+		return rr.Body.String(), rr.Header()
+	}
+
+	body, _ := get("1234")
+	expected := `{"group_by":["make"],"metrics":["count"],"groups":[{"make":"Honda","count":1},{"make":"Toyota","count":2}]}`
+	if body != expected {
+		t.Errorf("Expected only the default tenant's cars %s, got %s", expected, body)
+	}
+
+	// Same query, different tenant: not the cached default tenant stats
+	body, header := get("5678")
+	expected = `{"group_by":["make"],"metrics":["count"],"groups":[{"make":"Toyota","count":1}]}`
+	if header.Get("X-Cache") != "MISS" || body != expected {
+		t.Errorf("Expected acme's own stats %s, got %s %v", expected, body, header)
+	}
+}
+
+func TestCarStatsValidation(t *testing.T) {
+	for _, query := range []string{"group_by=tenant", "metrics=sum", "group_by=make&year=new"} {
+		req, _ := http.NewRequest("GET", "/v1/cars/stats?"+query, nil)
+		rr := serveCars(req)
+
+		if rr.Code != 400 {
+			t.Errorf("%s: expected: %d, but got: %d", query, 400, rr.Code)
+		}
+		if err := openapi.ValidateSchema("JsonError", rr.Body.Bytes()); err != nil {
+			t.Errorf("%s: response doesn't match JsonError schema: %s", query, err)
+		}
+	}
+}
+
+func TestCarStatsEncoding(t *testing.T) {
+	stats := handlers.CarStatsResponse{
+		GroupBy: []string{"year"},
+		Metrics: []string{"count"},
+		Groups: []handlers.CarStatsGroup{
+			{Columns: []string{"year", "count"}, Values: []interface{}{nil, int64(2)}},
+			{Columns: []string{"year", "count"}, Values: []interface{}{int64(2015), int64(1)}},
+		},
+	}
+
+	data, _ := json.Marshal(stats)
+	if string(data) != `{"group_by":["year"],"metrics":["count"],"groups":[{"year":null,"count":2},{"year":2015,"count":1}]}` {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+
+	var buf bytes.Buffer
+	if err := codec.XML.Encode(&buf, stats); err != nil {
+		t.Fatalf("Couldn't encode %s", err)
+	}
+	expected := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<stats><group><count>2</count></group><group><year>2015</year><count>1</count></group></stats>`
+	if buf.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := codec.CSV.Encode(&buf, stats); err != nil {
+		t.Fatalf("Couldn't encode %s", err)
+	}
+	if buf.String() != "year,count\n,2\n2015,1\n" {
+		t.Errorf("Unexpected CSV %q", buf.String())
+	}
+}
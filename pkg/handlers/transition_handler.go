@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/lifecycle"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/gorilla/mux"
+	"github.com/satori/go.uuid"
+)
+
+const (
+	// Who transitions are recorded as when X-CARS-ACTOR isn't sent
+	DefaultActor   = "api"
+	MaxActorLength = 128
+)
+
+type TransitionList struct {
+	XMLName     xml.Name               `json:"-" xml:"transitions"`
+	CarId       string                 `json:"car_id" xml:"car_id,attr"`
+	Status      string                 `json:"status" xml:"status,attr"`
+	Transitions []models.CarTransition `json:"transitions" xml:"transition"`
+	// What the car's current state allows next
+	Allowed []lifecycle.Next `json:"allowed" xml:"allowed"`
+}
+
+// The JsonError for a transition the car's state doesn't allow, with the
+// transitions it does
+type TransitionConflict struct {
+	logging.JsonError
+	From    lifecycle.State  `json:"from"`
+	Allowed []lifecycle.Next `json:"allowed"`
+}
+
+func CarTransitionHandler(w http.ResponseWriter, r *http.Request) {
+	serveMethod(w, r, "POST", TransitionCar)
+}
+
+func CarTransitionsHandler(w http.ResponseWriter, r *http.Request) {
+	serveMethod(w, r, "GET", ListTransitions)
+}
+
+// Fire the lifecycle event in the path on the car in the path
+func TransitionCar(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("TransitionCar: Processing Transition Car endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	vars := mux.Vars(r)
+	carId := vars["id"]
+	if _, err := uuid.FromString(carId); err != nil {
+		return 400, errors.New("Need a valid car Id to transition...")
+	}
+
+	event := strings.ToLower(vars["event"])
+	if !contains(lifecycle.Events(), event) {
+		return 404, fmt.Errorf("Unknown event %q, events must be some of %s", vars["event"], strings.Join(lifecycle.Events(), ", "))
+	}
+	// A hold needs a holder and an expiry
+	if event == "reserve" {
+		return 422, errors.New("Cars are reserved through their reservations endpoint")
+	}
+
+	actor, err := ParseActor(r.Header.Get("X-CARS-ACTOR"))
+	if err != nil {
+		return 400, err
+	}
+
+	// get db conn
+	log.Debug("TransitionCar: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	transition, err := models.TransitionCar(&db, tenant, carId, event, actor, CredentialFingerprint(r.Header.Get("X-CARS-ID")))
+	if _, ok := err.(*lifecycle.TransitionError); ok {
+		return 409, err
+	}
+	if err == models.ErrCarNotFound {
+		return 404, err
+	}
+	if err != nil {
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, transition)
+}
+
+// A car's transitions, oldest first, and what it can do next
+func ListTransitions(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("ListTransitions: Processing List Transitions endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	carId := mux.Vars(r)["id"]
+	if _, err := uuid.FromString(carId); err != nil {
+		return 400, errors.New("Need a valid car Id to list transitions...")
+	}
+
+	// get db conn
+	log.Debug("ListTransitions: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	car, err := models.GetCarColumns(&db, tenant, carId, []string{"status"})
+	if err == models.ErrCarNotFound {
+		return 404, err
+	}
+	if err != nil {
+		return 500, err
+	}
+
+	transitions, err := models.ListTransitions(&db, tenant, carId)
+	if err != nil {
+		return 500, err
+	}
+	return WriteEncoded(w, responseCodec, TransitionList{
+		CarId:       carId,
+		Status:      car.Status,
+		Transitions: transitions,
+		Allowed:     lifecycle.Allowed(lifecycle.State(car.Status)),
+	})
+}
+
+// The actor a transition is recorded as, from X-CARS-ACTOR. It's only a
+// claim, so transitions also record the credential's fingerprint.
+func ParseActor(header string) (string, error) {
+	actor := strings.TrimSpace(header)
+	if actor == "" {
+		return DefaultActor, nil
+	}
+	if utf8.RuneCountInString(actor) > MaxActorLength {
+		return "", fmt.Errorf("X-CARS-ACTOR must be at most %d characters", MaxActorLength)
+	}
+	return actor, nil
+}
+
+// Like logging.FormatError, with the transitions the car does allow
+func writeTransitionConflict(w http.ResponseWriter, r *http.Request, statusCode int, err *lifecycle.TransitionError) {
+	conflict := TransitionConflict{
+		JsonError: logging.JsonError{
+			Status:  strconv.Itoa(statusCode),
+			Code:    strconv.Itoa(statusCode),
+			Message: err.Error(),
+		},
+		From:    err.From,
+		Allowed: err.Allowed,
+	}
+
+	output, marshalErr := json.Marshal(conflict)
+	if marshalErr != nil {
+		logging.GetLog(r.Context()).WithError(marshalErr).Error("Unable to marshal error: ", marshalErr)
+		return
+	}
+	http.Error(w, string(output), statusCode)
+}
@@ -0,0 +1,151 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/harness"
+	"github.com/ericmcbride/go-dfw-testing/pkg/lifecycle"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
+)
+
+func fire(carId string, event string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/v1/cars/"+carId+"/transitions/"+event, nil)
+	req.Header.Set("X-CARS-ACTOR", "sales@example.com")
+	return serveCars(req)
+}
+
+func TestTransitionCar(t *testing.T) {
+	car := saveNegotiationCar(t)
+	defer harness.Truncate()
+	if car.Status != string(lifecycle.Draft) {
+		t.Errorf("Expected new cars to be drafts, got %q", car.Status)
+	}
+
+	// Listing needs a location
+	rr := fire(car.Id, "list")
+	if rr.Code != 409 {
+		t.Fatalf("Expected: %d, but got: %d %s", 409, rr.Code, rr.Body.String())
+	}
+	dealership := postDealership(t, "Downtown Motors")
+	move(car.Id, postLocation(t, dealership.Id, "North Lot").Id)
+
+	rr = fire(car.Id, "list")
+	if err := openapi.ValidateSchema("CarTransition", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match CarTransition schema: %s %s", err, rr.Body.String())
+	}
+	var listed models.CarTransition
+	json.Unmarshal(rr.Body.Bytes(), &listed)
+	if listed.From != lifecycle.Draft || listed.To != lifecycle.Listed || listed.Actor != "sales@example.com" {
+		t.Errorf("Expected draft to listed by the actor, got %+v", listed)
+	}
+	// The claimed actor comes with the credential that made the call, which
+	// isn't the credential itself
+	if listed.Credential != handlers.CredentialFingerprint("1234") || strings.Contains(listed.Credential, "1234") {
+		t.Errorf("Expected the credential's fingerprint, got %q", listed.Credential)
+	}
+
+	// Selling needs an owner
+	rr = fire(car.Id, "sell")
+	if rr.Code != 409 {
+		t.Errorf("Expected: %d, but got: %d", 409, rr.Code)
+	}
+	transfer(car.Id, postOwner(t, "Buyer").Id, "")
+	if rr = fire(car.Id, "sell"); rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+
+	req, _ := http.NewRequest("GET", "/v1/cars?status=sold", nil)
+	rr = serveCars(req)
+	var list handlers.CarList
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	if len(list.Cars) != 1 || list.Cars[0].Status != string(lifecycle.Sold) {
+		t.Errorf("Expected the sold car, got %s", rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/cars/"+car.Id+"/transitions", nil)
+	rr = serveCars(req)
+	if err := openapi.ValidateSchema("TransitionList", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match TransitionList schema: %s", err)
+	}
+	var history handlers.TransitionList
+	json.Unmarshal(rr.Body.Bytes(), &history)
+	if history.Status != "sold" || len(history.Transitions) != 2 || history.Transitions[1].Event != "sell" {
+		t.Errorf("Expected list then sell, got %s", rr.Body.String())
+	}
+	if len(history.Allowed) != 1 || history.Allowed[0].To != lifecycle.Retired {
+		t.Errorf("Expected a sold car can only be retired, got %v", history.Allowed)
+	}
+}
+
+func TestTransitionCarIllegal(t *testing.T) {
+	car := saveNegotiationCar(t)
+	defer harness.Truncate()
+
+	rr := fire(car.Id, "release")
+	if rr.Code != 409 {
+		t.Fatalf("Expected: %d, but got: %d", 409, rr.Code)
+	}
+	if err := openapi.ValidateSchema("TransitionConflict", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match TransitionConflict schema: %s %s", err, rr.Body.String())
+	}
+	var conflict handlers.TransitionConflict
+	json.Unmarshal(rr.Body.Bytes(), &conflict)
+	if conflict.From != lifecycle.Draft || len(conflict.Allowed) != 2 {
+		t.Errorf("Expected the transitions a draft allows, got %s", rr.Body.String())
+	}
+
+	if rr = fire(car.Id, "scrap"); rr.Code != 404 {
+		t.Errorf("Expected: %d, but got: %d", 404, rr.Code)
+	}
+	if rr = fire("00000000-0000-4000-8000-000000000000", "retire"); rr.Code != 404 {
+		t.Errorf("Expected: %d, but got: %d", 404, rr.Code)
+	}
+
+	// Nothing was recorded
+	req, _ := http.NewRequest("GET", "/v1/cars/"+car.Id+"/transitions", nil)
+	rr = serveCars(req)
+	if !strings.Contains(rr.Body.String(), `"transitions":[]`) {
+		t.Errorf("Expected no transitions, got %s", rr.Body.String())
+	}
+}
+
+func TestTransitionsOtherTenant(t *testing.T) {
+	car := otherTenantCar(t)
+	defer harness.Truncate()
+
+	req, _ := http.NewRequest("GET", "/v1/cars/"+car.Id+"/transitions", nil)
+	if rr := serveCars(req); rr.Code != 404 {
+		t.Errorf("Expected another tenant's transitions to be a %d, got %d %s", 404, rr.Code, rr.Body.String())
+	}
+	if rr := fire(car.Id, "retire"); rr.Code != 404 {
+		t.Errorf("Expected transitioning another tenant's car to be a %d, got %d", 404, rr.Code)
+	}
+}
+
+func TestCredentialFingerprint(t *testing.T) {
+	fingerprint := handlers.CredentialFingerprint("1234")
+	if !strings.HasPrefix(fingerprint, "sha256:") || fingerprint != handlers.CredentialFingerprint("1234") {
+		t.Errorf("Expected a stable sha256 fingerprint, got %q", fingerprint)
+	}
+	if fingerprint == handlers.CredentialFingerprint("5678") {
+		t.Errorf("Expected different credentials to have different fingerprints")
+	}
+}
+
+func TestParseActor(t *testing.T) {
+	if actor, err := handlers.ParseActor("  "); err != nil || actor != handlers.DefaultActor {
+		t.Errorf("Expected the default actor, got %q %v", actor, err)
+	}
+	if actor, err := handlers.ParseActor(" jo@example.com "); err != nil || actor != "jo@example.com" {
+		t.Errorf("Expected a trimmed actor, got %q %v", actor, err)
+	}
+	if _, err := handlers.ParseActor(strings.Repeat("a", handlers.MaxActorLength+1)); err == nil {
+		t.Errorf("Expected a long actor to be rejected")
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/catalog"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/vin"
+	"github.com/gorilla/mux"
+)
+
+func VinHandler(w http.ResponseWriter, r *http.Request) {
+	serveMethod(w, r, "GET", DecodeVin)
+}
+
+func DecodeVin(w http.ResponseWriter, r *http.Request) (int, error) {
+	log := logging.GetLog(r.Context())
+	log.Info("DecodeVin: Processing Decode VIN endpoint...")
+
+	responseCodec, statusCode, err := ResponseCodec(r, false)
+	if err != nil {
+		return statusCode, err
+	}
+
+	decoded, err := vin.Default.Decode(mux.Vars(r)["vin"])
+	if err != nil {
+		return 400, err
+	}
+	return WriteEncoded(w, responseCodec, decoded)
+}
+
+// Fill in the make, model and year the payload's VIN decodes to. Values the
+// caller sent are kept, and any that disagree with the VIN are returned as
+// warnings. A malformed VIN is an error.
+func ApplyVin(payload *CarPostPayload) ([]string, error) {
+	if strings.TrimSpace(payload.Vin) == "" {
+		return nil, nil
+	}
+
+	decoded, err := vin.Default.Decode(payload.Vin)
+	if err != nil {
+		return nil, err
+	}
+	payload.Vin = decoded.VIN
+
+	var warnings []string
+	if decoded.Make != "" {
+		if strings.TrimSpace(payload.Make) == "" {
+			payload.Make = decoded.Make
+		} else if !sameCatalogName(payload.Make, "", decoded.Make, "") {
+			warnings = append(warnings, fmt.Sprintf("Make %q doesn't match the VIN's %s", payload.Make, decoded.Make))
+		}
+	}
+	if decoded.Model != "" {
+		if strings.TrimSpace(payload.Model) == "" {
+			payload.Model = decoded.Model
+		} else if !sameCatalogName(decoded.Make, payload.Model, decoded.Make, decoded.Model) {
+			warnings = append(warnings, fmt.Sprintf("Model %q doesn't match the VIN's %s", payload.Model, decoded.Model))
+		}
+	}
+	if payload.Year == 0 {
+		payload.Year = decoded.Year
+	} else if payload.Year != decoded.Year {
+		warnings = append(warnings, fmt.Sprintf("Year %d doesn't match the VIN's %d", payload.Year, decoded.Year))
+	}
+	return warnings, nil
+}
+
+// Whether two makes, or models when they're given, are the same once
+// aliases, case and punctuation are set aside
+func sameCatalogName(makeA, modelA, makeB, modelB string) bool {
+	makeA, modelA, _ = catalog.Default.Resolve(makeA, modelA)
+	makeB, modelB, _ = catalog.Default.Resolve(makeB, modelB)
+	if modelA != "" || modelB != "" {
+		return catalog.Key(modelA) == catalog.Key(modelB)
+	}
+	return catalog.Key(makeA) == catalog.Key(makeB)
+}
@@ -0,0 +1,117 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/harness"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
+	"github.com/ericmcbride/go-dfw-testing/pkg/vin"
+)
+
+func TestDecodeVin(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/v1/vins/5yj3e1ea2kf317000", nil)
+	rr := serveCars(req)
+
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+	if err := openapi.ValidateSchema("VinDecoding", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match VinDecoding schema: %s", err)
+	}
+
+	var got vin.Decoded
+	json.Unmarshal(rr.Body.Bytes(), &got)
+	if got.VIN != "5YJ3E1EA2KF317000" || got.Make != "Tesla" || got.Model != "Model 3" || got.Year != 2019 || got.Plant != "Fremont, California" {
+		t.Errorf("Expected a 2019 Model 3 from Fremont, got %s", rr.Body.String())
+	}
+}
+
+func TestDecodeVinInvalid(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/v1/vins/5YJ3E1EA3KF317000", nil)
+	rr := serveCars(req)
+
+	if rr.Code != 400 {
+		t.Errorf("Expected: %d, but got: %d", 400, rr.Code)
+	}
+	if err := openapi.ValidateSchema("JsonError", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match JsonError schema: %s", err)
+	}
+}
+
+func TestCheckCarPayloadVinConflicts(t *testing.T) {
+	payload := handlers.CarPostPayload{Make: "chevy", Model: "Silverado", Year: 2021, Vin: "1FTEW1EP7MFA00001"}
+	warnings, err := handlers.CheckCarPayload(&payload)
+	if err != nil {
+		t.Fatalf("Expected conflicts to be warnings, got %s", err)
+	}
+
+	expected := []string{
+		`Make "chevy" doesn't match the VIN's Ford`,
+		`Model "Silverado" doesn't match the VIN's F-150`,
+	}
+	if !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Expected %q, got %q", expected, warnings)
+	}
+	if payload.Make != "Chevrolet" || payload.Model != "Silverado 1500" || payload.Year != 2021 {
+		t.Errorf("Expected the values sent to be kept, got %+v", payload)
+	}
+
+	// Aliases and case aren't conflicts
+	payload = handlers.CarPostPayload{Make: "TESLA", Model: "model 3", Color: "Red", Vin: "5YJ3E1EA2KF317000"}
+	if warnings, err := handlers.CheckCarPayload(&payload); err != nil || len(warnings) != 0 {
+		t.Errorf("Expected no warnings, got %q %v", warnings, err)
+	}
+
+	payload = handlers.CarPostPayload{Color: "Red", Vin: "5YJ3E1EA3KF317000"}
+	if _, err := handlers.CheckCarPayload(&payload); err == nil || err.Error() != "VIN check digit is 3 but should be 2" {
+		t.Errorf("Expected a check digit error, got %v", err)
+	}
+}
+
+func TestCarPostHandlerVinOnly(t *testing.T) {
+	defer harness.Truncate()
+
+	payload := []byte(`{"vin": "2hgfc2f54jh500001"}`)
+	req, _ := http.NewRequest("POST", "/v1/cars", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	rr := serveCars(req)
+
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get("Warning") != "" {
+		t.Errorf("Expected no warning, got %s", rr.Header().Get("Warning"))
+	}
+
+	var got models.CarModel
+	json.Unmarshal(rr.Body.Bytes(), &got)
+	if got.Make != "Honda" || got.Model != "Civic" || got.Year != 2018 || got.Color != "" {
+		t.Errorf("Expected a 2018 Civic from the VIN, got %+v", got)
+	}
+}
+
+func TestCarPostHandlerVinWarnings(t *testing.T) {
+	defer harness.Truncate()
+
+	payload := []byte(`{"vin": "2HGFC2F54JH500001", "make": "Honda", "model": "Accord", "color": "Blue", "year": 2017}`)
+	req, _ := http.NewRequest("POST", "/v1/cars", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	rr := serveCars(req)
+
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+	expected := []string{
+		`199 - "Model \"Accord\" doesn't match the VIN's Civic"`,
+		`199 - "Year 2017 doesn't match the VIN's 2018"`,
+	}
+	if !reflect.DeepEqual(rr.Header()["Warning"], expected) {
+		t.Errorf("Expected warnings %q, got %q", expected, rr.Header()["Warning"])
+	}
+}
@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/webhooks"
+	"github.com/satori/go.uuid"
+)
+
+// Car event types a webhook can subscribe to
+var WebhookEventTypes = []string{"created", "updated", "deleted", "reservation_expired"}
+
+const (
+	DefaultDeliveryLimit = 50
+	MaxDeliveryLimit     = 500
+)
+
+type WebhookPostPayload struct {
+	Url    string   `json:"url"`
+	Events []string `json:"events"`
+	// Generated when left out
+	Secret string `json:"secret"`
+}
+
+type WebhookList struct {
+	Webhooks []models.Webhook `json:"webhooks"`
+}
+
+type DeliveryList struct {
+	Deliveries []models.WebhookDelivery `json:"deliveries"`
+}
+
+type ReplayResponse struct {
+	Replayed int64 `json:"replayed"`
+}
+
+func WebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	if !authorize(w, r) {
+		return
+	}
+
+	var (
+		statusCode int
+		err        error
+	)
+
+	switch r.Method {
+	case "POST":
+		statusCode, err = PostWebhook(w, r)
+	case "DELETE":
+		statusCode, err = DeleteWebhook(w, r)
+	case "GET":
+		if _, ok := r.URL.Query()["webhook_id"]; ok {
+			statusCode, err = GetWebhook(w, r)
+		} else {
+			statusCode, err = ListWebhooks(w, r)
+		}
+	default:
+		http.Error(w, "Invalid Request Method.", 405)
+		return
+	}
+
+	writeHandlerError(w, r, statusCode, err)
+}
+
+func WebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	serveMethod(w, r, "GET", ListDeliveries)
+}
+
+func WebhookReplayHandler(w http.ResponseWriter, r *http.Request) {
+	serveMethod(w, r, "POST", ReplayDeliveries)
+}
+
+func PostWebhook(w http.ResponseWriter, r *http.Request) (int, error) {
+	var postPayload WebhookPostPayload
+
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("PostWebhook: Processing Add Webhook endpoint...")
+
+	log.Debug("PostWebhook: Decoding request body...")
+	statusCode, err := DecodeBody(r, &postPayload)
+	if err != nil {
+		return statusCode, err
+	}
+
+	log.Debug("PostWebhook: validating payload...")
+	err = ValidateWebhookPayload(&postPayload)
+	if err != nil {
+		return 422, err
+	}
+
+	if postPayload.Secret == "" {
+		postPayload.Secret, err = webhooks.NewSecret()
+		if err != nil {
+			return 500, err
+		}
+	}
+
+	// get db conn
+	log.Debug("PostWebhook: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	webhook := &models.Webhook{
+		Id:     uuid.NewV4().String(),
+		Url:    postPayload.Url,
+		Secret: postPayload.Secret,
+		Events: postPayload.Events,
+		Tenant: tenant,
+	}
+
+	log.Debug("PostWebhook: Saving Webhook")
+	err = models.SaveWebhook(&db, webhook)
+	if err != nil {
+		return 500, err
+	}
+
+	// The secret is only ever returned here
+	return writeWebhookJson(w, webhook)
+}
+
+func GetWebhook(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("GetWebhook: Processing Get Webhook endpoint...")
+
+	webhookId := r.URL.Query().Get("webhook_id")
+	if webhookId == "" {
+		return 400, errors.New("Need a webhook Id to GET...")
+	}
+
+	// get db conn
+	log.Debug("GetWebhook: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	webhook, status, err := findWebhook(&db, r, webhookId)
+	if err != nil {
+		return status, err
+	}
+	return writeWebhookJson(w, webhook)
+}
+
+func ListWebhooks(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("ListWebhooks: Processing List Webhooks endpoint...")
+
+	// get db conn
+	log.Debug("ListWebhooks: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	hooks, err := models.ListWebhooks(&db, tenant)
+	if err != nil {
+		return 500, err
+	}
+	return writeWebhookJson(w, WebhookList{Webhooks: hooks})
+}
+
+func DeleteWebhook(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("DeleteWebhook: Processing Delete Webhook endpoint...")
+
+	webhookId := r.URL.Query().Get("webhook_id")
+	if _, err := uuid.FromString(webhookId); err != nil {
+		return 400, errors.New("Need a valid webhook Id to delete...")
+	}
+
+	// get db conn
+	log.Debug("DeleteWebhook: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	err = models.DeleteWebhook(&db, tenant, webhookId)
+	if err == models.ErrWebhookNotFound {
+		return 404, err
+	}
+	if err != nil {
+		return 500, err
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	return 200, nil
+}
+
+func ListDeliveries(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("ListDeliveries: Processing List Deliveries endpoint...")
+
+	query := r.URL.Query()
+	status := query.Get("status")
+	if status != "" && status != models.DeliveryPending && status != models.DeliveryDelivered && status != models.DeliveryDead {
+		return 400, fmt.Errorf("status must be %s, %s or %s", models.DeliveryPending, models.DeliveryDelivered, models.DeliveryDead)
+	}
+
+	limit, err := queryInt(query, "limit", DefaultDeliveryLimit)
+	if err != nil {
+		return 400, err
+	}
+	if limit < 1 || limit > MaxDeliveryLimit {
+		return 400, fmt.Errorf("limit must be between 1 and %d", MaxDeliveryLimit)
+	}
+
+	// get db conn
+	log.Debug("ListDeliveries: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	webhook, statusCode, err := findWebhook(&db, r, query.Get("webhook_id"))
+	if err != nil {
+		return statusCode, err
+	}
+
+	deliveries, err := models.ListDeliveries(&db, webhook.Id, status, limit)
+	if err != nil {
+		return 500, err
+	}
+	return writeWebhookJson(w, DeliveryList{Deliveries: deliveries})
+}
+
+// Put dead deliveries back in the queue, either all of a webhook's or the one
+// named by delivery_id.
+func ReplayDeliveries(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("ReplayDeliveries: Processing Replay Deliveries endpoint...")
+
+	query := r.URL.Query()
+	var deliveryId int64
+	if value := query.Get("delivery_id"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 1 {
+			return 400, errors.New("delivery_id must be a positive whole number")
+		}
+		deliveryId = parsed
+	}
+
+	// get db conn
+	log.Debug("ReplayDeliveries: Getting Database Connection...")
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return 500, err
+	}
+	defer clients.Close(&db)
+
+	webhook, statusCode, err := findWebhook(&db, r, query.Get("webhook_id"))
+	if err != nil {
+		return statusCode, err
+	}
+
+	replayed, err := models.ReplayDeliveries(&db, webhook.Id, deliveryId)
+	if err != nil {
+		return 500, err
+	}
+	return writeWebhookJson(w, ReplayResponse{Replayed: replayed})
+}
+
+// Look up one of the caller's webhooks, with the status code to fail with
+func findWebhook(db *clients.DBClient, r *http.Request, webhookId string) (models.Webhook, int, error) {
+	if _, err := uuid.FromString(webhookId); err != nil {
+		return models.Webhook{}, 400, errors.New("webhook_id must be a valid webhook Id")
+	}
+
+	tenant, _ := TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	webhook, err := models.GetWebhook(db, tenant, webhookId)
+	if err == models.ErrWebhookNotFound {
+		return webhook, 404, err
+	}
+	if err != nil {
+		return webhook, 500, err
+	}
+	return webhook, 200, nil
+}
+
+func writeWebhookJson(w http.ResponseWriter, body interface{}) (int, error) {
+	bodyJson, err := json.Marshal(body)
+	if err != nil {
+		return 500, err
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(bodyJson)
+	return 200, nil
+}
+
+func ValidateWebhookPayload(payload *WebhookPostPayload) error {
+	if err := webhooks.CheckTarget(payload.Url); err != nil {
+		return err
+	}
+
+	for _, event := range payload.Events {
+		known := false
+		for _, eventType := range WebhookEventTypes {
+			if event == eventType {
+				known = true
+			}
+		}
+		if !known {
+			return fmt.Errorf("Unknown event type %q", event)
+		}
+	}
+
+	if payload.Secret != "" && len(payload.Secret) < 16 {
+		return errors.New("Secret must be at least 16 characters")
+	}
+	return nil
+}
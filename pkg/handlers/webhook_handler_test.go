@@ -0,0 +1,258 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
+	"github.com/ericmcbride/go-dfw-testing/pkg/outbox"
+	"github.com/ericmcbride/go-dfw-testing/pkg/server"
+	"github.com/ericmcbride/go-dfw-testing/pkg/webhooks"
+)
+
+// Records deliveries and answers with status
+type testReceiver struct {
+	mu       sync.Mutex
+	status   int
+	bodies   [][]byte
+	verified bool
+}
+
+func (rec *testReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+	timestamp, _ := strconv.ParseInt(r.Header.Get(webhooks.TimestampHeader), 10, 64)
+	rec.verified = webhooks.Verify("0123456789abcdef", timestamp, body, r.Header.Get(webhooks.SignatureHeader))
+	rec.bodies = append(rec.bodies, body)
+	w.WriteHeader(rec.status)
+}
+
+func (rec *testReceiver) setStatus(status int) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.status = status
+}
+
+func (rec *testReceiver) received() ([][]byte, bool) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.bodies, rec.verified
+}
+
+func webhookRequest(t *testing.T, method string, path string, payload []byte) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(method, path, bytes.NewBuffer(payload))
+	if err != nil {
+		t.Errorf("Error while building request: %s", err)
+	}
+	req.Header.Set("X-CARS-ID", "1234")
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	server.New().ServeHTTP(rr, req)
+	return rr
+}
+
+func createWebhook(t *testing.T, url string, events string) models.Webhook {
+	payload := []byte(fmt.Sprintf(`{"url": "%s", "events": %s, "secret": "0123456789abcdef"}`, url, events))
+	rr := webhookRequest(t, "POST", "/v1/webhooks", payload)
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+	if err := openapi.ValidateSchema("Webhook", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match Webhook schema: %s", err)
+	}
+
+	var webhook models.Webhook
+	json.Unmarshal(rr.Body.Bytes(), &webhook)
+	return webhook
+}
+
+func listDeliveries(t *testing.T, webhookId string) []models.WebhookDelivery {
+	rr := webhookRequest(t, "GET", "/v1/webhooks/deliveries?webhook_id="+webhookId, nil)
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+	if err := openapi.ValidateSchema("DeliveryList", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match DeliveryList schema: %s", err)
+	}
+
+	var list struct {
+		Deliveries []models.WebhookDelivery `json:"deliveries"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	return list.Deliveries
+}
+
+// Move everything in the outbox into webhook deliveries
+func relayWebhooks(t *testing.T, db *clients.DBClient) {
+	if _, err := outbox.Relay(db, outbox.WebhookPublisher{Db: db}); err != nil {
+		t.Fatalf("Relay failed: %s", err)
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	db, err := clients.NewDbConn()
+	if err != nil {
+		t.Fatalf("Couldnt connect to db")
+	}
+	defer clients.Close(&db)
+
+	receiver := &testReceiver{status: 200}
+	srv := httptest.NewServer(receiver)
+	// The receiver listens on loopback
+	webhooks.AllowPrivateTargets = true
+	defer func() { webhooks.AllowPrivateTargets = false }()
+	defer srv.Close()
+
+	webhook := createWebhook(t, srv.URL, `["created"]`)
+	carId := saveTestCar(t, &db)
+	// Deletes aren't subscribed to
+	models.DeleteCar(&db, models.DefaultTenant, carId)
+	relayWebhooks(t, &db)
+
+	// Queueing the same event again is a no-op
+	queued := listDeliveries(t, webhook.Id)
+	if len(queued) != 1 {
+		t.Fatalf("Expected 1 queued delivery, got %d", len(queued))
+	}
+	count, err := models.EnqueueDeliveries(&db, models.DefaultTenant, queued[0].EventId, "created", queued[0].Payload)
+	if err != nil || count != 0 {
+		t.Errorf("Expected nothing to be queued twice, got %d %v", count, err)
+	}
+
+	if _, err := webhooks.Deliver(&db, srv.Client()); err != nil {
+		t.Fatalf("Deliver failed: %s", err)
+	}
+
+	bodies, verified := receiver.received()
+	if len(bodies) != 1 {
+		t.Fatalf("Expected 1 delivery, got %d", len(bodies))
+	}
+	if !verified {
+		t.Errorf("Delivery signature didn't verify")
+	}
+	if err := openapi.ValidateSchema("CarEvent", bodies[0]); err != nil {
+		t.Errorf("Delivery doesn't match CarEvent schema: %s", err)
+	}
+
+	deliveries := listDeliveries(t, webhook.Id)
+	if len(deliveries) != 1 || deliveries[0].Status != models.DeliveryDelivered || deliveries[0].Attempts != 1 {
+		t.Errorf("Expected one delivered delivery, got %+v", deliveries)
+	}
+}
+
+func TestWebhookDeadLetterAndReplay(t *testing.T) {
+	db, err := clients.NewDbConn()
+	if err != nil {
+		t.Fatalf("Couldnt connect to db")
+	}
+	defer clients.Close(&db)
+
+	maxAttempts, baseBackoff := webhooks.MaxAttempts, webhooks.BaseBackoff
+	webhooks.MaxAttempts, webhooks.BaseBackoff = 2, 0
+	defer func() { webhooks.MaxAttempts, webhooks.BaseBackoff = maxAttempts, baseBackoff }()
+
+	receiver := &testReceiver{status: 500}
+	srv := httptest.NewServer(receiver)
+	// The receiver listens on loopback
+	webhooks.AllowPrivateTargets = true
+	defer func() { webhooks.AllowPrivateTargets = false }()
+	defer srv.Close()
+
+	webhook := createWebhook(t, srv.URL, `[]`)
+	saveTestCar(t, &db)
+	relayWebhooks(t, &db)
+
+	for i := 0; i < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+		if _, err := webhooks.Deliver(&db, srv.Client()); err != nil {
+			t.Fatalf("Deliver failed: %s", err)
+		}
+	}
+
+	deliveries := listDeliveries(t, webhook.Id)
+	if len(deliveries) != 1 || deliveries[0].Status != models.DeliveryDead || deliveries[0].Attempts != 2 {
+		t.Fatalf("Expected one dead delivery, got %+v", deliveries)
+	}
+	if deliveries[0].LastError == "" {
+		t.Errorf("Expected the last error to be recorded")
+	}
+
+	rr := webhookRequest(t, "POST", "/v1/webhooks:replay?webhook_id="+webhook.Id, nil)
+	if rr.Code != 200 {
+		t.Fatalf("Expected: %d, but got: %d %s", 200, rr.Code, rr.Body.String())
+	}
+	if err := openapi.ValidateSchema("ReplayResponse", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match ReplayResponse schema: %s", err)
+	}
+	if rr.Body.String() != `{"replayed":1}` {
+		t.Errorf("Expected one replayed delivery, got %s", rr.Body.String())
+	}
+
+	receiver.setStatus(200)
+	if _, err := webhooks.Deliver(&db, srv.Client()); err != nil {
+		t.Fatalf("Deliver failed: %s", err)
+	}
+
+	deliveries = listDeliveries(t, webhook.Id)
+	if len(deliveries) != 1 || deliveries[0].Status != models.DeliveryDelivered {
+		t.Errorf("Expected the replayed delivery to be delivered, got %+v", deliveries)
+	}
+	if bodies, _ := receiver.received(); len(bodies) != 3 {
+		t.Errorf("Expected 3 attempts to reach the receiver, got %d", len(bodies))
+	}
+}
+
+func TestWebhookInvalidPayload(t *testing.T) {
+	payloads := []string{
+		`{"url": "not a url"}`,
+		`{"url": "ftp://example.com/hook"}`,
+		`{"url": "https://example.com/hook", "events": ["exploded"]}`,
+		`{"url": "https://example.com/hook", "secret": "short"}`,
+		`{"url": "http://localhost:8080/hook"}`,
+		`{"url": "http://127.0.0.1/hook"}`,
+		`{"url": "http://169.254.169.254/latest/meta-data/"}`,
+		`{"url": "http://10.1.2.3/hook"}`,
+		`{"url": "http://192.168.0.10/hook"}`,
+		`{"url": "http://[::1]:8080/hook"}`,
+	}
+	for _, payload := range payloads {
+		rr := webhookRequest(t, "POST", "/v1/webhooks", []byte(payload))
+		if rr.Code != 422 {
+			t.Errorf("%s: expected: %d, but got: %d", payload, 422, rr.Code)
+		}
+	}
+}
+
+func TestWebhookTooLarge(t *testing.T) {
+	large := `{"url": "https://example.com/hook", "events": ["car.created"], "secret": "` + strings.Repeat("a", int(handlers.MaxBodyBytes)) + `"}`
+	if rr := webhookRequest(t, "POST", "/v1/webhooks", []byte(large)); rr.Code != 413 {
+		t.Errorf("Expected: %d, but got: %d %s", 413, rr.Code, rr.Body.String())
+	}
+}
+
+func TestWebhookNotFound(t *testing.T) {
+	rr := webhookRequest(t, "GET", "/v1/webhooks?webhook_id=9b2e3f4a-1c2d-4e5f-8a9b-0c1d2e3f4a5b", nil)
+	if rr.Code != 404 {
+		t.Errorf("Expected: %d, but got: %d", 404, rr.Code)
+	}
+
+	rr = webhookRequest(t, "POST", "/v1/webhooks:replay?webhook_id=nope", nil)
+	if rr.Code != 400 {
+		t.Errorf("Expected: %d, but got: %d", 400, rr.Code)
+	}
+}
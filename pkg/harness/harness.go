@@ -33,13 +33,262 @@ func CreateDatabase() {
 	defer clients.Close(&client)
 
 	query := `
+	CREATE EXTENSION IF NOT EXISTS citext;
+
+	CREATE TABLE IF NOT EXISTS dealerships (
+		id uuid PRIMARY KEY,
+		tenant text NOT NULL DEFAULT 'default',
+		name citext NOT NULL CONSTRAINT dealerships_name_length CHECK (char_length(name) <= 128),
+		created_at timestamptz NOT NULL DEFAULT now()
+	);
+
+	CREATE TABLE IF NOT EXISTS locations (
+		id uuid PRIMARY KEY,
+		dealership_id uuid NOT NULL REFERENCES dealerships (id),
+		tenant text NOT NULL DEFAULT 'default',
+		name citext NOT NULL CONSTRAINT locations_name_length CHECK (char_length(name) <= 128),
+		address text NOT NULL DEFAULT '' CONSTRAINT locations_address_length CHECK (char_length(address) <= 256),
+		created_at timestamptz NOT NULL DEFAULT now()
+	);
+
+	CREATE INDEX IF NOT EXISTS locations_dealership ON locations (dealership_id);
+
 	CREATE TABLE IF NOT EXISTS cars (
 		id uuid PRIMARY KEY,
-		model character(128),
-		make character(128),
-		color character(128),
-		year integer
-	)`
+		model citext CONSTRAINT cars_model_length CHECK (char_length(model) <= 128),
+		make citext CONSTRAINT cars_make_length CHECK (char_length(make) <= 128),
+		color citext CONSTRAINT cars_color_length CHECK (char_length(color) <= 128),
+		year integer,
+		tenant text NOT NULL DEFAULT 'default',
+		location_id uuid REFERENCES locations (id),
+		status text NOT NULL DEFAULT 'draft' CONSTRAINT cars_status CHECK (
+			status IN ('draft', 'listed', 'reserved', 'sold', 'retired')
+		),
+		price_minor bigint CONSTRAINT cars_price CHECK (price_minor >= 0),
+		currency text CONSTRAINT cars_currency CHECK (currency ~ '^[A-Z]{3}$'),
+		CONSTRAINT cars_priced CHECK ((price_minor IS NULL) = (currency IS NULL)),
+		mileage_km bigint CONSTRAINT cars_mileage CHECK (mileage_km >= 0),
+		search tsvector GENERATED ALWAYS AS (to_tsvector('simple',
+			coalesce(make::text, '') || ' ' || coalesce(model::text, '') || ' ' || coalesce(color::text, '')
+		)) STORED
+	);
+
+	CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+	CREATE INDEX IF NOT EXISTS cars_search ON cars USING GIN (search);
+
+	CREATE INDEX IF NOT EXISTS cars_search_trigrams ON cars USING GIN (
+		(coalesce(make::text, '') || ' ' || coalesce(model::text, '') || ' ' || coalesce(color::text, '')) gin_trgm_ops
+	);
+
+	CREATE SEQUENCE IF NOT EXISTS car_event_ids;
+
+	CREATE TABLE IF NOT EXISTS outbox (
+		id bigserial PRIMARY KEY,
+		event_id bigint NOT NULL,
+		event_type text NOT NULL,
+		tenant text NOT NULL,
+		payload jsonb NOT NULL,
+		attempts integer NOT NULL DEFAULT 0,
+		last_error text NOT NULL DEFAULT '',
+		published_at timestamptz,
+		dead_at timestamptz,
+		created_at timestamptz NOT NULL DEFAULT now()
+	);
+
+	CREATE INDEX IF NOT EXISTS outbox_unpublished
+		ON outbox (id) WHERE published_at IS NULL AND dead_at IS NULL;
+
+	CREATE TABLE IF NOT EXISTS webhooks (
+		id uuid PRIMARY KEY,
+		tenant text NOT NULL,
+		url text NOT NULL,
+		secret text NOT NULL,
+		events text[] NOT NULL DEFAULT '{}',
+		created_at timestamptz NOT NULL DEFAULT now()
+	);
+
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id bigserial PRIMARY KEY,
+		webhook_id uuid NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+		event_id bigint NOT NULL,
+		event_type text NOT NULL,
+		payload jsonb NOT NULL,
+		status text NOT NULL DEFAULT 'pending',
+		attempts integer NOT NULL DEFAULT 0,
+		next_attempt_at timestamptz NOT NULL DEFAULT now(),
+		last_error text NOT NULL DEFAULT '',
+		delivered_at timestamptz,
+		created_at timestamptz NOT NULL DEFAULT now(),
+		UNIQUE (webhook_id, event_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS webhook_deliveries_due
+		ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+	CREATE TABLE IF NOT EXISTS owners (
+		id uuid PRIMARY KEY,
+		tenant text NOT NULL DEFAULT 'default',
+		name citext NOT NULL CONSTRAINT owners_name_length CHECK (char_length(name) <= 128),
+		email citext NOT NULL DEFAULT '' CONSTRAINT owners_email_length CHECK (char_length(email) <= 254),
+		phone text NOT NULL DEFAULT '' CONSTRAINT owners_phone_length CHECK (char_length(phone) <= 32),
+		created_at timestamptz NOT NULL DEFAULT now()
+	);
+
+	CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+	CREATE TABLE IF NOT EXISTS car_ownerships (
+		id bigserial PRIMARY KEY,
+		car_id uuid NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+		owner_id uuid NOT NULL REFERENCES owners (id),
+		tenant text NOT NULL,
+		started_on date NOT NULL,
+		ended_on date,
+		created_at timestamptz NOT NULL DEFAULT now(),
+		CONSTRAINT car_ownerships_dates CHECK (ended_on > started_on),
+		CONSTRAINT car_ownerships_overlap EXCLUDE USING gist (
+			car_id WITH =, daterange(started_on, ended_on) WITH &&
+		)
+	);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS car_ownerships_current
+		ON car_ownerships (car_id) WHERE ended_on IS NULL;
+
+	CREATE INDEX IF NOT EXISTS car_ownerships_owner ON car_ownerships (owner_id);
+
+	CREATE INDEX IF NOT EXISTS cars_location ON cars (location_id);
+	CREATE INDEX IF NOT EXISTS cars_price ON cars (currency, price_minor);
+	CREATE INDEX IF NOT EXISTS cars_mileage ON cars (mileage_km);
+
+	CREATE TABLE IF NOT EXISTS car_moves (
+		id bigserial PRIMARY KEY,
+		car_id uuid NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+		tenant text NOT NULL,
+		from_location_id uuid REFERENCES locations (id) ON DELETE SET NULL,
+		to_location_id uuid REFERENCES locations (id) ON DELETE SET NULL,
+		note text NOT NULL DEFAULT '' CONSTRAINT car_moves_note_length CHECK (char_length(note) <= 512),
+		moved_at timestamptz NOT NULL DEFAULT now()
+	);
+
+	CREATE INDEX IF NOT EXISTS car_moves_car ON car_moves (car_id, moved_at);
+
+	CREATE TABLE IF NOT EXISTS car_transitions (
+		id bigserial PRIMARY KEY,
+		car_id uuid NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+		tenant text NOT NULL,
+		event text NOT NULL,
+		from_status text NOT NULL,
+		to_status text NOT NULL,
+		actor text NOT NULL CONSTRAINT car_transitions_actor_length CHECK (char_length(actor) <= 128),
+		created_at timestamptz NOT NULL DEFAULT now()
+	);
+
+	CREATE INDEX IF NOT EXISTS car_transitions_car ON car_transitions (car_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS reservations (
+		id uuid PRIMARY KEY,
+		car_id uuid NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+		tenant text NOT NULL,
+		holder_name text NOT NULL CONSTRAINT reservations_holder_name_length CHECK (char_length(holder_name) <= 128),
+		holder_email text NOT NULL DEFAULT '' CONSTRAINT reservations_holder_email_length CHECK (char_length(holder_email) <= 254),
+		holder_phone text NOT NULL DEFAULT '' CONSTRAINT reservations_holder_phone_length CHECK (char_length(holder_phone) <= 32),
+		deposit_cents bigint NOT NULL DEFAULT 0 CONSTRAINT reservations_deposit CHECK (deposit_cents >= 0),
+		status text NOT NULL DEFAULT 'active'
+			CONSTRAINT reservations_status CHECK (status IN ('active', 'released', 'expired', 'fulfilled')),
+		expires_at timestamptz NOT NULL,
+		ended_at timestamptz,
+		created_at timestamptz NOT NULL DEFAULT now()
+	);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS reservations_one_active ON reservations (car_id) WHERE status = 'active';
+	CREATE INDEX IF NOT EXISTS reservations_car ON reservations (car_id, created_at);
+	CREATE INDEX IF NOT EXISTS reservations_expiry ON reservations (expires_at) WHERE status = 'active';
+
+	CREATE TABLE IF NOT EXISTS price_history (
+		id bigserial PRIMARY KEY,
+		car_id uuid NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+		tenant text NOT NULL,
+		price_minor bigint NOT NULL CONSTRAINT price_history_price CHECK (price_minor >= 0),
+		currency text NOT NULL CONSTRAINT price_history_currency CHECK (currency ~ '^[A-Z]{3}$'),
+		actor text NOT NULL CONSTRAINT price_history_actor_length CHECK (char_length(actor) <= 128),
+		reason text NOT NULL DEFAULT '' CONSTRAINT price_history_reason_length CHECK (char_length(reason) <= 512),
+		changed_at timestamptz NOT NULL DEFAULT now()
+	);
+
+	CREATE INDEX IF NOT EXISTS price_history_car ON price_history (car_id, changed_at);
+
+	CREATE TABLE IF NOT EXISTS odometer_readings (
+		id bigserial PRIMARY KEY,
+		car_id uuid NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+		tenant text NOT NULL,
+		value bigint NOT NULL CONSTRAINT odometer_readings_value CHECK (value >= 0),
+		unit text NOT NULL CONSTRAINT odometer_readings_unit CHECK (unit IN ('miles', 'km')),
+		km bigint NOT NULL CONSTRAINT odometer_readings_km CHECK (km >= 0),
+		read_on date NOT NULL,
+		source text NOT NULL CONSTRAINT odometer_readings_source_length CHECK (char_length(source) <= 128),
+		suspected_rollback boolean NOT NULL DEFAULT false,
+		created_at timestamptz NOT NULL DEFAULT now()
+	);
+
+	CREATE INDEX IF NOT EXISTS odometer_readings_car ON odometer_readings (car_id, read_on);
+
+	CREATE TABLE IF NOT EXISTS service_records (
+		id bigserial PRIMARY KEY,
+		car_id uuid NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+		tenant text NOT NULL,
+		type text NOT NULL CONSTRAINT service_records_type CHECK (
+			type IN ('oil_change', 'tire_rotation', 'brakes', 'inspection', 'repair', 'other')
+		),
+		serviced_on date NOT NULL,
+		mileage_km bigint CONSTRAINT service_records_mileage CHECK (mileage_km >= 0),
+		cost_minor bigint CONSTRAINT service_records_cost CHECK (cost_minor >= 0),
+		currency text CONSTRAINT service_records_currency CHECK (currency ~ '^[A-Z]{3}$'),
+		CONSTRAINT service_records_costed CHECK ((cost_minor IS NULL) = (currency IS NULL)),
+		vendor text NOT NULL DEFAULT '' CONSTRAINT service_records_vendor_length CHECK (char_length(vendor) <= 128),
+		notes text NOT NULL DEFAULT '' CONSTRAINT service_records_notes_length CHECK (char_length(notes) <= 2000),
+		created_at timestamptz NOT NULL DEFAULT now()
+	);
+
+	CREATE INDEX IF NOT EXISTS service_records_car ON service_records (car_id, type, serviced_on);
+
+	CREATE OR REPLACE FUNCTION notify_car_change() RETURNS trigger AS $$
+	DECLARE
+		car record;
+		event_type text;
+		event_id bigint;
+		payload jsonb;
+	BEGIN
+		IF TG_OP = 'DELETE' THEN
+			car := OLD;
+			event_type := 'deleted';
+		ELSIF TG_OP = 'UPDATE' THEN
+			car := NEW;
+			event_type := 'updated';
+		ELSE
+			car := NEW;
+			event_type := 'created';
+		END IF;
+
+		event_id := nextval('car_event_ids');
+		payload := jsonb_build_object(
+			'id', event_id,
+			'type', event_type,
+			'tenant', car.tenant,
+			'car', to_jsonb(car) - 'tenant' - 'search'
+		);
+
+		INSERT INTO outbox (event_id, event_type, tenant, payload)
+		VALUES (event_id, event_type, car.tenant, payload);
+
+		PERFORM pg_notify('car_changes', payload::text);
+		RETURN NULL;
+	END;
+	$$ LANGUAGE plpgsql;
+
+	DROP TRIGGER IF EXISTS cars_notify ON cars;
+	CREATE TRIGGER cars_notify
+		AFTER INSERT OR UPDATE OR DELETE ON cars
+		FOR EACH ROW EXECUTE PROCEDURE notify_car_change();`
 
 	_, err = client.Db.Exec(query)
 	if err != nil {
@@ -55,7 +304,23 @@ func DropDatabase() {
 
 	defer clients.Close(&client)
 
-	query := `DROP TABLE IF EXISTS cars;`
+	query := `
+	DROP TABLE IF EXISTS service_records;
+	DROP TABLE IF EXISTS odometer_readings;
+	DROP TABLE IF EXISTS price_history;
+	DROP TABLE IF EXISTS reservations;
+	DROP TABLE IF EXISTS car_transitions;
+	DROP TABLE IF EXISTS car_moves;
+	DROP TABLE IF EXISTS car_ownerships;
+	DROP TABLE IF EXISTS owners;
+	DROP TABLE IF EXISTS cars;
+	DROP TABLE IF EXISTS locations;
+	DROP TABLE IF EXISTS dealerships;
+	DROP TABLE IF EXISTS webhook_deliveries;
+	DROP TABLE IF EXISTS webhooks;
+	DROP TABLE IF EXISTS outbox;
+	DROP FUNCTION IF EXISTS notify_car_change();
+	DROP SEQUENCE IF EXISTS car_event_ids;`
 
 	_, err = client.Db.Exec(query)
 	if err != nil {
@@ -71,7 +336,7 @@ func Truncate() {
 
 	defer clients.Close(&client)
 
-	query := `TRUNCATE ONLY cars;`
+	query := `TRUNCATE ONLY service_records, odometer_readings, price_history, reservations, car_transitions, car_moves, car_ownerships, owners, cars, locations, dealerships, outbox, webhook_deliveries, webhooks;`
 
 	_, err = client.Db.Exec(query)
 	if err != nil {
@@ -0,0 +1,99 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+)
+
+func ImportHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.GetLog(r.Context())
+
+	err := handlers.ValidateAuthId(r.Header.Get("X-CARS-ID"))
+	if err != nil {
+		log.Error("Unauthorized Auth Id: ", err)
+		http.Error(w, "Unauthorized", 401)
+		return
+	}
+
+	if r.Method != "POST" {
+		http.Error(w, "Invalid Request Method.", 405)
+		return
+	}
+
+	statusCode, err := ImportCars(w, r)
+	if err != nil {
+		log.Error(err)
+		jsonErr := &logging.JsonError{
+			Status:  http.StatusText(statusCode),
+			Code:    strconv.Itoa(statusCode),
+			Message: err.Error(),
+		}
+		logging.FormatError(r.Context(), w, statusCode, *jsonErr)
+	}
+}
+
+func ImportCars(w http.ResponseWriter, r *http.Request) (int, error) {
+	ctx := r.Context()
+	log := logging.GetLog(ctx)
+	log.Info("ImportCars: Processing Import Cars endpoint...")
+
+	format, ok := FormatFromContentType(r.Header.Get("Content-Type"))
+	if !ok {
+		return 415, errors.New("Content-Type must be text/csv or application/x-ndjson")
+	}
+
+	tenant, _ := handlers.TenantForAuthId(r.Header.Get("X-CARS-ID"))
+	opts := Options{BatchSize: DefaultBatchSize, Tenant: tenant}
+	if dryRun := r.URL.Query().Get("dry_run"); dryRun != "" {
+		parsed, err := strconv.ParseBool(dryRun)
+		if err != nil {
+			return 400, errors.New("dry_run must be true or false")
+		}
+		opts.DryRun = parsed
+	}
+
+	log.Debug("ImportCars: Reading header...")
+	rows, err := NewReader(format, r.Body)
+	if err != nil {
+		return 400, err
+	}
+
+	var db clients.DBClient
+	if !opts.DryRun {
+		log.Debug("ImportCars: Getting Database Connection...")
+		db, err = clients.NewDbConn()
+		if err != nil {
+			return 500, err
+		}
+		defer clients.Close(&db)
+	}
+
+	log.Debug("ImportCars: Importing rows...")
+	report, err := Import(&db, rows, opts)
+	if err != nil {
+		// Batches saved before the body broke off stay saved, so the caller
+		// gets the report to tell which rows made it in
+		log.Error("ImportCars: ", err)
+		return writeReport(w, 400, report)
+	}
+	return writeReport(w, 200, report)
+}
+
+func writeReport(w http.ResponseWriter, statusCode int, report Report) (int, error) {
+	reportJson, err := json.Marshal(report)
+	if err != nil {
+		return 500, err
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(statusCode)
+	w.Write(reportJson)
+
+	return statusCode, nil
+}
@@ -0,0 +1,118 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/satori/go.uuid"
+)
+
+const DefaultBatchSize = 1000
+
+type Options struct {
+	// Validate every row without writing anything
+	DryRun bool
+	// Rows saved per COPY; a failed batch fails every row in it
+	BatchSize int
+	// Tenant the imported cars belong to
+	Tenant string
+}
+
+type RowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+// Per-row outcome of an import
+type Report struct {
+	DryRun   bool       `json:"dry_run"`
+	Total    int        `json:"total"`
+	Imported int        `json:"imported"`
+	Failed   int        `json:"failed"`
+	Errors   []RowError `json:"errors"`
+	// Why reading stopped before the end of the input, if it did. Rows read
+	// before it were imported or failed as usual and stay imported; rows
+	// after it weren't read.
+	Aborted string `json:"aborted,omitempty"`
+}
+
+// Import streams rows out of the reader, validates each one and saves the
+// valid ones in batches. db may be nil for a dry run. If the reader fails
+// the rows already read are still saved, and the report comes back with the
+// error and says where the import stopped.
+func Import(db *clients.DBClient, rows RowReader, opts Options) (Report, error) {
+	report := Report{DryRun: opts.DryRun, Errors: []RowError{}}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	batch := make([]models.CarModel, 0, opts.BatchSize)
+	batchRows := make([]int, 0, opts.BatchSize)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		if opts.DryRun {
+			report.Imported += len(batch)
+		} else if err := models.CopyCars(db, batch); err != nil {
+			for _, row := range batchRows {
+				report.fail(row, err)
+			}
+		} else {
+			report.Imported += len(batch)
+		}
+
+		batch = batch[:0]
+		batchRows = batchRows[:0]
+	}
+
+	for {
+		row, err := rows.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			flush()
+			report.Aborted = fmt.Sprintf("Stopped reading after %d rows: %s", report.Total, err)
+			return report, err
+		}
+
+		report.Total++
+		if row.Err != nil {
+			report.fail(row.Row, row.Err)
+			continue
+		}
+
+		if err := handlers.ValidateCarPayload(&row.Payload); err != nil {
+			report.fail(row.Row, err)
+			continue
+		}
+
+		batch = append(batch, models.CarModel{
+			Id:     uuid.NewV4().String(),
+			Model:  row.Payload.Model,
+			Make:   row.Payload.Make,
+			Color:  row.Payload.Color,
+			Year:   row.Payload.Year,
+			Tenant: opts.Tenant,
+		})
+		batchRows = append(batchRows, row.Row)
+
+		if len(batch) >= opts.BatchSize {
+			flush()
+		}
+	}
+	flush()
+
+	return report, nil
+}
+
+func (r *Report) fail(row int, err error) {
+	r.Failed++
+	r.Errors = append(r.Errors, RowError{Row: row, Message: err.Error()})
+}
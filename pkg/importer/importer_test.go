@@ -0,0 +1,214 @@
+package importer_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/importer"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
+	"github.com/ericmcbride/go-dfw-testing/pkg/server"
+)
+
+func TestMain(m *testing.M) {
+	logging.ConfigureLogger("ERROR")
+	os.Exit(m.Run())
+}
+
+func readAll(t *testing.T, rows importer.RowReader) []importer.Row {
+	var got []importer.Row
+	for {
+		row, err := rows.Next()
+		if err == io.EOF {
+			return got
+		}
+		if err != nil {
+			t.Fatalf("Unexpected read error: %s", err)
+		}
+		got = append(got, row)
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	input := "Year,Make,Model,Color,Trim\n2015,Toyota,Camry,red,LE\n2016,Honda,Civic,blue\nabc,Ford,F150,white,XL\n"
+
+	rows, err := importer.NewReader(importer.FormatCSV, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Couldn't create reader: %s", err)
+	}
+
+	got := readAll(t, rows)
+	if len(got) != 3 {
+		t.Fatalf("Expected: 3 rows, got: %d", len(got))
+	}
+	if got[0].Payload.Make != "Toyota" || got[0].Payload.Year != 2015 {
+		t.Errorf("Unexpected first row: %+v", got[0])
+	}
+	if got[1].Payload.Color != "blue" || got[1].Err != nil {
+		t.Errorf("Unexpected second row: %+v", got[1])
+	}
+	if got[2].Err == nil {
+		t.Errorf("Expected a year parse error on row 3")
+	}
+}
+
+func TestCSVReaderMissingColumn(t *testing.T) {
+	_, err := importer.NewReader(importer.FormatCSV, strings.NewReader("make,model,year\n"))
+	if err == nil {
+		t.Errorf("Expected an error for a header without color")
+	}
+}
+
+func TestNDJSONReader(t *testing.T) {
+	input := `{"make": "Toyota", "model": "Camry", "color": "red", "year": 2015}
+
+{"make": "Honda", "model": "Civic"
+{"make": "Ford", "model": "F150", "color": "white", "year": 2018}`
+
+	rows, err := importer.NewReader(importer.FormatNDJSON, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Couldn't create reader: %s", err)
+	}
+
+	got := readAll(t, rows)
+	if len(got) != 3 {
+		t.Fatalf("Expected: 3 rows, got: %d", len(got))
+	}
+	if got[1].Err == nil {
+		t.Errorf("Expected a decode error on row 2")
+	}
+	if got[2].Row != 3 || got[2].Payload.Make != "Ford" {
+		t.Errorf("Unexpected last row: %+v", got[2])
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	input := "make,model,color,year\nToyota,Camry,red,2015\nHonda,,blue,2016\nFord,F150,white,2018\n"
+
+	rows, err := importer.NewReader(importer.FormatCSV, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Couldn't create reader: %s", err)
+	}
+
+	report, err := importer.Import(nil, rows, importer.Options{DryRun: true, BatchSize: 1})
+	if err != nil {
+		t.Fatalf("Unexpected import error: %s", err)
+	}
+
+	if report.Total != 3 || report.Imported != 2 || report.Failed != 1 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Row != 2 {
+		t.Errorf("Expected row 2 to fail, got: %+v", report.Errors)
+	}
+}
+
+// Gives up reading after the input it has, like a body that breaks off
+type brokenReader struct {
+	input io.Reader
+}
+
+func (b *brokenReader) Read(p []byte) (int, error) {
+	n, err := b.input.Read(p)
+	if err == io.EOF {
+		return n, errors.New("connection reset")
+	}
+	return n, err
+}
+
+func TestImportBrokenInput(t *testing.T) {
+	input := "make,model,color,year\nToyota,Camry,red,2015\nHonda,,blue,2016\nFord,F150,white,2018\n"
+	rows, err := importer.NewReader(importer.FormatCSV, &brokenReader{strings.NewReader(input)})
+	if err != nil {
+		t.Fatalf("Couldn't create reader: %s", err)
+	}
+
+	// The rows read before the input broke are still imported or failed
+	report, err := importer.Import(nil, rows, importer.Options{DryRun: true, BatchSize: 10})
+	if err == nil {
+		t.Fatalf("Expected the broken input to be an error")
+	}
+	if report.Total != 3 || report.Imported != 2 || report.Failed != 1 || !strings.Contains(report.Aborted, "after 3 rows: connection reset") {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+}
+
+func TestImportHandlerBrokenBody(t *testing.T) {
+	payload := "{\"make\": \"Toyota\", \"model\": \"Camry\", \"color\": \"red\", \"year\": 2015}\n"
+
+	req, err := http.NewRequest("POST", "/v1/cars:import?dry_run=true", &brokenReader{strings.NewReader(payload)})
+	if err != nil {
+		t.Errorf("Error while reading request payload: %s", err)
+	}
+	req.Header.Set("X-CARS-ID", "1234")
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	rr := httptest.NewRecorder()
+	handler := server.New()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != 400 {
+		t.Fatalf("Expected: 400, but got: %d %s", rr.Code, rr.Body.String())
+	}
+	if err := openapi.ValidateSchema("ImportReport", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match ImportReport schema: %s", err)
+	}
+
+	var report importer.Report
+	json.Unmarshal(rr.Body.Bytes(), &report)
+	if report.Imported != 1 || report.Aborted == "" {
+		t.Errorf("Expected the row before the break and where it stopped, got %+v", report)
+	}
+}
+
+func TestImportHandlerDryRun(t *testing.T) {
+	payload := []byte("{\"make\": \"Toyota\", \"model\": \"Camry\", \"color\": \"red\", \"year\": 2015}\n{\"make\": \"Toyota\"}\n")
+
+	req, err := http.NewRequest("POST", "/v1/cars:import?dry_run=true", bytes.NewBuffer(payload))
+	if err != nil {
+		t.Errorf("Error while reading request payload: %s", err)
+	}
+	req.Header.Set("X-CARS-ID", "1234")
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	rr := httptest.NewRecorder()
+	handler := server.New()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != 200 {
+		t.Fatalf("Expected: 200, but got: %d %s", rr.Code, rr.Body.String())
+	}
+	if err := openapi.ValidateSchema("ImportReport", rr.Body.Bytes()); err != nil {
+		t.Errorf("Response doesn't match ImportReport schema: %s", err)
+	}
+
+	var report importer.Report
+	json.Unmarshal(rr.Body.Bytes(), &report)
+	if !report.DryRun || report.Imported != 1 || report.Failed != 1 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+}
+
+func TestImportHandlerUnsupportedType(t *testing.T) {
+	req, err := http.NewRequest("POST", "/v1/cars:import", bytes.NewBufferString("{}"))
+	if err != nil {
+		t.Errorf("Error while reading request payload: %s", err)
+	}
+	req.Header.Set("X-CARS-ID", "1234")
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler := server.New()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != 415 {
+		t.Errorf("Expected: %d, but got: %d", 415, rr.Code)
+	}
+}
@@ -0,0 +1,173 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// A single record read from an import file. Err is set when the record
+// couldn't be parsed; the import carries on with the next one.
+type Row struct {
+	Row     int
+	Payload handlers.CarPostPayload
+	Err     error
+}
+
+// RowReader streams rows out of an import file. Next returns io.EOF once the
+// input is exhausted; any other error aborts the import.
+type RowReader interface {
+	Next() (Row, error)
+}
+
+// Pick the import format from a request Content-Type
+func FormatFromContentType(contentType string) (string, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", false
+	}
+
+	switch mediaType {
+	case "text/csv":
+		return FormatCSV, true
+	case "application/x-ndjson", "application/ndjson":
+		return FormatNDJSON, true
+	}
+	return "", false
+}
+
+// Pick the import format from a file extension
+func FormatFromFilename(name string) (string, bool) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return FormatCSV, true
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON, true
+	}
+	return "", false
+}
+
+func NewReader(format string, r io.Reader) (RowReader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatNDJSON:
+		return &ndjsonReader{reader: bufio.NewReader(r)}, nil
+	}
+	return nil, fmt.Errorf("Unsupported import format %s", format)
+}
+
+type csvReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+	row     int
+}
+
+// CSV files need a header row naming the make, model, color and year columns,
+// in any order. Extra columns are ignored.
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("CSV file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Could not read CSV header %s", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"make", "model", "color", "year"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("CSV header is missing the %s column", name)
+		}
+	}
+
+	return &csvReader{reader: reader, columns: columns}, nil
+}
+
+func (c *csvReader) Next() (Row, error) {
+	record, err := c.reader.Read()
+	if err == io.EOF {
+		return Row{}, io.EOF
+	}
+
+	c.row++
+	row := Row{Row: c.row}
+	if err != nil {
+		if _, ok := err.(*csv.ParseError); ok {
+			row.Err = err
+			return row, nil
+		}
+		return row, err
+	}
+
+	field := func(name string) string {
+		i := c.columns[name]
+		if i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	row.Payload.Make = field("make")
+	row.Payload.Model = field("model")
+	row.Payload.Color = field("color")
+	if year := field("year"); year != "" {
+		row.Payload.Year, err = strconv.Atoi(year)
+		if err != nil {
+			row.Err = fmt.Errorf("Year must be a whole number, got %q", year)
+		}
+	}
+
+	return row, nil
+}
+
+type ndjsonReader struct {
+	reader *bufio.Reader
+	row    int
+}
+
+func (n *ndjsonReader) Next() (Row, error) {
+	for {
+		line, err := n.reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return Row{}, err
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			if err == io.EOF {
+				return Row{}, io.EOF
+			}
+			continue
+		}
+
+		n.row++
+		row := Row{Row: n.row}
+		if jsonErr := json.Unmarshal(line, &row.Payload); jsonErr != nil {
+			row.Err = jsonErr
+		}
+		return row, nil
+	}
+}
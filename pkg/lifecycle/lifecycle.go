@@ -0,0 +1,157 @@
+package lifecycle
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Where a car is in its life on the lot
+type State string
+
+const (
+	// Being entered, not for sale yet. New cars start here.
+	Draft State = "draft"
+	// For sale
+	Listed State = "listed"
+	// Held for a buyer
+	Reserved State = "reserved"
+	Sold     State = "sold"
+	// Off the books for good
+	Retired State = "retired"
+)
+
+// Every state, in lifecycle order
+var States = []State{Draft, Listed, Reserved, Sold, Retired}
+
+// What the machine knows about a car besides its state, for the guards
+type Facts struct {
+	HasOwner    bool
+	HasLocation bool
+}
+
+// A guard returns why a transition can't happen, or nil when it can
+type Guard func(Facts) error
+
+// Fired by Event from any of From, moving the car to To when Guard allows
+type Transition struct {
+	Event string
+	From  []State
+	To    State
+	Guard Guard
+}
+
+// A transition the car's current state allows
+type Next struct {
+	Event string `json:"event" xml:"event,attr"`
+	To    State  `json:"to" xml:"to,attr"`
+}
+
+// Returned by Fire when the event can't happen from the car's state, or its
+// guard stops it. Allowed is every transition the state does allow.
+type TransitionError struct {
+	Event   string
+	From    State
+	Reason  string
+	Allowed []Next
+}
+
+func (e *TransitionError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("Can't %s a %s car, %s", e.Event, e.From, e.Reason)
+	}
+	return fmt.Sprintf("Can't %s a %s car", e.Event, e.From)
+}
+
+var ErrUnknownEvent = errors.New("Unknown transition event")
+
+// The transition table. Cars are listed from a location and sold to an
+// owner, so those guards check they have one.
+var Transitions = []Transition{
+	{Event: "list", From: []State{Draft}, To: Listed, Guard: needsLocation},
+	{Event: "unlist", From: []State{Listed}, To: Draft},
+	{Event: "reserve", From: []State{Listed}, To: Reserved},
+	{Event: "release", From: []State{Reserved}, To: Listed},
+	{Event: "sell", From: []State{Listed, Reserved}, To: Sold, Guard: needsOwner},
+	{Event: "retire", From: []State{Draft, Listed, Sold}, To: Retired},
+}
+
+func needsLocation(facts Facts) error {
+	if !facts.HasLocation {
+		return errors.New("it has to be at a location first")
+	}
+	return nil
+}
+
+func needsOwner(facts Facts) error {
+	if !facts.HasOwner {
+		return errors.New("it has to be transferred to its buyer first")
+	}
+	return nil
+}
+
+func ValidState(state string) bool {
+	for _, s := range States {
+		if string(s) == state {
+			return true
+		}
+	}
+	return false
+}
+
+// Every event in the table, in table order
+func Events() []string {
+	events := make([]string, 0, len(Transitions))
+	for _, t := range Transitions {
+		events = append(events, t.Event)
+	}
+	return events
+}
+
+// The transitions the table allows from the state, guards aside
+func Allowed(from State) []Next {
+	allowed := []Next{}
+	for _, t := range Transitions {
+		if t.from(from) {
+			allowed = append(allowed, Next{Event: t.Event, To: t.To})
+		}
+	}
+	return allowed
+}
+
+// The state the event moves a car in from to. An event the table doesn't
+// have is ErrUnknownEvent, and one the state or a guard doesn't allow is a
+// *TransitionError.
+func Fire(from State, event string, facts Facts) (State, error) {
+	event = strings.ToLower(strings.TrimSpace(event))
+	known := false
+	for _, t := range Transitions {
+		if t.Event != event {
+			continue
+		}
+		known = true
+		if !t.from(from) {
+			continue
+		}
+		if t.Guard != nil {
+			if err := t.Guard(facts); err != nil {
+				return from, &TransitionError{Event: event, From: from, Reason: err.Error(), Allowed: Allowed(from)}
+			}
+		}
+		return t.To, nil
+	}
+
+	if !known {
+		return from, ErrUnknownEvent
+	}
+	return from, &TransitionError{Event: event, From: from, Allowed: Allowed(from)}
+}
+
+func (t Transition) from(state State) bool {
+	for _, s := range t.From {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
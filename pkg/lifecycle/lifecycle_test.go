@@ -0,0 +1,65 @@
+package lifecycle_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/lifecycle"
+)
+
+func TestFire(t *testing.T) {
+	ready := lifecycle.Facts{HasOwner: true, HasLocation: true}
+	cases := []struct {
+		from  lifecycle.State
+		event string
+		to    lifecycle.State
+	}{
+		{lifecycle.Draft, "list", lifecycle.Listed},
+		{lifecycle.Listed, "unlist", lifecycle.Draft},
+		{lifecycle.Listed, "reserve", lifecycle.Reserved},
+		{lifecycle.Reserved, "release", lifecycle.Listed},
+		{lifecycle.Reserved, "sell", lifecycle.Sold},
+		{lifecycle.Listed, " Sell ", lifecycle.Sold},
+		{lifecycle.Sold, "retire", lifecycle.Retired},
+	}
+	for _, c := range cases {
+		to, err := lifecycle.Fire(c.from, c.event, ready)
+		if err != nil || to != c.to {
+			t.Errorf("%s from %s: expected %s, got %s %v", c.event, c.from, c.to, to, err)
+		}
+	}
+}
+
+func TestFireIllegal(t *testing.T) {
+	_, err := lifecycle.Fire(lifecycle.Draft, "sell", lifecycle.Facts{HasOwner: true})
+	transitionErr, ok := err.(*lifecycle.TransitionError)
+	if !ok {
+		t.Fatalf("Expected a TransitionError, got %v", err)
+	}
+	expected := []lifecycle.Next{{Event: "list", To: lifecycle.Listed}, {Event: "retire", To: lifecycle.Retired}}
+	if !reflect.DeepEqual(transitionErr.Allowed, expected) {
+		t.Errorf("Expected %v allowed from draft, got %v", expected, transitionErr.Allowed)
+	}
+
+	if _, err := lifecycle.Fire(lifecycle.Retired, "list", lifecycle.Facts{HasLocation: true}); err == nil {
+		t.Errorf("Expected retired to be final")
+	} else if allowed := err.(*lifecycle.TransitionError).Allowed; len(allowed) != 0 {
+		t.Errorf("Expected nothing allowed from retired, got %v", allowed)
+	}
+
+	if _, err := lifecycle.Fire(lifecycle.Draft, "scrap", lifecycle.Facts{}); err != lifecycle.ErrUnknownEvent {
+		t.Errorf("Expected an unknown event, got %v", err)
+	}
+}
+
+func TestFireGuards(t *testing.T) {
+	_, err := lifecycle.Fire(lifecycle.Listed, "sell", lifecycle.Facts{HasLocation: true})
+	if transitionErr, ok := err.(*lifecycle.TransitionError); !ok || transitionErr.Reason == "" {
+		t.Errorf("Expected selling without an owner to be stopped by its guard, got %v", err)
+	}
+
+	_, err = lifecycle.Fire(lifecycle.Draft, "list", lifecycle.Facts{HasOwner: true})
+	if transitionErr, ok := err.(*lifecycle.TransitionError); !ok || transitionErr.Reason == "" {
+		t.Errorf("Expected listing without a location to be stopped by its guard, got %v", err)
+	}
+}
@@ -0,0 +1,223 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/catalog"
+)
+
+// Kinds of service a record can be
+const (
+	OilChange    = "oil_change"
+	TireRotation = "tire_rotation"
+	Brakes       = "brakes"
+	Inspection   = "inspection"
+	Repair       = "repair"
+	Other        = "other"
+)
+
+var Types = []string{OilChange, TireRotation, Brakes, Inspection, Repair, Other}
+
+// The rule for makes without one of their own
+const AnyMake = "*"
+
+// How service dates are written, a plain calendar date
+const dateLayout = "2006-01-02"
+
+// The bundled rules unless Configure loads others
+var Default = MustParseJSON(strings.NewReader(defaultRules))
+
+func ValidType(name string) bool {
+	for _, t := range Types {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// A service is due every Km kilometers or every Months months, whichever
+// comes first. A zero leaves that limit out.
+type Interval struct {
+	Type   string `json:"type" xml:"type,attr"`
+	Km     int64  `json:"km,omitempty" xml:"km,attr,omitempty"`
+	Months int    `json:"months,omitempty" xml:"months,attr,omitempty"`
+}
+
+// The intervals for one make, or every other make when Make is AnyMake
+type Rule struct {
+	Make      string     `json:"make" xml:"make,attr"`
+	Intervals []Interval `json:"intervals" xml:"interval"`
+}
+
+// Service interval rules by make. Makes are matched the way the catalog
+// matches them, ignoring case, spaces and punctuation, and a make with a
+// rule of its own uses only its intervals.
+type Rules struct {
+	rules  []Rule
+	byMake map[string]int
+}
+
+// Replace the bundled rules with a JSON file. An empty path keeps them.
+func Configure(path string) error {
+	if path == "" {
+		return nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("Could not load service rules %s", err)
+	}
+	defer file.Close()
+
+	loaded, err := ParseJSON(file)
+	if err != nil {
+		return err
+	}
+	Default = loaded
+	return nil
+}
+
+// {"rules": [{"make": "*", "intervals": [{"type": "oil_change", "km": 8000, "months": 6}]}]}
+func ParseJSON(r io.Reader) (*Rules, error) {
+	var doc struct {
+		Rules []Rule `json:"rules"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("Could not load service rules %s", err)
+	}
+	return New(doc.Rules)
+}
+
+func MustParseJSON(r io.Reader) *Rules {
+	rules, err := ParseJSON(r)
+	if err != nil {
+		panic(err)
+	}
+	return rules
+}
+
+// Build rules, rejecting unknown service types, intervals without a limit
+// and makes with more than one rule
+func New(rules []Rule) (*Rules, error) {
+	rs := &Rules{byMake: make(map[string]int)}
+
+	rules = append([]Rule{}, rules...)
+	sort.Slice(rules, func(i, j int) bool { return strings.ToLower(rules[i].Make) < strings.ToLower(rules[j].Make) })
+
+	for i, rule := range rules {
+		key := ruleKey(rule.Make)
+		if key == "" {
+			return nil, errors.New("Could not load service rules, a rule has no make")
+		}
+		if _, ok := rs.byMake[key]; ok {
+			return nil, fmt.Errorf("Could not load service rules, %s has more than one rule", rule.Make)
+		}
+		rs.byMake[key] = i
+
+		seen := make(map[string]bool)
+		for _, interval := range rule.Intervals {
+			if !ValidType(interval.Type) {
+				return nil, fmt.Errorf("Could not load service rules, unknown service type %q for %s", interval.Type, rule.Make)
+			}
+			if seen[interval.Type] {
+				return nil, fmt.Errorf("Could not load service rules, %s has more than one %s interval", rule.Make, interval.Type)
+			}
+			seen[interval.Type] = true
+			if interval.Km < 0 || interval.Months < 0 || (interval.Km == 0 && interval.Months == 0) {
+				return nil, fmt.Errorf("Could not load service rules, the %s %s interval needs km or months", rule.Make, interval.Type)
+			}
+		}
+		if rules[i].Intervals == nil {
+			rules[i].Intervals = []Interval{}
+		}
+	}
+	rs.rules = rules
+	return rs, nil
+}
+
+// Every rule, by make
+func (rs *Rules) Rules() []Rule {
+	return rs.rules
+}
+
+// The intervals a make is serviced on, its own or the AnyMake ones
+func (rs *Rules) For(makeName string) []Interval {
+	if i, ok := rs.byMake[ruleKey(makeName)]; ok {
+		return rs.rules[i].Intervals
+	}
+	if i, ok := rs.byMake[AnyMake]; ok {
+		return rs.rules[i].Intervals
+	}
+	return []Interval{}
+}
+
+func ruleKey(makeName string) string {
+	if strings.TrimSpace(makeName) == AnyMake {
+		return AnyMake
+	}
+	return catalog.Key(makeName)
+}
+
+// The latest service of a type, on a date and at a mileage when one was
+// recorded
+type Last struct {
+	On string
+	Km *int64
+}
+
+// When a service is next due and whether it's overdue. A service the car
+// has never had is due by mileage from zero and by date from when the car
+// was first seen; without those there's nothing to measure from.
+type Due struct {
+	Type           string `json:"type" xml:"type,attr"`
+	LastServicedOn string `json:"last_serviced_on,omitempty" xml:"last_serviced_on,omitempty"`
+	LastMileageKm  *int64 `json:"last_mileage_km,omitempty" xml:"last_mileage_km,omitempty"`
+	DueOn          string `json:"due_on,omitempty" xml:"due_on,omitempty"`
+	DueKm          *int64 `json:"due_km,omitempty" xml:"due_km,omitempty"`
+	Overdue        bool   `json:"overdue" xml:"overdue,attr"`
+}
+
+// Work out each interval's next service from the car's latest services and
+// mileage, and since, the date the car was first seen. It's overdue once
+// today is after DueOn or the mileage is past DueKm.
+func Check(intervals []Interval, last map[string]Last, mileageKm *int64, since string, today time.Time) []Due {
+	day := today.UTC().Format(dateLayout)
+
+	due := make([]Due, 0, len(intervals))
+	for _, interval := range intervals {
+		next := Due{Type: interval.Type}
+		previous, serviced := last[interval.Type]
+
+		from := since
+		if serviced {
+			next.LastServicedOn = previous.On
+			next.LastMileageKm = previous.Km
+			from = previous.On
+		}
+		if on, err := time.Parse(dateLayout, from); err == nil && interval.Months > 0 {
+			next.DueOn = on.AddDate(0, interval.Months, 0).Format(dateLayout)
+		}
+		if interval.Km > 0 {
+			switch {
+			case !serviced:
+				km := interval.Km
+				next.DueKm = &km
+			case previous.Km != nil:
+				km := *previous.Km + interval.Km
+				next.DueKm = &km
+			}
+		}
+
+		next.Overdue = (next.DueOn != "" && day > next.DueOn) ||
+			(next.DueKm != nil && mileageKm != nil && *mileageKm > *next.DueKm)
+		due = append(due, next)
+	}
+	return due
+}
@@ -0,0 +1,107 @@
+package maintenance_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/maintenance"
+)
+
+const testRules = `{"rules": [
+  {"make": "*", "intervals": [{"type": "oil_change", "km": 8000, "months": 6}, {"type": "inspection", "months": 12}]},
+  {"make": "Toyota", "intervals": [{"type": "oil_change", "km": 16000, "months": 12}]}
+]}`
+
+func TestFor(t *testing.T) {
+	rules, err := maintenance.ParseJSON(strings.NewReader(testRules))
+	if err != nil {
+		t.Fatalf("Couldn't parse %s", err)
+	}
+
+	if intervals := rules.For(" TOYOTA "); len(intervals) != 1 || intervals[0].Km != 16000 {
+		t.Errorf("Expected Toyota's own interval, got %+v", intervals)
+	}
+	if intervals := rules.For("Ford"); len(intervals) != 2 || intervals[0].Km != 8000 {
+		t.Errorf("Expected the fallback intervals, got %+v", intervals)
+	}
+
+	if len(maintenance.Default.For("Honda")) == 0 {
+		t.Errorf("Expected the bundled rules to cover Honda")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []string{
+		`{"rules": [{"make": "", "intervals": []}]}`,
+		`{"rules": [{"make": "Ford", "intervals": [{"type": "wash", "km": 100}]}]}`,
+		`{"rules": [{"make": "Ford", "intervals": [{"type": "brakes"}]}]}`,
+		`{"rules": [{"make": "Ford", "intervals": [{"type": "brakes", "km": 100}, {"type": "brakes", "months": 1}]}]}`,
+		`{"rules": [{"make": "Ford", "intervals": []}, {"make": "ford", "intervals": []}]}`,
+	}
+	for _, c := range cases {
+		if _, err := maintenance.ParseJSON(strings.NewReader(c)); err == nil {
+			t.Errorf("Expected %s to be rejected", c)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	intervals := []maintenance.Interval{
+		{Type: maintenance.OilChange, Km: 8000, Months: 6},
+		{Type: maintenance.Inspection, Months: 12},
+		{Type: maintenance.Brakes, Km: 40000},
+	}
+	today := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	km := func(n int64) *int64 { return &n }
+
+	due := maintenance.Check(intervals, map[string]maintenance.Last{
+		maintenance.OilChange:  {On: "2025-01-10", Km: km(20000)},
+		maintenance.Inspection: {On: "2024-05-31"},
+	}, km(27000), "", today)
+
+	oil, inspection, brakes := due[0], due[1], due[2]
+	if oil.DueOn != "2025-07-10" || *oil.DueKm != 28000 || oil.Overdue {
+		t.Errorf("Expected an oil change due in July or at 28000 km, got %+v", oil)
+	}
+	if inspection.DueOn != "2025-05-31" || inspection.DueKm != nil || !inspection.Overdue {
+		t.Errorf("Expected an overdue inspection, got %+v", inspection)
+	}
+	// Never done, so due by mileage from zero
+	if brakes.DueOn != "" || *brakes.DueKm != 40000 || brakes.Overdue {
+		t.Errorf("Expected brakes due at 40000 km, got %+v", brakes)
+	}
+
+	due = maintenance.Check(intervals, map[string]maintenance.Last{
+		maintenance.OilChange: {On: "2025-05-01", Km: km(20000)},
+	}, km(28001), "", today)
+	if !due[0].Overdue {
+		t.Errorf("Expected an oil change overdue by mileage, got %+v", due[0])
+	}
+
+	// Without a mileage only dates count
+	due = maintenance.Check(intervals, nil, nil, "", today)
+	for _, d := range due {
+		if d.Overdue {
+			t.Errorf("Expected nothing overdue without a mileage or history, got %+v", d)
+		}
+	}
+
+	// Never serviced, dates count from when the car was first seen
+	due = maintenance.Check(intervals, nil, nil, "2024-03-15", today)
+	oil, inspection, brakes = due[0], due[1], due[2]
+	if oil.DueOn != "2024-09-15" || !oil.Overdue {
+		t.Errorf("Expected an oil change overdue since September, got %+v", oil)
+	}
+	if inspection.DueOn != "2025-03-15" || inspection.LastServicedOn != "" || !inspection.Overdue {
+		t.Errorf("Expected an inspection overdue since March, got %+v", inspection)
+	}
+	if brakes.DueOn != "" || brakes.Overdue {
+		t.Errorf("Expected brakes due by mileage only, got %+v", brakes)
+	}
+
+	due = maintenance.Check(intervals, nil, nil, "2025-01-01", today)
+	if due[1].DueOn != "2026-01-01" || due[1].Overdue {
+		t.Errorf("Expected an inspection due next year, got %+v", due[1])
+	}
+}
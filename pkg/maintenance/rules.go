@@ -0,0 +1,52 @@
+package maintenance
+
+// The bundled service intervals: common manufacturer schedules, rounded.
+// Set service_rules_path to load a shop's own.
+const defaultRules = `{
+  "rules": [
+    {
+      "make": "*",
+      "intervals": [
+        {"type": "oil_change", "km": 8000, "months": 6},
+        {"type": "tire_rotation", "km": 10000, "months": 12},
+        {"type": "brakes", "km": 40000, "months": 24},
+        {"type": "inspection", "months": 12}
+      ]
+    },
+    {
+      "make": "BMW",
+      "intervals": [
+        {"type": "oil_change", "km": 16000, "months": 12},
+        {"type": "tire_rotation", "km": 10000, "months": 12},
+        {"type": "brakes", "km": 50000, "months": 24},
+        {"type": "inspection", "months": 24}
+      ]
+    },
+    {
+      "make": "Honda",
+      "intervals": [
+        {"type": "oil_change", "km": 12000, "months": 12},
+        {"type": "tire_rotation", "km": 12000, "months": 12},
+        {"type": "brakes", "km": 40000, "months": 24},
+        {"type": "inspection", "months": 12}
+      ]
+    },
+    {
+      "make": "Tesla",
+      "intervals": [
+        {"type": "tire_rotation", "km": 10000, "months": 12},
+        {"type": "brakes", "months": 24},
+        {"type": "inspection", "months": 12}
+      ]
+    },
+    {
+      "make": "Toyota",
+      "intervals": [
+        {"type": "oil_change", "km": 16000, "months": 12},
+        {"type": "tire_rotation", "km": 8000, "months": 6},
+        {"type": "brakes", "km": 40000, "months": 24},
+        {"type": "inspection", "months": 12}
+      ]
+    }
+  ]
+}`
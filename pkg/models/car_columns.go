@@ -0,0 +1,119 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+)
+
+// Columns clients can pick with ?fields=, in the order responses list them
+var CarColumns = []string{"id", "make", "model", "color", "year", "status", "price_minor", "currency", "mileage_km"}
+
+// Everything ListCars and GetCar read
+var allCarColumns = []string{"id", "model", "make", "color", "year", "tenant", "location_id", "status", "price_minor", "currency", "mileage_km"}
+
+func ValidCarColumn(name string) bool {
+	for _, column := range CarColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Pointers into car for each column, in order, for Scan. Only known columns
+// are accepted, so the names are safe to put in SQL.
+func carColumnDest(car *CarModel, columns []string) ([]interface{}, error) {
+	dest := make([]interface{}, 0, len(columns))
+	for _, column := range columns {
+		switch column {
+		case "id":
+			dest = append(dest, &car.Id)
+		case "make":
+			dest = append(dest, &car.Make)
+		case "model":
+			dest = append(dest, &car.Model)
+		case "color":
+			dest = append(dest, &car.Color)
+		case "year":
+			dest = append(dest, &car.Year)
+		case "tenant":
+			dest = append(dest, &car.Tenant)
+		case "location_id":
+			dest = append(dest, &car.LocationId)
+		case "status":
+			dest = append(dest, &car.Status)
+		case "price_minor":
+			dest = append(dest, &car.PriceMinor)
+		case "currency":
+			dest = append(dest, &car.Currency)
+		case "mileage_km":
+			dest = append(dest, &car.MileageKm)
+		default:
+			return nil, fmt.Errorf("Unknown car column %q", column)
+		}
+	}
+	return dest, nil
+}
+
+// List cars in order reading only the given columns; the rest are left zero
+func ListCarColumns(db *clients.DBClient, filter CarFilter, order CarOrder, columns []string, limit int, offset int) ([]CarModel, error) {
+	if _, err := carColumnDest(&CarModel{}, columns); err != nil {
+		return nil, fmt.Errorf("Could not LIST cars %s", err)
+	}
+
+	where, args := filter.Where(1)
+	sqlStatement := fmt.Sprintf(`
+		SELECT %s
+		FROM "cars" %s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d;
+	`, strings.Join(columns, ", "), where, order.OrderBy(), len(args)+1, len(args)+2)
+
+	rows, err := db.Db.Query(sqlStatement, append(args, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("Could not LIST cars %s", err)
+	}
+	defer rows.Close()
+
+	cars := []CarModel{}
+	for rows.Next() {
+		var carModel CarModel
+		dest, _ := carColumnDest(&carModel, columns)
+		if err = rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("Could not LIST cars %s", err)
+		}
+		cars = append(cars, carModel)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Could not LIST cars %s", err)
+	}
+	return cars, nil
+}
+
+// Get one of the tenant's cars reading only the given columns. A missing
+// car, or another tenant's, is ErrCarNotFound.
+func GetCarColumns(db *clients.DBClient, tenant string, carId string, columns []string) (CarModel, error) {
+	var carModel CarModel
+	dest, err := carColumnDest(&carModel, columns)
+	if err != nil {
+		return CarModel{}, fmt.Errorf("Could not GET car %s", err)
+	}
+
+	sqlStatement := fmt.Sprintf(`
+		SELECT %s
+		FROM "cars" WHERE id = $1 AND tenant = $2;
+	`, strings.Join(columns, ", "))
+
+	err = db.Db.QueryRow(sqlStatement, carId, tenant).Scan(dest...)
+	if err == sql.ErrNoRows {
+		return CarModel{}, ErrCarNotFound
+	}
+	if err != nil {
+		return CarModel{}, fmt.Errorf("Could not GET car %s", err)
+	}
+	return carModel, nil
+}
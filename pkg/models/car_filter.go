@@ -0,0 +1,266 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+)
+
+// Filters shared by every endpoint that reads more than one car. Zero values
+// are ignored, except Tenant: a filter only ever matches one tenant's cars.
+type CarFilter struct {
+	// Set from the caller's credentials, never from a query. Empty is the
+	// default tenant.
+	Tenant  string `json:"-"`
+	Make    string `json:"make,omitempty"`
+	Model   string `json:"model,omitempty"`
+	Color   string `json:"color,omitempty"`
+	Year    int    `json:"year,omitempty"`
+	YearMin int    `json:"year_min,omitempty"`
+	YearMax int    `json:"year_max,omitempty"`
+	// Only cars sitting at this location
+	LocationId string `json:"location_id,omitempty"`
+	// Only cars in this lifecycle state
+	Status string `json:"status,omitempty"`
+	// List price bounds in minor units of Currency, inclusive. Unpriced cars
+	// never match them. ParseCarFilter won't take them without a Currency,
+	// amounts in different currencies don't compare.
+	PriceMin int64  `json:"price_min,omitempty"`
+	PriceMax int64  `json:"price_max,omitempty"`
+	Currency string `json:"currency,omitempty"`
+	// Latest odometer reading bounds in kilometers, inclusive. Cars without
+	// a reading never match them.
+	MileageMin int64 `json:"mileage_min,omitempty"`
+	MileageMax int64 `json:"mileage_max,omitempty"`
+}
+
+// Listing sorts by ?sort= name, and the column each sorts on
+var CarSortColumns = map[string]string{
+	"id":      "id",
+	"make":    "make",
+	"model":   "model",
+	"year":    "year",
+	"price":   "price_minor",
+	"mileage": "mileage_km",
+}
+
+// Sorts whose values only compare within a group, and the column that
+// groups them. Prices sort by currency first.
+var carSortGroups = map[string]string{
+	"price": "currency",
+}
+
+// How a listing is ordered, a CarSortColumns name ascending or descending.
+// The zero value orders by id, and id breaks ties so pages don't overlap.
+type CarOrder struct {
+	Sort string
+	Desc bool
+}
+
+func (o CarOrder) OrderBy() string {
+	column, ok := CarSortColumns[o.Sort]
+	if !ok {
+		column = "id"
+	}
+	direction := "ASC"
+	if o.Desc {
+		direction = "DESC"
+	}
+	if column == "id" {
+		return "id " + direction
+	}
+	// Cars without a price, mileage or year go last whichever way they're sorted
+	if group, ok := carSortGroups[o.Sort]; ok {
+		return fmt.Sprintf("%s NULLS LAST, %s %s NULLS LAST, id", group, column, direction)
+	}
+	return fmt.Sprintf("%s %s NULLS LAST, id", column, direction)
+}
+
+// Build the WHERE clause for the filter. Placeholders are numbered from
+// start so callers can append their own arguments before or after.
+func (f CarFilter) Where(start int) (string, []interface{}) {
+	var (
+		clauses []string
+		args    []interface{}
+	)
+
+	add := func(clause string, arg interface{}) {
+		args = append(args, arg)
+		clauses = append(clauses, fmt.Sprintf(clause, start+len(args)-1))
+	}
+
+	add("tenant = $%d", f.TenantOrDefault())
+	if f.Make != "" {
+		add("make = $%d", f.Make)
+	}
+	if f.Model != "" {
+		add("model = $%d", f.Model)
+	}
+	if f.Color != "" {
+		add("color = $%d", f.Color)
+	}
+	if f.Year != 0 {
+		add("year = $%d", f.Year)
+	}
+	if f.YearMin != 0 {
+		add("year >= $%d", f.YearMin)
+	}
+	if f.YearMax != 0 {
+		add("year <= $%d", f.YearMax)
+	}
+	if f.LocationId != "" {
+		add("location_id = $%d", f.LocationId)
+	}
+	if f.Status != "" {
+		add("status = $%d", f.Status)
+	}
+	if f.PriceMin != 0 {
+		add("price_minor >= $%d", f.PriceMin)
+	}
+	if f.PriceMax != 0 {
+		add("price_minor <= $%d", f.PriceMax)
+	}
+	if f.Currency != "" {
+		add("currency = $%d", f.Currency)
+	}
+	if f.MileageMin != 0 {
+		add("mileage_km >= $%d", f.MileageMin)
+	}
+	if f.MileageMax != 0 {
+		add("mileage_km <= $%d", f.MileageMax)
+	}
+
+	return "WHERE " + strings.Join(clauses, " AND "), args
+}
+
+func (f CarFilter) TenantOrDefault() string {
+	if f.Tenant == "" {
+		return DefaultTenant
+	}
+	return f.Tenant
+}
+
+// Report whether a car passes the filter, for filtering in memory the same
+// way Where does in SQL. Make, model and color are citext columns, so they
+// compare without case.
+func (f CarFilter) Matches(car CarModel) bool {
+	if f.Tenant != "" && car.Tenant != f.Tenant {
+		return false
+	}
+	if f.Make != "" && !strings.EqualFold(car.Make, f.Make) {
+		return false
+	}
+	if f.Model != "" && !strings.EqualFold(car.Model, f.Model) {
+		return false
+	}
+	if f.Color != "" && !strings.EqualFold(car.Color, f.Color) {
+		return false
+	}
+	if f.Year != 0 && car.Year != f.Year {
+		return false
+	}
+	if f.YearMin != 0 && car.Year < f.YearMin {
+		return false
+	}
+	if f.YearMax != 0 && car.Year > f.YearMax {
+		return false
+	}
+	if f.LocationId != "" && (car.LocationId == nil || *car.LocationId != f.LocationId) {
+		return false
+	}
+	if f.Status != "" && car.Status != f.Status {
+		return false
+	}
+	if f.PriceMin != 0 && (car.PriceMinor == nil || *car.PriceMinor < f.PriceMin) {
+		return false
+	}
+	if f.PriceMax != 0 && (car.PriceMinor == nil || *car.PriceMinor > f.PriceMax) {
+		return false
+	}
+	if f.Currency != "" && (car.Currency == nil || *car.Currency != f.Currency) {
+		return false
+	}
+	if f.MileageMin != 0 && (car.MileageKm == nil || *car.MileageKm < f.MileageMin) {
+		return false
+	}
+	if f.MileageMax != 0 && (car.MileageKm == nil || *car.MileageKm > f.MileageMax) {
+		return false
+	}
+	return true
+}
+
+func ListCars(db *clients.DBClient, filter CarFilter, limit int, offset int) ([]CarModel, error) {
+	return ListSortedCars(db, filter, CarOrder{}, limit, offset)
+}
+
+func ListSortedCars(db *clients.DBClient, filter CarFilter, order CarOrder, limit int, offset int) ([]CarModel, error) {
+	return ListCarColumns(db, filter, order, allCarColumns, limit, offset)
+}
+
+// Walk every car matching the filter through a server-side cursor, fetching
+// fetchSize rows at a time so memory stays flat whatever the table size.
+func StreamCars(db *clients.DBClient, filter CarFilter, fetchSize int, fn func(CarModel) error) error {
+	txn, err := db.Db.Begin()
+	if err != nil {
+		return fmt.Errorf("Could not STREAM cars %s", err)
+	}
+	// Read only, so rolling back is how the cursor gets cleaned up
+	defer txn.Rollback()
+
+	where, args := filter.Where(1)
+	declare := fmt.Sprintf(`
+		DECLARE cars_export NO SCROLL CURSOR FOR
+		SELECT id, model, make, color, year, tenant, location_id, status, price_minor, currency, mileage_km
+		FROM "cars" %s
+		ORDER BY id;
+	`, where)
+
+	_, err = txn.Exec(declare, args...)
+	if err != nil {
+		return fmt.Errorf("Could not STREAM cars %s", err)
+	}
+
+	fetch := fmt.Sprintf(`FETCH FORWARD %d FROM cars_export;`, fetchSize)
+	for {
+		rows, err := txn.Query(fetch)
+		if err != nil {
+			return fmt.Errorf("Could not STREAM cars %s", err)
+		}
+
+		fetched := 0
+		for rows.Next() {
+			var carModel CarModel
+			err = rows.Scan(
+				&carModel.Id,
+				&carModel.Model,
+				&carModel.Make,
+				&carModel.Color,
+				&carModel.Year,
+				&carModel.Tenant,
+				&carModel.LocationId,
+				&carModel.Status,
+				&carModel.PriceMinor,
+				&carModel.Currency,
+				&carModel.MileageKm,
+			)
+			if err == nil {
+				err = fn(carModel)
+			}
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			fetched++
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return fmt.Errorf("Could not STREAM cars %s", err)
+		}
+		if fetched < fetchSize {
+			return nil
+		}
+	}
+}
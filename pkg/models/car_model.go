@@ -1,72 +1,249 @@
 package models
 
 import (
+	"database/sql"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
+var ErrCarNotFound = errors.New("Car not found")
+
+// Tenant cars belong to when none is given
+const DefaultTenant = "default"
+
+// Satisfied by both *sql.DB and *sql.Tx so each query is written once and
+// the ...Tx variants can run inside a caller's transaction.
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 type CarModel struct {
-	Id    string `json:"id"`
-	Model string `json:"model"`
-	Make  string `json:"make"`
-	Color string `json:"color"`
-	Year  int    `json:"year"`
+	XMLName xml.Name `json:"-" xml:"car"`
+	Id      string   `json:"id" xml:"id"`
+	Model   string   `json:"model" xml:"model"`
+	Make    string   `json:"make" xml:"make"`
+	Color   string   `json:"color" xml:"color"`
+	Year    int      `json:"year" xml:"year"`
+	// Lifecycle state, set by the database and changed by transitions
+	Status string `json:"status,omitempty" xml:"status,omitempty"`
+	// The location the car sits at, left out until it's first moved
+	LocationId *string `json:"location_id,omitempty" xml:"location_id,omitempty"`
+	// List price in minor units of Currency, both left out until the car is
+	// priced. Changed through SetCarPrice so the history is kept.
+	PriceMinor *int64  `json:"price_minor,omitempty" xml:"price_minor,omitempty"`
+	Currency   *string `json:"currency,omitempty" xml:"currency,omitempty"`
+	// Kilometers on the latest odometer reading, left out until one is
+	// recorded. Changed through RecordOdometer.
+	MileageKm *int64 `json:"mileage_km,omitempty" xml:"mileage_km,omitempty"`
+	// The current owner, left out when the car has none or it wasn't loaded
+	Owner *Owner `json:"owner,omitempty" xml:"owner,omitempty"`
+	// Set from the caller's credentials, never from a payload
+	Tenant string `json:"-" xml:"-"`
+}
+
+func (car *CarModel) TenantOrDefault() string {
+	if car.Tenant == "" {
+		return DefaultTenant
+	}
+	return car.Tenant
 }
 
 func SaveCar(db *clients.DBClient, car *CarModel) (string, error) {
+	return saveCar(db.Db, car)
+}
+
+func SaveCarTx(txn *sql.Tx, car *CarModel) (string, error) {
+	return saveCar(txn, car)
+}
+
+func saveCar(q querier, car *CarModel) (string, error) {
+	NormalizeCar(car)
 	sqlStatement := `
-		INSERT INTO cars (id, model, make, color, year)
-		VALUES ($1, $2, $3, $4, $5) RETURNING id
+		INSERT INTO cars (id, model, make, color, year, tenant)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, status
 	`
 	var id string
 
-	err := db.Db.QueryRow(
+	err := q.QueryRow(
 		sqlStatement,
 		car.Id,
 		car.Model,
 		car.Make,
 		car.Color,
 		car.Year,
-	).Scan(&id)
+		car.TenantOrDefault(),
+	).Scan(&id, &car.Status)
 	if err != nil {
 		return "", fmt.Errorf("Could not SAVE car %s", err)
 	}
 	return id, nil
 }
 
-func DeleteCar(db *clients.DBClient, carId string) error {
+// Update one of the tenant's cars. Another tenant's car is ErrCarNotFound.
+func UpdateCar(db *clients.DBClient, tenant string, car *CarModel) error {
+	return updateCar(db.Db, tenant, car)
+}
+
+func UpdateCarTx(txn *sql.Tx, tenant string, car *CarModel) error {
+	return updateCar(txn, tenant, car)
+}
+
+func updateCar(q querier, tenant string, car *CarModel) error {
+	NormalizeCar(car)
 	sqlStatement := `
-		DELETE FROM cars
-		WHERE id = $1;
+		UPDATE cars
+		SET model = $2, make = $3, color = $4, year = $5
+		WHERE id = $1 AND tenant = $6;
 	`
 
-	_, err := db.Db.Exec(sqlStatement, carId)
+	result, err := q.Exec(
+		sqlStatement,
+		car.Id,
+		car.Model,
+		car.Make,
+		car.Color,
+		car.Year,
+		tenant,
+	)
+	if err != nil {
+		return fmt.Errorf("Could not UPDATE car %s", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Could not UPDATE car %s", err)
+	}
+	if updated == 0 {
+		return ErrCarNotFound
+	}
+	car.Tenant = tenant
+	return nil
+}
+
+// Save a batch of cars in one transaction using COPY. Either every car in the
+// batch is saved or none are.
+func CopyCars(db *clients.DBClient, cars []CarModel) error {
+	txn, err := db.Db.Begin()
+	if err != nil {
+		return fmt.Errorf("Could not COPY cars %s", err)
+	}
+
+	stmt, err := txn.Prepare(pq.CopyIn("cars", "id", "model", "make", "color", "year", "tenant"))
+	if err != nil {
+		txn.Rollback()
+		return fmt.Errorf("Could not COPY cars %s", err)
+	}
+
+	for _, car := range cars {
+		NormalizeCar(&car)
+		_, err = stmt.Exec(car.Id, car.Model, car.Make, car.Color, car.Year, car.TenantOrDefault())
+		if err != nil {
+			stmt.Close()
+			txn.Rollback()
+			return fmt.Errorf("Could not COPY cars %s", err)
+		}
+	}
+
+	// Flush buffered rows
+	_, err = stmt.Exec()
 	if err != nil {
-		return fmt.Errorf("Could not DELETE car %s", err)
+		stmt.Close()
+		txn.Rollback()
+		return fmt.Errorf("Could not COPY cars %s", err)
 	}
 
+	err = stmt.Close()
+	if err != nil {
+		txn.Rollback()
+		return fmt.Errorf("Could not COPY cars %s", err)
+	}
+
+	err = txn.Commit()
+	if err != nil {
+		return fmt.Errorf("Could not COPY cars %s", err)
+	}
+	return nil
+}
+
+// Delete one of the tenant's cars, leaving other tenants' cars alone
+func DeleteCar(db *clients.DBClient, tenant string, carId string) error {
+	_, err := deleteCar(db.Db, tenant, carId)
+	return err
+}
+
+// Unlike DeleteCar, deleting a car that doesn't exist is an error so a
+// transaction can be rolled back.
+func DeleteCarTx(txn *sql.Tx, tenant string, carId string) error {
+	deleted, err := deleteCar(txn, tenant, carId)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrCarNotFound
+	}
 	return nil
 }
 
-func GetCar(db *clients.DBClient, carId string) (CarModel, error) {
+func deleteCar(q querier, tenant string, carId string) (int64, error) {
+	sqlStatement := `
+		DELETE FROM cars
+		WHERE id = $1 AND tenant = $2;
+	`
+
+	result, err := q.Exec(sqlStatement, carId, tenant)
+	if err != nil {
+		return 0, fmt.Errorf("Could not DELETE car %s", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("Could not DELETE car %s", err)
+	}
+	return deleted, nil
+}
+
+// Get one of the tenant's cars. A missing car, or another tenant's, is
+// ErrCarNotFound.
+func GetCar(db *clients.DBClient, tenant string, carId string) (CarModel, error) {
+	return getCar(db.Db, tenant, carId)
+}
+
+func GetCarTx(txn *sql.Tx, tenant string, carId string) (CarModel, error) {
+	return getCar(txn, tenant, carId)
+}
+
+func getCar(q querier, tenant string, carId string) (CarModel, error) {
 	sqlStatement := `
-		SELECT id, model, make, color, year
-		FROM "cars" WHERE id = $1;
+		SELECT id, model, make, color, year, tenant, location_id, status, price_minor, currency, mileage_km
+		FROM "cars" WHERE id = $1 AND tenant = $2;
 	`
 	var carModel CarModel
 
-	err := db.Db.QueryRow(
+	err := q.QueryRow(
 		sqlStatement,
 		carId,
+		tenant,
 	).Scan(
 		&carModel.Id,
 		&carModel.Model,
 		&carModel.Make,
 		&carModel.Color,
 		&carModel.Year,
+		&carModel.Tenant,
+		&carModel.LocationId,
+		&carModel.Status,
+		&carModel.PriceMinor,
+		&carModel.Currency,
+		&carModel.MileageKm,
 	)
 
+	if err == sql.ErrNoRows {
+		return CarModel{}, ErrCarNotFound
+	}
 	if err != nil {
 		return CarModel{}, fmt.Errorf("Could not GET car %s", err)
 	}
@@ -74,3 +251,45 @@ func GetCar(db *clients.DBClient, carId string) (CarModel, error) {
 	return carModel, nil
 
 }
+
+// Get every one of the tenant's cars in ids with one query. Ids that don't
+// exist or belong to another tenant are left out.
+func GetCars(db *clients.DBClient, tenant string, ids []string) ([]CarModel, error) {
+	sqlStatement := `
+		SELECT id, model, make, color, year, tenant, location_id, status, price_minor, currency, mileage_km
+		FROM "cars" WHERE id = ANY($1::uuid[]) AND tenant = $2;
+	`
+
+	rows, err := db.Db.Query(sqlStatement, pq.Array(ids), tenant)
+	if err != nil {
+		return nil, fmt.Errorf("Could not GET cars %s", err)
+	}
+	defer rows.Close()
+
+	cars := []CarModel{}
+	for rows.Next() {
+		var carModel CarModel
+		err = rows.Scan(
+			&carModel.Id,
+			&carModel.Model,
+			&carModel.Make,
+			&carModel.Color,
+			&carModel.Year,
+			&carModel.Tenant,
+			&carModel.LocationId,
+			&carModel.Status,
+			&carModel.PriceMinor,
+			&carModel.Currency,
+			&carModel.MileageKm,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("Could not GET cars %s", err)
+		}
+		cars = append(cars, carModel)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Could not GET cars %s", err)
+	}
+	return cars, nil
+}
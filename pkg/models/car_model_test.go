@@ -1,6 +1,7 @@
 package models_test
 
 import (
+	"context"
 	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
 	"github.com/ericmcbride/go-dfw-testing/pkg/harness"
 	"github.com/ericmcbride/go-dfw-testing/pkg/models"
@@ -8,6 +9,7 @@ import (
 	"github.com/satori/go.uuid"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestMain(m *testing.M) {
@@ -79,7 +81,7 @@ func TestLookupCar(t *testing.T) {
 		t.Errorf("Expected: %s, got: %s", carId, id)
 	}
 
-	got, err := models.GetCar(&db, carId)
+	got, err := models.GetCar(&db, models.DefaultTenant, carId)
 	if err != nil {
 		t.Fatalf("Failed to lookup the database info %v", err)
 	}
@@ -87,10 +89,10 @@ func TestLookupCar(t *testing.T) {
 	if got.Id != carId {
 		t.Errorf("Expected: %s, got: %s", carId, got.Id)
 	}
-	if strings.EqualFold(got.Model, carModel.Model) {
+	if got.Model != carModel.Model {
 		t.Errorf("Expected: %s, got: %s", carModel.Model, got.Model)
 	}
-	if strings.EqualFold(got.Make, carModel.Make) {
+	if got.Make != carModel.Make {
 		t.Errorf("Expected: %s, got: %s", carModel.Make, got.Make)
 	}
 
@@ -123,12 +125,12 @@ func TestDeleteCar(t *testing.T) {
 		t.Errorf("Expected: %s, got: %s", carId, id)
 	}
 
-	err = models.DeleteCar(&db, carId)
+	err = models.DeleteCar(&db, models.DefaultTenant, carId)
 	if err != nil {
 		t.Fatalf("failed to delete car %v", err)
 	}
 
-	got, err = models.GetCar(&db, carId)
+	got, err = models.GetCar(&db, models.DefaultTenant, carId)
 	if err == nil {
 		t.Fatalf("Should be empty but got %v", got)
 	}
@@ -139,3 +141,255 @@ func TestDeleteCar(t *testing.T) {
 	harness.Truncate()
 
 }
+
+func TestCopyCars(t *testing.T) {
+	db, err := clients.NewDbConn()
+	if err != nil {
+		t.Errorf("failed to establish a connection to the database")
+	}
+	defer clients.Close(&db)
+
+	cars := []models.CarModel{
+		{Id: uuid.NewV4().String(), Model: "Camry", Make: "Toyota", Color: "red", Year: 2015},
+		{Id: uuid.NewV4().String(), Model: "Civic", Make: "Honda", Color: "blue", Year: 2016},
+	}
+
+	err = models.CopyCars(&db, cars)
+	if err != nil {
+		t.Fatalf("There was an error copying cars %s", err)
+	}
+
+	var count int
+	err = db.Db.QueryRow(`SELECT count(*) FROM "cars"`).Scan(&count)
+	if err != nil {
+		t.Fatalf("Failed to count cars %v", err)
+	}
+	if count != len(cars) {
+		t.Errorf("Expected: %d, got: %d", len(cars), count)
+	}
+	harness.Truncate()
+}
+
+func TestListCars(t *testing.T) {
+	db, err := clients.NewDbConn()
+	if err != nil {
+		t.Errorf("failed to establish a connection to the database")
+	}
+	defer clients.Close(&db)
+
+	cars := []models.CarModel{
+		{Id: uuid.NewV4().String(), Model: "Camry", Make: "Toyota", Color: "red", Year: 2015},
+		{Id: uuid.NewV4().String(), Model: "Corolla", Make: "Toyota", Color: "white", Year: 2018},
+		{Id: uuid.NewV4().String(), Model: "Civic", Make: "Honda", Color: "blue", Year: 2016},
+	}
+	err = models.CopyCars(&db, cars)
+	if err != nil {
+		t.Fatalf("There was an error copying cars %s", err)
+	}
+
+	got, err := models.ListCars(&db, models.CarFilter{Make: "Toyota", YearMin: 2016}, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to list cars %v", err)
+	}
+	if len(got) != 1 || got[0].Id != cars[1].Id {
+		t.Errorf("Expected only %s, got: %+v", cars[1].Id, got)
+	}
+
+	got, err = models.ListCars(&db, models.CarFilter{}, 2, 0)
+	if err != nil {
+		t.Fatalf("Failed to list cars %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("Expected: 2, got: %d", len(got))
+	}
+	harness.Truncate()
+}
+
+func TestStreamCars(t *testing.T) {
+	db, err := clients.NewDbConn()
+	if err != nil {
+		t.Errorf("failed to establish a connection to the database")
+	}
+	defer clients.Close(&db)
+
+	var cars []models.CarModel
+	for i := 0; i < 25; i++ {
+		cars = append(cars, models.CarModel{Id: uuid.NewV4().String(), Model: "Camry", Make: "Toyota", Color: "red", Year: 2000 + i})
+	}
+	err = models.CopyCars(&db, cars)
+	if err != nil {
+		t.Fatalf("There was an error copying cars %s", err)
+	}
+
+	// A fetch size smaller than the result set makes the cursor page
+	var streamed int
+	err = models.StreamCars(&db, models.CarFilter{YearMin: 2010}, 4, func(car models.CarModel) error {
+		streamed++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to stream cars %v", err)
+	}
+	if streamed != 15 {
+		t.Errorf("Expected: 15, got: %d", streamed)
+	}
+	harness.Truncate()
+}
+
+func TestUpdateCar(t *testing.T) {
+	db, err := clients.NewDbConn()
+	if err != nil {
+		t.Errorf("failed to establish a connection to the database")
+	}
+	defer clients.Close(&db)
+
+	carModel := &models.CarModel{
+		Id:    uuid.NewV4().String(),
+		Model: "Corolla",
+		Make:  "Toyota",
+		Color: "white",
+		Year:  2018,
+	}
+	_, err = models.SaveCar(&db, carModel)
+	if err != nil {
+		t.Fatalf("There was an error saving the carModel")
+	}
+
+	carModel.Year = 2019
+	err = models.UpdateCar(&db, models.DefaultTenant, carModel)
+	if err != nil {
+		t.Fatalf("failed to update car %v", err)
+	}
+
+	got, err := models.GetCar(&db, models.DefaultTenant, carModel.Id)
+	if err != nil {
+		t.Fatalf("Failed to lookup the database info %v", err)
+	}
+	if got.Year != 2019 {
+		t.Errorf("Expected: 2019, got: %d", got.Year)
+	}
+
+	missing := &models.CarModel{Id: uuid.NewV4().String(), Model: "Civic", Make: "Honda", Color: "blue", Year: 2016}
+	err = models.UpdateCar(&db, models.DefaultTenant, missing)
+	if err != models.ErrCarNotFound {
+		t.Errorf("Expected: %v, got: %v", models.ErrCarNotFound, err)
+	}
+	harness.Truncate()
+}
+
+func TestDeleteCarTxMissing(t *testing.T) {
+	db, err := clients.NewDbConn()
+	if err != nil {
+		t.Errorf("failed to establish a connection to the database")
+	}
+	defer clients.Close(&db)
+
+	txn, err := db.Db.Begin()
+	if err != nil {
+		t.Fatalf("failed to begin transaction %v", err)
+	}
+	defer txn.Rollback()
+
+	err = models.DeleteCarTx(txn, models.DefaultTenant, uuid.NewV4().String())
+	if err != models.ErrCarNotFound {
+		t.Errorf("Expected: %v, got: %v", models.ErrCarNotFound, err)
+	}
+}
+
+func TestCarChangeNotify(t *testing.T) {
+	db, err := clients.NewDbConn()
+	if err != nil {
+		t.Errorf("failed to establish a connection to the database")
+	}
+	defer clients.Close(&db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	payloads := make(chan string, 10)
+	go clients.Listen(ctx, "car_changes", func(payload string) {
+		payloads <- payload
+	}, nil)
+	// Give the listener time to LISTEN
+	time.Sleep(200 * time.Millisecond)
+
+	carModel := &models.CarModel{
+		Id:     uuid.NewV4().String(),
+		Model:  "Corolla",
+		Make:   "Toyota",
+		Color:  "white",
+		Year:   2018,
+		Tenant: "acme",
+	}
+	_, err = models.SaveCar(&db, carModel)
+	if err != nil {
+		t.Fatalf("There was an error saving the carModel")
+	}
+
+	select {
+	case payload := <-payloads:
+		if !strings.Contains(payload, `"type" : "created"`) || !strings.Contains(payload, carModel.Id) {
+			t.Errorf("Unexpected notification: %s", payload)
+		}
+		if !strings.Contains(payload, `"tenant" : "acme"`) {
+			t.Errorf("Expected the tenant in the notification: %s", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("No notification received")
+	}
+	harness.Truncate()
+}
+
+func TestCarTenantScope(t *testing.T) {
+	db, err := clients.NewDbConn()
+	if err != nil {
+		t.Errorf("failed to establish a connection to the database")
+	}
+	defer clients.Close(&db)
+	defer harness.Truncate()
+
+	carModel := &models.CarModel{
+		Id:     uuid.NewV4().String(),
+		Model:  "Corolla",
+		Make:   "Toyota",
+		Color:  "white",
+		Year:   2018,
+		Tenant: "acme",
+	}
+	if _, err = models.SaveCar(&db, carModel); err != nil {
+		t.Fatalf("There was an error saving the carModel")
+	}
+
+	if _, err = models.GetCar(&db, models.DefaultTenant, carModel.Id); err != models.ErrCarNotFound {
+		t.Errorf("Expected: %v, got: %v", models.ErrCarNotFound, err)
+	}
+	if _, err = models.GetCarColumns(&db, models.DefaultTenant, carModel.Id, []string{"id"}); err != models.ErrCarNotFound {
+		t.Errorf("Expected: %v, got: %v", models.ErrCarNotFound, err)
+	}
+	if cars, _ := models.GetCars(&db, models.DefaultTenant, []string{carModel.Id}); len(cars) != 0 {
+		t.Errorf("Expected no cars for another tenant, got %+v", cars)
+	}
+	if cars, _ := models.ListCars(&db, models.CarFilter{}, 10, 0); len(cars) != 0 {
+		t.Errorf("Expected the default tenant's listing to be empty, got %+v", cars)
+	}
+
+	update := *carModel
+	update.Year = 2019
+	if err = models.UpdateCar(&db, models.DefaultTenant, &update); err != models.ErrCarNotFound {
+		t.Errorf("Expected: %v, got: %v", models.ErrCarNotFound, err)
+	}
+	if err = models.DeleteCar(&db, models.DefaultTenant, carModel.Id); err != nil {
+		t.Fatalf("failed to delete car %v", err)
+	}
+
+	got, err := models.GetCar(&db, "acme", carModel.Id)
+	if err != nil {
+		t.Fatalf("Expected the car to survive another tenant's update and delete, got %v", err)
+	}
+	if got.Year != 2018 {
+		t.Errorf("Expected: 2018, got: %d", got.Year)
+	}
+	if cars, _ := models.ListCars(&db, models.CarFilter{Tenant: "acme"}, 10, 0); len(cars) != 1 {
+		t.Errorf("Expected the car in its own tenant's listing, got %+v", cars)
+	}
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/catalog"
+)
+
+// Longest make, model or color the cars table accepts, in characters
+const MaxCarTextLength = 128
+
+// Makes whose casing isn't just a capital at the start of each word, by
+// lower cased name. sql/migrations/001_cars_text_columns.sql has the same
+// list for the backfill.
+var CanonicalMakes = map[string]string{
+	"bmw":     "BMW",
+	"gmc":     "GMC",
+	"mini":    "MINI",
+	"mclaren": "McLaren",
+}
+
+// Tidy a car before it's written: make, model and color are trimmed with
+// inner whitespace collapsed to single spaces, and the make is cased the
+// canonical way
+func NormalizeCar(car *CarModel) {
+	car.Make = CanonicalMake(car.Make)
+	car.Model = NormalizeText(car.Model)
+	car.Color = NormalizeText(car.Color)
+}
+
+func NormalizeText(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// "  mercedes-benz " is "Mercedes-Benz" and "bmw" is "BMW". Makes in the
+// catalog are spelled the way it spells them, the rest are cased like
+// Postgres' initcap: each run of letters and digits starts with a capital
+// and the rest is lower case.
+func CanonicalMake(value string) string {
+	value = NormalizeText(value)
+	if canonical, ok := CanonicalMakes[strings.ToLower(value)]; ok {
+		return canonical
+	}
+	// Only the catalog's own name, aliases are for ValidateCarPayload
+	if known, ok := catalog.Default.FindMake(value); ok && catalog.Key(known.Name) == catalog.Key(value) {
+		return known.Name
+	}
+
+	var out strings.Builder
+	inWord := false
+	for _, r := range value {
+		alnum := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if alnum && !inWord {
+			out.WriteRune(unicode.ToUpper(r))
+		} else {
+			out.WriteRune(unicode.ToLower(r))
+		}
+		inWord = alnum
+	}
+	return out.String()
+}
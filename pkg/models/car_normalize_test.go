@@ -0,0 +1,69 @@
+package models_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/harness"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/satori/go.uuid"
+)
+
+func TestCanonicalMake(t *testing.T) {
+	cases := map[string]string{
+		"toyota":          "Toyota",
+		"  TOYOTA  ":      "Toyota",
+		"mercedes-benz":   "Mercedes-Benz",
+		"alfa   romeo":    "Alfa Romeo",
+		"bmw":             "BMW",
+		" Mclaren":        "McLaren",
+		"rolls-royce 2nd": "Rolls-Royce 2nd",
+		"citroën":         "Citroën",
+		"":                "",
+	}
+	for value, want := range cases {
+		if got := models.CanonicalMake(value); got != want {
+			t.Errorf("%q: expected %q, got %q", value, want, got)
+		}
+	}
+}
+
+func TestNormalizeText(t *testing.T) {
+	if got := models.NormalizeText(" \tCamry   LE\n"); got != "Camry LE" {
+		t.Errorf("Expected the whitespace collapsed, got %q", got)
+	}
+}
+
+func TestSaveCarNormalizes(t *testing.T) {
+	db, err := clients.NewDbConn()
+	if err != nil {
+		t.Fatalf("failed to establish a connection to the database")
+	}
+	defer clients.Close(&db)
+	defer harness.Truncate()
+
+	car := models.CarModel{Id: uuid.NewV4().String(), Make: " bmw ", Model: "M3  Competition", Color: "Black", Year: 2021}
+	if _, err := models.SaveCar(&db, &car); err != nil {
+		t.Fatalf("Couldn't save car %s", err)
+	}
+
+	got, err := models.GetCar(&db, models.DefaultTenant, car.Id)
+	if err != nil {
+		t.Fatalf("Couldn't get car %s", err)
+	}
+	if got.Make != "BMW" || got.Model != "M3 Competition" || got.Color != "Black" {
+		t.Errorf("Expected the car as normalized, got %+v", got)
+	}
+
+	cars, err := models.ListCars(&db, models.CarFilter{Make: "bmw", Model: "m3 competition"}, 10, 0)
+	if err != nil || len(cars) != 1 {
+		t.Errorf("Expected filters to ignore case, got %v %v", cars, err)
+	}
+
+	// The column limit holds even when validation is skipped
+	long := models.CarModel{Id: uuid.NewV4().String(), Make: "Toyota", Model: strings.Repeat("x", 129), Color: "red", Year: 2018}
+	if _, err := models.SaveCar(&db, &long); err == nil {
+		t.Errorf("Expected a model over %d characters to be rejected", models.MaxCarTextLength)
+	}
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/lib/pq"
+)
+
+// The text the search column and trigram index are built over. It has to
+// match the index expression in tables.sql for the index to be used.
+const carSearchDocument = `(coalesce(make::text, '') || ' ' || coalesce(model::text, '') || ' ' || coalesce(color::text, ''))`
+
+// A free text search like "red toyta camry 2015". Four digit numbers are
+// years, every other word has to match the make, model or color.
+type CarSearch struct {
+	Terms []string
+	Years []int
+}
+
+// Split a search into terms and years, lower cased, dropping punctuation
+func ParseCarSearch(q string) CarSearch {
+	var search CarSearch
+	words := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, word := range words {
+		if year, err := strconv.Atoi(word); err == nil && len(word) == 4 {
+			search.Years = append(search.Years, year)
+			continue
+		}
+		search.Terms = append(search.Terms, word)
+	}
+	return search
+}
+
+func (s CarSearch) Empty() bool {
+	return len(s.Terms) == 0 && len(s.Years) == 0
+}
+
+// A search hit. Rank is higher the better the car matches.
+type RankedCar struct {
+	Car  CarModel
+	Rank float64
+}
+
+// Search the tenant's cars, best match first. A term matches when it's a
+// whole word of the make, model or color, or close enough to one by trigram
+// word similarity (pg_trgm.word_similarity_threshold, 0.6 by default).
+func SearchCars(db *clients.DBClient, tenant string, search CarSearch, limit int, offset int) ([]RankedCar, error) {
+	var (
+		clauses []string
+		ranks   []string
+		args    []interface{}
+	)
+
+	for _, term := range search.Terms {
+		args = append(args, term)
+		n := len(args)
+		clauses = append(clauses, fmt.Sprintf(
+			"(search @@ plainto_tsquery('simple', $%d) OR $%d <%% %s)", n, n, carSearchDocument,
+		))
+		ranks = append(ranks, fmt.Sprintf(
+			"ts_rank(search, plainto_tsquery('simple', $%d)) + word_similarity($%d, %s)", n, n, carSearchDocument,
+		))
+	}
+	if len(search.Years) > 0 {
+		years := make([]int64, 0, len(search.Years))
+		for _, year := range search.Years {
+			years = append(years, int64(year))
+		}
+		args = append(args, pq.Array(years))
+		clauses = append(clauses, fmt.Sprintf("year = ANY($%d)", len(args)))
+	}
+	if len(clauses) == 0 {
+		return []RankedCar{}, nil
+	}
+	if len(ranks) == 0 {
+		ranks = append(ranks, "0")
+	}
+	args = append(args, tenant)
+	clauses = append(clauses, fmt.Sprintf("tenant = $%d", len(args)))
+
+	sqlStatement := fmt.Sprintf(`
+		SELECT id, model, make, color, year, tenant, location_id, status, price_minor, currency, mileage_km, %s AS rank
+		FROM "cars"
+		WHERE %s
+		ORDER BY rank DESC, id
+		LIMIT $%d OFFSET $%d;
+	`, strings.Join(ranks, " + "), strings.Join(clauses, " AND "), len(args)+1, len(args)+2)
+
+	rows, err := db.Db.Query(sqlStatement, append(args, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("Could not SEARCH cars %s", err)
+	}
+	defer rows.Close()
+
+	results := []RankedCar{}
+	for rows.Next() {
+		var result RankedCar
+		err = rows.Scan(
+			&result.Car.Id,
+			&result.Car.Model,
+			&result.Car.Make,
+			&result.Car.Color,
+			&result.Car.Year,
+			&result.Car.Tenant,
+			&result.Car.LocationId,
+			&result.Car.Status,
+			&result.Car.PriceMinor,
+			&result.Car.Currency,
+			&result.Car.MileageKm,
+			&result.Rank,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("Could not SEARCH cars %s", err)
+		}
+		results = append(results, result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Could not SEARCH cars %s", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,90 @@
+package models_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/harness"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/satori/go.uuid"
+)
+
+func TestParseCarSearch(t *testing.T) {
+	search := models.ParseCarSearch("  Red toyta, CAMRY 2015 le-2 ")
+	if !reflect.DeepEqual(search.Terms, []string{"red", "toyta", "camry", "le", "2"}) {
+		t.Errorf("Expected lower cased words, got %v", search.Terms)
+	}
+	if !reflect.DeepEqual(search.Years, []int{2015}) {
+		t.Errorf("Expected 2015 as a year, got %v", search.Years)
+	}
+
+	if !models.ParseCarSearch(" ,.- ").Empty() {
+		t.Errorf("Expected punctuation alone to be an empty search")
+	}
+}
+
+func TestSearchCars(t *testing.T) {
+	db, err := clients.NewDbConn()
+	if err != nil {
+		t.Fatalf("failed to establish a connection to the database")
+	}
+	defer clients.Close(&db)
+	defer harness.Truncate()
+
+	cars := []models.CarModel{
+		{Id: uuid.NewV4().String(), Make: "Toyota", Model: "Camry", Color: "Red", Year: 2015},
+		{Id: uuid.NewV4().String(), Make: "Toyota", Model: "Camry", Color: "Blue", Year: 2015},
+		{Id: uuid.NewV4().String(), Make: "Toyota", Model: "Corolla", Color: "Red", Year: 2015},
+		{Id: uuid.NewV4().String(), Make: "Toyota", Model: "Camry", Color: "Red", Year: 2012},
+	}
+	for i := range cars {
+		if _, err := models.SaveCar(&db, &cars[i]); err != nil {
+			t.Fatalf("Couldn't save car %s", err)
+		}
+	}
+
+	results, err := models.SearchCars(&db, models.DefaultTenant, models.ParseCarSearch("red toyta camry 2015"), 10, 0)
+	if err != nil {
+		t.Fatalf("Couldn't search %s", err)
+	}
+	if len(results) != 1 || results[0].Car.Id != cars[0].Id {
+		t.Fatalf("Expected only the red 2015 Camry, got %v", results)
+	}
+
+	// A misspelled word still matches, but ranks below the exact one
+	results, err = models.SearchCars(&db, models.DefaultTenant, models.ParseCarSearch("camry"), 10, 0)
+	if err != nil || len(results) != 3 {
+		t.Fatalf("Expected the three Camrys, got %v %v", results, err)
+	}
+	exact := results[0].Rank
+
+	results, err = models.SearchCars(&db, models.DefaultTenant, models.ParseCarSearch("camri"), 10, 0)
+	if err != nil || len(results) != 3 {
+		t.Fatalf("Expected the three Camrys for a typo, got %v %v", results, err)
+	}
+	if results[0].Rank >= exact {
+		t.Errorf("Expected a typo to rank below %f, got %f", exact, results[0].Rank)
+	}
+
+	page, err := models.SearchCars(&db, models.DefaultTenant, models.ParseCarSearch("toyota"), 2, 2)
+	if err != nil || len(page) != 2 {
+		t.Fatalf("Expected the second page of two, got %v %v", page, err)
+	}
+	if page[0].Car.Make != "Toyota" {
+		t.Errorf("Expected a Toyota, got %v", page[0].Car)
+	}
+	// Another tenant's exact match isn't found
+	other := models.CarModel{Id: uuid.NewV4().String(), Make: "Toyota", Model: "Camry", Color: "Red", Year: 2015, Tenant: "acme"}
+	if _, err := models.SaveCar(&db, &other); err != nil {
+		t.Fatalf("Couldn't save car %s", err)
+	}
+	results, err = models.SearchCars(&db, models.DefaultTenant, models.ParseCarSearch("red camry 2015"), 10, 0)
+	if err != nil || len(results) != 1 || results[0].Car.Id != cars[0].Id {
+		t.Errorf("Expected only the default tenant's red 2015 Camry, got %v %v", results, err)
+	}
+	results, err = models.SearchCars(&db, "acme", models.ParseCarSearch("camry"), 10, 0)
+	if err != nil || len(results) != 1 || results[0].Car.Id != other.Id {
+		t.Errorf("Expected only acme's Camry, got %v %v", results, err)
+	}
+}
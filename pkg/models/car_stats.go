@@ -0,0 +1,133 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+)
+
+// Something cars can be grouped by or summarized with, and the SQL for it.
+// Numeric columns come back as ints or floats, the rest as strings.
+type StatsColumn struct {
+	Name  string
+	Expr  string
+	Float bool
+	Int   bool
+}
+
+// What ?group_by= can name, in the order they're listed in errors
+var CarStatsGroups = []StatsColumn{
+	{Name: "make", Expr: "make"},
+	{Name: "model", Expr: "model"},
+	{Name: "color", Expr: "color"},
+	{Name: "year", Expr: "year", Int: true},
+	{Name: "decade", Expr: "year / 10 * 10", Int: true},
+	{Name: "status", Expr: "status"},
+}
+
+// What ?metrics= can name
+var CarStatsMetrics = []StatsColumn{
+	{Name: "count", Expr: "count(*)", Int: true},
+	{Name: "min_year", Expr: "min(year)", Int: true},
+	{Name: "max_year", Expr: "max(year)", Int: true},
+	{Name: "avg_year", Expr: "avg(year)::float8", Float: true},
+}
+
+func FindStatsColumn(columns []StatsColumn, name string) (StatsColumn, bool) {
+	for _, column := range columns {
+		if column.Name == name {
+			return column, true
+		}
+	}
+	return StatsColumn{}, false
+}
+
+func StatsColumnNames(columns []StatsColumn) []string {
+	names := make([]string, 0, len(columns))
+	for _, column := range columns {
+		names = append(names, column.Name)
+	}
+	return names
+}
+
+// Aggregate the filter's tenant's cars matching the filter. Each row has
+// the group values then the metrics, in the order given, with nil for
+// NULLs. Without any groups there's a single row totalling every matching
+// car.
+func CarStats(db *clients.DBClient, filter CarFilter, groups []StatsColumn, metrics []StatsColumn) ([][]interface{}, error) {
+	var selects, groupBy []string
+	for i, group := range groups {
+		selects = append(selects, group.Expr)
+		groupBy = append(groupBy, fmt.Sprint(i+1))
+	}
+	for _, metric := range metrics {
+		selects = append(selects, metric.Expr)
+	}
+	if len(selects) == 0 {
+		return nil, errors.New("Could not STAT cars, nothing to select")
+	}
+
+	where, args := filter.Where(1)
+	sqlStatement := fmt.Sprintf(`
+		SELECT %s
+		FROM "cars" %s
+	`, strings.Join(selects, ", "), where)
+	if len(groupBy) > 0 {
+		sqlStatement += fmt.Sprintf(`
+		GROUP BY %[1]s
+		ORDER BY %[1]s
+	`, strings.Join(groupBy, ", "))
+	}
+
+	rows, err := db.Db.Query(sqlStatement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Could not STAT cars %s", err)
+	}
+	defer rows.Close()
+
+	columns := append(append([]StatsColumn{}, groups...), metrics...)
+	results := [][]interface{}{}
+	for rows.Next() {
+		dest := make([]interface{}, len(columns))
+		for i, column := range columns {
+			switch {
+			case column.Float:
+				dest[i] = &sql.NullFloat64{}
+			case column.Int:
+				dest[i] = &sql.NullInt64{}
+			default:
+				dest[i] = &sql.NullString{}
+			}
+		}
+		if err = rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("Could not STAT cars %s", err)
+		}
+
+		row := make([]interface{}, len(columns))
+		for i, value := range dest {
+			switch value := value.(type) {
+			case *sql.NullFloat64:
+				if value.Valid {
+					row[i] = value.Float64
+				}
+			case *sql.NullInt64:
+				if value.Valid {
+					row[i] = value.Int64
+				}
+			case *sql.NullString:
+				if value.Valid {
+					row[i] = value.String
+				}
+			}
+		}
+		results = append(results, row)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Could not STAT cars %s", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,46 @@
+package models_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/harness"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/satori/go.uuid"
+)
+
+func TestCarStats(t *testing.T) {
+	db, err := clients.NewDbConn()
+	if err != nil {
+		t.Fatalf("failed to establish a connection to the database")
+	}
+	defer clients.Close(&db)
+	defer harness.Truncate()
+
+	for _, year := range []int{1999, 2004, 2009, 2015} {
+		car := models.CarModel{Id: uuid.NewV4().String(), Make: "Toyota", Model: "Camry", Color: "Red", Year: year}
+		if _, err := models.SaveCar(&db, &car); err != nil {
+			t.Fatalf("Couldn't save car %s", err)
+		}
+	}
+
+	decade, _ := models.FindStatsColumn(models.CarStatsGroups, "decade")
+	count, _ := models.FindStatsColumn(models.CarStatsMetrics, "count")
+	maxYear, _ := models.FindStatsColumn(models.CarStatsMetrics, "max_year")
+
+	rows, err := models.CarStats(&db, models.CarFilter{YearMin: 2000}, []models.StatsColumn{decade}, []models.StatsColumn{count, maxYear})
+	if err != nil {
+		t.Fatalf("Couldn't get stats %s", err)
+	}
+	expected := [][]interface{}{{int64(2000), int64(2), int64(2009)}, {int64(2010), int64(1), int64(2015)}}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected %v, got %v", expected, rows)
+	}
+
+	// Without groups every matching car is one row
+	rows, err = models.CarStats(&db, models.CarFilter{}, nil, []models.StatsColumn{count})
+	if err != nil || !reflect.DeepEqual(rows, [][]interface{}{{int64(4)}}) {
+		t.Errorf("Expected a count of 4, got %v %v", rows, err)
+	}
+}
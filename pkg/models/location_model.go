@@ -0,0 +1,464 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrDealershipNotFound = errors.New("Dealership not found")
+	ErrLocationNotFound   = errors.New("Location not found")
+	// Locations are deleted first, so a dealership can't take them with it
+	ErrDealershipHasLocations = errors.New("Dealership still has locations and can't be deleted")
+	// Cars have to be moved out before their location is deleted
+	ErrLocationHasCars   = errors.New("Location still holds cars and can't be deleted")
+	ErrAlreadyAtLocation = errors.New("Car is already at the location")
+)
+
+type Dealership struct {
+	XMLName   xml.Name  `json:"-" xml:"dealership"`
+	Id        string    `json:"id" xml:"id"`
+	Name      string    `json:"name" xml:"name"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	Tenant    string    `json:"-" xml:"-"`
+}
+
+type Location struct {
+	XMLName      xml.Name  `json:"-" xml:"location"`
+	Id           string    `json:"id" xml:"id"`
+	DealershipId string    `json:"dealership_id" xml:"dealership_id"`
+	Name         string    `json:"name" xml:"name"`
+	Address      string    `json:"address" xml:"address"`
+	CreatedAt    time.Time `json:"created_at" xml:"created_at"`
+	Tenant       string    `json:"-" xml:"-"`
+}
+
+// A car leaving one location for another. From is nil for a car's first
+// placement, and either side is nil once its location has been deleted.
+type Move struct {
+	XMLName        xml.Name  `json:"-" xml:"move"`
+	Id             int64     `json:"id" xml:"id"`
+	CarId          string    `json:"car_id" xml:"car_id"`
+	FromLocationId *string   `json:"from_location_id" xml:"from_location_id,omitempty"`
+	ToLocationId   *string   `json:"to_location_id" xml:"to_location_id,omitempty"`
+	Note           string    `json:"note" xml:"note"`
+	MovedAt        time.Time `json:"moved_at" xml:"moved_at"`
+}
+
+// How many cars sit at a location
+type LocationCount struct {
+	LocationId   string `json:"location_id" xml:"location_id,attr"`
+	DealershipId string `json:"dealership_id" xml:"dealership_id,attr"`
+	Name         string `json:"name" xml:"name,attr"`
+	Cars         int    `json:"cars" xml:",chardata"`
+}
+
+const (
+	dealershipColumns = `d.id, d.name, d.created_at, d.tenant`
+	locationColumns   = `l.id, l.dealership_id, l.name, l.address, l.created_at, l.tenant`
+	moveColumns       = `m.id, m.car_id, m.from_location_id, m.to_location_id, m.note, m.moved_at`
+)
+
+func scanDealership(row interface{ Scan(...interface{}) error }, dealership *Dealership) error {
+	return row.Scan(&dealership.Id, &dealership.Name, &dealership.CreatedAt, &dealership.Tenant)
+}
+
+func scanLocation(row interface{ Scan(...interface{}) error }, location *Location, extra ...interface{}) error {
+	return row.Scan(append([]interface{}{
+		&location.Id,
+		&location.DealershipId,
+		&location.Name,
+		&location.Address,
+		&location.CreatedAt,
+		&location.Tenant,
+	}, extra...)...)
+}
+
+func scanMove(row interface{ Scan(...interface{}) error }, move *Move) error {
+	return row.Scan(&move.Id, &move.CarId, &move.FromLocationId, &move.ToLocationId, &move.Note, &move.MovedAt)
+}
+
+func SaveDealership(db *clients.DBClient, dealership *Dealership) error {
+	sqlStatement := `
+		INSERT INTO dealerships (id, tenant, name)
+		VALUES ($1, $2, $3) RETURNING created_at
+	`
+
+	err := db.Db.QueryRow(sqlStatement, dealership.Id, dealership.Tenant, dealership.Name).Scan(&dealership.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("Could not SAVE dealership %s", err)
+	}
+	return nil
+}
+
+func UpdateDealership(db *clients.DBClient, dealership *Dealership) error {
+	sqlStatement := `
+		UPDATE dealerships SET name = $3
+		WHERE tenant = $1 AND id = $2 RETURNING created_at
+	`
+
+	err := db.Db.QueryRow(sqlStatement, dealership.Tenant, dealership.Id, dealership.Name).Scan(&dealership.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrDealershipNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("Could not UPDATE dealership %s", err)
+	}
+	return nil
+}
+
+func GetDealership(db *clients.DBClient, tenant string, dealershipId string) (Dealership, error) {
+	sqlStatement := `
+		SELECT ` + dealershipColumns + `
+		FROM dealerships d WHERE d.tenant = $1 AND d.id = $2;
+	`
+	var dealership Dealership
+
+	err := scanDealership(db.Db.QueryRow(sqlStatement, tenant, dealershipId), &dealership)
+	if err == sql.ErrNoRows {
+		return Dealership{}, ErrDealershipNotFound
+	}
+	if err != nil {
+		return Dealership{}, fmt.Errorf("Could not GET dealership %s", err)
+	}
+	return dealership, nil
+}
+
+func ListDealerships(db *clients.DBClient, tenant string, limit int, offset int) ([]Dealership, error) {
+	sqlStatement := `
+		SELECT ` + dealershipColumns + `
+		FROM dealerships d WHERE d.tenant = $1
+		ORDER BY d.name, d.id
+		LIMIT $2 OFFSET $3;
+	`
+
+	rows, err := db.Db.Query(sqlStatement, tenant, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("Could not LIST dealerships %s", err)
+	}
+	defer rows.Close()
+
+	dealerships := []Dealership{}
+	for rows.Next() {
+		var dealership Dealership
+		if err = scanDealership(rows, &dealership); err != nil {
+			return nil, fmt.Errorf("Could not LIST dealerships %s", err)
+		}
+		dealerships = append(dealerships, dealership)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Could not LIST dealerships %s", err)
+	}
+	return dealerships, nil
+}
+
+func DeleteDealership(db *clients.DBClient, tenant string, dealershipId string) error {
+	sqlStatement := `
+		DELETE FROM dealerships
+		WHERE tenant = $1 AND id = $2;
+	`
+
+	result, err := db.Db.Exec(sqlStatement, tenant, dealershipId)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "foreign_key_violation" {
+		return ErrDealershipHasLocations
+	}
+	if err != nil {
+		return fmt.Errorf("Could not DELETE dealership %s", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Could not DELETE dealership %s", err)
+	}
+	if deleted == 0 {
+		return ErrDealershipNotFound
+	}
+	return nil
+}
+
+// Save a location under one of the tenant's dealerships. A dealership that
+// doesn't exist, or belongs to another tenant, is ErrDealershipNotFound.
+func SaveLocation(db *clients.DBClient, location *Location) error {
+	sqlStatement := `
+		INSERT INTO locations (id, dealership_id, tenant, name, address)
+		SELECT $1, d.id, d.tenant, $4, $5
+		FROM dealerships d WHERE d.tenant = $3 AND d.id = $2
+		RETURNING created_at
+	`
+
+	err := db.Db.QueryRow(
+		sqlStatement,
+		location.Id,
+		location.DealershipId,
+		location.Tenant,
+		location.Name,
+		location.Address,
+	).Scan(&location.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrDealershipNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("Could not SAVE location %s", err)
+	}
+	return nil
+}
+
+// Replace a location's name and address. Locations stay with the
+// dealership they were made under.
+func UpdateLocation(db *clients.DBClient, location *Location) error {
+	sqlStatement := `
+		UPDATE locations SET name = $3, address = $4
+		WHERE tenant = $1 AND id = $2 RETURNING dealership_id, created_at
+	`
+
+	err := db.Db.QueryRow(
+		sqlStatement,
+		location.Tenant,
+		location.Id,
+		location.Name,
+		location.Address,
+	).Scan(&location.DealershipId, &location.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrLocationNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("Could not UPDATE location %s", err)
+	}
+	return nil
+}
+
+func GetLocation(db *clients.DBClient, tenant string, locationId string) (Location, error) {
+	sqlStatement := `
+		SELECT ` + locationColumns + `
+		FROM locations l WHERE l.tenant = $1 AND l.id = $2;
+	`
+	var location Location
+
+	err := scanLocation(db.Db.QueryRow(sqlStatement, tenant, locationId), &location)
+	if err == sql.ErrNoRows {
+		return Location{}, ErrLocationNotFound
+	}
+	if err != nil {
+		return Location{}, fmt.Errorf("Could not GET location %s", err)
+	}
+	return location, nil
+}
+
+// List the tenant's locations, only the dealership's when one is given
+func ListLocations(db *clients.DBClient, tenant string, dealershipId string, limit int, offset int) ([]Location, error) {
+	sqlStatement := `
+		SELECT ` + locationColumns + `
+		FROM locations l
+		WHERE l.tenant = $1 AND ($2 = '' OR l.dealership_id::text = $2)
+		ORDER BY l.name, l.id
+		LIMIT $3 OFFSET $4;
+	`
+
+	rows, err := db.Db.Query(sqlStatement, tenant, dealershipId, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("Could not LIST locations %s", err)
+	}
+	defer rows.Close()
+
+	locations := []Location{}
+	for rows.Next() {
+		var location Location
+		if err = scanLocation(rows, &location); err != nil {
+			return nil, fmt.Errorf("Could not LIST locations %s", err)
+		}
+		locations = append(locations, location)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Could not LIST locations %s", err)
+	}
+	return locations, nil
+}
+
+func DeleteLocation(db *clients.DBClient, tenant string, locationId string) error {
+	sqlStatement := `
+		DELETE FROM locations
+		WHERE tenant = $1 AND id = $2;
+	`
+
+	result, err := db.Db.Exec(sqlStatement, tenant, locationId)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "foreign_key_violation" {
+		return ErrLocationHasCars
+	}
+	if err != nil {
+		return fmt.Errorf("Could not DELETE location %s", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Could not DELETE location %s", err)
+	}
+	if deleted == 0 {
+		return ErrLocationNotFound
+	}
+	return nil
+}
+
+// The locations the cars sit at, keyed by car id. Cars without one are left
+// out.
+func CarLocations(db *clients.DBClient, carIds []string) (map[string]Location, error) {
+	sqlStatement := `
+		SELECT ` + locationColumns + `, c.id
+		FROM cars c JOIN locations l ON l.id = c.location_id
+		WHERE c.id = ANY($1::uuid[]);
+	`
+
+	rows, err := db.Db.Query(sqlStatement, pq.Array(carIds))
+	if err != nil {
+		return nil, fmt.Errorf("Could not GET locations %s", err)
+	}
+	defer rows.Close()
+
+	locations := make(map[string]Location)
+	for rows.Next() {
+		var (
+			location Location
+			carId    string
+		)
+		if err = scanLocation(rows, &location, &carId); err != nil {
+			return nil, fmt.Errorf("Could not GET locations %s", err)
+		}
+		locations[carId] = location
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Could not GET locations %s", err)
+	}
+	return locations, nil
+}
+
+// Move the car to the location and record the move, in one transaction.
+// The car row is locked so concurrent moves of a car run one at a time and
+// each one's from is the previous one's to.
+func MoveCar(db *clients.DBClient, tenant string, carId string, locationId string, note string) (Move, error) {
+	txn, err := db.Db.Begin()
+	if err != nil {
+		return Move{}, fmt.Errorf("Could not MOVE car %s", err)
+	}
+	defer txn.Rollback()
+
+	var from *string
+	err = txn.QueryRow(`SELECT location_id FROM cars WHERE tenant = $1 AND id = $2 FOR UPDATE;`, tenant, carId).Scan(&from)
+	if err == sql.ErrNoRows {
+		return Move{}, ErrCarNotFound
+	}
+	if err != nil {
+		return Move{}, fmt.Errorf("Could not MOVE car %s", err)
+	}
+
+	// Locking the location stops it being deleted before the car lands
+	var to string
+	err = txn.QueryRow(`SELECT id FROM locations WHERE tenant = $1 AND id = $2 FOR SHARE;`, tenant, locationId).Scan(&to)
+	if err == sql.ErrNoRows {
+		return Move{}, ErrLocationNotFound
+	}
+	if err != nil {
+		return Move{}, fmt.Errorf("Could not MOVE car %s", err)
+	}
+	if from != nil && *from == to {
+		return Move{}, ErrAlreadyAtLocation
+	}
+
+	if _, err = txn.Exec(`UPDATE cars SET location_id = $2 WHERE id = $1;`, carId, to); err != nil {
+		return Move{}, fmt.Errorf("Could not MOVE car %s", err)
+	}
+
+	var move Move
+	err = scanMove(txn.QueryRow(`
+		INSERT INTO car_moves AS m (car_id, tenant, from_location_id, to_location_id, note)
+		VALUES ($1, $2, $3, $4, $5) RETURNING `+moveColumns,
+		carId, tenant, from, to, note,
+	), &move)
+	if err != nil {
+		return Move{}, fmt.Errorf("Could not MOVE car %s", err)
+	}
+
+	if err = txn.Commit(); err != nil {
+		return Move{}, fmt.Errorf("Could not MOVE car %s", err)
+	}
+	return move, nil
+}
+
+// A car's moves, oldest first
+func ListMoves(db *clients.DBClient, tenant string, carId string) ([]Move, error) {
+	sqlStatement := `
+		SELECT ` + moveColumns + `
+		FROM car_moves m
+		WHERE m.tenant = $1 AND m.car_id = $2
+		ORDER BY m.moved_at, m.id;
+	`
+
+	rows, err := db.Db.Query(sqlStatement, tenant, carId)
+	if err != nil {
+		return nil, fmt.Errorf("Could not LIST moves %s", err)
+	}
+	defer rows.Close()
+
+	moves := []Move{}
+	for rows.Next() {
+		var move Move
+		if err = scanMove(rows, &move); err != nil {
+			return nil, fmt.Errorf("Could not LIST moves %s", err)
+		}
+		moves = append(moves, move)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Could not LIST moves %s", err)
+	}
+	return moves, nil
+}
+
+// How many of the tenant's cars sit at each of its locations, empty ones
+// included, and how many aren't at any. Only the dealership's locations are
+// counted when one is given, and unassigned is then zero.
+func CountInventory(db *clients.DBClient, tenant string, dealershipId string) ([]LocationCount, int, error) {
+	sqlStatement := `
+		SELECT l.id, l.dealership_id, l.name, count(c.id)
+		FROM locations l LEFT JOIN cars c ON c.location_id = l.id
+		WHERE l.tenant = $1 AND ($2 = '' OR l.dealership_id::text = $2)
+		GROUP BY l.id
+		ORDER BY l.name, l.id;
+	`
+
+	rows, err := db.Db.Query(sqlStatement, tenant, dealershipId)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Could not COUNT inventory %s", err)
+	}
+	defer rows.Close()
+
+	counts := []LocationCount{}
+	for rows.Next() {
+		var count LocationCount
+		if err = rows.Scan(&count.LocationId, &count.DealershipId, &count.Name, &count.Cars); err != nil {
+			return nil, 0, fmt.Errorf("Could not COUNT inventory %s", err)
+		}
+		counts = append(counts, count)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("Could not COUNT inventory %s", err)
+	}
+
+	if dealershipId != "" {
+		return counts, 0, nil
+	}
+
+	var unassigned int
+	err = db.Db.QueryRow(`SELECT count(*) FROM cars WHERE tenant = $1 AND location_id IS NULL;`, tenant).Scan(&unassigned)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Could not COUNT inventory %s", err)
+	}
+	return counts, unassigned, nil
+}
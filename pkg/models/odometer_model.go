@@ -0,0 +1,184 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/odometer"
+)
+
+// One odometer reading, as read in Unit and converted to whole kilometers.
+// SuspectedRollback is set when the reading is lower than one taken on or
+// before ReadOn, or higher than one taken after it.
+type OdometerReading struct {
+	XMLName           xml.Name  `json:"-" xml:"reading"`
+	Id                int64     `json:"id" xml:"id"`
+	CarId             string    `json:"car_id" xml:"car_id"`
+	Value             int64     `json:"value" xml:"value"`
+	Unit              string    `json:"unit" xml:"unit"`
+	Km                int64     `json:"km" xml:"km"`
+	ReadOn            string    `json:"read_on" xml:"read_on"`
+	Source            string    `json:"source" xml:"source"`
+	SuspectedRollback bool      `json:"suspected_rollback" xml:"suspected_rollback"`
+	CreatedAt         time.Time `json:"created_at" xml:"created_at"`
+}
+
+const odometerColumns = `o.id, o.car_id, o.value, o.unit, o.km, o.read_on::text, o.source, o.suspected_rollback, o.created_at`
+
+func scanOdometer(row interface{ Scan(...interface{}) error }, reading *OdometerReading) error {
+	return row.Scan(
+		&reading.Id,
+		&reading.CarId,
+		&reading.Value,
+		&reading.Unit,
+		&reading.Km,
+		&reading.ReadOn,
+		&reading.Source,
+		&reading.SuspectedRollback,
+		&reading.CreatedAt,
+	)
+}
+
+// Record a reading and set the car's mileage from its latest one, in one
+// transaction. The reading is checked against the highest one taken on or
+// before it and the lowest one taken after it, so readings can be
+// backfilled; a backfilled reading is flagged itself rather than reflagging
+// later ones. The car row is locked so two readings can't both miss each
+// other.
+func RecordOdometer(db *clients.DBClient, tenant string, carId string, reading *OdometerReading) error {
+	txn, err := db.Db.Begin()
+	if err != nil {
+		return fmt.Errorf("Could not RECORD odometer %s", err)
+	}
+	defer txn.Rollback()
+
+	var locked string
+	err = txn.QueryRow(`
+		SELECT id FROM cars WHERE tenant = $1 AND id = $2 FOR UPDATE;
+	`, tenant, carId).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return ErrCarNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("Could not RECORD odometer %s", err)
+	}
+
+	if err = recordOdometerTx(txn, tenant, carId, reading); err != nil {
+		return err
+	}
+
+	if err = txn.Commit(); err != nil {
+		return fmt.Errorf("Could not RECORD odometer %s", err)
+	}
+	return nil
+}
+
+// Record a reading on a car the caller has locked
+func recordOdometerTx(txn *sql.Tx, tenant string, carId string, reading *OdometerReading) error {
+	var (
+		earlier     int64
+		earlierUnit string
+	)
+	err := txn.QueryRow(`
+		SELECT value, unit FROM odometer_readings
+		WHERE car_id = $1 AND read_on <= $2
+		ORDER BY km DESC, id DESC
+		LIMIT 1;
+	`, carId, reading.ReadOn).Scan(&earlier, &earlierUnit)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return fmt.Errorf("Could not RECORD odometer %s", err)
+	default:
+		reading.SuspectedRollback = odometer.Rollback(reading.Value, reading.Unit, earlier, earlierUnit)
+	}
+
+	var (
+		later     int64
+		laterUnit string
+	)
+	err = txn.QueryRow(`
+		SELECT value, unit FROM odometer_readings
+		WHERE car_id = $1 AND read_on > $2
+		ORDER BY km, id
+		LIMIT 1;
+	`, carId, reading.ReadOn).Scan(&later, &laterUnit)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return fmt.Errorf("Could not RECORD odometer %s", err)
+	default:
+		// A backfilled reading above a later one means the later one went back
+		reading.SuspectedRollback = reading.SuspectedRollback || odometer.Rollback(later, laterUnit, reading.Value, reading.Unit)
+	}
+
+	reading.CarId = carId
+	reading.Km = odometer.ToKm(reading.Value, reading.Unit)
+	err = scanOdometer(txn.QueryRow(`
+		INSERT INTO odometer_readings AS o (car_id, tenant, value, unit, km, read_on, source, suspected_rollback)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING `+odometerColumns,
+		carId, tenant, reading.Value, reading.Unit, reading.Km, reading.ReadOn, reading.Source, reading.SuspectedRollback,
+	), reading)
+	if err != nil {
+		return fmt.Errorf("Could not RECORD odometer %s", err)
+	}
+
+	_, err = txn.Exec(`
+		UPDATE cars SET mileage_km = (
+			SELECT km FROM odometer_readings WHERE car_id = $1 ORDER BY read_on DESC, id DESC LIMIT 1
+		) WHERE id = $1;
+	`, carId)
+	if err != nil {
+		return fmt.Errorf("Could not RECORD odometer %s", err)
+	}
+	return nil
+}
+
+// A car's odometer readings, oldest first
+func ListOdometer(db *clients.DBClient, tenant string, carId string) ([]OdometerReading, error) {
+	sqlStatement := `
+		SELECT ` + odometerColumns + `
+		FROM odometer_readings o
+		WHERE o.tenant = $1 AND o.car_id = $2
+		ORDER BY o.read_on, o.id;
+	`
+
+	rows, err := db.Db.Query(sqlStatement, tenant, carId)
+	if err != nil {
+		return nil, fmt.Errorf("Could not LIST odometer %s", err)
+	}
+	defer rows.Close()
+
+	readings := []OdometerReading{}
+	for rows.Next() {
+		var reading OdometerReading
+		if err = scanOdometer(rows, &reading); err != nil {
+			return nil, fmt.Errorf("Could not LIST odometer %s", err)
+		}
+		readings = append(readings, reading)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Could not LIST odometer %s", err)
+	}
+	return readings, nil
+}
+
+// The date of a car's first odometer reading, the earliest the car is known
+// to have been on the road, or empty when it has none
+func FirstReadingOn(db *clients.DBClient, tenant string, carId string) (string, error) {
+	sqlStatement := `
+		SELECT coalesce(min(read_on)::text, '')
+		FROM odometer_readings
+		WHERE tenant = $1 AND car_id = $2;
+	`
+
+	var on string
+	if err := db.Db.QueryRow(sqlStatement, tenant, carId).Scan(&on); err != nil {
+		return "", fmt.Errorf("Could not GET first odometer reading %s", err)
+	}
+	return on, nil
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+)
+
+// A car change waiting in the outbox to be published. Payload is the
+// CarEvent JSON.
+type OutboxMessage struct {
+	Id        int64
+	EventId   int64
+	EventType string
+	Tenant    string
+	Payload   json.RawMessage
+	Attempts  int
+	CreatedAt time.Time
+}
+
+// Lock up to limit unpublished messages that aren't dead, oldest first, for
+// the rest of the transaction. Messages another transaction already holds are skipped, so
+// several relays can drain the outbox at once.
+func ClaimOutboxTx(txn *sql.Tx, limit int) ([]OutboxMessage, error) {
+	sqlStatement := `
+		SELECT id, event_id, event_type, tenant, payload, attempts, created_at
+		FROM outbox
+		WHERE published_at IS NULL AND dead_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED;
+	`
+
+	rows, err := txn.Query(sqlStatement, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Could not CLAIM outbox %s", err)
+	}
+	defer rows.Close()
+
+	messages := []OutboxMessage{}
+	for rows.Next() {
+		var message OutboxMessage
+		err = rows.Scan(
+			&message.Id,
+			&message.EventId,
+			&message.EventType,
+			&message.Tenant,
+			&message.Payload,
+			&message.Attempts,
+			&message.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("Could not CLAIM outbox %s", err)
+		}
+		messages = append(messages, message)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Could not CLAIM outbox %s", err)
+	}
+	return messages, nil
+}
+
+func MarkPublishedTx(txn *sql.Tx, messageId int64) error {
+	sqlStatement := `
+		UPDATE outbox
+		SET published_at = now(), attempts = attempts + 1, last_error = ''
+		WHERE id = $1;
+	`
+
+	_, err := txn.Exec(sqlStatement, messageId)
+	if err != nil {
+		return fmt.Errorf("Could not PUBLISH outbox message %s", err)
+	}
+	return nil
+}
+
+// Record a failed attempt, marking the message dead once it has failed
+// maxAttempts times. Reports whether it's dead.
+func MarkFailedTx(txn *sql.Tx, messageId int64, reason string, maxAttempts int) (bool, error) {
+	sqlStatement := `
+		UPDATE outbox
+		SET attempts = attempts + 1, last_error = $2,
+			dead_at = CASE WHEN attempts + 1 >= $3 THEN now() END
+		WHERE id = $1
+		RETURNING dead_at IS NOT NULL;
+	`
+
+	var dead bool
+	err := txn.QueryRow(sqlStatement, messageId, reason, maxAttempts).Scan(&dead)
+	if err != nil {
+		return false, fmt.Errorf("Could not FAIL outbox message %s", err)
+	}
+	return dead, nil
+}
+
+// Delete messages published before the cutoff. Returns how many were deleted.
+func PruneOutbox(db *clients.DBClient, before time.Time) (int64, error) {
+	sqlStatement := `
+		DELETE FROM outbox
+		WHERE published_at < $1;
+	`
+
+	result, err := db.Db.Exec(sqlStatement, before)
+	if err != nil {
+		return 0, fmt.Errorf("Could not PRUNE outbox %s", err)
+	}
+
+	pruned, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("Could not PRUNE outbox %s", err)
+	}
+	return pruned, nil
+}
@@ -0,0 +1,316 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrOwnerNotFound = errors.New("Owner not found")
+	// Owners keep their ownership history, so they can't be deleted once
+	// they've owned a car
+	ErrOwnerHasCars = errors.New("Owner has owned cars and can't be deleted")
+	// The new ownership would start on or before the current one did, or
+	// cross an earlier one
+	ErrOwnershipOverlap = errors.New("Transfer overlaps an existing ownership")
+	ErrAlreadyOwner     = errors.New("Owner already owns the car")
+)
+
+// DateLayout is how ownership dates are written, a plain calendar date
+const DateLayout = "2006-01-02"
+
+type Owner struct {
+	XMLName   xml.Name  `json:"-" xml:"owner"`
+	Id        string    `json:"id" xml:"id"`
+	Name      string    `json:"name" xml:"name"`
+	Email     string    `json:"email" xml:"email"`
+	Phone     string    `json:"phone" xml:"phone"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	Tenant    string    `json:"-" xml:"-"`
+}
+
+// One owner's time with a car, from StartedOn up to but not including
+// EndedOn. The current ownership has no EndedOn.
+type Ownership struct {
+	Id        int64   `json:"id" xml:"id"`
+	CarId     string  `json:"car_id" xml:"car_id"`
+	Owner     Owner   `json:"owner" xml:"owner"`
+	StartedOn string  `json:"started_on" xml:"started_on"`
+	EndedOn   *string `json:"ended_on" xml:"ended_on,omitempty"`
+}
+
+// What a transfer closed and opened. Previous is nil for a car's first owner.
+type Transfer struct {
+	XMLName  xml.Name   `json:"-" xml:"transfer"`
+	Previous *Ownership `json:"previous" xml:"previous,omitempty"`
+	Current  Ownership  `json:"current" xml:"current"`
+}
+
+const ownerColumns = `o.id, o.name, o.email, o.phone, o.created_at, o.tenant`
+
+func scanOwner(row interface{ Scan(...interface{}) error }, owner *Owner, extra ...interface{}) error {
+	return row.Scan(append([]interface{}{
+		&owner.Id,
+		&owner.Name,
+		&owner.Email,
+		&owner.Phone,
+		&owner.CreatedAt,
+		&owner.Tenant,
+	}, extra...)...)
+}
+
+func SaveOwner(db *clients.DBClient, owner *Owner) error {
+	sqlStatement := `
+		INSERT INTO owners (id, tenant, name, email, phone)
+		VALUES ($1, $2, $3, $4, $5) RETURNING created_at
+	`
+
+	err := db.Db.QueryRow(
+		sqlStatement,
+		owner.Id,
+		owner.Tenant,
+		owner.Name,
+		owner.Email,
+		owner.Phone,
+	).Scan(&owner.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("Could not SAVE owner %s", err)
+	}
+	return nil
+}
+
+// Replace an owner's name, email and phone
+func UpdateOwner(db *clients.DBClient, owner *Owner) error {
+	sqlStatement := `
+		UPDATE owners SET name = $3, email = $4, phone = $5
+		WHERE tenant = $1 AND id = $2 RETURNING created_at
+	`
+
+	err := db.Db.QueryRow(
+		sqlStatement,
+		owner.Tenant,
+		owner.Id,
+		owner.Name,
+		owner.Email,
+		owner.Phone,
+	).Scan(&owner.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrOwnerNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("Could not UPDATE owner %s", err)
+	}
+	return nil
+}
+
+func GetOwner(db *clients.DBClient, tenant string, ownerId string) (Owner, error) {
+	sqlStatement := `
+		SELECT ` + ownerColumns + `
+		FROM owners o WHERE o.tenant = $1 AND o.id = $2;
+	`
+	var owner Owner
+
+	err := scanOwner(db.Db.QueryRow(sqlStatement, tenant, ownerId), &owner)
+	if err == sql.ErrNoRows {
+		return Owner{}, ErrOwnerNotFound
+	}
+	if err != nil {
+		return Owner{}, fmt.Errorf("Could not GET owner %s", err)
+	}
+	return owner, nil
+}
+
+func ListOwners(db *clients.DBClient, tenant string, limit int, offset int) ([]Owner, error) {
+	sqlStatement := `
+		SELECT ` + ownerColumns + `
+		FROM owners o WHERE o.tenant = $1
+		ORDER BY o.name, o.id
+		LIMIT $2 OFFSET $3;
+	`
+
+	rows, err := db.Db.Query(sqlStatement, tenant, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("Could not LIST owners %s", err)
+	}
+	defer rows.Close()
+
+	owners := []Owner{}
+	for rows.Next() {
+		var owner Owner
+		if err = scanOwner(rows, &owner); err != nil {
+			return nil, fmt.Errorf("Could not LIST owners %s", err)
+		}
+		owners = append(owners, owner)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Could not LIST owners %s", err)
+	}
+	return owners, nil
+}
+
+func DeleteOwner(db *clients.DBClient, tenant string, ownerId string) error {
+	sqlStatement := `
+		DELETE FROM owners
+		WHERE tenant = $1 AND id = $2;
+	`
+
+	result, err := db.Db.Exec(sqlStatement, tenant, ownerId)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "foreign_key_violation" {
+		return ErrOwnerHasCars
+	}
+	if err != nil {
+		return fmt.Errorf("Could not DELETE owner %s", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Could not DELETE owner %s", err)
+	}
+	if deleted == 0 {
+		return ErrOwnerNotFound
+	}
+	return nil
+}
+
+// Each car's current owner, keyed by car id. Cars without one are left out.
+func CurrentOwners(db *clients.DBClient, carIds []string) (map[string]Owner, error) {
+	sqlStatement := `
+		SELECT ` + ownerColumns + `, co.car_id
+		FROM car_ownerships co JOIN owners o ON o.id = co.owner_id
+		WHERE co.car_id = ANY($1::uuid[]) AND co.ended_on IS NULL;
+	`
+
+	rows, err := db.Db.Query(sqlStatement, pq.Array(carIds))
+	if err != nil {
+		return nil, fmt.Errorf("Could not GET owners %s", err)
+	}
+	defer rows.Close()
+
+	owners := make(map[string]Owner)
+	for rows.Next() {
+		var (
+			owner Owner
+			carId string
+		)
+		if err = scanOwner(rows, &owner, &carId); err != nil {
+			return nil, fmt.Errorf("Could not GET owners %s", err)
+		}
+		owners[carId] = owner
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Could not GET owners %s", err)
+	}
+	return owners, nil
+}
+
+// A car's ownerships, oldest first
+func ListOwnerships(db *clients.DBClient, tenant string, carId string) ([]Ownership, error) {
+	sqlStatement := `
+		SELECT ` + ownerColumns + `, co.id, co.car_id, co.started_on::text, co.ended_on::text
+		FROM car_ownerships co JOIN owners o ON o.id = co.owner_id
+		WHERE co.tenant = $1 AND co.car_id = $2
+		ORDER BY co.started_on;
+	`
+
+	rows, err := db.Db.Query(sqlStatement, tenant, carId)
+	if err != nil {
+		return nil, fmt.Errorf("Could not LIST ownerships %s", err)
+	}
+	defer rows.Close()
+
+	ownerships := []Ownership{}
+	for rows.Next() {
+		var ownership Ownership
+		err = scanOwner(rows, &ownership.Owner, &ownership.Id, &ownership.CarId, &ownership.StartedOn, &ownership.EndedOn)
+		if err != nil {
+			return nil, fmt.Errorf("Could not LIST ownerships %s", err)
+		}
+		ownerships = append(ownerships, ownership)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Could not LIST ownerships %s", err)
+	}
+	return ownerships, nil
+}
+
+// Pass the car to the owner on effective: the current ownership, if there
+// is one, ends that day and the new one starts it, in one transaction. The
+// car row is locked so concurrent transfers of a car run one at a time.
+func TransferCar(db *clients.DBClient, tenant string, carId string, ownerId string, effective time.Time) (Transfer, error) {
+	var transfer Transfer
+	day := effective.Format(DateLayout)
+
+	txn, err := db.Db.Begin()
+	if err != nil {
+		return transfer, fmt.Errorf("Could not TRANSFER car %s", err)
+	}
+	defer txn.Rollback()
+
+	var locked string
+	err = txn.QueryRow(`SELECT id FROM cars WHERE tenant = $1 AND id = $2 FOR UPDATE;`, tenant, carId).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return transfer, ErrCarNotFound
+	}
+	if err != nil {
+		return transfer, fmt.Errorf("Could not TRANSFER car %s", err)
+	}
+
+	var owner Owner
+	err = scanOwner(txn.QueryRow(`SELECT `+ownerColumns+` FROM owners o WHERE o.tenant = $1 AND o.id = $2;`, tenant, ownerId), &owner)
+	if err == sql.ErrNoRows {
+		return transfer, ErrOwnerNotFound
+	}
+	if err != nil {
+		return transfer, fmt.Errorf("Could not TRANSFER car %s", err)
+	}
+
+	var previous Ownership
+	err = scanOwner(txn.QueryRow(`
+		SELECT `+ownerColumns+`, co.id, co.car_id, co.started_on::text
+		FROM car_ownerships co JOIN owners o ON o.id = co.owner_id
+		WHERE co.car_id = $1 AND co.ended_on IS NULL;
+	`, carId), &previous.Owner, &previous.Id, &previous.CarId, &previous.StartedOn)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return transfer, fmt.Errorf("Could not TRANSFER car %s", err)
+	case previous.Owner.Id == ownerId:
+		return transfer, ErrAlreadyOwner
+	case day <= previous.StartedOn:
+		return transfer, ErrOwnershipOverlap
+	default:
+		_, err = txn.Exec(`UPDATE car_ownerships SET ended_on = $2 WHERE id = $1;`, previous.Id, day)
+		if err != nil {
+			return transfer, fmt.Errorf("Could not TRANSFER car %s", err)
+		}
+		previous.EndedOn = &day
+		transfer.Previous = &previous
+	}
+
+	transfer.Current = Ownership{CarId: carId, Owner: owner, StartedOn: day}
+	err = txn.QueryRow(`
+		INSERT INTO car_ownerships (car_id, owner_id, tenant, started_on)
+		VALUES ($1, $2, $3, $4) RETURNING id
+	`, carId, ownerId, tenant, day).Scan(&transfer.Current.Id)
+	// An earlier, closed ownership that runs past the effective date
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "exclusion_violation" {
+		return Transfer{}, ErrOwnershipOverlap
+	}
+	if err != nil {
+		return Transfer{}, fmt.Errorf("Could not TRANSFER car %s", err)
+	}
+
+	if err = txn.Commit(); err != nil {
+		return Transfer{}, fmt.Errorf("Could not TRANSFER car %s", err)
+	}
+	return transfer, nil
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/money"
+)
+
+var ErrSamePrice = errors.New("Car already has that price")
+
+// One list price a car had, who set it and why. Amount is PriceMinor
+// written as a decimal, for display.
+type PriceChange struct {
+	XMLName    xml.Name  `json:"-" xml:"price"`
+	Id         int64     `json:"id" xml:"id"`
+	CarId      string    `json:"car_id" xml:"car_id"`
+	PriceMinor int64     `json:"price_minor" xml:"price_minor"`
+	Currency   string    `json:"currency" xml:"currency"`
+	Amount     string    `json:"amount" xml:"amount"`
+	Actor      string    `json:"actor" xml:"actor"`
+	Reason     string    `json:"reason" xml:"reason"`
+	ChangedAt  time.Time `json:"changed_at" xml:"changed_at"`
+}
+
+const priceColumns = `p.id, p.car_id, p.price_minor, p.currency, p.actor, p.reason, p.changed_at`
+
+func scanPrice(row interface{ Scan(...interface{}) error }, price *PriceChange) error {
+	err := row.Scan(
+		&price.Id,
+		&price.CarId,
+		&price.PriceMinor,
+		&price.Currency,
+		&price.Actor,
+		&price.Reason,
+		&price.ChangedAt,
+	)
+	price.Amount = money.Format(price.PriceMinor, price.Currency)
+	return err
+}
+
+// Give the car a new list price and record it in its history, in one
+// transaction. The car row is locked so concurrent changes are recorded in
+// the order they're made.
+func SetCarPrice(db *clients.DBClient, tenant string, carId string, priceMinor int64, currency string, actor string, reason string) (PriceChange, error) {
+	txn, err := db.Db.Begin()
+	if err != nil {
+		return PriceChange{}, fmt.Errorf("Could not PRICE car %s", err)
+	}
+	defer txn.Rollback()
+
+	var (
+		current         *int64
+		currentCurrency *string
+	)
+	err = txn.QueryRow(`
+		SELECT price_minor, currency FROM cars WHERE tenant = $1 AND id = $2 FOR UPDATE;
+	`, tenant, carId).Scan(&current, &currentCurrency)
+	if err == sql.ErrNoRows {
+		return PriceChange{}, ErrCarNotFound
+	}
+	if err != nil {
+		return PriceChange{}, fmt.Errorf("Could not PRICE car %s", err)
+	}
+	if current != nil && *current == priceMinor && *currentCurrency == currency {
+		return PriceChange{}, ErrSamePrice
+	}
+
+	_, err = txn.Exec(`UPDATE cars SET price_minor = $2, currency = $3 WHERE id = $1;`, carId, priceMinor, currency)
+	if err != nil {
+		return PriceChange{}, fmt.Errorf("Could not PRICE car %s", err)
+	}
+
+	var price PriceChange
+	err = scanPrice(txn.QueryRow(`
+		INSERT INTO price_history AS p (car_id, tenant, price_minor, currency, actor, reason)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING `+priceColumns,
+		carId, tenant, priceMinor, currency, actor, reason,
+	), &price)
+	if err != nil {
+		return PriceChange{}, fmt.Errorf("Could not PRICE car %s", err)
+	}
+
+	if err = txn.Commit(); err != nil {
+		return PriceChange{}, fmt.Errorf("Could not PRICE car %s", err)
+	}
+	return price, nil
+}
+
+// A car's list prices, oldest first
+func ListPrices(db *clients.DBClient, tenant string, carId string) ([]PriceChange, error) {
+	sqlStatement := `
+		SELECT ` + priceColumns + `
+		FROM price_history p
+		WHERE p.tenant = $1 AND p.car_id = $2
+		ORDER BY p.changed_at, p.id;
+	`
+
+	rows, err := db.Db.Query(sqlStatement, tenant, carId)
+	if err != nil {
+		return nil, fmt.Errorf("Could not LIST prices %s", err)
+	}
+	defer rows.Close()
+
+	prices := []PriceChange{}
+	for rows.Next() {
+		var price PriceChange
+		if err = scanPrice(rows, &price); err != nil {
+			return nil, fmt.Errorf("Could not LIST prices %s", err)
+		}
+		prices = append(prices, price)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Could not LIST prices %s", err)
+	}
+	return prices, nil
+}
@@ -0,0 +1,341 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/lifecycle"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrCarReserved   = errors.New("Car already has an active reservation")
+	ErrNoReservation = errors.New("Car has no active reservation")
+)
+
+const (
+	ReservationActive    = "active"
+	ReservationReleased  = "released"
+	ReservationExpired   = "expired"
+	ReservationFulfilled = "fulfilled"
+
+	// Who the releases of expired holds are recorded as
+	ExpiryActor = "reservations"
+)
+
+// How a car's active reservation ends when it's transitioned directly
+var reservationEndings = map[string]string{
+	"release": ReservationReleased,
+	"sell":    ReservationFulfilled,
+}
+
+// A hold on a car for a customer until ExpiresAt. Only an active
+// reservation holds the car; the others are its history.
+type Reservation struct {
+	XMLName      xml.Name   `json:"-" xml:"reservation"`
+	Id           string     `json:"id" xml:"id"`
+	CarId        string     `json:"car_id" xml:"car_id"`
+	HolderName   string     `json:"holder_name" xml:"holder_name"`
+	HolderEmail  string     `json:"holder_email" xml:"holder_email"`
+	HolderPhone  string     `json:"holder_phone" xml:"holder_phone"`
+	DepositCents int64      `json:"deposit_cents" xml:"deposit_cents"`
+	Status       string     `json:"status" xml:"status"`
+	ExpiresAt    time.Time  `json:"expires_at" xml:"expires_at"`
+	EndedAt      *time.Time `json:"ended_at" xml:"ended_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at" xml:"created_at"`
+	Tenant       string     `json:"-" xml:"-"`
+}
+
+const reservationColumns = `r.id, r.car_id, r.holder_name, r.holder_email, r.holder_phone, r.deposit_cents,
+	r.status, r.expires_at, r.ended_at, r.created_at, r.tenant`
+
+func scanReservation(row interface{ Scan(...interface{}) error }, reservation *Reservation) error {
+	return row.Scan(
+		&reservation.Id,
+		&reservation.CarId,
+		&reservation.HolderName,
+		&reservation.HolderEmail,
+		&reservation.HolderPhone,
+		&reservation.DepositCents,
+		&reservation.Status,
+		&reservation.ExpiresAt,
+		&reservation.EndedAt,
+		&reservation.CreatedAt,
+		&reservation.Tenant,
+	)
+}
+
+// Hold the car for the reservation's holder and fire "reserve" on it, in one
+// transaction. The car row is locked so concurrent reservations of a car run
+// one at a time, and the reservations_one_active index backs that up. A hold
+// that has run out but not been swept yet is expired first.
+func ReserveCar(db *clients.DBClient, reservation *Reservation, actor string, credential string) error {
+	txn, err := db.Db.Begin()
+	if err != nil {
+		return fmt.Errorf("Could not RESERVE car %s", err)
+	}
+	defer txn.Rollback()
+
+	from, facts, err := lockCarTx(txn, reservation.Tenant, reservation.CarId)
+	if err == ErrCarNotFound {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("Could not RESERVE car %s", err)
+	}
+
+	from, _, err = expireCarTx(txn, reservation.Tenant, reservation.CarId, from, facts, time.Now())
+	if err != nil {
+		return fmt.Errorf("Could not RESERVE car %s", err)
+	}
+
+	var held bool
+	err = txn.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM reservations WHERE car_id = $1 AND status = 'active');
+	`, reservation.CarId).Scan(&held)
+	if err != nil {
+		return fmt.Errorf("Could not RESERVE car %s", err)
+	}
+	if held {
+		return ErrCarReserved
+	}
+
+	if _, err = fireTx(txn, reservation.Tenant, reservation.CarId, from, "reserve", facts, actor, credential); err != nil {
+		return err
+	}
+
+	err = txn.QueryRow(`
+		INSERT INTO reservations (id, car_id, tenant, holder_name, holder_email, holder_phone, deposit_cents, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING status, created_at;
+	`, reservation.Id, reservation.CarId, reservation.Tenant, reservation.HolderName, reservation.HolderEmail,
+		reservation.HolderPhone, reservation.DepositCents, reservation.ExpiresAt,
+	).Scan(&reservation.Status, &reservation.CreatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+		return ErrCarReserved
+	}
+	if err != nil {
+		return fmt.Errorf("Could not RESERVE car %s", err)
+	}
+
+	if err = txn.Commit(); err != nil {
+		return fmt.Errorf("Could not RESERVE car %s", err)
+	}
+	return nil
+}
+
+// End the car's active reservation early and fire "release" on it, so it's
+// listed again
+func ReleaseReservation(db *clients.DBClient, tenant string, carId string, actor string, credential string) (Reservation, error) {
+	txn, err := db.Db.Begin()
+	if err != nil {
+		return Reservation{}, fmt.Errorf("Could not RELEASE reservation %s", err)
+	}
+	defer txn.Rollback()
+
+	from, facts, err := lockCarTx(txn, tenant, carId)
+	if err == ErrCarNotFound {
+		return Reservation{}, err
+	}
+	if err != nil {
+		return Reservation{}, fmt.Errorf("Could not RELEASE reservation %s", err)
+	}
+
+	reservation, err := endReservationTx(txn, carId, ReservationReleased)
+	if err == ErrNoReservation {
+		return Reservation{}, err
+	}
+	if err != nil {
+		return Reservation{}, fmt.Errorf("Could not RELEASE reservation %s", err)
+	}
+
+	if from == lifecycle.Reserved {
+		if _, err = fireTx(txn, tenant, carId, from, "release", facts, actor, credential); err != nil {
+			return Reservation{}, err
+		}
+	}
+
+	if err = txn.Commit(); err != nil {
+		return Reservation{}, fmt.Errorf("Could not RELEASE reservation %s", err)
+	}
+	return reservation, nil
+}
+
+// A car's reservations, oldest first
+func ListReservations(db *clients.DBClient, tenant string, carId string) ([]Reservation, error) {
+	sqlStatement := `
+		SELECT ` + reservationColumns + `
+		FROM reservations r
+		WHERE r.tenant = $1 AND r.car_id = $2
+		ORDER BY r.created_at, r.id;
+	`
+
+	rows, err := db.Db.Query(sqlStatement, tenant, carId)
+	if err != nil {
+		return nil, fmt.Errorf("Could not LIST reservations %s", err)
+	}
+	defer rows.Close()
+
+	reservations := []Reservation{}
+	for rows.Next() {
+		var reservation Reservation
+		if err = scanReservation(rows, &reservation); err != nil {
+			return nil, fmt.Errorf("Could not LIST reservations %s", err)
+		}
+		reservations = append(reservations, reservation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Could not LIST reservations %s", err)
+	}
+	return reservations, nil
+}
+
+// Expire up to limit active reservations that ran out by now, oldest first,
+// each in its own transaction. Returns how many were expired. Holds released
+// or expired by someone else in the meantime are skipped.
+func ExpireReservations(db *clients.DBClient, now time.Time, limit int) (int, error) {
+	rows, err := db.Db.Query(`
+		SELECT car_id, tenant FROM reservations
+		WHERE status = 'active' AND expires_at <= $1
+		ORDER BY expires_at LIMIT $2;
+	`, now, limit)
+	if err != nil {
+		return 0, fmt.Errorf("Could not EXPIRE reservations %s", err)
+	}
+
+	type due struct{ carId, tenant string }
+	var dues []due
+	for rows.Next() {
+		var d due
+		if err = rows.Scan(&d.carId, &d.tenant); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("Could not EXPIRE reservations %s", err)
+		}
+		dues = append(dues, d)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("Could not EXPIRE reservations %s", err)
+	}
+
+	expired := 0
+	for _, d := range dues {
+		ok, err := expireCar(db, d.tenant, d.carId, now)
+		if err != nil {
+			return expired, err
+		}
+		if ok {
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+func expireCar(db *clients.DBClient, tenant string, carId string, now time.Time) (bool, error) {
+	txn, err := db.Db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("Could not EXPIRE reservations %s", err)
+	}
+	defer txn.Rollback()
+
+	from, facts, err := lockCarTx(txn, tenant, carId)
+	// Deleted since, along with its reservations
+	if err == ErrCarNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("Could not EXPIRE reservations %s", err)
+	}
+
+	_, expired, err := expireCarTx(txn, tenant, carId, from, facts, now)
+	if err != nil {
+		return false, fmt.Errorf("Could not EXPIRE reservations %s", err)
+	}
+
+	if err = txn.Commit(); err != nil {
+		return false, fmt.Errorf("Could not EXPIRE reservations %s", err)
+	}
+	return expired, nil
+}
+
+// Expire the active reservation of a car locked by lockCarTx if it ran out
+// by now: release the car and publish a reservation_expired event with it.
+// Returns the car's status afterwards and whether a hold expired.
+func expireCarTx(txn *sql.Tx, tenant string, carId string, from lifecycle.State, facts lifecycle.Facts, now time.Time) (lifecycle.State, bool, error) {
+	var reservation Reservation
+	err := scanReservation(txn.QueryRow(`
+		UPDATE reservations r SET status = 'expired', ended_at = now()
+		WHERE r.car_id = $1 AND r.status = 'active' AND r.expires_at <= $2
+		RETURNING `+reservationColumns+`;
+	`, carId, now), &reservation)
+	if err == sql.ErrNoRows {
+		return from, false, nil
+	}
+	if err != nil {
+		return from, false, err
+	}
+
+	if from == lifecycle.Reserved {
+		transition, err := fireTx(txn, tenant, carId, from, "release", facts, ExpiryActor, "")
+		if err != nil {
+			return from, false, err
+		}
+		from = transition.To
+	}
+
+	if err = publishExpiryTx(txn, carId, reservation); err != nil {
+		return from, false, err
+	}
+	return from, true, nil
+}
+
+// Write a reservation_expired event to the outbox and notify the listeners,
+// shaped like the events notify_car_change publishes with the reservation
+// added
+func publishExpiryTx(txn *sql.Tx, carId string, reservation Reservation) error {
+	encoded, err := json.Marshal(reservation)
+	if err != nil {
+		return err
+	}
+
+	var payload string
+	err = txn.QueryRow(`
+		WITH e AS (SELECT nextval('car_event_ids') AS id)
+		INSERT INTO outbox (event_id, event_type, tenant, payload)
+		SELECT e.id, 'reservation_expired', c.tenant, jsonb_build_object(
+			'id', e.id,
+			'type', 'reservation_expired',
+			'tenant', c.tenant,
+			'car', to_jsonb(c) - 'tenant' - 'search',
+			'reservation', $2::jsonb
+		)
+		FROM cars c, e WHERE c.id = $1
+		RETURNING payload::text;
+	`, carId, string(encoded)).Scan(&payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = txn.Exec(`SELECT pg_notify('car_changes', $1);`, payload)
+	return err
+}
+
+// Close the car's active reservation with status. The car should be locked
+// by lockCarTx.
+func endReservationTx(txn *sql.Tx, carId string, status string) (Reservation, error) {
+	var reservation Reservation
+	err := scanReservation(txn.QueryRow(`
+		UPDATE reservations r SET status = $2, ended_at = now()
+		WHERE r.car_id = $1 AND r.status = 'active'
+		RETURNING `+reservationColumns+`;
+	`, carId, status), &reservation)
+	if err == sql.ErrNoRows {
+		return Reservation{}, ErrNoReservation
+	}
+	return reservation, err
+}
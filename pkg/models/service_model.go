@@ -0,0 +1,261 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/maintenance"
+	"github.com/ericmcbride/go-dfw-testing/pkg/money"
+)
+
+// An oil change, repair, inspection or other work done on a car. Cost is
+// CostMinor written as a decimal, and both are left out for free work.
+// Odometer is the reading the record's mileage was logged as, only set on
+// the response to adding it.
+type ServiceRecord struct {
+	XMLName    xml.Name         `json:"-" xml:"service_record"`
+	Id         int64            `json:"id" xml:"id"`
+	CarId      string           `json:"car_id" xml:"car_id"`
+	Type       string           `json:"type" xml:"type"`
+	ServicedOn string           `json:"serviced_on" xml:"serviced_on"`
+	MileageKm  *int64           `json:"mileage_km,omitempty" xml:"mileage_km,omitempty"`
+	CostMinor  *int64           `json:"cost_minor,omitempty" xml:"cost_minor,omitempty"`
+	Currency   *string          `json:"currency,omitempty" xml:"currency,omitempty"`
+	Cost       string           `json:"cost,omitempty" xml:"cost,omitempty"`
+	Vendor     string           `json:"vendor" xml:"vendor"`
+	Notes      string           `json:"notes" xml:"notes"`
+	CreatedAt  time.Time        `json:"created_at" xml:"created_at"`
+	Odometer   *OdometerReading `json:"odometer,omitempty" xml:"reading,omitempty"`
+}
+
+// A car with services past due, for the overdue report
+type OverdueCar struct {
+	XMLName   xml.Name          `json:"-" xml:"car"`
+	CarId     string            `json:"car_id" xml:"car_id,attr"`
+	Make      string            `json:"make" xml:"make"`
+	Model     string            `json:"model" xml:"model"`
+	Year      int               `json:"year" xml:"year"`
+	MileageKm *int64            `json:"mileage_km,omitempty" xml:"mileage_km,omitempty"`
+	Overdue   []maintenance.Due `json:"overdue" xml:"service"`
+}
+
+const serviceColumns = `s.id, s.car_id, s.type, s.serviced_on::text, s.mileage_km, s.cost_minor, s.currency, s.vendor, s.notes, s.created_at`
+
+func scanService(row interface{ Scan(...interface{}) error }, record *ServiceRecord) error {
+	err := row.Scan(
+		&record.Id,
+		&record.CarId,
+		&record.Type,
+		&record.ServicedOn,
+		&record.MileageKm,
+		&record.CostMinor,
+		&record.Currency,
+		&record.Vendor,
+		&record.Notes,
+		&record.CreatedAt,
+	)
+	if err == nil && record.CostMinor != nil {
+		record.Cost = money.Format(*record.CostMinor, *record.Currency)
+	}
+	return err
+}
+
+// Add a service record, and log its mileage as an odometer reading when
+// reading isn't nil, in one transaction. The reading is dated the day of
+// the service.
+func AddServiceRecord(db *clients.DBClient, tenant string, carId string, record *ServiceRecord, reading *OdometerReading) error {
+	txn, err := db.Db.Begin()
+	if err != nil {
+		return fmt.Errorf("Could not ADD service record %s", err)
+	}
+	defer txn.Rollback()
+
+	var locked string
+	err = txn.QueryRow(`
+		SELECT id FROM cars WHERE tenant = $1 AND id = $2 FOR UPDATE;
+	`, tenant, carId).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return ErrCarNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("Could not ADD service record %s", err)
+	}
+
+	var mileageKm *int64
+	if reading != nil {
+		reading.ReadOn = record.ServicedOn
+		if err = recordOdometerTx(txn, tenant, carId, reading); err != nil {
+			return err
+		}
+		mileageKm = &reading.Km
+	}
+
+	err = scanService(txn.QueryRow(`
+		INSERT INTO service_records AS s (car_id, tenant, type, serviced_on, mileage_km, cost_minor, currency, vendor, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING `+serviceColumns,
+		carId, tenant, record.Type, record.ServicedOn, mileageKm, record.CostMinor, record.Currency, record.Vendor, record.Notes,
+	), record)
+	if err != nil {
+		return fmt.Errorf("Could not ADD service record %s", err)
+	}
+	record.Odometer = reading
+
+	if err = txn.Commit(); err != nil {
+		return fmt.Errorf("Could not ADD service record %s", err)
+	}
+	return nil
+}
+
+// A car's service records, oldest first
+func ListServiceRecords(db *clients.DBClient, tenant string, carId string) ([]ServiceRecord, error) {
+	sqlStatement := `
+		SELECT ` + serviceColumns + `
+		FROM service_records s
+		WHERE s.tenant = $1 AND s.car_id = $2
+		ORDER BY s.serviced_on, s.id;
+	`
+
+	rows, err := db.Db.Query(sqlStatement, tenant, carId)
+	if err != nil {
+		return nil, fmt.Errorf("Could not LIST service records %s", err)
+	}
+	defer rows.Close()
+
+	records := []ServiceRecord{}
+	for rows.Next() {
+		var record ServiceRecord
+		if err = scanService(rows, &record); err != nil {
+			return nil, fmt.Errorf("Could not LIST service records %s", err)
+		}
+		records = append(records, record)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Could not LIST service records %s", err)
+	}
+	return records, nil
+}
+
+// The latest service of each type a car has had
+func LastServices(db *clients.DBClient, tenant string, carId string) (map[string]maintenance.Last, error) {
+	sqlStatement := `
+		SELECT DISTINCT ON (type) type, serviced_on::text, mileage_km
+		FROM service_records
+		WHERE tenant = $1 AND car_id = $2
+		ORDER BY type, serviced_on DESC, id DESC;
+	`
+
+	rows, err := db.Db.Query(sqlStatement, tenant, carId)
+	if err != nil {
+		return nil, fmt.Errorf("Could not GET last services %s", err)
+	}
+	defer rows.Close()
+
+	last := make(map[string]maintenance.Last)
+	for rows.Next() {
+		var (
+			serviceType string
+			service     maintenance.Last
+		)
+		if err = rows.Scan(&serviceType, &service.On, &service.Km); err != nil {
+			return nil, fmt.Errorf("Could not GET last services %s", err)
+		}
+		last[serviceType] = service
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Could not GET last services %s", err)
+	}
+	return last, nil
+}
+
+// A page of the tenant's cars matching the filter with a service overdue
+// under the rules, by id, and how many there are in all. The rules live
+// outside the database, so every matching car is read and checked on each
+// call to count them; the rows are streamed and only the page is kept.
+// Narrow the filter to keep that cheap on big fleets.
+func OverdueCars(db *clients.DBClient, tenant string, filter CarFilter, rules *maintenance.Rules, today time.Time, limit int, offset int) ([]OverdueCar, int, error) {
+	filter.Tenant = tenant
+	where, args := filter.Where(1)
+
+	// One row per car and latest service type, or one row with no service
+	// for cars that have none. Services the car has never had count from its
+	// first odometer reading.
+	sqlStatement := fmt.Sprintf(`
+		SELECT c.id, c.make, c.model, c.year, c.mileage_km, s.type, s.serviced_on::text, s.serviced_km,
+			coalesce((SELECT min(read_on) FROM odometer_readings o WHERE o.car_id = c.id)::text, '')
+		FROM cars c
+		LEFT JOIN LATERAL (
+			SELECT DISTINCT ON (type) type, serviced_on, mileage_km AS serviced_km
+			FROM service_records
+			WHERE car_id = c.id
+			ORDER BY type, serviced_on DESC, id DESC
+		) s ON true
+		%s
+		ORDER BY c.id;
+	`, where)
+
+	rows, err := db.Db.Query(sqlStatement, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Could not LIST overdue cars %s", err)
+	}
+	defer rows.Close()
+
+	page := []OverdueCar{}
+	var (
+		total int
+		car   *OverdueCar
+		last  map[string]maintenance.Last
+		since string
+	)
+	check := func() {
+		if car == nil {
+			return
+		}
+		for _, due := range maintenance.Check(rules.For(car.Make), last, car.MileageKm, since, today) {
+			if due.Overdue {
+				car.Overdue = append(car.Overdue, due)
+			}
+		}
+		if len(car.Overdue) == 0 {
+			return
+		}
+		if total >= offset && total < offset+limit {
+			page = append(page, *car)
+		}
+		total++
+	}
+
+	for rows.Next() {
+		var (
+			row         OverdueCar
+			serviceType *string
+			service     maintenance.Last
+			servicedOn  *string
+			firstOn     string
+		)
+		err = rows.Scan(&row.CarId, &row.Make, &row.Model, &row.Year, &row.MileageKm, &serviceType, &servicedOn, &service.Km, &firstOn)
+		if err != nil {
+			return nil, 0, fmt.Errorf("Could not LIST overdue cars %s", err)
+		}
+
+		if car == nil || car.CarId != row.CarId {
+			check()
+			row.Overdue = []maintenance.Due{}
+			car, last, since = &row, make(map[string]maintenance.Last), firstOn
+		}
+		if serviceType != nil {
+			service.On = *servicedOn
+			last[*serviceType] = service
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("Could not LIST overdue cars %s", err)
+	}
+	check()
+	return page, total, nil
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/lifecycle"
+)
+
+// One lifecycle transition of a car, who made it and when. Actor is who the
+// caller says they are; Credential is a fingerprint of the credential the
+// call was authenticated with, empty for the server's own transitions.
+type CarTransition struct {
+	XMLName    xml.Name        `json:"-" xml:"transition"`
+	Id         int64           `json:"id" xml:"id"`
+	CarId      string          `json:"car_id" xml:"car_id"`
+	Event      string          `json:"event" xml:"event"`
+	From       lifecycle.State `json:"from" xml:"from"`
+	To         lifecycle.State `json:"to" xml:"to"`
+	Actor      string          `json:"actor" xml:"actor"`
+	Credential string          `json:"credential" xml:"credential"`
+	CreatedAt  time.Time       `json:"created_at" xml:"created_at"`
+}
+
+const transitionColumns = `t.id, t.car_id, t.event, t.from_status, t.to_status, t.actor, t.credential, t.created_at`
+
+func scanTransition(row interface{ Scan(...interface{}) error }, transition *CarTransition) error {
+	return row.Scan(
+		&transition.Id,
+		&transition.CarId,
+		&transition.Event,
+		&transition.From,
+		&transition.To,
+		&transition.Actor,
+		&transition.Credential,
+		&transition.CreatedAt,
+	)
+}
+
+// Fire the event on the car and record the transition, in one transaction.
+// The car row is locked so the guards see the car as it is when the status
+// changes. Errors from lifecycle.Fire are returned as they are. Releasing or
+// selling a reserved car ends its active reservation.
+func TransitionCar(db *clients.DBClient, tenant string, carId string, event string, actor string, credential string) (CarTransition, error) {
+	txn, err := db.Db.Begin()
+	if err != nil {
+		return CarTransition{}, fmt.Errorf("Could not TRANSITION car %s", err)
+	}
+	defer txn.Rollback()
+
+	from, facts, err := lockCarTx(txn, tenant, carId)
+	if err == ErrCarNotFound {
+		return CarTransition{}, err
+	}
+	if err != nil {
+		return CarTransition{}, fmt.Errorf("Could not TRANSITION car %s", err)
+	}
+
+	transition, err := fireTx(txn, tenant, carId, from, event, facts, actor, credential)
+	if err != nil {
+		return CarTransition{}, err
+	}
+
+	if status, ok := reservationEndings[transition.Event]; ok {
+		if _, err = endReservationTx(txn, carId, status); err != nil && err != ErrNoReservation {
+			return CarTransition{}, fmt.Errorf("Could not TRANSITION car %s", err)
+		}
+	}
+
+	if err = txn.Commit(); err != nil {
+		return CarTransition{}, fmt.Errorf("Could not TRANSITION car %s", err)
+	}
+	return transition, nil
+}
+
+// Lock the car row for the rest of the transaction and read its status and
+// what the guards need to know about it
+func lockCarTx(txn *sql.Tx, tenant string, carId string) (lifecycle.State, lifecycle.Facts, error) {
+	var (
+		from  lifecycle.State
+		facts lifecycle.Facts
+	)
+	err := txn.QueryRow(`
+		SELECT c.status, c.location_id IS NOT NULL, EXISTS (
+			SELECT 1 FROM car_ownerships co WHERE co.car_id = c.id AND co.ended_on IS NULL
+		)
+		FROM cars c WHERE c.tenant = $1 AND c.id = $2 FOR UPDATE;
+	`, tenant, carId).Scan(&from, &facts.HasLocation, &facts.HasOwner)
+	if err == sql.ErrNoRows {
+		return from, facts, ErrCarNotFound
+	}
+	return from, facts, err
+}
+
+// Fire the event on a car locked by lockCarTx, then update its status and
+// record the transition
+func fireTx(txn *sql.Tx, tenant string, carId string, from lifecycle.State, event string, facts lifecycle.Facts, actor string, credential string) (CarTransition, error) {
+	to, err := lifecycle.Fire(from, event, facts)
+	if err != nil {
+		return CarTransition{}, err
+	}
+	event = strings.ToLower(strings.TrimSpace(event))
+
+	if _, err = txn.Exec(`UPDATE cars SET status = $2 WHERE id = $1;`, carId, to); err != nil {
+		return CarTransition{}, fmt.Errorf("Could not TRANSITION car %s", err)
+	}
+
+	var transition CarTransition
+	err = scanTransition(txn.QueryRow(`
+		INSERT INTO car_transitions AS t (car_id, tenant, event, from_status, to_status, actor, credential)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING `+transitionColumns,
+		carId, tenant, event, from, to, actor, credential,
+	), &transition)
+	if err != nil {
+		return CarTransition{}, fmt.Errorf("Could not TRANSITION car %s", err)
+	}
+	return transition, nil
+}
+
+// A car's transitions, oldest first
+func ListTransitions(db *clients.DBClient, tenant string, carId string) ([]CarTransition, error) {
+	sqlStatement := `
+		SELECT ` + transitionColumns + `
+		FROM car_transitions t
+		WHERE t.tenant = $1 AND t.car_id = $2
+		ORDER BY t.created_at, t.id;
+	`
+
+	rows, err := db.Db.Query(sqlStatement, tenant, carId)
+	if err != nil {
+		return nil, fmt.Errorf("Could not LIST transitions %s", err)
+	}
+	defer rows.Close()
+
+	transitions := []CarTransition{}
+	for rows.Next() {
+		var transition CarTransition
+		if err = scanTransition(rows, &transition); err != nil {
+			return nil, fmt.Errorf("Could not LIST transitions %s", err)
+		}
+		transitions = append(transitions, transition)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Could not LIST transitions %s", err)
+	}
+	return transitions, nil
+}
@@ -0,0 +1,330 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/lib/pq"
+)
+
+var ErrWebhookNotFound = errors.New("Webhook not found")
+
+// Delivery states. Pending deliveries are retried until they're delivered or
+// run out of attempts and go dead.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+type Webhook struct {
+	Id  string `json:"id"`
+	Url string `json:"url"`
+	// Only returned when the webhook is created
+	Secret string `json:"secret,omitempty"`
+	// Event types to deliver, every type when empty
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+	Tenant    string    `json:"-"`
+}
+
+type WebhookDelivery struct {
+	Id            int64           `json:"id"`
+	WebhookId     string          `json:"webhook_id"`
+	EventId       int64           `json:"event_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     string          `json:"last_error"`
+	DeliveredAt   *time.Time      `json:"delivered_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+	// Filled in when the delivery is claimed for sending
+	Url    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+func SaveWebhook(db *clients.DBClient, webhook *Webhook) error {
+	if webhook.Events == nil {
+		webhook.Events = []string{}
+	}
+
+	sqlStatement := `
+		INSERT INTO webhooks (id, tenant, url, secret, events)
+		VALUES ($1, $2, $3, $4, $5) RETURNING created_at
+	`
+
+	err := db.Db.QueryRow(
+		sqlStatement,
+		webhook.Id,
+		webhook.Tenant,
+		webhook.Url,
+		webhook.Secret,
+		pq.Array(webhook.Events),
+	).Scan(&webhook.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("Could not SAVE webhook %s", err)
+	}
+	return nil
+}
+
+func GetWebhook(db *clients.DBClient, tenant string, webhookId string) (Webhook, error) {
+	sqlStatement := `
+		SELECT id, url, events, created_at, tenant
+		FROM webhooks WHERE tenant = $1 AND id = $2;
+	`
+	var webhook Webhook
+
+	err := db.Db.QueryRow(sqlStatement, tenant, webhookId).Scan(
+		&webhook.Id,
+		&webhook.Url,
+		pq.Array(&webhook.Events),
+		&webhook.CreatedAt,
+		&webhook.Tenant,
+	)
+	if err == sql.ErrNoRows {
+		return Webhook{}, ErrWebhookNotFound
+	}
+	if err != nil {
+		return Webhook{}, fmt.Errorf("Could not GET webhook %s", err)
+	}
+	return webhook, nil
+}
+
+func ListWebhooks(db *clients.DBClient, tenant string) ([]Webhook, error) {
+	sqlStatement := `
+		SELECT id, url, events, created_at, tenant
+		FROM webhooks WHERE tenant = $1
+		ORDER BY created_at, id;
+	`
+
+	rows, err := db.Db.Query(sqlStatement, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("Could not LIST webhooks %s", err)
+	}
+	defer rows.Close()
+
+	webhooks := []Webhook{}
+	for rows.Next() {
+		var webhook Webhook
+		err = rows.Scan(
+			&webhook.Id,
+			&webhook.Url,
+			pq.Array(&webhook.Events),
+			&webhook.CreatedAt,
+			&webhook.Tenant,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("Could not LIST webhooks %s", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Could not LIST webhooks %s", err)
+	}
+	return webhooks, nil
+}
+
+// Delete a webhook along with its deliveries
+func DeleteWebhook(db *clients.DBClient, tenant string, webhookId string) error {
+	sqlStatement := `
+		DELETE FROM webhooks
+		WHERE tenant = $1 AND id = $2;
+	`
+
+	result, err := db.Db.Exec(sqlStatement, tenant, webhookId)
+	if err != nil {
+		return fmt.Errorf("Could not DELETE webhook %s", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Could not DELETE webhook %s", err)
+	}
+	if deleted == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// Queue a delivery of an event to each of the tenant's webhooks subscribed to
+// its type. Queueing the same event twice is a no-op, so a relay retrying
+// after a crash doesn't deliver it twice. Returns how many were queued.
+func EnqueueDeliveries(db *clients.DBClient, tenant string, eventId int64, eventType string, payload []byte) (int64, error) {
+	sqlStatement := `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+		SELECT id, $2::bigint, $3::text, $4::jsonb
+		FROM webhooks
+		WHERE tenant = $1 AND (events = '{}' OR $3::text = ANY (events))
+		ON CONFLICT (webhook_id, event_id) DO NOTHING;
+	`
+
+	result, err := db.Db.Exec(sqlStatement, tenant, eventId, eventType, string(payload))
+	if err != nil {
+		return 0, fmt.Errorf("Could not ENQUEUE deliveries %s", err)
+	}
+
+	queued, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("Could not ENQUEUE deliveries %s", err)
+	}
+	return queued, nil
+}
+
+// List a webhook's deliveries, newest first. An empty status lists all of
+// them.
+func ListDeliveries(db *clients.DBClient, webhookId string, status string, limit int) ([]WebhookDelivery, error) {
+	sqlStatement := `
+		SELECT id, webhook_id, event_id, event_type, payload, status, attempts,
+			next_attempt_at, last_error, delivered_at, created_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1 AND ($2::text = '' OR status = $2)
+		ORDER BY id DESC
+		LIMIT $3;
+	`
+
+	rows, err := db.Db.Query(sqlStatement, webhookId, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Could not LIST deliveries %s", err)
+	}
+	defer rows.Close()
+
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		var delivery WebhookDelivery
+		err = rows.Scan(
+			&delivery.Id,
+			&delivery.WebhookId,
+			&delivery.EventId,
+			&delivery.EventType,
+			&delivery.Payload,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.NextAttemptAt,
+			&delivery.LastError,
+			&delivery.DeliveredAt,
+			&delivery.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("Could not LIST deliveries %s", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Could not LIST deliveries %s", err)
+	}
+	return deliveries, nil
+}
+
+// Claim up to limit pending deliveries that are due. Claimed deliveries are
+// pushed back by lease so other workers skip them while they're being sent,
+// and picked up again if this worker dies before recording the outcome.
+func ClaimDeliveries(db *clients.DBClient, limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	sqlStatement := `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = now() + $2::interval
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= now()
+			ORDER BY next_attempt_at, id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.attempts, w.url, w.secret;
+	`
+
+	rows, err := db.Db.Query(sqlStatement, limit, fmt.Sprintf("%d milliseconds", lease/time.Millisecond))
+	if err != nil {
+		return nil, fmt.Errorf("Could not CLAIM deliveries %s", err)
+	}
+	defer rows.Close()
+
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		delivery := WebhookDelivery{Status: DeliveryPending}
+		err = rows.Scan(
+			&delivery.Id,
+			&delivery.WebhookId,
+			&delivery.EventId,
+			&delivery.EventType,
+			&delivery.Payload,
+			&delivery.Attempts,
+			&delivery.Url,
+			&delivery.Secret,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("Could not CLAIM deliveries %s", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Could not CLAIM deliveries %s", err)
+	}
+	return deliveries, nil
+}
+
+func CompleteDelivery(db *clients.DBClient, deliveryId int64) error {
+	sqlStatement := `
+		UPDATE webhook_deliveries
+		SET status = 'delivered', attempts = attempts + 1, last_error = '', delivered_at = now()
+		WHERE id = $1;
+	`
+
+	_, err := db.Db.Exec(sqlStatement, deliveryId)
+	if err != nil {
+		return fmt.Errorf("Could not COMPLETE delivery %s", err)
+	}
+	return nil
+}
+
+// Record a failed attempt. The delivery is retried at retryAt, or goes dead
+// when dead is set.
+func FailDelivery(db *clients.DBClient, deliveryId int64, reason string, retryAt time.Time, dead bool) error {
+	status := DeliveryPending
+	if dead {
+		status = DeliveryDead
+	}
+
+	sqlStatement := `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = attempts + 1, last_error = $3, next_attempt_at = $4
+		WHERE id = $1;
+	`
+
+	_, err := db.Db.Exec(sqlStatement, deliveryId, status, reason, retryAt)
+	if err != nil {
+		return fmt.Errorf("Could not FAIL delivery %s", err)
+	}
+	return nil
+}
+
+// Put a webhook's dead deliveries back in the queue with a fresh set of
+// attempts. A deliveryId other than 0 replays just that delivery. Returns
+// how many were replayed.
+func ReplayDeliveries(db *clients.DBClient, webhookId string, deliveryId int64) (int64, error) {
+	sqlStatement := `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = now()
+		WHERE webhook_id = $1 AND status = 'dead' AND ($2::bigint = 0 OR id = $2);
+	`
+
+	result, err := db.Db.Exec(sqlStatement, webhookId, deliveryId)
+	if err != nil {
+		return 0, fmt.Errorf("Could not REPLAY deliveries %s", err)
+	}
+
+	replayed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("Could not REPLAY deliveries %s", err)
+	}
+	return replayed, nil
+}
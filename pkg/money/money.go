@@ -0,0 +1,109 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ISO 4217 currencies prices can be in, with how many minor units each has.
+// Amounts are only ever held as integers of minor units, never floats.
+var Currencies = map[string]int{
+	"AUD": 2,
+	"BHD": 3,
+	"BRL": 2,
+	"CAD": 2,
+	"CHF": 2,
+	"CNY": 2,
+	"DKK": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"INR": 2,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"MXN": 2,
+	"NOK": 2,
+	"NZD": 2,
+	"SEK": 2,
+	"USD": 2,
+}
+
+// The most minor units a price can have, whatever the currency
+const MaxMinor = math.MaxInt64
+
+var ErrUnknownCurrency = errors.New("Unknown currency")
+
+func ValidCurrency(code string) bool {
+	_, ok := Currencies[code]
+	return ok
+}
+
+// Every currency code, sorted
+func Codes() []string {
+	codes := make([]string, 0, len(Currencies))
+	for code := range Currencies {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Parse a decimal amount like "18995.5" into minor units of the currency,
+// 1899550 for USD. Amounts can't be negative or have more decimals than the
+// currency has minor units.
+func Parse(amount string, currency string) (int64, error) {
+	exponent, ok := Currencies[currency]
+	if !ok {
+		return 0, ErrUnknownCurrency
+	}
+
+	whole, fraction := amount, ""
+	if dot := strings.IndexByte(amount, '.'); dot >= 0 {
+		whole, fraction = amount[:dot], amount[dot+1:]
+		if fraction == "" {
+			return 0, fmt.Errorf("Amount %q needs digits after the decimal point", amount)
+		}
+	}
+	if whole == "" {
+		return 0, fmt.Errorf("Amount %q needs digits before the decimal point", amount)
+	}
+	if len(fraction) > exponent {
+		return 0, fmt.Errorf("Amount %q has more decimals than %s allows (%d)", amount, currency, exponent)
+	}
+
+	digits := whole + fraction + strings.Repeat("0", exponent-len(fraction))
+	var minor int64
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("Amount %q must be a plain decimal like 18995.00", amount)
+		}
+		if minor > (MaxMinor-int64(r-'0'))/10 {
+			return 0, fmt.Errorf("Amount %q is too large", amount)
+		}
+		minor = minor*10 + int64(r-'0')
+	}
+	return minor, nil
+}
+
+// Format minor units of the currency as a decimal amount, "18995.50" for
+// 1899550 USD
+func Format(minor int64, currency string) string {
+	exponent := Currencies[currency]
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+	}
+	digits := strings.TrimPrefix(strconv.FormatInt(minor, 10), "-")
+	if exponent == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	split := len(digits) - exponent
+	return sign + digits[:split] + "." + digits[split:]
+}
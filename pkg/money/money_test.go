@@ -0,0 +1,75 @@
+package money_test
+
+import (
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/money"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		amount   string
+		currency string
+		minor    int64
+	}{
+		{"18995", "USD", 1899500},
+		{"18995.5", "USD", 1899550},
+		{"18995.50", "USD", 1899550},
+		{"0.01", "EUR", 1},
+		{"2500000", "JPY", 2500000},
+		{"12.345", "KWD", 12345},
+		{"9223372036854775807", "JPY", 9223372036854775807},
+	}
+	for _, c := range cases {
+		minor, err := money.Parse(c.amount, c.currency)
+		if err != nil || minor != c.minor {
+			t.Errorf("%s %s: expected %d, got %d %v", c.amount, c.currency, c.minor, minor, err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct{ amount, currency string }{
+		{"10", "XYZ"},
+		{"", "USD"},
+		{".50", "USD"},
+		{"10.", "USD"},
+		{"10.001", "USD"},
+		{"10.5", "JPY"},
+		{"-10", "USD"},
+		{"1e3", "USD"},
+		{"1,000", "USD"},
+		{"9223372036854775808", "JPY"},
+		{"92233720368547758.08", "USD"},
+	}
+	for _, c := range cases {
+		if minor, err := money.Parse(c.amount, c.currency); err == nil {
+			t.Errorf("%s %s: expected an error, got %d", c.amount, c.currency, minor)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	cases := []struct {
+		minor    int64
+		currency string
+		amount   string
+	}{
+		{1899550, "USD", "18995.50"},
+		{5, "USD", "0.05"},
+		{0, "EUR", "0.00"},
+		{2500000, "JPY", "2500000"},
+		{12345, "KWD", "12.345"},
+		{-150, "GBP", "-1.50"},
+	}
+	for _, c := range cases {
+		if amount := money.Format(c.minor, c.currency); amount != c.amount {
+			t.Errorf("%d %s: expected %q, got %q", c.minor, c.currency, c.amount, amount)
+		}
+		if c.minor >= 0 {
+			if minor, err := money.Parse(c.amount, c.currency); err != nil || minor != c.minor {
+				t.Errorf("%q %s: expected to parse back to %d, got %d %v", c.amount, c.currency, c.minor, minor, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package odometer
+
+import (
+	"sort"
+)
+
+const (
+	Miles      = "miles"
+	Kilometers = "km"
+)
+
+// Units readings can be taken in, with how many millimeters one of each is,
+// so conversions stay in integers
+var Units = map[string]int64{
+	Miles:      1609344,
+	Kilometers: 1000000,
+}
+
+// The highest reading accepted, what a seven digit odometer shows
+const MaxValue = 9999999
+
+func ValidUnit(unit string) bool {
+	_, ok := Units[unit]
+	return ok
+}
+
+// Every unit name, sorted
+func UnitNames() []string {
+	names := make([]string, 0, len(Units))
+	for name := range Units {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Convert a reading to whole kilometers, rounding half up. 10000 miles is
+// 16093 km.
+func ToKm(value int64, unit string) int64 {
+	return (value*Units[unit] + Units[Kilometers]/2) / Units[Kilometers]
+}
+
+// Report whether a reading is certainly lower than an earlier one. Odometers
+// show whole units, so a reading of value could be anything up to value+1;
+// only when even that falls short of the earlier reading has the odometer
+// gone backwards. In the same unit that's simply value < earlier.
+func Rollback(value int64, unit string, earlier int64, earlierUnit string) bool {
+	return (value+1)*Units[unit] <= earlier*Units[earlierUnit]
+}
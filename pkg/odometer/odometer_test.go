@@ -0,0 +1,51 @@
+package odometer_test
+
+import (
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/odometer"
+)
+
+func TestToKm(t *testing.T) {
+	cases := []struct {
+		value int64
+		unit  string
+		km    int64
+	}{
+		{0, odometer.Miles, 0},
+		{10000, odometer.Miles, 16093},
+		{1, odometer.Miles, 2},
+		{16093, odometer.Kilometers, 16093},
+		{odometer.MaxValue, odometer.Miles, 16093438},
+	}
+	for _, c := range cases {
+		if km := odometer.ToKm(c.value, c.unit); km != c.km {
+			t.Errorf("%d %s: expected %d km, got %d", c.value, c.unit, c.km, km)
+		}
+	}
+}
+
+func TestRollback(t *testing.T) {
+	cases := []struct {
+		value       int64
+		unit        string
+		earlier     int64
+		earlierUnit string
+		rollback    bool
+	}{
+		{41999, odometer.Miles, 42000, odometer.Miles, true},
+		{42000, odometer.Miles, 42000, odometer.Miles, false},
+		{42001, odometer.Miles, 42000, odometer.Miles, false},
+		// 10000 miles is 16093.44 km, so 16093 km could be the same distance
+		{16093, odometer.Kilometers, 10000, odometer.Miles, false},
+		{16092, odometer.Kilometers, 10000, odometer.Miles, true},
+		// 16093 km could be up to 16094 km, which is 10000.6 miles
+		{10000, odometer.Miles, 16093, odometer.Kilometers, false},
+		{9999, odometer.Miles, 16094, odometer.Kilometers, true},
+	}
+	for _, c := range cases {
+		if got := odometer.Rollback(c.value, c.unit, c.earlier, c.earlierUnit); got != c.rollback {
+			t.Errorf("%d %s after %d %s: expected rollback %t, got %t", c.value, c.unit, c.earlier, c.earlierUnit, c.rollback, got)
+		}
+	}
+}
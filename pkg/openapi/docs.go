@@ -0,0 +1,140 @@
+package openapi
+
+import (
+	"io"
+	"net/http"
+)
+
+// The docs page is built into the binary and loads nothing but /openapi.json,
+// so it works offline and can't change under us. The policy header keeps it
+// that way.
+const docsPolicy = "default-src 'none'; connect-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'"
+
+func DocsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.Header().Set("Content-Security-Policy", docsPolicy)
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, docsPage)
+}
+
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8" />
+  <title>go-dfw-testing cars API</title>
+  <style>
+    body { font-family: sans-serif; margin: 0 auto; max-width: 1100px; padding: 1em; color: #222; }
+    h1 small { color: #777; font-size: 0.5em; }
+    details { border: 1px solid #ddd; border-radius: 4px; margin: 0.4em 0; }
+    details[open] > summary { border-bottom: 1px solid #ddd; }
+    summary { cursor: pointer; padding: 0.5em; }
+    .op { padding: 0.5em 1em; }
+    .method { display: inline-block; width: 5em; font-weight: bold; text-transform: uppercase; }
+    .get { color: #1a7f37; } .post { color: #0969da; } .put, .patch { color: #9a6700; } .delete { color: #cf222e; }
+    .deprecated { text-decoration: line-through; color: #777; }
+    code, pre { background: #f6f8fa; border-radius: 3px; padding: 0.1em 0.3em; }
+    pre { padding: 0.5em; overflow: auto; }
+    table { border-collapse: collapse; margin: 0.5em 0; }
+    td, th { border: 1px solid #ddd; padding: 0.2em 0.5em; text-align: left; vertical-align: top; }
+  </style>
+</head>
+<body>
+  <h1 id="title">go-dfw-testing cars API</h1>
+  <p>The raw document is at <a href="/openapi.json">/openapi.json</a>.</p>
+  <label><input type="checkbox" id="show-deprecated" /> Show deprecated unversioned paths</label>
+  <h2>Paths</h2>
+  <div id="paths"></div>
+  <h2>Schemas</h2>
+  <div id="schemas"></div>
+  <script>
+    var methods = ["get", "put", "post", "delete", "options", "head", "patch", "trace"];
+
+    function el(tag, attrs, children) {
+      var node = document.createElement(tag);
+      for (var key in attrs || {}) {
+        if (key === "text") { node.textContent = attrs[key]; } else { node.setAttribute(key, attrs[key]); }
+      }
+      (children || []).forEach(function (child) { node.appendChild(child); });
+      return node;
+    }
+
+    function schemaName(schema) {
+      if (!schema) { return ""; }
+      if (schema.$ref) { return schema.$ref.split("/").pop(); }
+      if (schema.type === "array") { return "[" + schemaName(schema.items) + "]"; }
+      return schema.type || "object";
+    }
+
+    function content(body) {
+      var types = Object.keys((body && body.content) || {});
+      return types.map(function (type) { return type + " " + schemaName(body.content[type].schema); }).join(", ");
+    }
+
+    function operation(path, method, op) {
+      var summary = el("summary", {}, [
+        el("span", { "class": "method " + method, text: method }),
+        el("code", { text: path }),
+        el("span", { text: " " + (op.summary || "") })
+      ]);
+      var body = el("div", { "class": "op" });
+      if (op.description) { body.appendChild(el("p", { text: op.description })); }
+
+      if ((op.parameters || []).length) {
+        var rows = [el("tr", {}, [el("th", { text: "Parameter" }), el("th", { text: "In" }), el("th", { text: "Type" }), el("th", { text: "Description" })])];
+        op.parameters.forEach(function (p) {
+          rows.push(el("tr", {}, [
+            el("td", { text: p.name + (p.required ? " *" : "") }),
+            el("td", { text: p["in"] }),
+            el("td", { text: schemaName(p.schema) }),
+            el("td", { text: p.description || "" })
+          ]));
+        });
+        body.appendChild(el("table", {}, rows));
+      }
+      if (op.requestBody) { body.appendChild(el("p", { text: "Request body: " + content(op.requestBody) })); }
+
+      var responses = [el("tr", {}, [el("th", { text: "Status" }), el("th", { text: "Description" }), el("th", { text: "Body" })])];
+      Object.keys(op.responses || {}).sort().forEach(function (status) {
+        var r = op.responses[status];
+        responses.push(el("tr", {}, [el("td", { text: status }), el("td", { text: r.description || "" }), el("td", { text: content(r) })]));
+      });
+      body.appendChild(el("table", {}, responses));
+
+      var node = el("details", {}, [summary, body]);
+      if (op.deprecated) { node.className = "deprecated"; node.hidden = true; }
+      return node;
+    }
+
+    function render(spec) {
+      document.getElementById("title").appendChild(el("small", { text: " " + spec.info.version }));
+
+      var paths = document.getElementById("paths");
+      Object.keys(spec.paths).sort().forEach(function (path) {
+        methods.forEach(function (method) {
+          if (spec.paths[path][method]) { paths.appendChild(operation(path, method, spec.paths[path][method])); }
+        });
+      });
+
+      var schemas = document.getElementById("schemas");
+      var defined = (spec.components && spec.components.schemas) || {};
+      Object.keys(defined).sort().forEach(function (name) {
+        schemas.appendChild(el("details", {}, [
+          el("summary", {}, [el("code", { text: name })]),
+          el("pre", { text: JSON.stringify(defined[name], null, 2) })
+        ]));
+      });
+
+      document.getElementById("show-deprecated").onchange = function (e) {
+        Array.prototype.forEach.call(document.querySelectorAll("details.deprecated"), function (node) {
+          node.hidden = !e.target.checked;
+        });
+      };
+    }
+
+    fetch("/openapi.json").then(function (r) { return r.json(); }).then(render, function (err) {
+      document.getElementById("paths").textContent = "Couldn't load /openapi.json: " + err;
+    });
+  </script>
+</body>
+</html>
+`
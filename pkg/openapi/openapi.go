@@ -0,0 +1,98 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	spec     map[string]interface{}
+	specJson []byte
+	specOnce sync.Once
+
+	versionPrefix = regexp.MustCompile(`^/v[0-9]+/`)
+	pathVariable  = regexp.MustCompile(`\{([^:}]+)(?::([^}]*))?\}`)
+)
+
+var operations = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Spec returns the parsed OpenAPI document. Each versioned path also gets an
+// unversioned alias with its operations marked deprecated, matching the routes
+// server.New mounts at the root.
+func Spec() map[string]interface{} {
+	specOnce.Do(func() {
+		if err := json.Unmarshal([]byte(document), &spec); err != nil {
+			panic(err)
+		}
+
+		paths := spec["paths"].(map[string]interface{})
+		keys := make([]string, 0, len(paths))
+		for path := range paths {
+			keys = append(keys, path)
+		}
+		sort.Strings(keys)
+
+		for _, path := range keys {
+			if !versionPrefix.MatchString(path) {
+				continue
+			}
+			alias := versionPrefix.ReplaceAllString(path, "/")
+			paths[alias] = deprecatedCopy(paths[path])
+		}
+
+		var err error
+		specJson, err = json.Marshal(spec)
+		if err != nil {
+			panic(err)
+		}
+	})
+	return spec
+}
+
+func deprecatedCopy(item interface{}) interface{} {
+	raw, _ := json.Marshal(item)
+	var copied map[string]interface{}
+	json.Unmarshal(raw, &copied)
+
+	for _, method := range operations {
+		if op, ok := copied[method].(map[string]interface{}); ok {
+			op["deprecated"] = true
+			if id, ok := op["operationId"].(string); ok {
+				op["operationId"] = id + "Unversioned"
+			}
+		}
+	}
+	return copied
+}
+
+// Turn a gorilla/mux path template into an OpenAPI path, e.g.
+// "/v1/{cars:cars(?:\/)?}" becomes "/v1/cars" and "/cars/{id:[0-9a-f-]+}"
+// becomes "/cars/{id}".
+func NormalizePath(template string) string {
+	return pathVariable.ReplaceAllStringFunc(template, func(v string) string {
+		match := pathVariable.FindStringSubmatch(v)
+		name, pattern := match[1], match[2]
+		if strings.HasPrefix(pattern, name) {
+			return name
+		}
+		return "{" + name + "}"
+	})
+}
+
+// Report whether a path (in OpenAPI form) is described by the document
+func Documented(path string) bool {
+	paths := Spec()["paths"].(map[string]interface{})
+	_, ok := paths[path]
+	return ok
+}
+
+func SpecHandler(w http.ResponseWriter, r *http.Request) {
+	Spec()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(specJson)
+}
@@ -0,0 +1,89 @@
+package openapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
+)
+
+func TestNormalizePath(t *testing.T) {
+	cases := map[string]string{
+		"/{health:health(?:\\/)?}":    "/health",
+		"/v1/{cars:cars(?:\\/)?}":     "/v1/cars",
+		"/cars/{id:[0-9a-f-]+}":       "/cars/{id}",
+		"/cars/{id}/transitions/{ev}": "/cars/{id}/transitions/{ev}",
+	}
+
+	for template, expected := range cases {
+		if got := openapi.NormalizePath(template); got != expected {
+			t.Errorf("Template %s: expected %s, got %s", template, expected, got)
+		}
+	}
+}
+
+func TestUnversionedAliasDeprecated(t *testing.T) {
+	paths := openapi.Spec()["paths"].(map[string]interface{})
+	cars, ok := paths["/cars"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected /cars to be documented")
+	}
+
+	get := cars["get"].(map[string]interface{})
+	if get["deprecated"] != true {
+		t.Errorf("Expected GET /cars to be deprecated")
+	}
+
+	versioned := paths["/v1/cars"].(map[string]interface{})["get"].(map[string]interface{})
+	if _, ok := versioned["deprecated"]; ok {
+		t.Errorf("Expected GET /v1/cars not to be deprecated")
+	}
+}
+
+func TestValidateCarModel(t *testing.T) {
+	valid := []byte(`{"id": "9b2e3f4a-1c2d-4e5f-8a9b-0c1d2e3f4a5b", "make": "Toyota", "model": "Camry", "color": "green", "year": 2005}`)
+	if err := openapi.ValidateSchema("CarModel", valid); err != nil {
+		t.Errorf("Expected valid CarModel, got: %s", err)
+	}
+
+	invalid := map[string][]byte{
+		"bad uuid":     []byte(`{"id": "nope", "make": "Toyota", "model": "Camry", "color": "green", "year": 2005}`),
+		"missing year": []byte(`{"id": "9b2e3f4a-1c2d-4e5f-8a9b-0c1d2e3f4a5b", "make": "Toyota", "model": "Camry", "color": "green"}`),
+		"float year":   []byte(`{"id": "9b2e3f4a-1c2d-4e5f-8a9b-0c1d2e3f4a5b", "make": "Toyota", "model": "Camry", "color": "green", "year": 20.5}`),
+		"extra field":  []byte(`{"id": "9b2e3f4a-1c2d-4e5f-8a9b-0c1d2e3f4a5b", "make": "Toyota", "model": "Camry", "color": "green", "year": 2005, "foo": 1}`),
+	}
+	for name, body := range invalid {
+		if err := openapi.ValidateSchema("CarModel", body); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestValidateJsonError(t *testing.T) {
+	body := []byte(`{"status":"422","code":"422","message":"Make must be included in the payload","title":""}` + "\n")
+	if err := openapi.ValidateSchema("JsonError", body); err != nil {
+		t.Errorf("Expected valid JsonError, got: %s", err)
+	}
+}
+
+func TestValidateUnknownSchema(t *testing.T) {
+	if err := openapi.ValidateSchema("Nope", []byte(`{}`)); err == nil {
+		t.Errorf("Expected an error for an unknown schema")
+	}
+}
+
+func TestDocsSelfContained(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/docs", nil)
+	rr := httptest.NewRecorder()
+	openapi.DocsHandler(rr, req)
+
+	if rr.Header().Get("Content-Security-Policy") == "" {
+		t.Errorf("Expected the docs page to set a Content-Security-Policy")
+	}
+	body := rr.Body.String()
+	if strings.Contains(body, "http://") || strings.Contains(body, "https://") || !strings.Contains(body, "/openapi.json") {
+		t.Errorf("Expected the docs page to load only /openapi.json")
+	}
+}
@@ -0,0 +1,2464 @@
+package openapi
+
+// OpenAPI 3 document for the service. Operations are documented under their
+// versioned path; the unversioned aliases are added by Spec().
+const document = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "go-dfw-testing cars API",
+    "description": "Example RESTful API for posting cars to a postgres database.",
+    "version": "1.0.0"
+  },
+  "paths": {
+    "/health": {
+      "get": {
+        "operationId": "health",
+        "summary": "Service health check",
+        "responses": {
+          "200": {
+            "description": "Service is up",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Health"}}}
+          }
+        }
+      }
+    },
+    "/openapi.json": {
+      "get": {
+        "operationId": "openapi",
+        "summary": "This document",
+        "responses": {
+          "200": {"description": "OpenAPI document", "content": {"application/json": {}}}
+        }
+      }
+    },
+    "/docs": {
+      "get": {
+        "operationId": "docs",
+        "summary": "Swagger UI for this document",
+        "responses": {
+          "200": {"description": "HTML page", "content": {"text/html": {}}}
+        }
+      }
+    },
+    "/v1/cars": {
+      "parameters": [{"$ref": "#/components/parameters/CarsId"}],
+      "get": {
+        "operationId": "getCar",
+        "summary": "Look up a car by id, or list cars when car_id is left off",
+        "parameters": [
+          {
+            "name": "car_id",
+            "in": "query",
+            "description": "Car to look up. Without it the matching cars are listed.",
+            "schema": {"type": "string", "format": "uuid"}
+          },
+          {"$ref": "#/components/parameters/Make"},
+          {"$ref": "#/components/parameters/Model"},
+          {"$ref": "#/components/parameters/Color"},
+          {"$ref": "#/components/parameters/Year"},
+          {"$ref": "#/components/parameters/YearMin"},
+          {"$ref": "#/components/parameters/YearMax"},
+          {"$ref": "#/components/parameters/Location"},
+          {"$ref": "#/components/parameters/Status"},
+          {"$ref": "#/components/parameters/PriceMin"},
+          {"$ref": "#/components/parameters/PriceMax"},
+          {"$ref": "#/components/parameters/Currency"},
+          {"$ref": "#/components/parameters/MileageMin"},
+          {"$ref": "#/components/parameters/MileageMax"},
+          {
+            "name": "sort",
+            "in": "query",
+            "description": "Listing order, descending with a leading -. Unpriced cars and cars without a reading sort last either way. Prices in different currencies aren't converted, so price sorts by currency first and by price within each currency.",
+            "schema": {"type": "string", "enum": ["id", "-id", "make", "-make", "model", "-model", "year", "-year", "price", "-price", "mileage", "-mileage"], "default": "id"}
+          },
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1, "maximum": 500, "default": 50}},
+          {"name": "offset", "in": "query", "schema": {"type": "integer", "minimum": 0, "default": 0}},
+          {
+            "name": "fields",
+            "in": "query",
+            "description": "Comma separated car fields to return, only these columns are read",
+            "schema": {"type": "string"},
+            "example": "id,make,year"
+          },
+          {
+            "name": "include",
+            "in": "query",
+            "description": "Comma separated related resources to embed in each car",
+            "schema": {"type": "string"}
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The car, or a page of cars when listing. The Accept header picks JSON, XML, MessagePack or, for listings, CSV. With fields or include the cars are SparseCars.",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "oneOf": [
+                    {"$ref": "#/components/schemas/CarModel"},
+                    {"$ref": "#/components/schemas/CarList"},
+                    {"$ref": "#/components/schemas/SparseCar"},
+                    {"$ref": "#/components/schemas/SparseCarList"}
+                  ]
+                }
+              },
+              "application/xml": {
+                "schema": {
+                  "oneOf": [
+                    {"$ref": "#/components/schemas/CarModel"},
+                    {"$ref": "#/components/schemas/CarList"},
+                    {"$ref": "#/components/schemas/SparseCar"},
+                    {"$ref": "#/components/schemas/SparseCarList"}
+                  ]
+                }
+              },
+              "application/msgpack": {
+                "schema": {
+                  "oneOf": [
+                    {"$ref": "#/components/schemas/CarModel"},
+                    {"$ref": "#/components/schemas/CarList"},
+                    {"$ref": "#/components/schemas/SparseCar"},
+                    {"$ref": "#/components/schemas/SparseCarList"}
+                  ]
+                }
+              },
+              "text/csv": {"schema": {"type": "string"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "operationId": "postCar",
+        "summary": "Add a car",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/CarPostPayload"}},
+            "application/xml": {"schema": {"$ref": "#/components/schemas/CarPostPayload"}},
+            "application/msgpack": {"schema": {"$ref": "#/components/schemas/CarPostPayload"}}
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The saved car, in the format the Accept header asks for. Known makes and models are saved by their catalog names. When the catalog policy is flag, an unknown make or model is saved as given with a Warning header, and with reject it's a 422. A make, model or year that disagrees with the VIN is saved as given with a Warning header.",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/CarModel"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/CarModel"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/CarModel"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "413": {"$ref": "#/components/responses/Error"},
+          "415": {"$ref": "#/components/responses/Error"},
+          "422": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "delete": {
+        "operationId": "deleteCar",
+        "summary": "Delete a car by id",
+        "parameters": [{"$ref": "#/components/parameters/CarId"}],
+        "responses": {
+          "200": {"description": "Car deleted"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/cars:import": {
+      "parameters": [{"$ref": "#/components/parameters/CarsId"}],
+      "post": {
+        "operationId": "importCars",
+        "summary": "Bulk import cars from CSV or NDJSON",
+        "description": "Rows are streamed, validated one by one and saved in batches. CSV needs a header row with make, model, color and year columns.",
+        "parameters": [
+          {"name": "dry_run", "in": "query", "schema": {"type": "boolean", "default": false}}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "text/csv": {"schema": {"type": "string"}},
+            "application/x-ndjson": {"schema": {"type": "string"}}
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Per-row import report",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ImportReport"}}}
+          },
+          "400": {
+            "description": "A bad request, or a body that broke off part way. Then it's the ImportReport so far, with aborted saying where it stopped: rows before that were saved.",
+            "content": {"application/json": {"schema": {"oneOf": [{"$ref": "#/components/schemas/JsonError"}, {"$ref": "#/components/schemas/ImportReport"}]}}}
+          },
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "413": {"$ref": "#/components/responses/Error"},
+          "415": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/cars:export": {
+      "parameters": [{"$ref": "#/components/parameters/CarsId"}],
+      "get": {
+        "operationId": "exportCars",
+        "summary": "Stream every matching car as CSV, NDJSON or Parquet",
+        "description": "The format comes from the format parameter, then the Accept header, and defaults to NDJSON.",
+        "parameters": [
+          {"name": "format", "in": "query", "schema": {"type": "string", "enum": ["csv", "ndjson", "parquet"]}},
+          {"$ref": "#/components/parameters/Make"},
+          {"$ref": "#/components/parameters/Model"},
+          {"$ref": "#/components/parameters/Color"},
+          {"$ref": "#/components/parameters/Year"},
+          {"$ref": "#/components/parameters/YearMin"},
+          {"$ref": "#/components/parameters/YearMax"},
+          {"$ref": "#/components/parameters/Location"},
+          {"$ref": "#/components/parameters/Status"},
+          {"$ref": "#/components/parameters/PriceMin"},
+          {"$ref": "#/components/parameters/PriceMax"},
+          {"$ref": "#/components/parameters/Currency"},
+          {"$ref": "#/components/parameters/MileageMin"},
+          {"$ref": "#/components/parameters/MileageMax"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Every matching car",
+            "content": {
+              "text/csv": {"schema": {"type": "string"}},
+              "application/x-ndjson": {"schema": {"type": "string"}},
+              "application/vnd.apache.parquet": {"schema": {"type": "string", "format": "binary"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/cars:batch": {
+      "parameters": [{"$ref": "#/components/parameters/CarsId"}],
+      "post": {
+        "operationId": "batchCars",
+        "summary": "Run a batch of create, update and delete operations",
+        "description": "In atomic mode every operation runs in one transaction that is rolled back on the first failure. In best_effort mode each operation succeeds or fails on its own. Updates and deletes only reach the tenant's own cars, any other id fails with a 404.",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/BatchRequest"}}}
+        },
+        "responses": {
+          "200": {
+            "description": "Per-operation status",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/BatchResponse"}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "413": {"$ref": "#/components/responses/Error"},
+          "415": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/cars/events": {
+      "parameters": [{"$ref": "#/components/parameters/CarsId"}],
+      "get": {
+        "operationId": "carEvents",
+        "summary": "Stream created, updated and deleted car events",
+        "description": "Server-Sent Events for the caller's tenant. Each event's id can be sent back as Last-Event-ID to resume, as long as it's still in the replay buffer. The data of each event is a CarEvent.",
+        "parameters": [
+          {"name": "Last-Event-ID", "in": "header", "schema": {"type": "integer"}},
+          {"name": "last_event_id", "in": "query", "schema": {"type": "integer"}}
+        ],
+        "responses": {
+          "200": {
+            "description": "Event stream",
+            "content": {"text/event-stream": {"schema": {"type": "string"}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"}
+        }
+      }
+    },
+    "/v1/cars/ws": {
+      "get": {
+        "operationId": "carSocket",
+        "summary": "WebSocket subscriptions to filtered car changes",
+        "description": "JSON messages over a WebSocket. Authenticate with the X-CARS-ID header or a first {\"type\": \"auth\", \"cars_id\": \"...\"} message. Then send {\"type\": \"subscribe\", \"id\": \"toyotas\", \"filter\": {\"make\": \"Toyota\", \"year_min\": 2015}, \"events\": [\"created\"]} and {\"type\": \"unsubscribe\", \"id\": \"toyotas\"}; each is answered with an ack or an error. Matching changes arrive as {\"type\": \"event\", \"id\": \"toyotas\", \"event\": CarEvent}. The server sends a heartbeat message and a ping frame every 30 seconds.",
+        "parameters": [
+          {"name": "X-CARS-ID", "in": "header", "schema": {"type": "string"}},
+          {
+            "name": "slow",
+            "in": "query",
+            "description": "What happens when the client falls behind: drop events (reported in a dropped message) or disconnect",
+            "schema": {"type": "string", "enum": ["drop", "disconnect"], "default": "disconnect"}
+          }
+        ],
+        "responses": {
+          "101": {"description": "Switching to the WebSocket protocol"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"}
+        }
+      }
+    },
+    "/v1/cars/search": {
+      "parameters": [{"$ref": "#/components/parameters/CarsId"}],
+      "get": {
+        "operationId": "searchCars",
+        "summary": "Free text search over make, model and color, best match first",
+        "description": "Every word has to match the make, model or color, either as a whole word or a close spelling, so typos still find the car. Four digit numbers match the year.",
+        "parameters": [
+          {"name": "q", "in": "query", "required": true, "schema": {"type": "string"}, "example": "red toyta camry 2015"},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1, "maximum": 500, "default": 50}},
+          {"name": "offset", "in": "query", "schema": {"type": "integer", "minimum": 0, "default": 0}}
+        ],
+        "responses": {
+          "200": {
+            "description": "A page of ranked results. The Accept header picks JSON, XML or MessagePack.",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/CarSearchList"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/CarSearchList"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/CarSearchList"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/cars/stats": {
+      "parameters": [{"$ref": "#/components/parameters/CarsId"}],
+      "get": {
+        "operationId": "carStats",
+        "summary": "Count and summarize cars, optionally grouped",
+        "description": "Only counts the caller's tenant's cars and takes the same filters as listing. Results are cached per tenant for a configurable time (stats_cache_ttl, a minute by default) and Cache-Control says how much longer they're good for. X-Cache is HIT when they came from the cache.",
+        "parameters": [
+          {"$ref": "#/components/parameters/Make"},
+          {"$ref": "#/components/parameters/Model"},
+          {"$ref": "#/components/parameters/Color"},
+          {"$ref": "#/components/parameters/Year"},
+          {"$ref": "#/components/parameters/YearMin"},
+          {"$ref": "#/components/parameters/YearMax"},
+          {"$ref": "#/components/parameters/Location"},
+          {"$ref": "#/components/parameters/Status"},
+          {"$ref": "#/components/parameters/PriceMin"},
+          {"$ref": "#/components/parameters/PriceMax"},
+          {"$ref": "#/components/parameters/Currency"},
+          {"$ref": "#/components/parameters/MileageMin"},
+          {"$ref": "#/components/parameters/MileageMax"},
+          {
+            "name": "group_by",
+            "in": "query",
+            "description": "Comma separated, some of make, model, color, year, decade and status. Without it there's one group for every matching car.",
+            "schema": {"type": "string"},
+            "example": "make,decade"
+          },
+          {
+            "name": "metrics",
+            "in": "query",
+            "description": "Comma separated, some of count, min_year, max_year and avg_year",
+            "schema": {"type": "string", "default": "count"}
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "A group for each combination of the group_by values, ordered by them. The Accept header picks JSON, XML, MessagePack or CSV.",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/CarStats"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/CarStats"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/CarStats"}},
+              "text/csv": {"schema": {"type": "string"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/cars/service/overdue": {
+      "parameters": [{"$ref": "#/components/parameters/CarsId"}],
+      "get": {
+        "operationId": "listOverdueService",
+        "summary": "The tenant's cars with a service overdue, by id",
+        "description": "Each matching car is checked against the service interval rules for its make. A service is overdue once today is after its due_on or the car's mileage is past its due_km. Every matching car is checked to count the total, so narrow the filter on big fleets.",
+        "parameters": [
+          {"$ref": "#/components/parameters/Make"},
+          {"$ref": "#/components/parameters/Model"},
+          {"$ref": "#/components/parameters/Color"},
+          {"$ref": "#/components/parameters/Year"},
+          {"$ref": "#/components/parameters/YearMin"},
+          {"$ref": "#/components/parameters/YearMax"},
+          {"$ref": "#/components/parameters/Location"},
+          {"$ref": "#/components/parameters/Status"},
+          {"$ref": "#/components/parameters/PriceMin"},
+          {"$ref": "#/components/parameters/PriceMax"},
+          {"$ref": "#/components/parameters/Currency"},
+          {"$ref": "#/components/parameters/MileageMin"},
+          {"$ref": "#/components/parameters/MileageMax"},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1, "maximum": 500, "default": 50}},
+          {"name": "offset", "in": "query", "schema": {"type": "integer", "minimum": 0, "default": 0}}
+        ],
+        "responses": {
+          "200": {
+            "description": "Cars with the services they're overdue for",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/OverdueReport"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/OverdueReport"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/OverdueReport"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/service/rules": {
+      "parameters": [{"$ref": "#/components/parameters/CarsId"}],
+      "get": {
+        "operationId": "listServiceRules",
+        "summary": "The service interval rules by make",
+        "description": "A make with a rule of its own uses only its intervals, every other make uses the * rule. Set service_rules_path to load others.",
+        "responses": {
+          "200": {
+            "description": "The rules in use",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/ServiceRules"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/ServiceRules"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/ServiceRules"}}
+            }
+          },
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "406": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/catalog/makes": {
+      "parameters": [{"$ref": "#/components/parameters/CarsId"}],
+      "get": {
+        "operationId": "listCatalogMakes",
+        "summary": "Every make in the reference catalog, alphabetically",
+        "responses": {
+          "200": {
+            "description": "Makes with the aliases that resolve to them",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/CatalogMakeList"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/CatalogMakeList"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/CatalogMakeList"}}
+            }
+          },
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "406": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/catalog/makes/{make}/models": {
+      "parameters": [
+        {"$ref": "#/components/parameters/CarsId"},
+        {
+          "name": "make",
+          "in": "path",
+          "required": true,
+          "description": "A make's name or one of its aliases, in any case",
+          "schema": {"type": "string"},
+          "example": "chevy"
+        }
+      ],
+      "get": {
+        "operationId": "listCatalogModels",
+        "summary": "A make's models in the reference catalog, alphabetically",
+        "responses": {
+          "200": {
+            "description": "The make's canonical name and its models",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/CatalogModelList"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/CatalogModelList"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/CatalogModelList"}}
+            }
+          },
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/vins/{vin}": {
+      "parameters": [
+        {"$ref": "#/components/parameters/CarsId"},
+        {
+          "name": "vin",
+          "in": "path",
+          "required": true,
+          "description": "A 17 character VIN, in any case",
+          "schema": {"type": "string"},
+          "example": "5YJ3E1EA2KF317000"
+        }
+      ],
+      "get": {
+        "operationId": "decodeVin",
+        "summary": "Decode a VIN with the bundled manufacturer dataset",
+        "responses": {
+          "200": {
+            "description": "What the VIN says about the car. Fields the dataset doesn't know are left out.",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/VinDecoding"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/VinDecoding"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/VinDecoding"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "406": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/cars/{id}/transfer": {
+      "parameters": [
+        {"$ref": "#/components/parameters/CarsId"},
+        {
+          "name": "id",
+          "in": "path",
+          "required": true,
+          "schema": {"type": "string", "format": "uuid"}
+        }
+      ],
+      "post": {
+        "operationId": "transferCar",
+        "summary": "Pass a car to another owner",
+        "description": "Ends the current ownership on effective_date and starts the new owner's that day, in one transaction. Ownerships run from started_on up to but not including ended_on and can't overlap.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/TransferPayload"}},
+            "application/xml": {"schema": {"$ref": "#/components/schemas/TransferPayload"}},
+            "application/msgpack": {"schema": {"$ref": "#/components/schemas/TransferPayload"}}
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The ownership that was closed, null for a first owner, and the one that was opened",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/Transfer"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/Transfer"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/Transfer"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"},
+          "413": {"$ref": "#/components/responses/Error"},
+          "415": {"$ref": "#/components/responses/Error"},
+          "422": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/cars/{id}/ownerships": {
+      "parameters": [
+        {"$ref": "#/components/parameters/CarsId"},
+        {
+          "name": "id",
+          "in": "path",
+          "required": true,
+          "schema": {"type": "string", "format": "uuid"}
+        }
+      ],
+      "get": {
+        "operationId": "listOwnerships",
+        "summary": "A car's ownership history, oldest first",
+        "responses": {
+          "200": {
+            "description": "Every ownership of the car, the current one last with a null ended_on",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/OwnershipList"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/OwnershipList"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/OwnershipList"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/cars/{id}/transitions/{event}": {
+      "parameters": [
+        {"$ref": "#/components/parameters/CarsId"},
+        {
+          "name": "id",
+          "in": "path",
+          "required": true,
+          "schema": {"type": "string", "format": "uuid"}
+        },
+        {
+          "name": "event",
+          "in": "path",
+          "required": true,
+          "schema": {"type": "string", "enum": ["list", "unlist", "reserve", "release", "sell", "retire"]}
+        },
+        {
+          "name": "X-CARS-ACTOR",
+          "in": "header",
+          "description": "Who the transition is recorded as, api when left out. Only a claim: the credential's fingerprint is recorded beside it",
+          "schema": {"type": "string", "maxLength": 128}
+        }
+      ],
+      "post": {
+        "operationId": "transitionCar",
+        "summary": "Move a car through its lifecycle",
+        "description": "draft -list-> listed, listed -unlist-> draft, listed -reserve-> reserved, reserved -release-> listed, listed or reserved -sell-> sold, and draft, listed or sold -retire-> retired. Listing needs the car at a location and selling needs it transferred to an owner. Anything else is a 409 listing the transitions the car's state allows. Cars are reserved through /v1/cars/{id}/reservations, so reserve here is a 422; release and sell end the car's active reservation.",
+        "responses": {
+          "200": {
+            "description": "The recorded transition",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/CarTransition"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/CarTransition"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/CarTransition"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "409": {
+            "description": "The car's state or a guard doesn't allow the event",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/TransitionConflict"}}}
+          },
+          "422": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/cars/{id}/transitions": {
+      "parameters": [
+        {"$ref": "#/components/parameters/CarsId"},
+        {
+          "name": "id",
+          "in": "path",
+          "required": true,
+          "schema": {"type": "string", "format": "uuid"}
+        }
+      ],
+      "get": {
+        "operationId": "listTransitions",
+        "summary": "A car's lifecycle transitions, oldest first, and what it can do next",
+        "responses": {
+          "200": {
+            "description": "The car's status, its transitions and the transitions its status allows",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/TransitionList"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/TransitionList"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/TransitionList"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/cars/{id}/reservations": {
+      "parameters": [
+        {"$ref": "#/components/parameters/CarsId"},
+        {
+          "name": "id",
+          "in": "path",
+          "required": true,
+          "schema": {"type": "string", "format": "uuid"}
+        }
+      ],
+      "get": {
+        "operationId": "listReservations",
+        "summary": "A car's reservations, oldest first",
+        "responses": {
+          "200": {
+            "description": "The car's reservations",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/ReservationList"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/ReservationList"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/ReservationList"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "operationId": "reserveCar",
+        "summary": "Hold a listed car for a customer until expires_at",
+        "description": "Fires reserve on the car. A car has at most one active reservation; holds run 48 hours unless expires_at says otherwise, up to 14 days. Once a hold runs out the car is released and a reservation_expired event is published.",
+        "parameters": [
+          {
+            "name": "X-CARS-ACTOR",
+            "in": "header",
+            "description": "Who the reserve transition is recorded as, api when left out",
+            "schema": {"type": "string", "maxLength": 128}
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/ReservationPayload"}},
+            "application/xml": {"schema": {"$ref": "#/components/schemas/ReservationPayload"}},
+            "application/msgpack": {"schema": {"$ref": "#/components/schemas/ReservationPayload"}}
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The active reservation",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/Reservation"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/Reservation"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/Reservation"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "409": {
+            "description": "The car is already held, or its state doesn't allow reserving it",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "oneOf": [
+                    {"$ref": "#/components/schemas/JsonError"},
+                    {"$ref": "#/components/schemas/TransitionConflict"}
+                  ]
+                }
+              }
+            }
+          },
+          "413": {"$ref": "#/components/responses/Error"},
+          "415": {"$ref": "#/components/responses/Error"},
+          "422": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "delete": {
+        "operationId": "releaseReservation",
+        "summary": "End a car's active reservation early and list it again",
+        "parameters": [
+          {
+            "name": "X-CARS-ACTOR",
+            "in": "header",
+            "description": "Who the release transition is recorded as, api when left out",
+            "schema": {"type": "string", "maxLength": 128}
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The released reservation",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/Reservation"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/Reservation"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/Reservation"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/cars/{id}/prices": {
+      "parameters": [
+        {"$ref": "#/components/parameters/CarsId"},
+        {
+          "name": "id",
+          "in": "path",
+          "required": true,
+          "schema": {"type": "string", "format": "uuid"}
+        }
+      ],
+      "get": {
+        "operationId": "listPrices",
+        "summary": "A car's current list price and every price it's had, oldest first",
+        "responses": {
+          "200": {
+            "description": "The car's price history",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/PriceHistory"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/PriceHistory"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/PriceHistory"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "operationId": "setCarPrice",
+        "summary": "Give a car a new list price",
+        "description": "The price is sent as integer minor units or a decimal string, never a JSON number with a fraction, and recorded in the car's history with the actor and reason. Setting the price the car already has is a 409.",
+        "parameters": [
+          {
+            "name": "X-CARS-ACTOR",
+            "in": "header",
+            "description": "Who the change is recorded as, api when left out",
+            "schema": {"type": "string", "maxLength": 128}
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/PricePayload"}},
+            "application/xml": {"schema": {"$ref": "#/components/schemas/PricePayload"}},
+            "application/msgpack": {"schema": {"$ref": "#/components/schemas/PricePayload"}}
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The recorded price",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/PriceChange"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/PriceChange"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/PriceChange"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"},
+          "413": {"$ref": "#/components/responses/Error"},
+          "415": {"$ref": "#/components/responses/Error"},
+          "422": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/cars/{id}/odometer": {
+      "parameters": [
+        {"$ref": "#/components/parameters/CarsId"},
+        {
+          "name": "id",
+          "in": "path",
+          "required": true,
+          "schema": {"type": "string", "format": "uuid"}
+        }
+      ],
+      "get": {
+        "operationId": "listOdometer",
+        "summary": "A car's latest mileage and every odometer reading, oldest first",
+        "responses": {
+          "200": {
+            "description": "The car's odometer readings",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/OdometerHistory"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/OdometerHistory"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/OdometerHistory"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "operationId": "recordOdometer",
+        "summary": "Record an odometer reading",
+        "description": "Readings in miles are converted to whole kilometers, and the car's mileage_km is set from its latest dated reading. A reading lower than one taken on or before its date, or higher than one taken after it, is still recorded, with suspected_rollback set.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/OdometerPayload"}},
+            "application/xml": {"schema": {"$ref": "#/components/schemas/OdometerPayload"}},
+            "application/msgpack": {"schema": {"$ref": "#/components/schemas/OdometerPayload"}}
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The recorded reading",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/OdometerReading"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/OdometerReading"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/OdometerReading"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "413": {"$ref": "#/components/responses/Error"},
+          "415": {"$ref": "#/components/responses/Error"},
+          "422": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/cars/{id}/service": {
+      "parameters": [
+        {"$ref": "#/components/parameters/CarsId"},
+        {
+          "name": "id",
+          "in": "path",
+          "required": true,
+          "schema": {"type": "string", "format": "uuid"}
+        }
+      ],
+      "get": {
+        "operationId": "listServiceRecords",
+        "summary": "A car's service records, oldest first",
+        "responses": {
+          "200": {
+            "description": "The car's service records",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/ServiceRecordList"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/ServiceRecordList"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/ServiceRecordList"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "operationId": "addServiceRecord",
+        "summary": "Log an oil change, repair, inspection or other work on a car",
+        "description": "A mileage is also recorded as an odometer reading dated serviced_on, returned as odometer, so the car's mileage_km stays current.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/ServicePayload"}},
+            "application/xml": {"schema": {"$ref": "#/components/schemas/ServicePayload"}},
+            "application/msgpack": {"schema": {"$ref": "#/components/schemas/ServicePayload"}}
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The service record",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/ServiceRecord"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/ServiceRecord"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/ServiceRecord"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "413": {"$ref": "#/components/responses/Error"},
+          "415": {"$ref": "#/components/responses/Error"},
+          "422": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/cars/{id}/service/due": {
+      "parameters": [
+        {"$ref": "#/components/parameters/CarsId"},
+        {
+          "name": "id",
+          "in": "path",
+          "required": true,
+          "schema": {"type": "string", "format": "uuid"}
+        }
+      ],
+      "get": {
+        "operationId": "getServiceDue",
+        "summary": "When each service on the car's make is next due",
+        "description": "Measured from the latest service of each type. A service the car has never had is due by mileage from zero and by date from the car's first odometer reading.",
+        "responses": {
+          "200": {
+            "description": "The car's due services",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/ServiceDue"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/ServiceDue"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/ServiceDue"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/owners": {
+      "parameters": [{"$ref": "#/components/parameters/CarsId"}],
+      "get": {
+        "operationId": "getOwners",
+        "summary": "List the tenant's owners by name, or get one by owner_id",
+        "parameters": [
+          {"name": "owner_id", "in": "query", "schema": {"type": "string", "format": "uuid"}},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1, "maximum": 500, "default": 50}},
+          {"name": "offset", "in": "query", "schema": {"type": "integer", "minimum": 0, "default": 0}}
+        ],
+        "responses": {
+          "200": {
+            "description": "An OwnerList, or a single Owner when owner_id is given",
+            "content": {
+              "application/json": {"schema": {"oneOf": [{"$ref": "#/components/schemas/OwnerList"}, {"$ref": "#/components/schemas/Owner"}]}},
+              "application/xml": {"schema": {"oneOf": [{"$ref": "#/components/schemas/OwnerList"}, {"$ref": "#/components/schemas/Owner"}]}},
+              "application/msgpack": {"schema": {"oneOf": [{"$ref": "#/components/schemas/OwnerList"}, {"$ref": "#/components/schemas/Owner"}]}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "operationId": "createOwner",
+        "summary": "Add an owner",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/OwnerPostPayload"}},
+            "application/xml": {"schema": {"$ref": "#/components/schemas/OwnerPostPayload"}},
+            "application/msgpack": {"schema": {"$ref": "#/components/schemas/OwnerPostPayload"}}
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The saved owner",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/Owner"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/Owner"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/Owner"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "413": {"$ref": "#/components/responses/Error"},
+          "415": {"$ref": "#/components/responses/Error"},
+          "422": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "put": {
+        "operationId": "updateOwner",
+        "summary": "Replace an owner's name, email and phone",
+        "parameters": [{"$ref": "#/components/parameters/OwnerId"}],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/OwnerPostPayload"}},
+            "application/xml": {"schema": {"$ref": "#/components/schemas/OwnerPostPayload"}},
+            "application/msgpack": {"schema": {"$ref": "#/components/schemas/OwnerPostPayload"}}
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The updated owner",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/Owner"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/Owner"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/Owner"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "413": {"$ref": "#/components/responses/Error"},
+          "415": {"$ref": "#/components/responses/Error"},
+          "422": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "delete": {
+        "operationId": "deleteOwner",
+        "summary": "Delete an owner who has never owned a car",
+        "parameters": [{"$ref": "#/components/parameters/OwnerId"}],
+        "responses": {
+          "200": {"description": "Owner deleted"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/cars/{id}/move": {
+      "parameters": [
+        {"$ref": "#/components/parameters/CarsId"},
+        {
+          "name": "id",
+          "in": "path",
+          "required": true,
+          "schema": {"type": "string", "format": "uuid"}
+        }
+      ],
+      "post": {
+        "operationId": "moveCar",
+        "summary": "Move a car to another location",
+        "description": "Sets the car's location_id and records the move, in one transaction. A car's first move has a null from_location_id.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/MovePayload"}},
+            "application/xml": {"schema": {"$ref": "#/components/schemas/MovePayload"}},
+            "application/msgpack": {"schema": {"$ref": "#/components/schemas/MovePayload"}}
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The recorded move",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/Move"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/Move"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/Move"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"},
+          "413": {"$ref": "#/components/responses/Error"},
+          "415": {"$ref": "#/components/responses/Error"},
+          "422": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/cars/{id}/moves": {
+      "parameters": [
+        {"$ref": "#/components/parameters/CarsId"},
+        {
+          "name": "id",
+          "in": "path",
+          "required": true,
+          "schema": {"type": "string", "format": "uuid"}
+        }
+      ],
+      "get": {
+        "operationId": "listMoves",
+        "summary": "A car's moves between locations, oldest first",
+        "responses": {
+          "200": {
+            "description": "Every move of the car",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/MoveList"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/MoveList"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/MoveList"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/dealerships": {
+      "parameters": [{"$ref": "#/components/parameters/CarsId"}],
+      "get": {
+        "operationId": "getDealerships",
+        "summary": "List the tenant's dealerships by name, or get one by dealership_id",
+        "parameters": [
+          {"name": "dealership_id", "in": "query", "schema": {"type": "string", "format": "uuid"}},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1, "maximum": 500, "default": 50}},
+          {"name": "offset", "in": "query", "schema": {"type": "integer", "minimum": 0, "default": 0}}
+        ],
+        "responses": {
+          "200": {
+            "description": "A DealershipList, or a single Dealership when dealership_id is given",
+            "content": {
+              "application/json": {"schema": {"oneOf": [{"$ref": "#/components/schemas/DealershipList"}, {"$ref": "#/components/schemas/Dealership"}]}},
+              "application/xml": {"schema": {"oneOf": [{"$ref": "#/components/schemas/DealershipList"}, {"$ref": "#/components/schemas/Dealership"}]}},
+              "application/msgpack": {"schema": {"oneOf": [{"$ref": "#/components/schemas/DealershipList"}, {"$ref": "#/components/schemas/Dealership"}]}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "operationId": "createDealership",
+        "summary": "Add a dealership",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/DealershipPostPayload"}},
+            "application/xml": {"schema": {"$ref": "#/components/schemas/DealershipPostPayload"}},
+            "application/msgpack": {"schema": {"$ref": "#/components/schemas/DealershipPostPayload"}}
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The saved dealership",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/Dealership"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/Dealership"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/Dealership"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "413": {"$ref": "#/components/responses/Error"},
+          "415": {"$ref": "#/components/responses/Error"},
+          "422": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "put": {
+        "operationId": "updateDealership",
+        "summary": "Rename a dealership",
+        "parameters": [{"$ref": "#/components/parameters/DealershipId"}],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/DealershipPostPayload"}},
+            "application/xml": {"schema": {"$ref": "#/components/schemas/DealershipPostPayload"}},
+            "application/msgpack": {"schema": {"$ref": "#/components/schemas/DealershipPostPayload"}}
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The updated dealership",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/Dealership"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/Dealership"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/Dealership"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "413": {"$ref": "#/components/responses/Error"},
+          "415": {"$ref": "#/components/responses/Error"},
+          "422": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "delete": {
+        "operationId": "deleteDealership",
+        "summary": "Delete a dealership that has no locations",
+        "parameters": [{"$ref": "#/components/parameters/DealershipId"}],
+        "responses": {
+          "200": {"description": "Dealership deleted"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/locations": {
+      "parameters": [{"$ref": "#/components/parameters/CarsId"}],
+      "get": {
+        "operationId": "getLocations",
+        "summary": "List the tenant's locations by name, or get one by location_id",
+        "parameters": [
+          {"name": "location_id", "in": "query", "schema": {"type": "string", "format": "uuid"}},
+          {"name": "dealership_id", "in": "query", "description": "Only this dealership's locations", "schema": {"type": "string", "format": "uuid"}},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1, "maximum": 500, "default": 50}},
+          {"name": "offset", "in": "query", "schema": {"type": "integer", "minimum": 0, "default": 0}}
+        ],
+        "responses": {
+          "200": {
+            "description": "A LocationList, or a single Location when location_id is given",
+            "content": {
+              "application/json": {"schema": {"oneOf": [{"$ref": "#/components/schemas/LocationList"}, {"$ref": "#/components/schemas/Location"}]}},
+              "application/xml": {"schema": {"oneOf": [{"$ref": "#/components/schemas/LocationList"}, {"$ref": "#/components/schemas/Location"}]}},
+              "application/msgpack": {"schema": {"oneOf": [{"$ref": "#/components/schemas/LocationList"}, {"$ref": "#/components/schemas/Location"}]}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "operationId": "createLocation",
+        "summary": "Add a location",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/LocationPostPayload"}},
+            "application/xml": {"schema": {"$ref": "#/components/schemas/LocationPostPayload"}},
+            "application/msgpack": {"schema": {"$ref": "#/components/schemas/LocationPostPayload"}}
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The saved location",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/Location"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/Location"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/Location"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "413": {"$ref": "#/components/responses/Error"},
+          "415": {"$ref": "#/components/responses/Error"},
+          "422": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "put": {
+        "operationId": "updateLocation",
+        "summary": "Replace a location's name and address",
+        "parameters": [{"$ref": "#/components/parameters/LocationId"}],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/LocationPostPayload"}},
+            "application/xml": {"schema": {"$ref": "#/components/schemas/LocationPostPayload"}},
+            "application/msgpack": {"schema": {"$ref": "#/components/schemas/LocationPostPayload"}}
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The updated location",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/Location"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/Location"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/Location"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "413": {"$ref": "#/components/responses/Error"},
+          "415": {"$ref": "#/components/responses/Error"},
+          "422": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "delete": {
+        "operationId": "deleteLocation",
+        "summary": "Delete a location that holds no cars",
+        "parameters": [{"$ref": "#/components/parameters/LocationId"}],
+        "responses": {
+          "200": {"description": "Location deleted"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/locations/inventory": {
+      "parameters": [{"$ref": "#/components/parameters/CarsId"}],
+      "get": {
+        "operationId": "getInventory",
+        "summary": "How many cars sit at each location",
+        "description": "Every location is listed, empty ones included. unassigned counts the cars that aren't at any location and is 0 when dealership_id is given.",
+        "parameters": [
+          {"name": "dealership_id", "in": "query", "description": "Only count this dealership's locations", "schema": {"type": "string", "format": "uuid"}}
+        ],
+        "responses": {
+          "200": {
+            "description": "Cars per location",
+            "content": {
+              "application/json": {"schema": {"$ref": "#/components/schemas/Inventory"}},
+              "application/xml": {"schema": {"$ref": "#/components/schemas/Inventory"}},
+              "application/msgpack": {"schema": {"$ref": "#/components/schemas/Inventory"}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "406": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/webhooks": {
+      "parameters": [{"$ref": "#/components/parameters/CarsId"}],
+      "get": {
+        "operationId": "getWebhooks",
+        "summary": "List the tenant's webhooks, or get one by webhook_id",
+        "parameters": [{"name": "webhook_id", "in": "query", "schema": {"type": "string", "format": "uuid"}}],
+        "responses": {
+          "200": {
+            "description": "A WebhookList, or a single Webhook when webhook_id is given",
+            "content": {"application/json": {"schema": {"oneOf": [
+              {"$ref": "#/components/schemas/WebhookList"},
+              {"$ref": "#/components/schemas/Webhook"}
+            ]}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "operationId": "createWebhook",
+        "summary": "Subscribe a URL to the tenant's car changes",
+        "description": "Deliveries are POSTed with an X-CARS-Signature header of sha256=HMAC-SHA256(secret, \"<X-CARS-Timestamp>.<body>\") in hex. Failed deliveries are retried with exponential backoff and go dead after 8 attempts. The url can't name a loopback, link-local or private address, which is a 422, and redirects aren't followed. The secret is only returned here.",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/WebhookPostPayload"}}}
+        },
+        "responses": {
+          "200": {
+            "description": "The created webhook, including its secret",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Webhook"}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "413": {"$ref": "#/components/responses/Error"},
+          "415": {"$ref": "#/components/responses/Error"},
+          "422": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "delete": {
+        "operationId": "deleteWebhook",
+        "summary": "Delete a webhook and its deliveries",
+        "parameters": [{"$ref": "#/components/parameters/WebhookId"}],
+        "responses": {
+          "200": {"description": "Webhook deleted"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/webhooks/deliveries": {
+      "parameters": [{"$ref": "#/components/parameters/CarsId"}],
+      "get": {
+        "operationId": "listWebhookDeliveries",
+        "summary": "List a webhook's deliveries, newest first",
+        "parameters": [
+          {"$ref": "#/components/parameters/WebhookId"},
+          {"name": "status", "in": "query", "schema": {"type": "string", "enum": ["pending", "delivered", "dead"]}},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1, "maximum": 500, "default": 50}}
+        ],
+        "responses": {
+          "200": {
+            "description": "Deliveries",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/DeliveryList"}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/webhooks:replay": {
+      "parameters": [{"$ref": "#/components/parameters/CarsId"}],
+      "post": {
+        "operationId": "replayWebhookDeliveries",
+        "summary": "Queue a webhook's dead deliveries again",
+        "description": "Every dead delivery of the webhook, or only the one named by delivery_id, goes back to pending with a fresh set of attempts.",
+        "parameters": [
+          {"$ref": "#/components/parameters/WebhookId"},
+          {"name": "delivery_id", "in": "query", "schema": {"type": "integer", "minimum": 1}}
+        ],
+        "responses": {
+          "200": {
+            "description": "How many deliveries were queued again",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ReplayResponse"}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/graphql": {
+      "parameters": [{"$ref": "#/components/parameters/CarsId"}],
+      "get": {
+        "operationId": "graphqlQuery",
+        "summary": "Run a GraphQL query",
+        "description": "Queries only; mutations need POST. Variables are a JSON object.",
+        "parameters": [
+          {"name": "query", "in": "query", "required": true, "schema": {"type": "string"}},
+          {"name": "variables", "in": "query", "schema": {"type": "string"}},
+          {"name": "operationName", "in": "query", "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"$ref": "#/components/responses/GraphQL"},
+          "400": {"$ref": "#/components/responses/GraphQL"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "405": {"$ref": "#/components/responses/Error"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "operationId": "graphqlExecute",
+        "summary": "Run a GraphQL query or mutation",
+        "description": "The schema has car(id), cars(make, model, color, year, yearMin, yearMax, limit, offset), and the createCar, updateCar and deleteCar mutations. Queries deeper than 8 levels or resolving more than 5000 fields are rejected with a 400 before they run.",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/GraphQLRequest"}}}
+        },
+        "responses": {
+          "200": {"$ref": "#/components/responses/GraphQL"},
+          "400": {"$ref": "#/components/responses/GraphQL"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "500": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    }
+  },
+  "components": {
+    "parameters": {
+      "Make": {"name": "make", "in": "query", "schema": {"type": "string"}},
+      "Model": {"name": "model", "in": "query", "schema": {"type": "string"}},
+      "Color": {"name": "color", "in": "query", "schema": {"type": "string"}},
+      "Year": {"name": "year", "in": "query", "schema": {"type": "integer"}},
+      "YearMin": {"name": "year_min", "in": "query", "schema": {"type": "integer"}},
+      "YearMax": {"name": "year_max", "in": "query", "schema": {"type": "integer"}},
+      "Status": {"name": "status", "in": "query", "description": "Only cars in this lifecycle state", "schema": {"type": "string", "enum": ["draft", "listed", "reserved", "sold", "retired"]}},
+      "Location": {"name": "location_id", "in": "query", "description": "Only cars sitting at this location", "schema": {"type": "string", "format": "uuid"}},
+      "PriceMin": {"name": "price_min", "in": "query", "description": "Only cars listed at this price or more, in minor units of currency, which is required with it", "schema": {"type": "integer", "format": "int64", "minimum": 0}},
+      "PriceMax": {"name": "price_max", "in": "query", "description": "Only cars listed at this price or less, in minor units of currency, which is required with it", "schema": {"type": "integer", "format": "int64", "minimum": 0}},
+      "MileageMin": {"name": "mileage_min", "in": "query", "description": "Only cars whose latest odometer reading is this many kilometers or more", "schema": {"type": "integer", "format": "int64", "minimum": 0}},
+      "MileageMax": {"name": "mileage_max", "in": "query", "description": "Only cars whose latest odometer reading is this many kilometers or less", "schema": {"type": "integer", "format": "int64", "minimum": 0}},
+      "Currency": {"name": "currency", "in": "query", "description": "Only cars priced in this ISO 4217 currency", "schema": {"type": "string", "enum": ["AUD", "BHD", "BRL", "CAD", "CHF", "CNY", "DKK", "EUR", "GBP", "INR", "JPY", "KRW", "KWD", "MXN", "NOK", "NZD", "SEK", "USD"]}},
+      "WebhookId": {
+        "name": "webhook_id",
+        "in": "query",
+        "required": true,
+        "schema": {"type": "string", "format": "uuid"}
+      },
+      "OwnerId": {
+        "name": "owner_id",
+        "in": "query",
+        "required": true,
+        "schema": {"type": "string", "format": "uuid"}
+      },
+      "DealershipId": {
+        "name": "dealership_id",
+        "in": "query",
+        "required": true,
+        "schema": {"type": "string", "format": "uuid"}
+      },
+      "LocationId": {
+        "name": "location_id",
+        "in": "query",
+        "required": true,
+        "schema": {"type": "string", "format": "uuid"}
+      },
+      "CarsId": {
+        "name": "X-CARS-ID",
+        "in": "header",
+        "required": true,
+        "schema": {"type": "string"}
+      },
+      "CarId": {
+        "name": "car_id",
+        "in": "query",
+        "required": true,
+        "schema": {"type": "string", "format": "uuid"}
+      }
+    },
+    "responses": {
+      "Error": {
+        "description": "Error",
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/JsonError"}}}
+      },
+      "Unauthorized": {
+        "description": "Missing or invalid X-CARS-ID",
+        "content": {"text/plain": {"schema": {"type": "string"}}}
+      },
+      "GraphQL": {
+        "description": "A GraphQL result",
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/GraphQLResponse"}}}
+      }
+    },
+    "schemas": {
+      "Health": {
+        "type": "object",
+        "required": ["status"],
+        "properties": {
+          "status": {"type": "string", "enum": ["OK"]}
+        }
+      },
+      "CarPostPayload": {
+        "type": "object",
+        "description": "make, model, color and year are required, except that a vin fills in whichever of make, model and year it can and makes color optional",
+        "properties": {
+          "make": {"type": "string", "minLength": 1, "maxLength": 128},
+          "model": {"type": "string", "minLength": 1, "maxLength": 128},
+          "color": {"type": "string", "minLength": 1, "maxLength": 128},
+          "year": {"type": "integer", "minimum": 1},
+          "vin": {"type": "string", "pattern": "^[A-HJ-NPR-Za-hj-npr-z0-9]{17}$"}
+        }
+      },
+      "CarModel": {
+        "type": "object",
+        "required": ["id", "make", "model", "color", "year"],
+        "additionalProperties": false,
+        "properties": {
+          "id": {"type": "string", "format": "uuid"},
+          "make": {"type": "string", "maxLength": 128},
+          "model": {"type": "string", "maxLength": 128},
+          "color": {"type": "string", "maxLength": 128},
+          "year": {"type": "integer"},
+          "status": {"type": "string", "enum": ["draft", "listed", "reserved", "sold", "retired"]},
+          "location_id": {"type": "string", "format": "uuid", "nullable": true},
+          "price_minor": {"type": "integer", "format": "int64", "minimum": 0, "nullable": true, "description": "List price in minor units of currency"},
+          "currency": {"type": "string", "pattern": "^[A-Z]{3}$", "nullable": true},
+          "mileage_km": {"type": "integer", "format": "int64", "minimum": 0, "nullable": true, "description": "Kilometers on the latest odometer reading"},
+          "owner": {"$ref": "#/components/schemas/Owner"}
+        }
+      },
+      "BatchRequest": {
+        "type": "object",
+        "required": ["operations"],
+        "properties": {
+          "mode": {"type": "string", "enum": ["atomic", "best_effort"], "default": "atomic"},
+          "operations": {
+            "type": "array",
+            "maxItems": 1000,
+            "items": {
+              "type": "object",
+              "required": ["op"],
+              "properties": {
+                "op": {"type": "string", "enum": ["create", "update", "delete"]},
+                "id": {"type": "string", "format": "uuid"},
+                "car": {"$ref": "#/components/schemas/CarPostPayload"}
+              }
+            }
+          }
+        }
+      },
+      "BatchResponse": {
+        "type": "object",
+        "required": ["mode", "committed", "results"],
+        "additionalProperties": false,
+        "properties": {
+          "mode": {"type": "string", "enum": ["atomic", "best_effort"]},
+          "committed": {"type": "boolean"},
+          "results": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["index", "op", "status"],
+              "additionalProperties": false,
+              "properties": {
+                "index": {"type": "integer"},
+                "op": {"type": "string"},
+                "status": {"type": "string", "enum": ["ok", "failed", "rolled_back", "skipped"]},
+                "code": {"type": "integer"},
+                "car": {"$ref": "#/components/schemas/CarModel"},
+                "error": {"type": "string"}
+              }
+            }
+          }
+        }
+      },
+      "CarEvent": {
+        "type": "object",
+        "description": "A reset has only an id and type: changes may have been missed, so refetch. It reaches every stream and subscription.",
+        "required": ["id", "type"],
+        "additionalProperties": false,
+        "properties": {
+          "id": {"type": "integer"},
+          "type": {"type": "string", "enum": ["created", "updated", "deleted", "reservation_expired", "reset"]},
+          "tenant": {"type": "string"},
+          "car": {"$ref": "#/components/schemas/CarModel"},
+          "reservation": {"$ref": "#/components/schemas/Reservation"}
+        }
+      },
+      "SocketMessage": {
+        "type": "object",
+        "required": ["type"],
+        "additionalProperties": false,
+        "properties": {
+          "type": {"type": "string", "enum": ["auth", "subscribe", "unsubscribe", "ping", "ack", "error", "event", "heartbeat", "pong", "dropped"]},
+          "id": {"type": "string"},
+          "ack": {"type": "string"},
+          "cars_id": {"type": "string"},
+          "filter": {"$ref": "#/components/schemas/CarFilter"},
+          "events": {"type": "array", "items": {"type": "string", "enum": ["created", "updated", "deleted", "reservation_expired"]}},
+          "event": {"$ref": "#/components/schemas/CarEvent"},
+          "dropped": {"type": "integer"},
+          "message": {"type": "string"}
+        }
+      },
+      "CarFilter": {
+        "type": "object",
+        "additionalProperties": false,
+        "properties": {
+          "make": {"type": "string"},
+          "model": {"type": "string"},
+          "color": {"type": "string"},
+          "year": {"type": "integer"},
+          "year_min": {"type": "integer"},
+          "year_max": {"type": "integer"},
+          "location_id": {"type": "string", "format": "uuid"},
+          "status": {"type": "string", "enum": ["draft", "listed", "reserved", "sold", "retired"]},
+          "price_min": {"type": "integer", "format": "int64", "minimum": 0},
+          "price_max": {"type": "integer", "format": "int64", "minimum": 0},
+          "currency": {"type": "string"},
+          "mileage_min": {"type": "integer", "format": "int64", "minimum": 0},
+          "mileage_max": {"type": "integer", "format": "int64", "minimum": 0}
+        }
+      },
+      "WebhookPostPayload": {
+        "type": "object",
+        "required": ["url"],
+        "properties": {
+          "url": {"type": "string", "format": "uri"},
+          "events": {"type": "array", "items": {"type": "string", "enum": ["created", "updated", "deleted", "reservation_expired"]}},
+          "secret": {"type": "string", "minLength": 16, "description": "Generated when left out"}
+        }
+      },
+      "Webhook": {
+        "type": "object",
+        "required": ["id", "url", "events", "created_at"],
+        "additionalProperties": false,
+        "properties": {
+          "id": {"type": "string", "format": "uuid"},
+          "url": {"type": "string"},
+          "secret": {"type": "string"},
+          "events": {"type": "array", "items": {"type": "string", "enum": ["created", "updated", "deleted", "reservation_expired"]}},
+          "created_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "WebhookList": {
+        "type": "object",
+        "required": ["webhooks"],
+        "additionalProperties": false,
+        "properties": {
+          "webhooks": {"type": "array", "items": {"$ref": "#/components/schemas/Webhook"}}
+        }
+      },
+      "WebhookDelivery": {
+        "type": "object",
+        "required": ["id", "webhook_id", "event_id", "event_type", "payload", "status", "attempts", "next_attempt_at", "last_error", "delivered_at", "created_at"],
+        "additionalProperties": false,
+        "properties": {
+          "id": {"type": "integer"},
+          "webhook_id": {"type": "string", "format": "uuid"},
+          "event_id": {"type": "integer"},
+          "event_type": {"type": "string", "enum": ["created", "updated", "deleted", "reservation_expired"]},
+          "payload": {"$ref": "#/components/schemas/CarEvent"},
+          "status": {"type": "string", "enum": ["pending", "delivered", "dead"]},
+          "attempts": {"type": "integer", "minimum": 0},
+          "next_attempt_at": {"type": "string", "format": "date-time"},
+          "last_error": {"type": "string"},
+          "delivered_at": {"type": "string", "format": "date-time", "nullable": true},
+          "created_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "DeliveryList": {
+        "type": "object",
+        "required": ["deliveries"],
+        "additionalProperties": false,
+        "properties": {
+          "deliveries": {"type": "array", "items": {"$ref": "#/components/schemas/WebhookDelivery"}}
+        }
+      },
+      "ReplayResponse": {
+        "type": "object",
+        "required": ["replayed"],
+        "additionalProperties": false,
+        "properties": {
+          "replayed": {"type": "integer", "minimum": 0}
+        }
+      },
+      "GraphQLRequest": {
+        "type": "object",
+        "required": ["query"],
+        "properties": {
+          "query": {"type": "string"},
+          "variables": {"type": "object", "nullable": true},
+          "operationName": {"type": "string"}
+        }
+      },
+      "GraphQLResponse": {
+        "type": "object",
+        "additionalProperties": false,
+        "properties": {
+          "data": {"type": "object", "nullable": true},
+          "errors": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["message"],
+              "properties": {
+                "message": {"type": "string"},
+                "locations": {"type": "array"},
+                "path": {"type": "array"}
+              }
+            }
+          }
+        }
+      },
+      "SparseCar": {
+        "type": "object",
+        "description": "A car with only the requested fields, followed by any includes",
+        "properties": {
+          "id": {"type": "string", "format": "uuid"},
+          "make": {"type": "string"},
+          "model": {"type": "string"},
+          "color": {"type": "string"},
+          "year": {"type": "integer"},
+          "status": {"type": "string", "enum": ["draft", "listed", "reserved", "sold", "retired"]},
+          "price_minor": {"type": "integer", "format": "int64", "nullable": true},
+          "currency": {"type": "string", "nullable": true},
+          "mileage_km": {"type": "integer", "format": "int64", "nullable": true}
+        }
+      },
+      "SparseCarList": {
+        "type": "object",
+        "required": ["cars", "limit", "offset"],
+        "additionalProperties": false,
+        "properties": {
+          "cars": {"type": "array", "items": {"$ref": "#/components/schemas/SparseCar"}},
+          "limit": {"type": "integer"},
+          "offset": {"type": "integer"}
+        }
+      },
+      "CarSearchList": {
+        "type": "object",
+        "required": ["query", "results", "limit", "offset"],
+        "additionalProperties": false,
+        "properties": {
+          "query": {"type": "string"},
+          "results": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["car", "rank", "highlights"],
+              "additionalProperties": false,
+              "properties": {
+                "car": {"$ref": "#/components/schemas/CarModel"},
+                "rank": {"type": "number"},
+                "highlights": {
+                  "type": "object",
+                  "description": "Fields that matched, HTML escaped with the matching words in <mark>",
+                  "additionalProperties": false,
+                  "properties": {
+                    "make": {"type": "string"},
+                    "model": {"type": "string"},
+                    "color": {"type": "string"},
+                    "year": {"type": "string"}
+                  }
+                }
+              }
+            }
+          },
+          "limit": {"type": "integer"},
+          "offset": {"type": "integer"}
+        }
+      },
+      "CarStats": {
+        "type": "object",
+        "required": ["group_by", "metrics", "groups"],
+        "additionalProperties": false,
+        "properties": {
+          "group_by": {"type": "array", "items": {"type": "string"}},
+          "metrics": {"type": "array", "items": {"type": "string"}},
+          "groups": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "description": "The group_by values then the metrics, by name",
+              "properties": {
+                "make": {"type": "string", "nullable": true},
+                "model": {"type": "string", "nullable": true},
+                "color": {"type": "string", "nullable": true},
+                "year": {"type": "integer", "nullable": true},
+                "decade": {"type": "integer", "nullable": true},
+                "count": {"type": "integer"},
+                "min_year": {"type": "integer", "nullable": true},
+                "max_year": {"type": "integer", "nullable": true},
+                "avg_year": {"type": "number", "nullable": true}
+              }
+            }
+          }
+        }
+      },
+      "CatalogMakeList": {
+        "type": "object",
+        "required": ["makes"],
+        "additionalProperties": false,
+        "properties": {
+          "makes": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["name", "aliases"],
+              "additionalProperties": false,
+              "properties": {
+                "name": {"type": "string"},
+                "aliases": {"type": "array", "items": {"type": "string"}}
+              }
+            }
+          }
+        }
+      },
+      "CatalogModelList": {
+        "type": "object",
+        "required": ["make", "models"],
+        "additionalProperties": false,
+        "properties": {
+          "make": {"type": "string"},
+          "models": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["name", "aliases"],
+              "additionalProperties": false,
+              "properties": {
+                "name": {"type": "string"},
+                "aliases": {"type": "array", "items": {"type": "string"}}
+              }
+            }
+          }
+        }
+      },
+      "VinDecoding": {
+        "type": "object",
+        "required": ["vin", "wmi", "year"],
+        "additionalProperties": false,
+        "properties": {
+          "vin": {"type": "string"},
+          "wmi": {"type": "string", "description": "World manufacturer identifier, the first three characters"},
+          "country": {"type": "string"},
+          "manufacturer": {"type": "string"},
+          "make": {"type": "string"},
+          "model": {"type": "string"},
+          "year": {"type": "integer", "description": "Model year, from the 10th character"},
+          "plant": {"type": "string", "description": "Assembly plant, from the 11th character"}
+        }
+      },
+      "Owner": {
+        "type": "object",
+        "required": ["id", "name", "email", "phone", "created_at"],
+        "additionalProperties": false,
+        "properties": {
+          "id": {"type": "string", "format": "uuid"},
+          "name": {"type": "string", "maxLength": 128},
+          "email": {"type": "string", "maxLength": 254},
+          "phone": {"type": "string", "maxLength": 32},
+          "created_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "OwnerPostPayload": {
+        "type": "object",
+        "required": ["name"],
+        "properties": {
+          "name": {"type": "string", "minLength": 1, "maxLength": 128},
+          "email": {"type": "string", "maxLength": 254},
+          "phone": {"type": "string", "maxLength": 32}
+        }
+      },
+      "OwnerList": {
+        "type": "object",
+        "required": ["owners", "limit", "offset"],
+        "properties": {
+          "owners": {"type": "array", "items": {"$ref": "#/components/schemas/Owner"}},
+          "limit": {"type": "integer"},
+          "offset": {"type": "integer"}
+        }
+      },
+      "TransferPayload": {
+        "type": "object",
+        "required": ["owner_id"],
+        "properties": {
+          "owner_id": {"type": "string", "format": "uuid"},
+          "effective_date": {"type": "string", "format": "date", "description": "Today when left out, can't be in the future"}
+        }
+      },
+      "Ownership": {
+        "type": "object",
+        "required": ["id", "car_id", "owner", "started_on", "ended_on"],
+        "additionalProperties": false,
+        "properties": {
+          "id": {"type": "integer"},
+          "car_id": {"type": "string", "format": "uuid"},
+          "owner": {"$ref": "#/components/schemas/Owner"},
+          "started_on": {"type": "string", "format": "date"},
+          "ended_on": {"type": "string", "format": "date", "nullable": true}
+        }
+      },
+      "OwnershipList": {
+        "type": "object",
+        "required": ["car_id", "ownerships"],
+        "properties": {
+          "car_id": {"type": "string", "format": "uuid"},
+          "ownerships": {"type": "array", "items": {"$ref": "#/components/schemas/Ownership"}}
+        }
+      },
+      "Transfer": {
+        "type": "object",
+        "required": ["previous", "current"],
+        "properties": {
+          "previous": {"allOf": [{"$ref": "#/components/schemas/Ownership"}], "nullable": true},
+          "current": {"$ref": "#/components/schemas/Ownership"}
+        }
+      },
+      "Dealership": {
+        "type": "object",
+        "required": ["id", "name", "created_at"],
+        "additionalProperties": false,
+        "properties": {
+          "id": {"type": "string", "format": "uuid"},
+          "name": {"type": "string", "maxLength": 128},
+          "created_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "DealershipPostPayload": {
+        "type": "object",
+        "required": ["name"],
+        "properties": {
+          "name": {"type": "string", "minLength": 1, "maxLength": 128}
+        }
+      },
+      "DealershipList": {
+        "type": "object",
+        "required": ["dealerships", "limit", "offset"],
+        "properties": {
+          "dealerships": {"type": "array", "items": {"$ref": "#/components/schemas/Dealership"}},
+          "limit": {"type": "integer"},
+          "offset": {"type": "integer"}
+        }
+      },
+      "Location": {
+        "type": "object",
+        "required": ["id", "dealership_id", "name", "address", "created_at"],
+        "additionalProperties": false,
+        "properties": {
+          "id": {"type": "string", "format": "uuid"},
+          "dealership_id": {"type": "string", "format": "uuid"},
+          "name": {"type": "string", "maxLength": 128},
+          "address": {"type": "string", "maxLength": 256},
+          "created_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "LocationPostPayload": {
+        "type": "object",
+        "description": "dealership_id is required when adding a location and ignored when replacing one, locations stay with their dealership",
+        "required": ["name"],
+        "properties": {
+          "dealership_id": {"type": "string", "format": "uuid"},
+          "name": {"type": "string", "minLength": 1, "maxLength": 128},
+          "address": {"type": "string", "maxLength": 256}
+        }
+      },
+      "LocationList": {
+        "type": "object",
+        "required": ["locations", "limit", "offset"],
+        "properties": {
+          "locations": {"type": "array", "items": {"$ref": "#/components/schemas/Location"}},
+          "limit": {"type": "integer"},
+          "offset": {"type": "integer"}
+        }
+      },
+      "MovePayload": {
+        "type": "object",
+        "required": ["location_id"],
+        "properties": {
+          "location_id": {"type": "string", "format": "uuid"},
+          "note": {"type": "string", "maxLength": 512}
+        }
+      },
+      "Move": {
+        "type": "object",
+        "required": ["id", "car_id", "from_location_id", "to_location_id", "note", "moved_at"],
+        "additionalProperties": false,
+        "properties": {
+          "id": {"type": "integer"},
+          "car_id": {"type": "string", "format": "uuid"},
+          "from_location_id": {"type": "string", "format": "uuid", "nullable": true, "description": "Null for a car's first move, or once the location is deleted"},
+          "to_location_id": {"type": "string", "format": "uuid", "nullable": true, "description": "Null once the location is deleted"},
+          "note": {"type": "string", "maxLength": 512},
+          "moved_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "MoveList": {
+        "type": "object",
+        "required": ["car_id", "moves"],
+        "properties": {
+          "car_id": {"type": "string", "format": "uuid"},
+          "moves": {"type": "array", "items": {"$ref": "#/components/schemas/Move"}}
+        }
+      },
+      "Inventory": {
+        "type": "object",
+        "required": ["locations", "unassigned", "total"],
+        "properties": {
+          "locations": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["location_id", "dealership_id", "name", "cars"],
+              "properties": {
+                "location_id": {"type": "string", "format": "uuid"},
+                "dealership_id": {"type": "string", "format": "uuid"},
+                "name": {"type": "string"},
+                "cars": {"type": "integer", "minimum": 0}
+              }
+            }
+          },
+          "unassigned": {"type": "integer", "minimum": 0},
+          "total": {"type": "integer", "minimum": 0}
+        }
+      },
+      "CarTransition": {
+        "type": "object",
+        "required": ["id", "car_id", "event", "from", "to", "actor", "credential", "created_at"],
+        "additionalProperties": false,
+        "properties": {
+          "id": {"type": "integer"},
+          "car_id": {"type": "string", "format": "uuid"},
+          "event": {"type": "string"},
+          "from": {"type": "string", "enum": ["draft", "listed", "reserved", "sold", "retired"]},
+          "to": {"type": "string", "enum": ["draft", "listed", "reserved", "sold", "retired"]},
+          "actor": {"type": "string", "maxLength": 128, "description": "Who the caller said they were, from X-CARS-ACTOR"},
+          "credential": {"type": "string", "description": "Fingerprint of the credential the transition was made with, empty for the server's own"},
+          "created_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "NextTransition": {
+        "type": "object",
+        "required": ["event", "to"],
+        "properties": {
+          "event": {"type": "string"},
+          "to": {"type": "string", "enum": ["draft", "listed", "reserved", "sold", "retired"]}
+        }
+      },
+      "TransitionList": {
+        "type": "object",
+        "required": ["car_id", "status", "transitions", "allowed"],
+        "properties": {
+          "car_id": {"type": "string", "format": "uuid"},
+          "status": {"type": "string", "enum": ["draft", "listed", "reserved", "sold", "retired"]},
+          "transitions": {"type": "array", "items": {"$ref": "#/components/schemas/CarTransition"}},
+          "allowed": {"type": "array", "items": {"$ref": "#/components/schemas/NextTransition"}}
+        }
+      },
+      "TransitionConflict": {
+        "type": "object",
+        "description": "A JsonError with the car's state and the transitions it allows",
+        "required": ["status", "code", "message", "title", "from", "allowed"],
+        "additionalProperties": false,
+        "properties": {
+          "status": {"type": "string"},
+          "code": {"type": "string"},
+          "message": {"type": "string"},
+          "title": {"type": "string"},
+          "from": {"type": "string", "enum": ["draft", "listed", "reserved", "sold", "retired"]},
+          "allowed": {"type": "array", "items": {"$ref": "#/components/schemas/NextTransition"}}
+        }
+      },
+      "ReservationPayload": {
+        "type": "object",
+        "required": ["holder_name"],
+        "properties": {
+          "holder_name": {"type": "string", "maxLength": 128},
+          "holder_email": {"type": "string", "format": "email", "maxLength": 254},
+          "holder_phone": {"type": "string", "maxLength": 32},
+          "deposit_cents": {"type": "integer", "minimum": 0},
+          "expires_at": {"type": "string", "format": "date-time", "description": "48 hours from now when left out, at most 14 days away"}
+        }
+      },
+      "Reservation": {
+        "type": "object",
+        "required": ["id", "car_id", "holder_name", "holder_email", "holder_phone", "deposit_cents", "status", "expires_at", "ended_at", "created_at"],
+        "additionalProperties": false,
+        "properties": {
+          "id": {"type": "string", "format": "uuid"},
+          "car_id": {"type": "string", "format": "uuid"},
+          "holder_name": {"type": "string"},
+          "holder_email": {"type": "string"},
+          "holder_phone": {"type": "string"},
+          "deposit_cents": {"type": "integer", "minimum": 0},
+          "status": {"type": "string", "enum": ["active", "released", "expired", "fulfilled"]},
+          "expires_at": {"type": "string", "format": "date-time"},
+          "ended_at": {"type": "string", "format": "date-time", "nullable": true},
+          "created_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "ReservationList": {
+        "type": "object",
+        "required": ["car_id", "reservations"],
+        "additionalProperties": false,
+        "properties": {
+          "car_id": {"type": "string", "format": "uuid"},
+          "reservations": {"type": "array", "items": {"$ref": "#/components/schemas/Reservation"}}
+        }
+      },
+      "PricePayload": {
+        "type": "object",
+        "required": ["currency"],
+        "description": "Send exactly one of price_minor and amount",
+        "properties": {
+          "price_minor": {"type": "integer", "format": "int64", "minimum": 0},
+          "amount": {"type": "string", "pattern": "^[0-9]+(\\.[0-9]+)?$", "example": "18995.00"},
+          "currency": {"type": "string", "enum": ["AUD", "BHD", "BRL", "CAD", "CHF", "CNY", "DKK", "EUR", "GBP", "INR", "JPY", "KRW", "KWD", "MXN", "NOK", "NZD", "SEK", "USD"]},
+          "reason": {"type": "string", "maxLength": 512}
+        }
+      },
+      "PriceChange": {
+        "type": "object",
+        "required": ["id", "car_id", "price_minor", "currency", "amount", "actor", "reason", "changed_at"],
+        "additionalProperties": false,
+        "properties": {
+          "id": {"type": "integer"},
+          "car_id": {"type": "string", "format": "uuid"},
+          "price_minor": {"type": "integer", "format": "int64", "minimum": 0},
+          "currency": {"type": "string", "pattern": "^[A-Z]{3}$"},
+          "amount": {"type": "string", "description": "price_minor as a decimal in the currency's minor units"},
+          "actor": {"type": "string"},
+          "reason": {"type": "string"},
+          "changed_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "PriceHistory": {
+        "type": "object",
+        "required": ["car_id", "prices"],
+        "additionalProperties": false,
+        "properties": {
+          "car_id": {"type": "string", "format": "uuid"},
+          "price_minor": {"type": "integer", "format": "int64", "minimum": 0},
+          "currency": {"type": "string", "pattern": "^[A-Z]{3}$"},
+          "prices": {"type": "array", "items": {"$ref": "#/components/schemas/PriceChange"}}
+        }
+      },
+      "OdometerPayload": {
+        "type": "object",
+        "required": ["value", "unit", "source"],
+        "properties": {
+          "value": {"type": "integer", "format": "int64", "minimum": 0, "maximum": 9999999},
+          "unit": {"type": "string", "enum": ["km", "miles"]},
+          "read_on": {"type": "string", "format": "date", "description": "Defaults to today, can't be in the future"},
+          "source": {"type": "string", "maxLength": 128, "example": "service visit"}
+        }
+      },
+      "OdometerReading": {
+        "type": "object",
+        "required": ["id", "car_id", "value", "unit", "km", "read_on", "source", "suspected_rollback", "created_at"],
+        "additionalProperties": false,
+        "properties": {
+          "id": {"type": "integer"},
+          "car_id": {"type": "string", "format": "uuid"},
+          "value": {"type": "integer", "format": "int64", "minimum": 0},
+          "unit": {"type": "string", "enum": ["km", "miles"]},
+          "km": {"type": "integer", "format": "int64", "minimum": 0, "description": "value in whole kilometers"},
+          "read_on": {"type": "string", "format": "date"},
+          "source": {"type": "string"},
+          "suspected_rollback": {"type": "boolean", "description": "The reading is lower than one taken on or before read_on, or higher than one taken after it"},
+          "created_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "OdometerHistory": {
+        "type": "object",
+        "required": ["car_id", "readings"],
+        "additionalProperties": false,
+        "properties": {
+          "car_id": {"type": "string", "format": "uuid"},
+          "mileage_km": {"type": "integer", "format": "int64", "minimum": 0},
+          "readings": {"type": "array", "items": {"$ref": "#/components/schemas/OdometerReading"}}
+        }
+      },
+      "ServicePayload": {
+        "type": "object",
+        "required": ["type"],
+        "properties": {
+          "type": {"type": "string", "enum": ["oil_change", "tire_rotation", "brakes", "inspection", "repair", "other"]},
+          "serviced_on": {"type": "string", "format": "date", "description": "Defaults to today, can't be in the future"},
+          "mileage": {"type": "integer", "format": "int64", "minimum": 0, "maximum": 9999999},
+          "unit": {"type": "string", "enum": ["km", "miles"], "description": "Required with mileage"},
+          "cost": {"type": "string", "pattern": "^[0-9]+(\\.[0-9]+)?$", "example": "89.95"},
+          "currency": {"type": "string", "enum": ["AUD", "BHD", "BRL", "CAD", "CHF", "CNY", "DKK", "EUR", "GBP", "INR", "JPY", "KRW", "KWD", "MXN", "NOK", "NZD", "SEK", "USD"], "description": "Required with cost"},
+          "vendor": {"type": "string", "maxLength": 128},
+          "notes": {"type": "string", "maxLength": 2000}
+        }
+      },
+      "ServiceRecord": {
+        "type": "object",
+        "required": ["id", "car_id", "type", "serviced_on", "vendor", "notes", "created_at"],
+        "additionalProperties": false,
+        "properties": {
+          "id": {"type": "integer"},
+          "car_id": {"type": "string", "format": "uuid"},
+          "type": {"type": "string", "enum": ["oil_change", "tire_rotation", "brakes", "inspection", "repair", "other"]},
+          "serviced_on": {"type": "string", "format": "date"},
+          "mileage_km": {"type": "integer", "format": "int64", "minimum": 0},
+          "cost_minor": {"type": "integer", "format": "int64", "minimum": 0},
+          "currency": {"type": "string", "pattern": "^[A-Z]{3}$"},
+          "cost": {"type": "string", "description": "cost_minor as a decimal in the currency's minor units"},
+          "vendor": {"type": "string"},
+          "notes": {"type": "string"},
+          "created_at": {"type": "string", "format": "date-time"},
+          "odometer": {"$ref": "#/components/schemas/OdometerReading"}
+        }
+      },
+      "ServiceRecordList": {
+        "type": "object",
+        "required": ["car_id", "service_records"],
+        "additionalProperties": false,
+        "properties": {
+          "car_id": {"type": "string", "format": "uuid"},
+          "service_records": {"type": "array", "items": {"$ref": "#/components/schemas/ServiceRecord"}}
+        }
+      },
+      "DueService": {
+        "type": "object",
+        "required": ["type", "overdue"],
+        "additionalProperties": false,
+        "properties": {
+          "type": {"type": "string", "enum": ["oil_change", "tire_rotation", "brakes", "inspection", "repair", "other"]},
+          "last_serviced_on": {"type": "string", "format": "date"},
+          "last_mileage_km": {"type": "integer", "format": "int64"},
+          "due_on": {"type": "string", "format": "date"},
+          "due_km": {"type": "integer", "format": "int64"},
+          "overdue": {"type": "boolean"}
+        }
+      },
+      "ServiceDue": {
+        "type": "object",
+        "required": ["car_id", "make", "services"],
+        "additionalProperties": false,
+        "properties": {
+          "car_id": {"type": "string", "format": "uuid"},
+          "make": {"type": "string"},
+          "mileage_km": {"type": "integer", "format": "int64", "minimum": 0},
+          "services": {"type": "array", "items": {"$ref": "#/components/schemas/DueService"}}
+        }
+      },
+      "OverdueCar": {
+        "type": "object",
+        "required": ["car_id", "make", "model", "year", "overdue"],
+        "additionalProperties": false,
+        "properties": {
+          "car_id": {"type": "string", "format": "uuid"},
+          "make": {"type": "string"},
+          "model": {"type": "string"},
+          "year": {"type": "integer"},
+          "mileage_km": {"type": "integer", "format": "int64", "minimum": 0},
+          "overdue": {"type": "array", "items": {"$ref": "#/components/schemas/DueService"}}
+        }
+      },
+      "OverdueReport": {
+        "type": "object",
+        "required": ["cars", "total", "limit", "offset"],
+        "additionalProperties": false,
+        "properties": {
+          "cars": {"type": "array", "items": {"$ref": "#/components/schemas/OverdueCar"}},
+          "total": {"type": "integer", "minimum": 0},
+          "limit": {"type": "integer"},
+          "offset": {"type": "integer"}
+        }
+      },
+      "ServiceRules": {
+        "type": "object",
+        "required": ["rules"],
+        "additionalProperties": false,
+        "properties": {
+          "rules": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["make", "intervals"],
+              "additionalProperties": false,
+              "properties": {
+                "make": {"type": "string", "description": "* for every make without a rule of its own"},
+                "intervals": {
+                  "type": "array",
+                  "items": {
+                    "type": "object",
+                    "required": ["type"],
+                    "additionalProperties": false,
+                    "properties": {
+                      "type": {"type": "string", "enum": ["oil_change", "tire_rotation", "brakes", "inspection", "repair", "other"]},
+                      "km": {"type": "integer", "format": "int64", "minimum": 1},
+                      "months": {"type": "integer", "minimum": 1}
+                    }
+                  }
+                }
+              }
+            }
+          }
+        }
+      },
+      "CarList": {
+        "type": "object",
+        "required": ["cars", "limit", "offset"],
+        "additionalProperties": false,
+        "properties": {
+          "cars": {"type": "array", "items": {"$ref": "#/components/schemas/CarModel"}},
+          "limit": {"type": "integer"},
+          "offset": {"type": "integer"}
+        }
+      },
+      "ImportReport": {
+        "type": "object",
+        "required": ["dry_run", "total", "imported", "failed", "errors"],
+        "additionalProperties": false,
+        "properties": {
+          "dry_run": {"type": "boolean"},
+          "total": {"type": "integer"},
+          "imported": {"type": "integer"},
+          "failed": {"type": "integer"},
+          "aborted": {"type": "string", "description": "Why reading stopped before the end of the input, left out when it didn't"},
+          "errors": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["row", "message"],
+              "properties": {
+                "row": {"type": "integer"},
+                "message": {"type": "string"}
+              }
+            }
+          }
+        }
+      },
+      "JsonError": {
+        "type": "object",
+        "required": ["status", "code", "message", "title"],
+        "additionalProperties": false,
+        "properties": {
+          "status": {"type": "string"},
+          "code": {"type": "string"},
+          "message": {"type": "string"},
+          "title": {"type": "string"}
+        }
+      }
+    }
+  }
+}`
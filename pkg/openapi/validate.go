@@ -0,0 +1,248 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var uuidFormat = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Validate a JSON body against one of the schemas in components/schemas.
+// Only the subset of JSON Schema the document uses is supported.
+func ValidateSchema(name string, body []byte) error {
+	schemas := Spec()["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	schema, ok := schemas[name].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("Unknown schema %s", name)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("Invalid JSON: %s", err)
+	}
+
+	return Validate(schema, value)
+}
+
+// Validate a decoded JSON value against a schema. Numbers must have been
+// decoded as json.Number so integers can be told apart from floats.
+func Validate(schema map[string]interface{}, value interface{}) error {
+	return validate(schema, value, "$")
+}
+
+func resolve(ref string) (map[string]interface{}, error) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("Unsupported $ref %s", ref)
+	}
+
+	var node interface{} = Spec()
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		part = strings.Replace(strings.Replace(part, "~1", "/", -1), "~0", "~", -1)
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Unresolvable $ref %s", ref)
+		}
+		node = m[part]
+	}
+
+	schema, ok := node.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Unresolvable $ref %s", ref)
+	}
+	return schema, nil
+}
+
+func validate(schema map[string]interface{}, value interface{}, path string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		resolved, err := resolve(ref)
+		if err != nil {
+			return err
+		}
+		return validate(resolved, value, path)
+	}
+
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable || schema["type"] == nil {
+			return nil
+		}
+		return fmt.Errorf("%s: must not be null", path)
+	}
+
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			if err := validate(sub.(map[string]interface{}), value, path); err != nil {
+				return err
+			}
+		}
+	}
+
+	if one, ok := schema["oneOf"].([]interface{}); ok {
+		matched := 0
+		for _, sub := range one {
+			if validate(sub.(map[string]interface{}), value, path) == nil {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return fmt.Errorf("%s: must match exactly one schema, matched %d", path, matched)
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %v", path, value, enum)
+		}
+	}
+
+	switch schema["type"] {
+	case "object":
+		return validateObject(schema, value, path)
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected array, got %s", path, kind(value))
+		}
+		if itemSchema, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range items {
+				if err := validate(itemSchema, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected string, got %s", path, kind(value))
+		}
+		return validateString(schema, s, path)
+	case "integer", "number":
+		n, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s: expected %s, got %s", path, schema["type"], kind(value))
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+		if schema["type"] == "integer" {
+			if _, err := n.Int64(); err != nil {
+				return fmt.Errorf("%s: expected integer, got %s", path, n)
+			}
+		}
+		if min, ok := schema["minimum"].(float64); ok && f < min {
+			return fmt.Errorf("%s: %s is less than %v", path, n, min)
+		}
+		if max, ok := schema["maximum"].(float64); ok && f > max {
+			return fmt.Errorf("%s: %s is greater than %v", path, n, max)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected boolean, got %s", path, kind(value))
+		}
+	}
+
+	return nil
+}
+
+func validateObject(schema map[string]interface{}, value interface{}, path string) error {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s: expected object, got %s", path, kind(value))
+	}
+
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if _, ok := object[name.(string)]; !ok {
+				return fmt.Errorf("%s: missing required property %s", path, name)
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	for name, v := range object {
+		childPath := path + "." + name
+		if propSchema, ok := properties[name].(map[string]interface{}); ok {
+			if err := validate(propSchema, v, childPath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch extra := schema["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				return fmt.Errorf("%s: unexpected property", childPath)
+			}
+		case map[string]interface{}:
+			if err := validate(extra, v, childPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateString(schema map[string]interface{}, s string, path string) error {
+	if min, ok := schema["minLength"].(float64); ok && float64(utf8.RuneCountInString(s)) < min {
+		return fmt.Errorf("%s: shorter than %v characters", path, min)
+	}
+	if max, ok := schema["maxLength"].(float64); ok && float64(utf8.RuneCountInString(s)) > max {
+		return fmt.Errorf("%s: longer than %v characters", path, max)
+	}
+
+	switch schema["format"] {
+	case "uuid":
+		if !uuidFormat.MatchString(s) {
+			return fmt.Errorf("%s: %q is not a uuid", path, s)
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			return fmt.Errorf("%s: %q is not a date-time", path, s)
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			return fmt.Errorf("%s: %q is not a date", path, s)
+		}
+	}
+
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("%s: invalid pattern %s", path, pattern)
+		}
+		if !re.MatchString(s) {
+			return fmt.Errorf("%s: %q does not match %s", path, s, pattern)
+		}
+	}
+
+	return nil
+}
+
+func kind(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case json.Number:
+		return "number"
+	}
+	return reflect.TypeOf(value).String()
+}
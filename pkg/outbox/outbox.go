@@ -0,0 +1,111 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+)
+
+var (
+	// Messages claimed per transaction
+	BatchSize = 100
+	// Failed attempts before a message is dead and no longer retried
+	MaxAttempts = 10
+	// How often the relay looks for new messages
+	PollInterval = time.Second
+	// How long published messages are kept before they're pruned
+	Retention  = 7 * 24 * time.Hour
+	PruneEvery = time.Hour
+)
+
+// Publisher sends outbox messages somewhere. A message can be published more
+// than once if the relay dies before committing, so publishers should be
+// idempotent on EventId.
+type Publisher interface {
+	Publish(message models.OutboxMessage) error
+}
+
+// Relay publishes one batch of unpublished messages in order and marks them
+// published, all in one transaction. The first failure is recorded and ends
+// the batch so nothing overtakes it; it's retried on the next call. After
+// MaxAttempts failures the message is marked dead and the batch goes on
+// without it, so one message a publisher always rejects can't hold up the
+// outbox. Returns how many messages were published.
+func Relay(db *clients.DBClient, publisher Publisher) (int, error) {
+	txn, err := db.Db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	messages, err := models.ClaimOutboxTx(txn, BatchSize)
+	if err != nil {
+		txn.Rollback()
+		return 0, err
+	}
+
+	published := 0
+	for _, message := range messages {
+		var dead bool
+		publishErr := publisher.Publish(message)
+		if publishErr != nil {
+			dead, err = models.MarkFailedTx(txn, message.Id, publishErr.Error(), MaxAttempts)
+		} else {
+			err = models.MarkPublishedTx(txn, message.Id)
+		}
+		if err != nil {
+			txn.Rollback()
+			return 0, err
+		}
+		if publishErr != nil && !dead {
+			break
+		}
+		if publishErr == nil {
+			published++
+		}
+	}
+
+	if err = txn.Commit(); err != nil {
+		return 0, err
+	}
+	return published, nil
+}
+
+// Run relays the outbox every PollInterval until ctx is done. Any number of
+// instances can run at once; each claims messages the others haven't locked.
+func Run(ctx context.Context, db *clients.DBClient, publisher Publisher) error {
+	log := logging.GetLog(ctx)
+
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+
+	var pruned time.Time
+	for {
+		// Keep going while there's a backlog
+		for {
+			published, err := Relay(db, publisher)
+			if err != nil {
+				log.WithError(err).Error("Outbox: relay failed")
+				break
+			}
+			if published < BatchSize {
+				break
+			}
+		}
+
+		if time.Since(pruned) >= PruneEvery {
+			if _, err := models.PruneOutbox(db, time.Now().Add(-Retention)); err != nil {
+				log.WithError(err).Error("Outbox: prune failed")
+			}
+			pruned = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
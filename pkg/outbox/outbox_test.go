@@ -0,0 +1,225 @@
+package outbox_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/harness"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
+	"github.com/ericmcbride/go-dfw-testing/pkg/outbox"
+	"github.com/satori/go.uuid"
+)
+
+func TestMain(m *testing.M) {
+	harness.Run(m)
+}
+
+func connect(t *testing.T) clients.DBClient {
+	db, err := clients.NewDbConn()
+	if err != nil {
+		t.Fatalf("Couldnt connect to db")
+	}
+	return db
+}
+
+// Start every test with an empty outbox
+func drain(t *testing.T, db *clients.DBClient) {
+	if _, err := db.Db.Exec(`DELETE FROM outbox`); err != nil {
+		t.Fatalf("Couldn't empty outbox %s", err)
+	}
+}
+
+func testCar() *models.CarModel {
+	return &models.CarModel{
+		Id:    uuid.NewV4().String(),
+		Model: "Corolla",
+		Make:  "Toyota",
+		Color: "White",
+		Year:  2018,
+	}
+}
+
+func TestRelayPublishesInOrder(t *testing.T) {
+	db := connect(t)
+	defer clients.Close(&db)
+	drain(t, &db)
+
+	car := testCar()
+	models.SaveCar(&db, car)
+	car.Color = "Red"
+	models.UpdateCar(&db, models.DefaultTenant, car)
+	models.DeleteCar(&db, models.DefaultTenant, car.Id)
+
+	sink := &outbox.MemoryPublisher{}
+	published, err := outbox.Relay(&db, sink)
+	if err != nil {
+		t.Fatalf("Relay failed: %s", err)
+	}
+	if published != 3 {
+		t.Fatalf("Expected 3 published, got %d", published)
+	}
+
+	messages := sink.Messages()
+	for i, eventType := range []string{"created", "updated", "deleted"} {
+		if messages[i].EventType != eventType || messages[i].Tenant != models.DefaultTenant {
+			t.Errorf("Message %d: expected a %s event, got %+v", i, eventType, messages[i])
+		}
+		if err := openapi.ValidateSchema("CarEvent", messages[i].Payload); err != nil {
+			t.Errorf("Message %d doesn't match CarEvent schema: %s", i, err)
+		}
+	}
+	if messages[0].EventId >= messages[1].EventId || messages[1].EventId >= messages[2].EventId {
+		t.Errorf("Expected event ids in order, got %d %d %d", messages[0].EventId, messages[1].EventId, messages[2].EventId)
+	}
+
+	// Nothing is published twice
+	published, err = outbox.Relay(&db, sink)
+	if err != nil || published != 0 {
+		t.Errorf("Expected nothing left to publish, got %d %v", published, err)
+	}
+}
+
+func TestOutboxWrittenWithTransaction(t *testing.T) {
+	db := connect(t)
+	defer clients.Close(&db)
+	drain(t, &db)
+
+	txn, err := db.Db.Begin()
+	if err != nil {
+		t.Fatalf("Couldn't begin %s", err)
+	}
+	models.SaveCarTx(txn, testCar())
+	txn.Rollback()
+
+	sink := &outbox.MemoryPublisher{}
+	if published, _ := outbox.Relay(&db, sink); published != 0 {
+		t.Errorf("Expected a rolled back change not to be published, got %d", published)
+	}
+}
+
+func TestRelayRetriesFailures(t *testing.T) {
+	db := connect(t)
+	defer clients.Close(&db)
+	drain(t, &db)
+
+	models.SaveCar(&db, testCar())
+	models.SaveCar(&db, testCar())
+
+	sink := &outbox.MemoryPublisher{}
+	sink.SetErr(errors.New("sink down"))
+	published, err := outbox.Relay(&db, sink)
+	if err != nil || published != 0 {
+		t.Fatalf("Expected nothing published, got %d %v", published, err)
+	}
+
+	var (
+		attempts  int
+		lastError string
+	)
+	db.Db.QueryRow(`SELECT attempts, last_error FROM outbox ORDER BY id LIMIT 1`).Scan(&attempts, &lastError)
+	if attempts != 1 || lastError != "sink down" {
+		t.Errorf("Expected the failure to be recorded, got %d %q", attempts, lastError)
+	}
+
+	sink.SetErr(nil)
+	published, err = outbox.Relay(&db, sink)
+	if err != nil || published != 2 {
+		t.Errorf("Expected both messages published on retry, got %d %v", published, err)
+	}
+}
+
+// Rejects one event every time, like a payload a publisher can't accept
+type rejectPublisher struct {
+	eventId int64
+	sink    *outbox.MemoryPublisher
+}
+
+func (p rejectPublisher) Publish(message models.OutboxMessage) error {
+	if message.EventId == p.eventId {
+		return errors.New("rejected")
+	}
+	return p.sink.Publish(message)
+}
+
+func TestRelayMarksDeadAfterMaxAttempts(t *testing.T) {
+	db := connect(t)
+	defer clients.Close(&db)
+	drain(t, &db)
+
+	defer func(max int) { outbox.MaxAttempts = max }(outbox.MaxAttempts)
+	outbox.MaxAttempts = 3
+
+	models.SaveCar(&db, testCar())
+	models.SaveCar(&db, testCar())
+
+	var rejected int64
+	db.Db.QueryRow(`SELECT event_id FROM outbox ORDER BY id LIMIT 1`).Scan(&rejected)
+	sink := &outbox.MemoryPublisher{}
+	publisher := rejectPublisher{eventId: rejected, sink: sink}
+
+	for attempt := 1; attempt < outbox.MaxAttempts; attempt++ {
+		if published, err := outbox.Relay(&db, publisher); err != nil || published != 0 {
+			t.Fatalf("Attempt %d: expected the failure to hold up the batch, got %d %v", attempt, published, err)
+		}
+	}
+
+	// The last attempt gives up on it and publishes what's behind it
+	published, err := outbox.Relay(&db, publisher)
+	if err != nil || published != 1 || sink.Messages()[0].EventId == rejected {
+		t.Fatalf("Expected the next message published past the dead one, got %d %v", published, err)
+	}
+
+	var (
+		attempts int
+		dead     bool
+	)
+	db.Db.QueryRow(`SELECT attempts, dead_at IS NOT NULL FROM outbox WHERE event_id = $1`, rejected).Scan(&attempts, &dead)
+	if attempts != outbox.MaxAttempts || !dead {
+		t.Errorf("Expected the message dead after %d attempts, got %d %v", outbox.MaxAttempts, attempts, dead)
+	}
+
+	if published, err = outbox.Relay(&db, publisher); err != nil || published != 0 || len(sink.Messages()) != 1 {
+		t.Errorf("Expected the dead message not to be retried, got %d %v", published, err)
+	}
+}
+
+func TestRelaySkipsLockedMessages(t *testing.T) {
+	db := connect(t)
+	defer clients.Close(&db)
+	drain(t, &db)
+
+	models.SaveCar(&db, testCar())
+
+	// Another relay holds the message
+	txn, err := db.Db.Begin()
+	if err != nil {
+		t.Fatalf("Couldn't begin %s", err)
+	}
+	defer txn.Rollback()
+	claimed, err := models.ClaimOutboxTx(txn, outbox.BatchSize)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("Expected to claim 1 message, got %d %v", len(claimed), err)
+	}
+
+	sink := &outbox.MemoryPublisher{}
+	if published, err := outbox.Relay(&db, sink); err != nil || published != 0 {
+		t.Errorf("Expected the locked message to be skipped, got %d %v", published, err)
+	}
+}
+
+func TestStdoutPublisher(t *testing.T) {
+	var out bytes.Buffer
+	sink := outbox.StdoutPublisher{W: &out}
+
+	sink.Publish(models.OutboxMessage{Payload: json.RawMessage(`{"id":1}`)})
+	sink.Publish(models.OutboxMessage{Payload: json.RawMessage(`{"id":2}`)})
+
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 2 || lines[1] != `{"id":2}` {
+		t.Errorf("Expected one line per message, got %q", out.String())
+	}
+}
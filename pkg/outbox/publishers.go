@@ -0,0 +1,78 @@
+package outbox
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+)
+
+// Publish to each publisher in turn, stopping at the first failure. The ones
+// before it see the message again when it's retried.
+type Fanout []Publisher
+
+func (f Fanout) Publish(message models.OutboxMessage) error {
+	for _, publisher := range f {
+		if err := publisher.Publish(message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Queues a delivery for each of the tenant's matching webhooks
+type WebhookPublisher struct {
+	Db *clients.DBClient
+}
+
+func (p WebhookPublisher) Publish(message models.OutboxMessage) error {
+	_, err := models.EnqueueDeliveries(p.Db, message.Tenant, message.EventId, message.EventType, message.Payload)
+	return err
+}
+
+// Writes each message's CarEvent as a line of JSON, to stdout unless W is set
+type StdoutPublisher struct {
+	W io.Writer
+}
+
+func (p StdoutPublisher) Publish(message models.OutboxMessage) error {
+	w := p.W
+	if w == nil {
+		w = os.Stdout
+	}
+	_, err := w.Write(append(append([]byte{}, message.Payload...), '\n'))
+	return err
+}
+
+// Keeps published messages in memory, for tests
+type MemoryPublisher struct {
+	mu       sync.Mutex
+	messages []models.OutboxMessage
+	err      error
+}
+
+func (p *MemoryPublisher) Publish(message models.OutboxMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+	p.messages = append(p.messages, message)
+	return nil
+}
+
+func (p *MemoryPublisher) Messages() []models.OutboxMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]models.OutboxMessage{}, p.messages...)
+}
+
+// Make every Publish fail with err until it's set back to nil
+func (p *MemoryPublisher) SetErr(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
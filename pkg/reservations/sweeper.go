@@ -0,0 +1,61 @@
+package reservations
+
+import (
+	"context"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+)
+
+var (
+	// How often the sweeper looks for expired holds
+	SweepInterval = 30 * time.Second
+	// Holds expired per pass
+	SweepSize = 100
+)
+
+// Sweep expires the reservations that ran out by now, releasing their cars
+// and publishing a reservation_expired event for each. Returns how many
+// expired.
+func Sweep(db *clients.DBClient, now time.Time) (int, error) {
+	expired := 0
+	for {
+		n, err := models.ExpireReservations(db, now, SweepSize)
+		expired += n
+		if err != nil || n < SweepSize {
+			return expired, err
+		}
+	}
+}
+
+// Run sweeps every SweepInterval until ctx is done. Several instances can
+// run at once; the car row lock keeps a hold from being expired twice.
+func Run(ctx context.Context) error {
+	log := logging.GetLog(ctx)
+
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return err
+	}
+	defer clients.Close(&db)
+
+	ticker := time.NewTicker(SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		expired, err := Sweep(&db, time.Now())
+		if err != nil {
+			log.WithError(err).Error("Reservations: sweep failed")
+		} else if expired > 0 {
+			log.Infof("Reservations: expired %d holds", expired)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
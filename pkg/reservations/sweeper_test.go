@@ -0,0 +1,123 @@
+package reservations_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/harness"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
+	"github.com/ericmcbride/go-dfw-testing/pkg/reservations"
+	"github.com/satori/go.uuid"
+)
+
+func TestMain(m *testing.M) {
+	harness.Run(m)
+}
+
+func connect(t *testing.T) clients.DBClient {
+	db, err := clients.NewDbConn()
+	if err != nil {
+		t.Fatalf("Couldnt connect to db")
+	}
+	return db
+}
+
+// Save a listed car and hold it until expiresAt
+func heldCar(t *testing.T, db *clients.DBClient, expiresAt time.Time) models.Reservation {
+	car := &models.CarModel{Id: uuid.NewV4().String(), Model: "Corolla", Make: "Toyota", Color: "White", Year: 2018}
+	if _, err := models.SaveCar(db, car); err != nil {
+		t.Fatalf("Couldn't save car %s", err)
+	}
+	if _, err := db.Db.Exec(`UPDATE cars SET status = 'listed' WHERE id = $1`, car.Id); err != nil {
+		t.Fatalf("Couldn't list car %s", err)
+	}
+
+	reservation := models.Reservation{
+		Id:         uuid.NewV4().String(),
+		CarId:      car.Id,
+		HolderName: "Jo Buyer",
+		ExpiresAt:  expiresAt,
+		Tenant:     models.DefaultTenant,
+	}
+	if err := models.ReserveCar(db, &reservation, "test", ""); err != nil {
+		t.Fatalf("Couldn't reserve car %s", err)
+	}
+	return reservation
+}
+
+func TestSweepExpiresHolds(t *testing.T) {
+	db := connect(t)
+	defer clients.Close(&db)
+	defer harness.Truncate()
+
+	now := time.Now()
+	due := heldCar(t, &db, now.Add(time.Hour))
+	later := heldCar(t, &db, now.Add(3*time.Hour))
+
+	expired, err := reservations.Sweep(&db, now.Add(2*time.Hour))
+	if err != nil || expired != 1 {
+		t.Fatalf("Expected one hold to expire, got %d %v", expired, err)
+	}
+
+	held, _ := models.ListReservations(&db, models.DefaultTenant, due.CarId)
+	if len(held) != 1 || held[0].Status != models.ReservationExpired {
+		t.Errorf("Expected the due hold to expire, got %+v", held)
+	}
+	held, _ = models.ListReservations(&db, models.DefaultTenant, later.CarId)
+	if len(held) != 1 || held[0].Status != models.ReservationActive {
+		t.Errorf("Expected the later hold to stay active, got %+v", held)
+	}
+
+	car, _ := models.GetCarColumns(&db, models.DefaultTenant, due.CarId, []string{"status"})
+	if car.Status != "listed" {
+		t.Errorf("Expected the car to be released, got %q", car.Status)
+	}
+	transitions, _ := models.ListTransitions(&db, models.DefaultTenant, due.CarId)
+	if last := transitions[len(transitions)-1]; last.Event != "release" || last.Actor != models.ExpiryActor || last.Credential != "" {
+		t.Errorf("Expected a release by the sweeper, got %+v", last)
+	}
+
+	var payload []byte
+	err = db.Db.QueryRow(`SELECT payload FROM outbox WHERE event_type = 'reservation_expired'`).Scan(&payload)
+	if err != nil {
+		t.Fatalf("Expected a reservation_expired event %s", err)
+	}
+	if err := openapi.ValidateSchema("CarEvent", payload); err != nil {
+		t.Errorf("Event doesn't match CarEvent schema: %s %s", err, payload)
+	}
+
+	// Nothing is expired twice
+	if expired, err = reservations.Sweep(&db, now.Add(2*time.Hour)); err != nil || expired != 0 {
+		t.Errorf("Expected nothing left to expire, got %d %v", expired, err)
+	}
+}
+
+func TestReserveAfterUnsweptExpiry(t *testing.T) {
+	db := connect(t)
+	defer clients.Close(&db)
+	defer harness.Truncate()
+
+	stale := heldCar(t, &db, time.Now().Add(time.Hour))
+	if _, err := db.Db.Exec(`UPDATE reservations SET expires_at = now() - interval '1 minute' WHERE id = $1`, stale.Id); err != nil {
+		t.Fatalf("Couldn't age reservation %s", err)
+	}
+
+	// The run out hold doesn't block a new one
+	next := models.Reservation{
+		Id:         uuid.NewV4().String(),
+		CarId:      stale.CarId,
+		HolderName: "Next Buyer",
+		ExpiresAt:  time.Now().Add(time.Hour),
+		Tenant:     models.DefaultTenant,
+	}
+	if err := models.ReserveCar(&db, &next, "test", ""); err != nil {
+		t.Fatalf("Expected the stale hold to be expired first, got %s", err)
+	}
+
+	held, _ := models.ListReservations(&db, models.DefaultTenant, stale.CarId)
+	if len(held) != 2 || held[0].Status != models.ReservationExpired || held[1].Status != models.ReservationActive {
+		t.Errorf("Expected the stale hold expired and the new one active, got %+v", held)
+	}
+}
@@ -0,0 +1,77 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata key carrying the caller's credential, the X-CARS-ID header of the
+// HTTP API
+const AuthMetadataKey = "x-cars-id"
+
+// Health checks come from load balancers and orchestrators that don't hold
+// a credential
+const healthPrefix = "/grpc.health.v1.Health/"
+
+type tenantKey struct{}
+
+// Tenant the authenticated caller acts for
+func TenantFrom(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+func authenticate(ctx context.Context, method string) (context.Context, error) {
+	if strings.HasPrefix(method, healthPrefix) {
+		return ctx, nil
+	}
+
+	var auth string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(AuthMetadataKey); len(values) > 0 {
+			auth = values[0]
+		}
+	}
+
+	tenant, err := handlers.TenantForAuthId(auth)
+	if err != nil {
+		logging.GetLog(ctx).Error("Unauthorized Auth Id: ", err)
+		return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+	}
+	return context.WithValue(ctx, tenantKey{}, tenant), nil
+}
+
+// Reject unary calls without a valid x-cars-id, the same way ValidateAuthId
+// guards the HTTP handlers
+func UnaryAuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// Reject streaming calls without a valid x-cars-id
+func StreamAuthInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authedStream{ServerStream: ss, ctx: ctx})
+}
+
+type authedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authedStream) Context() context.Context {
+	return s.ctx
+}
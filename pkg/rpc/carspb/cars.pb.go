@@ -0,0 +1,770 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: cars.proto
+
+package carspb
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type Car struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Make                 string   `protobuf:"bytes,2,opt,name=make,proto3" json:"make,omitempty"`
+	Model                string   `protobuf:"bytes,3,opt,name=model,proto3" json:"model,omitempty"`
+	Color                string   `protobuf:"bytes,4,opt,name=color,proto3" json:"color,omitempty"`
+	Year                 int32    `protobuf:"varint,5,opt,name=year,proto3" json:"year,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Car) Reset()         { *m = Car{} }
+func (m *Car) String() string { return proto.CompactTextString(m) }
+func (*Car) ProtoMessage()    {}
+func (*Car) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8351e959ca30fd84, []int{0}
+}
+
+func (m *Car) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Car.Unmarshal(m, b)
+}
+func (m *Car) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Car.Marshal(b, m, deterministic)
+}
+func (m *Car) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Car.Merge(m, src)
+}
+func (m *Car) XXX_Size() int {
+	return xxx_messageInfo_Car.Size(m)
+}
+func (m *Car) XXX_DiscardUnknown() {
+	xxx_messageInfo_Car.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Car proto.InternalMessageInfo
+
+func (m *Car) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Car) GetMake() string {
+	if m != nil {
+		return m.Make
+	}
+	return ""
+}
+
+func (m *Car) GetModel() string {
+	if m != nil {
+		return m.Model
+	}
+	return ""
+}
+
+func (m *Car) GetColor() string {
+	if m != nil {
+		return m.Color
+	}
+	return ""
+}
+
+func (m *Car) GetYear() int32 {
+	if m != nil {
+		return m.Year
+	}
+	return 0
+}
+
+type CreateCarRequest struct {
+	Make                 string   `protobuf:"bytes,1,opt,name=make,proto3" json:"make,omitempty"`
+	Model                string   `protobuf:"bytes,2,opt,name=model,proto3" json:"model,omitempty"`
+	Color                string   `protobuf:"bytes,3,opt,name=color,proto3" json:"color,omitempty"`
+	Year                 int32    `protobuf:"varint,4,opt,name=year,proto3" json:"year,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CreateCarRequest) Reset()         { *m = CreateCarRequest{} }
+func (m *CreateCarRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateCarRequest) ProtoMessage()    {}
+func (*CreateCarRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8351e959ca30fd84, []int{1}
+}
+
+func (m *CreateCarRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateCarRequest.Unmarshal(m, b)
+}
+func (m *CreateCarRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateCarRequest.Marshal(b, m, deterministic)
+}
+func (m *CreateCarRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateCarRequest.Merge(m, src)
+}
+func (m *CreateCarRequest) XXX_Size() int {
+	return xxx_messageInfo_CreateCarRequest.Size(m)
+}
+func (m *CreateCarRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateCarRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateCarRequest proto.InternalMessageInfo
+
+func (m *CreateCarRequest) GetMake() string {
+	if m != nil {
+		return m.Make
+	}
+	return ""
+}
+
+func (m *CreateCarRequest) GetModel() string {
+	if m != nil {
+		return m.Model
+	}
+	return ""
+}
+
+func (m *CreateCarRequest) GetColor() string {
+	if m != nil {
+		return m.Color
+	}
+	return ""
+}
+
+func (m *CreateCarRequest) GetYear() int32 {
+	if m != nil {
+		return m.Year
+	}
+	return 0
+}
+
+type GetCarRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetCarRequest) Reset()         { *m = GetCarRequest{} }
+func (m *GetCarRequest) String() string { return proto.CompactTextString(m) }
+func (*GetCarRequest) ProtoMessage()    {}
+func (*GetCarRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8351e959ca30fd84, []int{2}
+}
+
+func (m *GetCarRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetCarRequest.Unmarshal(m, b)
+}
+func (m *GetCarRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetCarRequest.Marshal(b, m, deterministic)
+}
+func (m *GetCarRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetCarRequest.Merge(m, src)
+}
+func (m *GetCarRequest) XXX_Size() int {
+	return xxx_messageInfo_GetCarRequest.Size(m)
+}
+func (m *GetCarRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetCarRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetCarRequest proto.InternalMessageInfo
+
+func (m *GetCarRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type DeleteCarRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteCarRequest) Reset()         { *m = DeleteCarRequest{} }
+func (m *DeleteCarRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteCarRequest) ProtoMessage()    {}
+func (*DeleteCarRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8351e959ca30fd84, []int{3}
+}
+
+func (m *DeleteCarRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteCarRequest.Unmarshal(m, b)
+}
+func (m *DeleteCarRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteCarRequest.Marshal(b, m, deterministic)
+}
+func (m *DeleteCarRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteCarRequest.Merge(m, src)
+}
+func (m *DeleteCarRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteCarRequest.Size(m)
+}
+func (m *DeleteCarRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteCarRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteCarRequest proto.InternalMessageInfo
+
+func (m *DeleteCarRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type DeleteCarResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteCarResponse) Reset()         { *m = DeleteCarResponse{} }
+func (m *DeleteCarResponse) String() string { return proto.CompactTextString(m) }
+func (*DeleteCarResponse) ProtoMessage()    {}
+func (*DeleteCarResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8351e959ca30fd84, []int{4}
+}
+
+func (m *DeleteCarResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteCarResponse.Unmarshal(m, b)
+}
+func (m *DeleteCarResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteCarResponse.Marshal(b, m, deterministic)
+}
+func (m *DeleteCarResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteCarResponse.Merge(m, src)
+}
+func (m *DeleteCarResponse) XXX_Size() int {
+	return xxx_messageInfo_DeleteCarResponse.Size(m)
+}
+func (m *DeleteCarResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteCarResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteCarResponse proto.InternalMessageInfo
+
+// Unset filters are ignored
+type ListCarsRequest struct {
+	Make                 string   `protobuf:"bytes,1,opt,name=make,proto3" json:"make,omitempty"`
+	Model                string   `protobuf:"bytes,2,opt,name=model,proto3" json:"model,omitempty"`
+	Color                string   `protobuf:"bytes,3,opt,name=color,proto3" json:"color,omitempty"`
+	Year                 int32    `protobuf:"varint,4,opt,name=year,proto3" json:"year,omitempty"`
+	YearMin              int32    `protobuf:"varint,5,opt,name=year_min,json=yearMin,proto3" json:"year_min,omitempty"`
+	YearMax              int32    `protobuf:"varint,6,opt,name=year_max,json=yearMax,proto3" json:"year_max,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListCarsRequest) Reset()         { *m = ListCarsRequest{} }
+func (m *ListCarsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListCarsRequest) ProtoMessage()    {}
+func (*ListCarsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8351e959ca30fd84, []int{5}
+}
+
+func (m *ListCarsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListCarsRequest.Unmarshal(m, b)
+}
+func (m *ListCarsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListCarsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListCarsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListCarsRequest.Merge(m, src)
+}
+func (m *ListCarsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListCarsRequest.Size(m)
+}
+func (m *ListCarsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListCarsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListCarsRequest proto.InternalMessageInfo
+
+func (m *ListCarsRequest) GetMake() string {
+	if m != nil {
+		return m.Make
+	}
+	return ""
+}
+
+func (m *ListCarsRequest) GetModel() string {
+	if m != nil {
+		return m.Model
+	}
+	return ""
+}
+
+func (m *ListCarsRequest) GetColor() string {
+	if m != nil {
+		return m.Color
+	}
+	return ""
+}
+
+func (m *ListCarsRequest) GetYear() int32 {
+	if m != nil {
+		return m.Year
+	}
+	return 0
+}
+
+func (m *ListCarsRequest) GetYearMin() int32 {
+	if m != nil {
+		return m.YearMin
+	}
+	return 0
+}
+
+func (m *ListCarsRequest) GetYearMax() int32 {
+	if m != nil {
+		return m.YearMax
+	}
+	return 0
+}
+
+type WatchCarsRequest struct {
+	// Resume after this event, as long as it's still buffered
+	LastEventId          int64    `protobuf:"varint,1,opt,name=last_event_id,json=lastEventId,proto3" json:"last_event_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchCarsRequest) Reset()         { *m = WatchCarsRequest{} }
+func (m *WatchCarsRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchCarsRequest) ProtoMessage()    {}
+func (*WatchCarsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8351e959ca30fd84, []int{6}
+}
+
+func (m *WatchCarsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchCarsRequest.Unmarshal(m, b)
+}
+func (m *WatchCarsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchCarsRequest.Marshal(b, m, deterministic)
+}
+func (m *WatchCarsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchCarsRequest.Merge(m, src)
+}
+func (m *WatchCarsRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchCarsRequest.Size(m)
+}
+func (m *WatchCarsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchCarsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchCarsRequest proto.InternalMessageInfo
+
+func (m *WatchCarsRequest) GetLastEventId() int64 {
+	if m != nil {
+		return m.LastEventId
+	}
+	return 0
+}
+
+type CarEvent struct {
+	Id int64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// created, updated or deleted, or reset with no car
+	// when changes may have been missed and the client should refetch
+	Type                 string   `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Car                  *Car     `protobuf:"bytes,3,opt,name=car,proto3" json:"car,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CarEvent) Reset()         { *m = CarEvent{} }
+func (m *CarEvent) String() string { return proto.CompactTextString(m) }
+func (*CarEvent) ProtoMessage()    {}
+func (*CarEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8351e959ca30fd84, []int{7}
+}
+
+func (m *CarEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CarEvent.Unmarshal(m, b)
+}
+func (m *CarEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CarEvent.Marshal(b, m, deterministic)
+}
+func (m *CarEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CarEvent.Merge(m, src)
+}
+func (m *CarEvent) XXX_Size() int {
+	return xxx_messageInfo_CarEvent.Size(m)
+}
+func (m *CarEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_CarEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CarEvent proto.InternalMessageInfo
+
+func (m *CarEvent) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *CarEvent) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *CarEvent) GetCar() *Car {
+	if m != nil {
+		return m.Car
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*Car)(nil), "cars.v1.Car")
+	proto.RegisterType((*CreateCarRequest)(nil), "cars.v1.CreateCarRequest")
+	proto.RegisterType((*GetCarRequest)(nil), "cars.v1.GetCarRequest")
+	proto.RegisterType((*DeleteCarRequest)(nil), "cars.v1.DeleteCarRequest")
+	proto.RegisterType((*DeleteCarResponse)(nil), "cars.v1.DeleteCarResponse")
+	proto.RegisterType((*ListCarsRequest)(nil), "cars.v1.ListCarsRequest")
+	proto.RegisterType((*WatchCarsRequest)(nil), "cars.v1.WatchCarsRequest")
+	proto.RegisterType((*CarEvent)(nil), "cars.v1.CarEvent")
+}
+
+func init() { proto.RegisterFile("cars.proto", fileDescriptor_8351e959ca30fd84) }
+
+var fileDescriptor_8351e959ca30fd84 = []byte{
+	// 400 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x53, 0xc1, 0x8e, 0x9b, 0x30,
+	0x10, 0x15, 0x90, 0x10, 0x98, 0x34, 0x6d, 0xe2, 0x56, 0x15, 0xe1, 0xd0, 0x46, 0x3e, 0xe5, 0x84,
+	0xd2, 0x34, 0xea, 0xa5, 0xb7, 0xd0, 0xaa, 0xaa, 0xd4, 0xf6, 0x40, 0x0f, 0x95, 0x7a, 0x89, 0x1c,
+	0x98, 0xaa, 0x68, 0x09, 0xb0, 0xc6, 0x1b, 0x25, 0xdf, 0xb2, 0x5f, 0xb0, 0x7f, 0xb9, 0xb2, 0x49,
+	0x88, 0x83, 0xd0, 0xde, 0xf6, 0xc4, 0xcc, 0x9b, 0xb1, 0x1f, 0xf3, 0xde, 0x18, 0x20, 0x66, 0xbc,
+	0x0a, 0x4a, 0x5e, 0x88, 0x82, 0x0c, 0x54, 0xbc, 0xff, 0x40, 0x53, 0xb0, 0x42, 0xc6, 0xc9, 0x4b,
+	0x30, 0xd3, 0xc4, 0x33, 0x66, 0xc6, 0xdc, 0x8d, 0xcc, 0x34, 0x21, 0x04, 0x7a, 0x3b, 0x76, 0x83,
+	0x9e, 0xa9, 0x10, 0x15, 0x93, 0x37, 0xd0, 0xdf, 0x15, 0x09, 0x66, 0x9e, 0xa5, 0xc0, 0x3a, 0x91,
+	0x68, 0x5c, 0x64, 0x05, 0xf7, 0x7a, 0x35, 0xaa, 0x12, 0x79, 0xfe, 0x88, 0x8c, 0x7b, 0xfd, 0x99,
+	0x31, 0xef, 0x47, 0x2a, 0xa6, 0xff, 0x60, 0x1c, 0x72, 0x64, 0x02, 0x43, 0xc6, 0x23, 0xbc, 0xbd,
+	0xc3, 0x4a, 0x34, 0x3c, 0x46, 0x17, 0x8f, 0xd9, 0xc9, 0x63, 0x75, 0xf1, 0xf4, 0x34, 0x9e, 0xf7,
+	0x30, 0xfa, 0x86, 0x42, 0x23, 0x69, 0x0d, 0x47, 0x29, 0x8c, 0xbf, 0x60, 0x86, 0x02, 0x9f, 0xe8,
+	0x79, 0x0d, 0x13, 0xad, 0xa7, 0x2a, 0x8b, 0xbc, 0x42, 0x7a, 0x6f, 0xc0, 0xab, 0x1f, 0x69, 0x25,
+	0xef, 0xae, 0x9e, 0x71, 0x02, 0x32, 0x05, 0x47, 0x7e, 0x37, 0xbb, 0x34, 0x3f, 0x29, 0x38, 0x90,
+	0xf9, 0xcf, 0x34, 0xbf, 0x94, 0xd8, 0xc1, 0xb3, 0xb5, 0x12, 0x3b, 0xd0, 0x4f, 0x30, 0xfe, 0xc3,
+	0x44, 0xfc, 0x5f, 0xff, 0x3b, 0x0a, 0xa3, 0x8c, 0x55, 0x62, 0x83, 0x7b, 0xcc, 0xc5, 0xe6, 0x34,
+	0xa1, 0x15, 0x0d, 0x25, 0xf8, 0x55, 0x62, 0xdf, 0x13, 0xfa, 0x0b, 0x9c, 0x90, 0x71, 0x95, 0x69,
+	0x32, 0x58, 0xe7, 0x3d, 0x10, 0xc7, 0xb2, 0xd9, 0x03, 0x19, 0x93, 0x77, 0x60, 0xc5, 0xac, 0x9e,
+	0x62, 0xb8, 0x7c, 0x11, 0x9c, 0x36, 0x29, 0x90, 0x42, 0xc9, 0xc2, 0xf2, 0xc1, 0x04, 0x08, 0x19,
+	0xff, 0x8d, 0x7c, 0x9f, 0xc6, 0x48, 0x56, 0xe0, 0x36, 0xb6, 0x93, 0xe9, 0xa5, 0xbd, 0xb5, 0x0a,
+	0xfe, 0xd5, 0x4d, 0x24, 0x00, 0xbb, 0x36, 0x91, 0xbc, 0x6d, 0xf0, 0x2b, 0x57, 0x5b, 0xfd, 0x6b,
+	0x70, 0x1b, 0xbf, 0x34, 0x96, 0xb6, 0xcf, 0xbe, 0xdf, 0x55, 0xaa, 0xed, 0x25, 0x2b, 0x70, 0xce,
+	0xee, 0x12, 0xaf, 0xe9, 0x6b, 0x19, 0x7e, 0xcd, 0xbb, 0x30, 0xc8, 0x67, 0x70, 0x1b, 0xd9, 0x35,
+	0xe6, 0xb6, 0x15, 0xfe, 0x44, 0x3f, 0xa7, 0xd4, 0x5e, 0x18, 0x6b, 0xe7, 0xaf, 0x2d, 0xd1, 0x72,
+	0xbb, 0xb5, 0xd5, 0xc3, 0xfc, 0xf8, 0x38, 0x00, 0xb4, 0x4b, 0xd6, 0x41, 0xa6, 0x03, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// CarServiceClient is the client API for CarService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type CarServiceClient interface {
+	CreateCar(ctx context.Context, in *CreateCarRequest, opts ...grpc.CallOption) (*Car, error)
+	GetCar(ctx context.Context, in *GetCarRequest, opts ...grpc.CallOption) (*Car, error)
+	DeleteCar(ctx context.Context, in *DeleteCarRequest, opts ...grpc.CallOption) (*DeleteCarResponse, error)
+	// Every car matching the filters, streamed in id order
+	ListCars(ctx context.Context, in *ListCarsRequest, opts ...grpc.CallOption) (CarService_ListCarsClient, error)
+	// The caller's tenant's car changes as they happen
+	WatchCars(ctx context.Context, in *WatchCarsRequest, opts ...grpc.CallOption) (CarService_WatchCarsClient, error)
+}
+
+type carServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCarServiceClient(cc grpc.ClientConnInterface) CarServiceClient {
+	return &carServiceClient{cc}
+}
+
+func (c *carServiceClient) CreateCar(ctx context.Context, in *CreateCarRequest, opts ...grpc.CallOption) (*Car, error) {
+	out := new(Car)
+	err := c.cc.Invoke(ctx, "/cars.v1.CarService/CreateCar", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *carServiceClient) GetCar(ctx context.Context, in *GetCarRequest, opts ...grpc.CallOption) (*Car, error) {
+	out := new(Car)
+	err := c.cc.Invoke(ctx, "/cars.v1.CarService/GetCar", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *carServiceClient) DeleteCar(ctx context.Context, in *DeleteCarRequest, opts ...grpc.CallOption) (*DeleteCarResponse, error) {
+	out := new(DeleteCarResponse)
+	err := c.cc.Invoke(ctx, "/cars.v1.CarService/DeleteCar", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *carServiceClient) ListCars(ctx context.Context, in *ListCarsRequest, opts ...grpc.CallOption) (CarService_ListCarsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_CarService_serviceDesc.Streams[0], "/cars.v1.CarService/ListCars", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &carServiceListCarsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CarService_ListCarsClient interface {
+	Recv() (*Car, error)
+	grpc.ClientStream
+}
+
+type carServiceListCarsClient struct {
+	grpc.ClientStream
+}
+
+func (x *carServiceListCarsClient) Recv() (*Car, error) {
+	m := new(Car)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *carServiceClient) WatchCars(ctx context.Context, in *WatchCarsRequest, opts ...grpc.CallOption) (CarService_WatchCarsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_CarService_serviceDesc.Streams[1], "/cars.v1.CarService/WatchCars", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &carServiceWatchCarsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CarService_WatchCarsClient interface {
+	Recv() (*CarEvent, error)
+	grpc.ClientStream
+}
+
+type carServiceWatchCarsClient struct {
+	grpc.ClientStream
+}
+
+func (x *carServiceWatchCarsClient) Recv() (*CarEvent, error) {
+	m := new(CarEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// CarServiceServer is the server API for CarService service.
+type CarServiceServer interface {
+	CreateCar(context.Context, *CreateCarRequest) (*Car, error)
+	GetCar(context.Context, *GetCarRequest) (*Car, error)
+	DeleteCar(context.Context, *DeleteCarRequest) (*DeleteCarResponse, error)
+	// Every car matching the filters, streamed in id order
+	ListCars(*ListCarsRequest, CarService_ListCarsServer) error
+	// The caller's tenant's car changes as they happen
+	WatchCars(*WatchCarsRequest, CarService_WatchCarsServer) error
+}
+
+// UnimplementedCarServiceServer can be embedded to have forward compatible implementations.
+type UnimplementedCarServiceServer struct {
+}
+
+func (*UnimplementedCarServiceServer) CreateCar(ctx context.Context, req *CreateCarRequest) (*Car, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateCar not implemented")
+}
+func (*UnimplementedCarServiceServer) GetCar(ctx context.Context, req *GetCarRequest) (*Car, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCar not implemented")
+}
+func (*UnimplementedCarServiceServer) DeleteCar(ctx context.Context, req *DeleteCarRequest) (*DeleteCarResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteCar not implemented")
+}
+func (*UnimplementedCarServiceServer) ListCars(req *ListCarsRequest, srv CarService_ListCarsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListCars not implemented")
+}
+func (*UnimplementedCarServiceServer) WatchCars(req *WatchCarsRequest, srv CarService_WatchCarsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchCars not implemented")
+}
+
+func RegisterCarServiceServer(s *grpc.Server, srv CarServiceServer) {
+	s.RegisterService(&_CarService_serviceDesc, srv)
+}
+
+func _CarService_CreateCar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCarRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CarServiceServer).CreateCar(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cars.v1.CarService/CreateCar",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CarServiceServer).CreateCar(ctx, req.(*CreateCarRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CarService_GetCar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCarRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CarServiceServer).GetCar(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cars.v1.CarService/GetCar",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CarServiceServer).GetCar(ctx, req.(*GetCarRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CarService_DeleteCar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteCarRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CarServiceServer).DeleteCar(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cars.v1.CarService/DeleteCar",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CarServiceServer).DeleteCar(ctx, req.(*DeleteCarRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CarService_ListCars_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListCarsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CarServiceServer).ListCars(m, &carServiceListCarsServer{stream})
+}
+
+type CarService_ListCarsServer interface {
+	Send(*Car) error
+	grpc.ServerStream
+}
+
+type carServiceListCarsServer struct {
+	grpc.ServerStream
+}
+
+func (x *carServiceListCarsServer) Send(m *Car) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _CarService_WatchCars_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchCarsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CarServiceServer).WatchCars(m, &carServiceWatchCarsServer{stream})
+}
+
+type CarService_WatchCarsServer interface {
+	Send(*CarEvent) error
+	grpc.ServerStream
+}
+
+type carServiceWatchCarsServer struct {
+	grpc.ServerStream
+}
+
+func (x *carServiceWatchCarsServer) Send(m *CarEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _CarService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "cars.v1.CarService",
+	HandlerType: (*CarServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateCar",
+			Handler:    _CarService_CreateCar_Handler,
+		},
+		{
+			MethodName: "GetCar",
+			Handler:    _CarService_GetCar_Handler,
+		},
+		{
+			MethodName: "DeleteCar",
+			Handler:    _CarService_DeleteCar_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListCars",
+			Handler:       _CarService_ListCars_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchCars",
+			Handler:       _CarService_WatchCars_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "cars.proto",
+}
@@ -0,0 +1,241 @@
+package rpc_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/events"
+	"github.com/ericmcbride/go-dfw-testing/pkg/harness"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/rpc"
+	"github.com/ericmcbride/go-dfw-testing/pkg/rpc/carspb"
+	"github.com/satori/go.uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestMain(m *testing.M) {
+	harness.Run(m)
+}
+
+// Start a server over an in memory listener and connect to it
+func dial(t *testing.T, broker *events.Broker) (*grpc.ClientConn, func()) {
+	listener := bufconn.Listen(1024 * 1024)
+	server := rpc.New(broker)
+	go server.Serve(listener)
+
+	conn, err := grpc.Dial(
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		t.Fatalf("Couldn't dial bufconn %s", err)
+	}
+
+	return conn, func() {
+		conn.Close()
+		server.Stop()
+	}
+}
+
+func authed() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	return metadata.AppendToOutgoingContext(ctx, rpc.AuthMetadataKey, "1234"), cancel
+}
+
+func expectCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+	if status.Code(err) != code {
+		t.Errorf("Expected: %s, but got: %v", code, err)
+	}
+}
+
+func TestUnauthenticated(t *testing.T) {
+	conn, stop := dial(t, events.NewBroker(10))
+	defer stop()
+	client := carspb.NewCarServiceClient(conn)
+
+	_, err := client.GetCar(context.Background(), &carspb.GetCarRequest{Id: uuid.NewV4().String()})
+	expectCode(t, err, codes.Unauthenticated)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), rpc.AuthMetadataKey, "nope")
+	stream, err := client.ListCars(ctx, &carspb.ListCarsRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	expectCode(t, err, codes.Unauthenticated)
+}
+
+func TestHealthCheck(t *testing.T) {
+	conn, stop := dial(t, events.NewBroker(10))
+	defer stop()
+	client := healthpb.NewHealthClient(conn)
+
+	// No credential needed
+	for _, service := range []string{"", rpc.ServiceName} {
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatalf("Health check for %q failed: %s", service, err)
+		}
+		if resp.Status != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("Expected %q to be SERVING, got %s", service, resp.Status)
+		}
+	}
+}
+
+func TestCarLifecycle(t *testing.T) {
+	conn, stop := dial(t, events.NewBroker(10))
+	defer stop()
+	client := carspb.NewCarServiceClient(conn)
+	ctx, cancel := authed()
+	defer cancel()
+
+	make := "Grpc-" + uuid.NewV4().String()[:8]
+	created := []*carspb.Car{}
+	for _, year := range []int32{2012, 2015, 2019} {
+		car, err := client.CreateCar(ctx, &carspb.CreateCarRequest{Make: make, Model: "Camry", Color: "red", Year: year})
+		if err != nil {
+			t.Fatalf("Couldn't create car %s", err)
+		}
+		created = append(created, car)
+	}
+
+	car, err := client.GetCar(ctx, &carspb.GetCarRequest{Id: created[0].Id})
+	if err != nil {
+		t.Fatalf("Couldn't get car %s", err)
+	}
+	if car.Id != created[0].Id || car.Year != 2012 {
+		t.Errorf("Expected %v, got %v", created[0], car)
+	}
+
+	stream, err := client.ListCars(ctx, &carspb.ListCarsRequest{Make: make, YearMin: 2013})
+	if err != nil {
+		t.Fatalf("Couldn't list cars %s", err)
+	}
+	var listed []*carspb.Car
+	for {
+		car, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Couldn't receive car %s", err)
+		}
+		listed = append(listed, car)
+	}
+	if len(listed) != 2 {
+		t.Errorf("Expected 2 cars from 2013 on, got %d", len(listed))
+	}
+
+	for _, car := range created {
+		if _, err := client.DeleteCar(ctx, &carspb.DeleteCarRequest{Id: car.Id}); err != nil {
+			t.Errorf("Couldn't delete car %s", err)
+		}
+	}
+
+	_, err = client.GetCar(ctx, &carspb.GetCarRequest{Id: created[0].Id})
+	expectCode(t, err, codes.NotFound)
+	_, err = client.DeleteCar(ctx, &carspb.DeleteCarRequest{Id: created[0].Id})
+	expectCode(t, err, codes.NotFound)
+}
+
+func TestOtherTenantsCars(t *testing.T) {
+	conn, stop := dial(t, events.NewBroker(10))
+	defer stop()
+	client := carspb.NewCarServiceClient(conn)
+	ctx, cancel := authed()
+	defer cancel()
+
+	db, err := clients.NewDbConn()
+	if err != nil {
+		t.Fatalf("Couldnt connect to db")
+	}
+	defer clients.Close(&db)
+
+	other := models.CarModel{Id: uuid.NewV4().String(), Make: "Grpc-" + uuid.NewV4().String()[:8], Model: "Camry", Color: "red", Year: 2018, Tenant: "acme"}
+	if _, err := models.SaveCar(&db, &other); err != nil {
+		t.Fatalf("Couldn't save car %s", err)
+	}
+	defer models.DeleteCar(&db, "acme", other.Id)
+
+	_, err = client.GetCar(ctx, &carspb.GetCarRequest{Id: other.Id})
+	expectCode(t, err, codes.NotFound)
+	_, err = client.DeleteCar(ctx, &carspb.DeleteCarRequest{Id: other.Id})
+	expectCode(t, err, codes.NotFound)
+
+	stream, err := client.ListCars(ctx, &carspb.ListCarsRequest{Make: other.Make})
+	if err != nil {
+		t.Fatalf("Couldn't list cars %s", err)
+	}
+	if car, err := stream.Recv(); err != io.EOF {
+		t.Errorf("Expected no cars from another tenant, got %v %v", car, err)
+	}
+
+	if _, err := models.GetCar(&db, "acme", other.Id); err != nil {
+		t.Errorf("Expected another tenant's car to survive the delete, got %v", err)
+	}
+}
+
+func TestInvalidRequests(t *testing.T) {
+	conn, stop := dial(t, events.NewBroker(10))
+	defer stop()
+	client := carspb.NewCarServiceClient(conn)
+	ctx, cancel := authed()
+	defer cancel()
+
+	_, err := client.CreateCar(ctx, &carspb.CreateCarRequest{Make: "Toyota", Model: "Camry", Color: "red"})
+	expectCode(t, err, codes.InvalidArgument)
+
+	_, err = client.GetCar(ctx, &carspb.GetCarRequest{Id: "not-a-uuid"})
+	expectCode(t, err, codes.InvalidArgument)
+
+	_, err = client.DeleteCar(ctx, &carspb.DeleteCarRequest{})
+	expectCode(t, err, codes.InvalidArgument)
+}
+
+func TestWatchCars(t *testing.T) {
+	broker := events.NewBroker(10)
+	conn, stop := dial(t, broker)
+	defer stop()
+	client := carspb.NewCarServiceClient(conn)
+	ctx, cancel := authed()
+	defer cancel()
+
+	car := models.CarModel{Id: uuid.NewV4().String(), Make: "Toyota", Model: "Camry", Color: "red", Year: 2018}
+	broker.Publish(events.Event{Id: 1, Type: events.Created, Tenant: models.DefaultTenant, Car: car})
+	broker.Publish(events.Event{Id: 2, Type: events.Updated, Tenant: models.DefaultTenant, Car: car})
+
+	// Resuming after 1 replays 2, and the subscription is live once it's read
+	stream, err := client.WatchCars(ctx, &carspb.WatchCarsRequest{LastEventId: 1})
+	if err != nil {
+		t.Fatalf("Couldn't watch cars %s", err)
+	}
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Couldn't receive event %s", err)
+	}
+	if event.Id != 2 || event.Type != events.Updated || event.Car.Id != car.Id {
+		t.Errorf("Expected the replayed update, got %v", event)
+	}
+
+	broker.Publish(events.Event{Id: 3, Type: events.Updated, Tenant: "other", Car: car})
+	broker.Publish(events.Event{Id: 4, Type: events.Deleted, Tenant: models.DefaultTenant, Car: car})
+	event, err = stream.Recv()
+	if err != nil {
+		t.Fatalf("Couldn't receive event %s", err)
+	}
+	if event.Id != 4 || event.Type != events.Deleted {
+		t.Errorf("Expected only the default tenant's delete, got %v", event)
+	}
+}
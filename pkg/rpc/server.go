@@ -0,0 +1,246 @@
+package rpc
+
+import (
+	"context"
+	"net"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/events"
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/rpc/carspb"
+	"github.com/satori/go.uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	ServiceName = "cars.v1.CarService"
+
+	// Rows fetched per round trip while streaming ListCars
+	ListFetchSize = 1000
+)
+
+// CarServer implements carspb.CarServiceServer over the model layer, with
+// WatchCars fed by Broker. Every call acts for the tenant the auth
+// interceptor resolved, so another tenant's cars are NotFound.
+type CarServer struct {
+	Broker *events.Broker
+}
+
+// Build a gRPC server with CarService, the health service and the auth
+// interceptors
+func New(broker *events.Broker) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryAuthInterceptor),
+		grpc.StreamInterceptor(StreamAuthInterceptor),
+	)
+	carspb.RegisterCarServiceServer(server, &CarServer{Broker: broker})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	return server
+}
+
+// Serve gRPC on addr until ctx is done
+func Serve(ctx context.Context, addr string, broker *events.Broker) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	server := New(broker)
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+	return server.Serve(listener)
+}
+
+func (s *CarServer) CreateCar(ctx context.Context, req *carspb.CreateCarRequest) (*carspb.Car, error) {
+	log := logging.GetLog(ctx)
+	log.Info("CreateCar: Processing gRPC CreateCar...")
+
+	payload := handlers.CarPostPayload{
+		Make:  req.GetMake(),
+		Model: req.GetModel(),
+		Color: req.GetColor(),
+		Year:  int(req.GetYear()),
+	}
+	if err := handlers.ValidateCarPayload(&payload); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	defer clients.Close(&db)
+
+	car := models.CarModel{
+		Id:     uuid.NewV4().String(),
+		Make:   payload.Make,
+		Model:  payload.Model,
+		Color:  payload.Color,
+		Year:   payload.Year,
+		Tenant: TenantFrom(ctx),
+	}
+	if _, err := models.SaveCar(&db, &car); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return toProto(car), nil
+}
+
+func (s *CarServer) GetCar(ctx context.Context, req *carspb.GetCarRequest) (*carspb.Car, error) {
+	log := logging.GetLog(ctx)
+	log.Info("GetCar: Processing gRPC GetCar...")
+
+	if err := validateId(req.GetId()); err != nil {
+		return nil, err
+	}
+
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	defer clients.Close(&db)
+
+	car, err := models.GetCar(&db, TenantFrom(ctx), req.GetId())
+	if err == models.ErrCarNotFound {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return toProto(car), nil
+}
+
+func (s *CarServer) DeleteCar(ctx context.Context, req *carspb.DeleteCarRequest) (*carspb.DeleteCarResponse, error) {
+	log := logging.GetLog(ctx)
+	log.Info("DeleteCar: Processing gRPC DeleteCar...")
+
+	if err := validateId(req.GetId()); err != nil {
+		return nil, err
+	}
+
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	defer clients.Close(&db)
+
+	txn, err := db.Db.Begin()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if err := models.DeleteCarTx(txn, TenantFrom(ctx), req.GetId()); err != nil {
+		txn.Rollback()
+		if err == models.ErrCarNotFound {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if err := txn.Commit(); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &carspb.DeleteCarResponse{}, nil
+}
+
+func (s *CarServer) ListCars(req *carspb.ListCarsRequest, stream carspb.CarService_ListCarsServer) error {
+	log := logging.GetLog(stream.Context())
+	log.Info("ListCars: Processing gRPC ListCars...")
+
+	filter := models.CarFilter{
+		Tenant:  TenantFrom(stream.Context()),
+		Make:    req.GetMake(),
+		Model:   req.GetModel(),
+		Color:   req.GetColor(),
+		Year:    int(req.GetYear()),
+		YearMin: int(req.GetYearMin()),
+		YearMax: int(req.GetYearMax()),
+	}
+
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	defer clients.Close(&db)
+
+	err = models.StreamCars(&db, filter, ListFetchSize, func(car models.CarModel) error {
+		return stream.Send(toProto(car))
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+func (s *CarServer) WatchCars(req *carspb.WatchCarsRequest, stream carspb.CarService_WatchCarsServer) error {
+	ctx := stream.Context()
+	log := logging.GetLog(ctx)
+	log.Info("WatchCars: Processing gRPC WatchCars...")
+
+	sub, missed := s.Broker.Subscribe(TenantFrom(ctx), req.GetLastEventId())
+	defer sub.Close()
+
+	for _, event := range missed {
+		if err := stream.Send(toProtoEvent(event)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-sub.C:
+			if !ok {
+				// Same as the SSE stream: the client resumes with the last
+				// event id it saw
+				log.Warn("WatchCars: subscriber fell behind, closing stream")
+				return status.Error(codes.ResourceExhausted, "Fell too far behind, resume with last_event_id")
+			}
+			if err := stream.Send(toProtoEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func validateId(id string) error {
+	if _, err := uuid.FromString(id); err != nil {
+		return status.Error(codes.InvalidArgument, "id must be a valid car Id")
+	}
+	return nil
+}
+
+func toProto(car models.CarModel) *carspb.Car {
+	return &carspb.Car{
+		Id:    car.Id,
+		Make:  car.Make,
+		Model: car.Model,
+		Color: car.Color,
+		Year:  int32(car.Year),
+	}
+}
+
+func toProtoEvent(event events.Event) *carspb.CarEvent {
+	if event.Type == events.Reset {
+		return &carspb.CarEvent{Id: event.Id, Type: event.Type}
+	}
+	return &carspb.CarEvent{
+		Id:   event.Id,
+		Type: event.Type,
+		Car:  toProto(event.Car),
+	}
+}
@@ -1,7 +1,7 @@
 package server
 
 import (
-	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
 	"github.com/gorilla/mux"
 	"io"
 	"net/http"
@@ -15,7 +15,22 @@ func New() http.Handler {
 	m := mux.NewRouter()
 
 	m.HandleFunc("/{health:health(?:\\/)?}", HealthEndpointHandler)
-	m.HandleFunc("/{cars:cars(?:\\/)?}", handlers.CarsHandler)
+	m.HandleFunc("/openapi.json", openapi.SpecHandler)
+	m.HandleFunc("/{docs:docs(?:\\/)?}", openapi.DocsHandler)
+
+	// Versioned route trees, e.g. /v1/cars
+	for _, v := range Versions {
+		sub := m.PathPrefix("/" + v.Name).Subrouter()
+		for _, route := range v.Routes {
+			sub.Handle(route.Path, Deprecate(route)).Name(v.Name + "." + route.Name)
+		}
+	}
+
+	// Unversioned routes negotiate the version through the Accept header
+	latest := Versions[len(Versions)-1]
+	for _, route := range latest.Routes {
+		m.Handle(route.Path, Negotiate(route.Name)).Name(route.Name)
+	}
 
 	return m
 }
@@ -0,0 +1,182 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/openapi"
+	"github.com/ericmcbride/go-dfw-testing/pkg/server"
+	"github.com/gorilla/mux"
+)
+
+func TestMain(m *testing.M) {
+	logging.ConfigureLogger("ERROR")
+	os.Exit(m.Run())
+}
+
+func TestVersionedRouteNotDeprecated(t *testing.T) {
+	req, err := http.NewRequest("GET", "/v1/cars", nil)
+	if err != nil {
+		t.Errorf("Error while building request: %s", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := server.New()
+	handler.ServeHTTP(rr, req)
+
+	// No X-CARS-ID so the cars handler rejects it, but the route matched
+	if rr.Code != 401 {
+		t.Errorf("Expected: %d, but got: %d", 401, rr.Code)
+	}
+	if got := rr.Header().Get("Deprecation"); got != "" {
+		t.Errorf("Expected no Deprecation header, got: %s", got)
+	}
+}
+
+func TestUnversionedRouteDeprecated(t *testing.T) {
+	req, err := http.NewRequest("GET", "/cars", nil)
+	if err != nil {
+		t.Errorf("Error while building request: %s", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := server.New()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != 401 {
+		t.Errorf("Expected: %d, but got: %d", 401, rr.Code)
+	}
+	if rr.Header().Get("Deprecation") == "" {
+		t.Errorf("Expected a Deprecation header")
+	}
+	if got := rr.Header().Get("Sunset"); got != server.LegacySunset.Format(http.TimeFormat) {
+		t.Errorf("Expected Sunset: %s, got: %s", server.LegacySunset.Format(http.TimeFormat), got)
+	}
+	if got := rr.Header().Get("Link"); got != `</v1/cars>; rel="successor-version"` {
+		t.Errorf("Unexpected Link header: %s", got)
+	}
+}
+
+func TestAcceptVersionNegotiation(t *testing.T) {
+	req, err := http.NewRequest("GET", "/cars", nil)
+	if err != nil {
+		t.Errorf("Error while building request: %s", err)
+	}
+	req.Header.Set("Accept", "application/json; version=1")
+
+	rr := httptest.NewRecorder()
+	handler := server.New()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != 401 {
+		t.Errorf("Expected: %d, but got: %d", 401, rr.Code)
+	}
+	// Asking for a version doesn't make the unversioned path any less
+	// deprecated
+	if rr.Header().Get("Deprecation") == "" || rr.Header().Get("Sunset") != server.LegacySunset.Format(http.TimeFormat) {
+		t.Errorf("Expected Deprecation and Sunset headers, got: %v", rr.Header())
+	}
+	if got := rr.Header().Get("Link"); got != `</v1/cars>; rel="successor-version"` {
+		t.Errorf("Unexpected Link header: %s", got)
+	}
+}
+
+func TestAcceptVersionUnsupported(t *testing.T) {
+	req, err := http.NewRequest("GET", "/cars", nil)
+	if err != nil {
+		t.Errorf("Error while building request: %s", err)
+	}
+	req.Header.Set("Accept", "application/json; version=9")
+
+	rr := httptest.NewRecorder()
+	handler := server.New()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != 406 {
+		t.Errorf("Expected: %d, but got: %d", 406, rr.Code)
+	}
+	if got := rr.Header().Get("Link"); rr.Header().Get("Deprecation") == "" || got != `</v1/cars>; rel="successor-version"` {
+		t.Errorf("Expected deprecation headers pointing at the default version, got: %v", rr.Header())
+	}
+}
+
+func TestAcceptVersion(t *testing.T) {
+	cases := map[string]string{
+		"":                                       "",
+		"application/json":                       "",
+		"application/json; version=1":            "1",
+		"text/html, application/json;version=v1": "v1",
+	}
+
+	for accept, expected := range cases {
+		if got := server.AcceptVersion(accept); got != expected {
+			t.Errorf("Accept %q: expected %q, got %q", accept, expected, got)
+		}
+	}
+}
+
+func TestRoutesDocumented(t *testing.T) {
+	router := server.New().(*mux.Router)
+
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil
+		}
+
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+
+		path := openapi.NormalizePath(template)
+		if !openapi.Documented(path) {
+			t.Errorf("Route %s is not documented in the OpenAPI spec", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error while walking routes: %s", err)
+	}
+}
+
+func TestOpenApiEndpoint(t *testing.T) {
+	req, err := http.NewRequest("GET", "/openapi.json", nil)
+	if err != nil {
+		t.Errorf("Error while building request: %s", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := server.New()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != 200 {
+		t.Errorf("Expected: %d, but got: %d", 200, rr.Code)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Couldn't decode spec: %s", err)
+	}
+	if doc["openapi"] != "3.0.3" {
+		t.Errorf("Expected an OpenAPI 3 document, got: %v", doc["openapi"])
+	}
+}
+
+func TestHealthMatchesSchema(t *testing.T) {
+	req, err := http.NewRequest("GET", "/health", nil)
+	if err != nil {
+		t.Errorf("Error while building request: %s", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := server.New()
+	handler.ServeHTTP(rr, req)
+
+	if err := openapi.ValidateSchema("Health", rr.Body.Bytes()); err != nil {
+		t.Errorf("Health response doesn't match schema: %s", err)
+	}
+}
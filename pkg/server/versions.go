@@ -0,0 +1,195 @@
+package server
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/events"
+	"github.com/ericmcbride/go-dfw-testing/pkg/exporter"
+	"github.com/ericmcbride/go-dfw-testing/pkg/graph"
+	"github.com/ericmcbride/go-dfw-testing/pkg/handlers"
+	"github.com/ericmcbride/go-dfw-testing/pkg/importer"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+)
+
+// Version the unversioned routes resolve to when the client doesn't ask for one
+const DefaultVersion = "v1"
+
+// Unversioned routes are kept around for old consumers until the sunset date
+var (
+	LegacySince  = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	LegacySunset = time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC)
+)
+
+// Deprecation describes when a route stopped being supported and when it
+// will be removed. Link points consumers at the replacement.
+type Deprecation struct {
+	Since  time.Time
+	Sunset time.Time
+	Link   string
+}
+
+// Route is a single endpoint registered under a version.
+type Route struct {
+	Name       string
+	Path       string
+	Handler    http.HandlerFunc
+	Deprecated *Deprecation
+}
+
+// Version is a named tree of routes mounted under /{name}.
+type Version struct {
+	Name   string
+	Routes []Route
+}
+
+// Versions holds every API version the service exposes, oldest first.
+var Versions = []Version{
+	{
+		Name: "v1",
+		Routes: []Route{
+			{Name: "cars", Path: "/{cars:cars(?:\\/)?}", Handler: handlers.CarsHandler},
+			{Name: "cars.import", Path: "/cars:import", Handler: importer.ImportHandler},
+			{Name: "cars.export", Path: "/cars:export", Handler: exporter.ExportHandler},
+			{Name: "cars.batch", Path: "/cars:batch", Handler: handlers.BatchHandler},
+			{Name: "cars.events", Path: "/cars/events", Handler: events.StreamHandler(events.DefaultBroker)},
+			{Name: "cars.ws", Path: "/cars/ws", Handler: events.SocketHandler(events.DefaultBroker)},
+			{Name: "cars.search", Path: "/cars/search", Handler: handlers.SearchHandler},
+			{Name: "cars.stats", Path: "/cars/stats", Handler: handlers.StatsHandler},
+			{Name: "cars.service.overdue", Path: "/cars/service/overdue", Handler: handlers.ServiceOverdueHandler},
+			{Name: "cars.transfer", Path: "/cars/{id}/transfer", Handler: handlers.CarTransferHandler},
+			{Name: "cars.ownerships", Path: "/cars/{id}/ownerships", Handler: handlers.CarOwnershipsHandler},
+			{Name: "cars.move", Path: "/cars/{id}/move", Handler: handlers.CarMoveHandler},
+			{Name: "cars.moves", Path: "/cars/{id}/moves", Handler: handlers.CarMovesHandler},
+			{Name: "cars.transition", Path: "/cars/{id}/transitions/{event}", Handler: handlers.CarTransitionHandler},
+			{Name: "cars.transitions", Path: "/cars/{id}/transitions", Handler: handlers.CarTransitionsHandler},
+			{Name: "cars.reservations", Path: "/cars/{id}/reservations", Handler: handlers.CarReservationsHandler},
+			{Name: "cars.prices", Path: "/cars/{id}/prices", Handler: handlers.CarPricesHandler},
+			{Name: "cars.odometer", Path: "/cars/{id}/odometer", Handler: handlers.CarOdometerHandler},
+			{Name: "cars.service", Path: "/cars/{id}/service", Handler: handlers.CarServiceHandler},
+			{Name: "cars.service.due", Path: "/cars/{id}/service/due", Handler: handlers.CarServiceDueHandler},
+			{Name: "owners", Path: "/owners", Handler: handlers.OwnersHandler},
+			{Name: "dealerships", Path: "/dealerships", Handler: handlers.DealershipsHandler},
+			{Name: "locations", Path: "/locations", Handler: handlers.LocationsHandler},
+			{Name: "locations.inventory", Path: "/locations/inventory", Handler: handlers.InventoryHandler},
+			{Name: "catalog.makes", Path: "/catalog/makes", Handler: handlers.CatalogMakesHandler},
+			{Name: "catalog.models", Path: "/catalog/makes/{make}/models", Handler: handlers.CatalogModelsHandler},
+			{Name: "service.rules", Path: "/service/rules", Handler: handlers.ServiceRulesHandler},
+			{Name: "vins.decode", Path: "/vins/{vin}", Handler: handlers.VinHandler},
+			{Name: "webhooks", Path: "/webhooks", Handler: handlers.WebhooksHandler},
+			{Name: "webhooks.deliveries", Path: "/webhooks/deliveries", Handler: handlers.WebhookDeliveriesHandler},
+			{Name: "webhooks.replay", Path: "/webhooks:replay", Handler: handlers.WebhookReplayHandler},
+			{Name: "graphql", Path: "/graphql", Handler: graph.Handler},
+		},
+	},
+}
+
+// Look up a version by name, accepting both "v1" and "1"
+func FindVersion(name string) (Version, bool) {
+	if name != "" && !strings.HasPrefix(name, "v") {
+		name = "v" + name
+	}
+
+	for _, v := range Versions {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return Version{}, false
+}
+
+// Look up a route by name within a version
+func (v Version) Route(name string) (Route, bool) {
+	for _, route := range v.Routes {
+		if route.Name == name {
+			return route, true
+		}
+	}
+	return Route{}, false
+}
+
+// Pull the version parameter out of an Accept header, e.g.
+// "application/json; version=1". Returns "" when none is requested.
+func AcceptVersion(accept string) string {
+	for _, part := range strings.Split(accept, ",") {
+		_, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if v, ok := params["version"]; ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// Wrap a route so it emits Deprecation, Sunset and Link headers when it has
+// been marked deprecated.
+func Deprecate(route Route) http.Handler {
+	if route.Deprecated == nil {
+		return route.Handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetDeprecationHeaders(w, *route.Deprecated)
+		route.Handler(w, r)
+	})
+}
+
+func SetDeprecationHeaders(w http.ResponseWriter, d Deprecation) {
+	w.Header().Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+	if !d.Sunset.IsZero() {
+		w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Link != "" {
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, d.Link))
+	}
+}
+
+// Negotiate serves an unversioned route by picking the version out of the
+// Accept header, falling back to DefaultVersion. Unversioned paths are
+// deprecated, so every request is told so, whichever version it asks for.
+func Negotiate(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+
+		requested := AcceptVersion(r.Header.Get("Accept"))
+		version, ok := FindVersion(requested)
+		if requested == "" {
+			version, ok = FindVersion(DefaultVersion)
+		}
+
+		// The successor is the versioned path of the version asked for, or
+		// of the default when that version doesn't exist
+		successor := DefaultVersion
+		if ok {
+			successor = version.Name
+		}
+		SetDeprecationHeaders(w, Deprecation{
+			Since:  LegacySince,
+			Sunset: LegacySunset,
+			Link:   "/" + successor + r.URL.Path,
+		})
+
+		if !ok {
+			logging.FormatError(r.Context(), w, http.StatusNotAcceptable, logging.JsonError{
+				Title:   http.StatusText(http.StatusNotAcceptable),
+				Code:    strconv.Itoa(http.StatusNotAcceptable),
+				Message: fmt.Sprintf("Unsupported API version %q", requested),
+			})
+			return
+		}
+
+		route, ok := version.Route(name)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		Deprecate(route).ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,252 @@
+package vin
+
+// The bundled WMI and VDS lookups. WMIs are the first three characters of a
+// VIN, VDS patterns are matched against characters 4 to 8 with * matching
+// anything, first match wins. Plants are keyed by the 11th character. Makes
+// and models use the catalog's names.
+const dataset = `{
+  "manufacturers": [
+    {
+      "wmi": ["5YJ", "7SA"],
+      "make": "Tesla",
+      "manufacturer": "Tesla, Inc.",
+      "models": [
+        {"vds": "S", "model": "Model S"},
+        {"vds": "X", "model": "Model X"},
+        {"vds": "3", "model": "Model 3"},
+        {"vds": "Y", "model": "Model Y"}
+      ],
+      "plants": {"A": "Austin, Texas", "F": "Fremont, California"}
+    },
+    {
+      "wmi": ["1FA"],
+      "make": "Ford",
+      "manufacturer": "Ford Motor Company",
+      "models": [
+        {"vds": "6P8", "model": "Mustang"},
+        {"vds": "6P3", "model": "Focus"},
+        {"vds": "6P0", "model": "Fusion"}
+      ],
+      "plants": {"5": "Flat Rock, Michigan", "L": "Wayne, Michigan", "R": "Hermosillo, Mexico"}
+    },
+    {
+      "wmi": ["1FT"],
+      "make": "Ford",
+      "manufacturer": "Ford Motor Company",
+      "models": [
+        {"vds": "*W1", "model": "F-150"},
+        {"vds": "*X1", "model": "F-150"},
+        {"vds": "*F1", "model": "F-150"},
+        {"vds": "*W2", "model": "F-250"},
+        {"vds": "*X2", "model": "F-250"},
+        {"vds": "*B2", "model": "F-250"},
+        {"vds": "*R4", "model": "Ranger"},
+        {"vds": "*W3", "model": "Maverick"}
+      ],
+      "plants": {"E": "Louisville, Kentucky", "F": "Dearborn, Michigan", "K": "Claycomo, Missouri", "L": "Wayne, Michigan", "R": "Hermosillo, Mexico"}
+    },
+    {
+      "wmi": ["1FM"],
+      "make": "Ford",
+      "manufacturer": "Ford Motor Company",
+      "models": [
+        {"vds": "*E5", "model": "Bronco"},
+        {"vds": "*K8", "model": "Explorer"},
+        {"vds": "*U9", "model": "Escape"},
+        {"vds": "*U0", "model": "Escape"}
+      ],
+      "plants": {"D": "Wayne, Michigan", "G": "Chicago, Illinois", "L": "Louisville, Kentucky", "U": "Louisville, Kentucky"}
+    },
+    {
+      "wmi": ["1G1"],
+      "make": "Chevrolet",
+      "manufacturer": "General Motors",
+      "models": [
+        {"vds": "F", "model": "Camaro"},
+        {"vds": "Y", "model": "Corvette"},
+        {"vds": "Z", "model": "Malibu"}
+      ],
+      "plants": {"0": "Lansing, Michigan", "5": "Bowling Green, Kentucky", "F": "Fairfax, Kansas"}
+    },
+    {
+      "wmi": ["1GC", "3GC"],
+      "make": "Chevrolet",
+      "manufacturer": "General Motors",
+      "models": [
+        {"vds": "G", "model": "Colorado"},
+        {"vds": "P", "model": "Silverado 1500"},
+        {"vds": "R", "model": "Silverado 1500"},
+        {"vds": "U", "model": "Silverado 1500"},
+        {"vds": "V", "model": "Silverado 1500"}
+      ],
+      "plants": {"1": "Wentzville, Missouri", "G": "Silao, Mexico", "Z": "Fort Wayne, Indiana"}
+    },
+    {
+      "wmi": ["1GN"],
+      "make": "Chevrolet",
+      "manufacturer": "General Motors",
+      "models": [
+        {"vds": "S", "model": "Tahoe"},
+        {"vds": "E", "model": "Traverse"}
+      ],
+      "plants": {"J": "Lansing, Michigan", "R": "Arlington, Texas"}
+    },
+    {
+      "wmi": ["1HG", "2HG", "19X", "JHM"],
+      "make": "Honda",
+      "manufacturer": "Honda Motor Company",
+      "models": [
+        {"vds": "FA", "model": "Civic"},
+        {"vds": "FB", "model": "Civic"},
+        {"vds": "FC", "model": "Civic"},
+        {"vds": "FE", "model": "Civic"},
+        {"vds": "FK", "model": "Civic"},
+        {"vds": "CP", "model": "Accord"},
+        {"vds": "CR", "model": "Accord"},
+        {"vds": "CV", "model": "Accord"},
+        {"vds": "GK", "model": "Fit"}
+      ],
+      "plants": {"A": "Marysville, Ohio", "C": "Sayama, Japan", "H": "Alliston, Ontario", "L": "East Liberty, Ohio"}
+    },
+    {
+      "wmi": ["2HK", "5J6", "5FN", "3CZ"],
+      "make": "Honda",
+      "manufacturer": "Honda Motor Company",
+      "models": [
+        {"vds": "RM", "model": "CR-V"},
+        {"vds": "RS", "model": "CR-V"},
+        {"vds": "RW", "model": "CR-V"},
+        {"vds": "RU", "model": "HR-V"},
+        {"vds": "RL", "model": "Odyssey"},
+        {"vds": "YF", "model": "Pilot"},
+        {"vds": "YK", "model": "Ridgeline"}
+      ],
+      "plants": {"B": "Lincoln, Alabama", "H": "Alliston, Ontario", "L": "East Liberty, Ohio", "M": "Celaya, Mexico"}
+    },
+    {
+      "wmi": ["4T1", "JTN"],
+      "make": "Toyota",
+      "manufacturer": "Toyota Motor Corporation",
+      "models": [
+        {"vds": "*11", "model": "Camry"},
+        {"vds": "*F1", "model": "Camry"},
+        {"vds": "*K1", "model": "Camry"}
+      ],
+      "plants": {"U": "Georgetown, Kentucky"}
+    },
+    {
+      "wmi": ["2T1", "5YF", "JTD"],
+      "make": "Toyota",
+      "manufacturer": "Toyota Motor Corporation",
+      "models": [
+        {"vds": "BU", "model": "Corolla"},
+        {"vds": "EP", "model": "Corolla"},
+        {"vds": "KR", "model": "Prius c"},
+        {"vds": "KN", "model": "Prius"},
+        {"vds": "KA", "model": "Prius"}
+      ],
+      "plants": {"C": "Cambridge, Ontario", "J": "Toyota City, Japan", "P": "Blue Springs, Mississippi"}
+    },
+    {
+      "wmi": ["2T3", "JTM", "4T3", "5TD", "5TF", "3TM"],
+      "make": "Toyota",
+      "manufacturer": "Toyota Motor Corporation",
+      "models": [
+        {"vds": "W1", "model": "RAV4"},
+        {"vds": "P1", "model": "RAV4"},
+        {"vds": "*Z", "model": "Highlander"},
+        {"vds": "Y5", "model": "Tundra"},
+        {"vds": "DY", "model": "Tundra"},
+        {"vds": "CZ", "model": "Tacoma"},
+        {"vds": "AZ", "model": "Tacoma"},
+        {"vds": "YK", "model": "Sienna"}
+      ],
+      "plants": {"C": "Cambridge, Ontario", "M": "Tijuana, Mexico", "S": "Princeton, Indiana", "W": "Woodstock, Ontario", "X": "San Antonio, Texas"}
+    },
+    {
+      "wmi": ["WBA", "5UX", "WBS"],
+      "make": "BMW",
+      "manufacturer": "BMW AG",
+      "models": [
+        {"vds": "5R", "model": "3 Series"},
+        {"vds": "8B", "model": "3 Series"},
+        {"vds": "JA", "model": "5 Series"},
+        {"vds": "JE", "model": "5 Series"},
+        {"vds": "TS", "model": "X3"},
+        {"vds": "CR", "model": "X5"},
+        {"vds": "JU", "model": "X5"}
+      ],
+      "plants": {"A": "Munich, Germany", "F": "Regensburg, Germany", "L": "Spartanburg, South Carolina", "N": "Spartanburg, South Carolina"}
+    },
+    {
+      "wmi": ["WVW", "3VW", "1VW", "1V2", "WVG"],
+      "make": "Volkswagen",
+      "manufacturer": "Volkswagen AG",
+      "models": [
+        {"vds": "*AU", "model": "Golf"},
+        {"vds": "*BJ", "model": "Jetta"},
+        {"vds": "*BM", "model": "Jetta"},
+        {"vds": "*A3", "model": "Passat"},
+        {"vds": "*CA", "model": "Atlas"},
+        {"vds": "*AX", "model": "Tiguan"}
+      ],
+      "plants": {"C": "Chattanooga, Tennessee", "M": "Puebla, Mexico", "W": "Wolfsburg, Germany"}
+    },
+    {
+      "wmi": ["1N4", "3N1", "5N1", "1N6", "JN1", "JN8"],
+      "make": "Nissan",
+      "manufacturer": "Nissan Motor Company",
+      "models": [
+        {"vds": "BL", "model": "Altima"},
+        {"vds": "AB", "model": "Sentra"},
+        {"vds": "AA", "model": "Maxima"},
+        {"vds": "AZ", "model": "Leaf"},
+        {"vds": "AT", "model": "Rogue"},
+        {"vds": "DR", "model": "Pathfinder"},
+        {"vds": "ED", "model": "Frontier"}
+      ],
+      "plants": {"C": "Smyrna, Tennessee", "L": "Aguascalientes, Mexico", "N": "Canton, Mississippi"}
+    },
+    {
+      "wmi": ["4S3", "4S4", "JF1", "JF2"],
+      "make": "Subaru",
+      "manufacturer": "Subaru Corporation",
+      "models": [
+        {"vds": "BW", "model": "Outback"},
+        {"vds": "SK", "model": "Forester"},
+        {"vds": "GT", "model": "Crosstrek"},
+        {"vds": "GU", "model": "Impreza"},
+        {"vds": "VA", "model": "WRX"},
+        {"vds": "WM", "model": "Ascent"}
+      ],
+      "plants": {"3": "Lafayette, Indiana", "G": "Gunma, Japan"}
+    },
+    {
+      "wmi": ["KMH", "5NP", "KM8", "5NM"],
+      "make": "Hyundai",
+      "manufacturer": "Hyundai Motor Company",
+      "models": [
+        {"vds": "D8", "model": "Elantra"},
+        {"vds": "E2", "model": "Sonata"},
+        {"vds": "J3", "model": "Tucson"},
+        {"vds": "S2", "model": "Santa Fe"},
+        {"vds": "R2", "model": "Palisade"},
+        {"vds": "KC", "model": "Ioniq 5"}
+      ],
+      "plants": {"H": "Montgomery, Alabama", "U": "Ulsan, South Korea"}
+    },
+    {
+      "wmi": ["WDD", "W1K", "WDC", "W1N", "4JG"],
+      "make": "Mercedes-Benz",
+      "manufacturer": "Mercedes-Benz Group",
+      "models": [
+        {"vds": "WF", "model": "C-Class"},
+        {"vds": "ZF", "model": "E-Class"},
+        {"vds": "UG", "model": "S-Class"},
+        {"vds": "0G", "model": "GLC"},
+        {"vds": "DA", "model": "GLE"}
+      ],
+      "plants": {"A": "Tuscaloosa, Alabama", "F": "Sindelfingen, Germany", "R": "Bremen, Germany"}
+    }
+  ]
+}`
@@ -0,0 +1,243 @@
+package vin
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	Length = 17
+	// Characters that are never used, they look too much like 1 and 0
+	forbidden = "IOQ"
+	// The 10th character, cycling every 30 years from 1980
+	yearCodes = "ABCDEFGHJKLMNPRSTVWXY123456789"
+)
+
+// The lookups VINs are decoded with, bundled with the service
+var Default = MustParseJSON(strings.NewReader(dataset))
+
+// What a VIN says about its car. Anything the dataset doesn't know is left
+// empty.
+type Decoded struct {
+	XMLName      xml.Name `json:"-" xml:"vin"`
+	VIN          string   `json:"vin" xml:"number,attr"`
+	WMI          string   `json:"wmi" xml:"wmi"`
+	Country      string   `json:"country,omitempty" xml:"country,omitempty"`
+	Manufacturer string   `json:"manufacturer,omitempty" xml:"manufacturer,omitempty"`
+	Make         string   `json:"make,omitempty" xml:"make,omitempty"`
+	Model        string   `json:"model,omitempty" xml:"model,omitempty"`
+	Year         int      `json:"year" xml:"year"`
+	Plant        string   `json:"plant,omitempty" xml:"plant,omitempty"`
+}
+
+type Model struct {
+	// Matched against characters 4 to 8, * matches any character
+	VDS   string `json:"vds"`
+	Model string `json:"model"`
+}
+
+type Manufacturer struct {
+	WMI          []string          `json:"wmi"`
+	Make         string            `json:"make"`
+	Manufacturer string            `json:"manufacturer"`
+	Models       []Model           `json:"models"`
+	Plants       map[string]string `json:"plants"`
+}
+
+type Dataset struct {
+	byWMI map[string]*Manufacturer
+}
+
+// {"manufacturers": [{"wmi": ["5YJ"], "make": "Tesla", "manufacturer": "Tesla, Inc.", "models": [{"vds": "3", "model": "Model 3"}], "plants": {"F": "Fremont, California"}}]}
+func ParseJSON(r io.Reader) (*Dataset, error) {
+	var doc struct {
+		Manufacturers []Manufacturer `json:"manufacturers"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("Could not load VIN dataset %s", err)
+	}
+
+	d := &Dataset{byWMI: make(map[string]*Manufacturer)}
+	for i := range doc.Manufacturers {
+		m := &doc.Manufacturers[i]
+		for _, wmi := range m.WMI {
+			if len(wmi) != 3 {
+				return nil, fmt.Errorf("Could not load VIN dataset, WMI %q must be 3 characters", wmi)
+			}
+			if _, ok := d.byWMI[wmi]; ok {
+				return nil, fmt.Errorf("Could not load VIN dataset, WMI %s is listed twice", wmi)
+			}
+			d.byWMI[wmi] = m
+		}
+	}
+	return d, nil
+}
+
+func MustParseJSON(r io.Reader) *Dataset {
+	d, err := ParseJSON(r)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// Check the VIN is well formed and decode it. Lower case is accepted.
+func (d *Dataset) Decode(vin string) (Decoded, error) {
+	vin = strings.ToUpper(strings.TrimSpace(vin))
+	if err := Validate(vin); err != nil {
+		return Decoded{}, err
+	}
+
+	year, err := ModelYear(vin)
+	if err != nil {
+		return Decoded{}, err
+	}
+
+	decoded := Decoded{VIN: vin, WMI: vin[:3], Country: Country(vin), Year: year}
+	m, ok := d.byWMI[decoded.WMI]
+	if !ok {
+		return decoded, nil
+	}
+
+	decoded.Make = m.Make
+	decoded.Manufacturer = m.Manufacturer
+	decoded.Plant = m.Plants[vin[10:11]]
+	for _, model := range m.Models {
+		if matchVDS(model.VDS, vin[3:8]) {
+			decoded.Model = model.Model
+			break
+		}
+	}
+	return decoded, nil
+}
+
+// A VIN is 17 letters and digits, without I, O or Q, whose 9th character
+// is its check digit
+func Validate(vin string) error {
+	if len(vin) != Length {
+		return fmt.Errorf("VIN must be %d characters", Length)
+	}
+	for _, r := range vin {
+		if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') || strings.ContainsRune(forbidden, r) {
+			return fmt.Errorf("VIN can't contain %q", r)
+		}
+	}
+
+	expected, err := CheckDigit(vin)
+	if err != nil {
+		return err
+	}
+	if vin[8] != expected {
+		return fmt.Errorf("VIN check digit is %c but should be %c", vin[8], expected)
+	}
+	return nil
+}
+
+// The 9th character the rest of the VIN calls for: the weighted sum of the
+// transliterated characters mod 11, with 10 written as X
+func CheckDigit(vin string) (byte, error) {
+	if len(vin) != Length {
+		return 0, fmt.Errorf("VIN must be %d characters", Length)
+	}
+
+	weights := [Length]int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+	sum := 0
+	for i := 0; i < Length; i++ {
+		value, err := transliterate(vin[i])
+		if err != nil {
+			return 0, err
+		}
+		sum += value * weights[i]
+	}
+
+	if sum%11 == 10 {
+		return 'X', nil
+	}
+	return byte('0' + sum%11), nil
+}
+
+// The model year from the 10th character. The code repeats every 30 years,
+// so a letter in the 7th character means 2010 onwards and a digit means
+// 1980 to 2009. Years more than one past the current one are taken to be
+// from the cycle before.
+func ModelYear(vin string) (int, error) {
+	if len(vin) != Length {
+		return 0, fmt.Errorf("VIN must be %d characters", Length)
+	}
+
+	i := strings.IndexByte(yearCodes, vin[9])
+	if i < 0 {
+		return 0, fmt.Errorf("VIN model year %q isn't a year code", vin[9])
+	}
+
+	year := 1980 + i
+	if vin[6] < '0' || vin[6] > '9' {
+		year += 30
+	}
+	if year > time.Now().Year()+1 {
+		year -= 30
+	}
+	return year, nil
+}
+
+// The country or region the WMI was assigned to
+func Country(vin string) string {
+	if vin == "" {
+		return ""
+	}
+
+	switch c := vin[0]; {
+	case c == '1' || c == '4' || c == '5':
+		return "United States"
+	case c == '2':
+		return "Canada"
+	case c == '3':
+		return "Mexico"
+	case c == 'J':
+		return "Japan"
+	case c == 'K' && len(vin) > 1 && vin[1] >= 'L' && vin[1] <= 'R':
+		return "South Korea"
+	case c == 'L':
+		return "China"
+	case c == 'S' && len(vin) > 1 && vin[1] >= 'A' && vin[1] <= 'M':
+		return "United Kingdom"
+	case c == 'V' && len(vin) > 1 && vin[1] >= 'F' && vin[1] <= 'R':
+		return "France"
+	case c == 'W':
+		return "Germany"
+	case c == 'Y' && len(vin) > 1 && vin[1] >= 'S' && vin[1] <= 'W':
+		return "Sweden"
+	case c == 'Z' && len(vin) > 1 && vin[1] >= 'A' && vin[1] <= 'R':
+		return "Italy"
+	}
+	return ""
+}
+
+func transliterate(c byte) (int, error) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0'), nil
+	case c >= 'A' && c <= 'Z' && !strings.ContainsRune(forbidden, rune(c)):
+		// A-H are 1-8, J-R are 1-9 skipping O and Q, S-Z are 2-9
+		values := "12345678.12345.7.923456789"
+		return int(values[c-'A'] - '0'), nil
+	}
+	return 0, errors.New("VIN can only contain letters and digits")
+}
+
+func matchVDS(pattern, vds string) bool {
+	if len(pattern) > len(vds) {
+		return false
+	}
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '*' && pattern[i] != vds[i] {
+			return false
+		}
+	}
+	return true
+}
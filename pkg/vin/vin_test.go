@@ -0,0 +1,61 @@
+package vin_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/vin"
+)
+
+func TestDecode(t *testing.T) {
+	cases := []vin.Decoded{
+		{VIN: "5YJ3E1EA2KF317000", WMI: "5YJ", Country: "United States", Manufacturer: "Tesla, Inc.", Make: "Tesla", Model: "Model 3", Year: 2019, Plant: "Fremont, California"},
+		{VIN: "1FTEW1EP7MFA00001", WMI: "1FT", Country: "United States", Manufacturer: "Ford Motor Company", Make: "Ford", Model: "F-150", Year: 2021, Plant: "Dearborn, Michigan"},
+		{VIN: "2HGFC2F54JH500001", WMI: "2HG", Country: "Canada", Manufacturer: "Honda Motor Company", Make: "Honda", Model: "Civic", Year: 2018, Plant: "Alliston, Ontario"},
+		{VIN: "JTDKN3DU8A0000001", WMI: "JTD", Country: "Japan", Manufacturer: "Toyota Motor Corporation", Make: "Toyota", Model: "Prius", Year: 2010},
+		// A digit in the 7th character puts the year in the 1980 cycle
+		{VIN: "1M8GDM9AXKP042788", WMI: "1M8", Country: "United States", Year: 1989},
+	}
+
+	for _, expected := range cases {
+		got, err := vin.Default.Decode(strings.ToLower(expected.VIN))
+		if err != nil {
+			t.Errorf("%s: couldn't decode %s", expected.VIN, err)
+			continue
+		}
+		if got != expected {
+			t.Errorf("Expected %+v, got %+v", expected, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	invalid := map[string]string{
+		"5YJ3E1EA2KF31700":  "VIN must be 17 characters",
+		"5YJ3E1EA2KF3170O0": `VIN can't contain 'O'`,
+		"5YJ3E1EA2KF31700-": `VIN can't contain '-'`,
+		"5YJ3E1EA3KF317000": "VIN check digit is 3 but should be 2",
+	}
+	for number, expected := range invalid {
+		if err := vin.Validate(number); err == nil || err.Error() != expected {
+			t.Errorf("%s: expected %q, got %v", number, expected, err)
+		}
+	}
+
+	if err := vin.Validate("11111111111111111"); err != nil {
+		t.Errorf("Expected a valid VIN, got %s", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	bodies := []string{
+		`{"manufacturers": [{"wmi": ["5Y"], "make": "Tesla"}]}`,
+		`{"manufacturers": [{"wmi": ["5YJ"], "make": "Tesla"}, {"wmi": ["5YJ"], "make": "Ford"}]}`,
+		`{"manufacturers": {}}`,
+	}
+	for _, body := range bodies {
+		if _, err := vin.ParseJSON(strings.NewReader(body)); err == nil {
+			t.Errorf("Expected an error loading %s", body)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/clients"
+	"github.com/ericmcbride/go-dfw-testing/pkg/logging"
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+)
+
+var (
+	// Attempts before a delivery goes dead
+	MaxAttempts = 8
+	// Wait before the first retry, doubling with every attempt after it
+	BaseBackoff = 30 * time.Second
+	MaxBackoff  = time.Hour
+	// How often the worker looks for due deliveries
+	PollInterval = 5 * time.Second
+	// Deliveries claimed per poll
+	ClaimSize = 50
+	// How long a claimed delivery is hidden from other workers
+	ClaimLease = time.Minute
+
+	Client = NewClient(10 * time.Second)
+)
+
+// Wait before retrying a delivery that has failed attempts times
+func Backoff(attempts int) time.Duration {
+	backoff := BaseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= MaxBackoff {
+			return MaxBackoff
+		}
+	}
+	return backoff
+}
+
+// Send one delivery to its webhook. Anything but a 2xx response is a failure.
+func Send(client *http.Client, delivery models.WebhookDelivery, now time.Time) error {
+	timestamp := now.Unix()
+
+	req, err := http.NewRequest("POST", delivery.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, timestamp, delivery.Payload))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.Id, 10))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Drain the body so the connection can be reused
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Webhook responded with %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Deliver claims the deliveries that are due, sends them and records the
+// outcome. Returns how many were claimed.
+func Deliver(db *clients.DBClient, client *http.Client) (int, error) {
+	deliveries, err := models.ClaimDeliveries(db, ClaimSize, ClaimLease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, delivery := range deliveries {
+		sendErr := Send(client, delivery, time.Now())
+		if sendErr == nil {
+			err = models.CompleteDelivery(db, delivery.Id)
+		} else {
+			attempts := delivery.Attempts + 1
+			dead := attempts >= MaxAttempts
+			err = models.FailDelivery(db, delivery.Id, sendErr.Error(), time.Now().Add(Backoff(attempts)), dead)
+		}
+		if err != nil {
+			return len(deliveries), err
+		}
+	}
+	return len(deliveries), nil
+}
+
+// Run delivers webhooks every PollInterval until ctx is done. Several
+// instances can run at once; claims keep them from sending the same delivery.
+func Run(ctx context.Context) error {
+	log := logging.GetLog(ctx)
+
+	db, err := clients.NewDbConn()
+	if err != nil {
+		return err
+	}
+	defer clients.Close(&db)
+
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while there's a backlog
+		for {
+			claimed, err := Deliver(&db, Client)
+			if err != nil {
+				log.WithError(err).Error("Webhooks: delivery failed")
+				break
+			}
+			if claimed < ClaimSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Headers sent with every delivery
+const (
+	SignatureHeader = "X-CARS-Signature"
+	TimestampHeader = "X-CARS-Timestamp"
+	EventHeader     = "X-CARS-Event"
+	DeliveryHeader  = "X-CARS-Delivery"
+)
+
+// Sign a delivery body. The signature covers the timestamp as well as the
+// body, "<timestamp>.<body>", so receivers can reject old deliveries that are
+// replayed at them.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify a signature the way a receiver would
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Generate a random signing secret for a new webhook
+func NewSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
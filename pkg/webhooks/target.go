@@ -0,0 +1,131 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Let webhooks reach loopback, link-local and private addresses. Off by
+// default, otherwise anyone who can register a webhook can make the server
+// call its own network, cloud metadata included.
+var AllowPrivateTargets = false
+
+var ErrPrivateTarget = errors.New("Webhooks can't target loopback, link-local or private addresses")
+
+// Ranges a webhook can't reach unless AllowPrivateTargets is set
+var privateNetworks = mustParseNetworks(
+	"0.0.0.0/8",      // this network
+	"10.0.0.0/8",     // RFC 1918
+	"100.64.0.0/10",  // carrier-grade NAT
+	"127.0.0.0/8",    // loopback
+	"169.254.0.0/16", // link-local, cloud metadata
+	"172.16.0.0/12",  // RFC 1918
+	"192.0.0.0/24",   // IETF protocol assignments
+	"192.168.0.0/16", // RFC 1918
+	"198.18.0.0/15",  // benchmarking
+	"224.0.0.0/4",    // multicast
+	"240.0.0.0/4",    // reserved, broadcast
+	"::/128",         // unspecified
+	"::1/128",        // loopback
+	"64:ff9b::/96",   // NAT64 onto IPv4
+	"fc00::/7",       // unique local
+	"fe80::/10",      // link-local
+	"ff00::/8",       // multicast
+)
+
+func mustParseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+func privateIP(ip net.IP) bool {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check a webhook URL when it's registered: an absolute http or https URL
+// that doesn't name a private address. Host names are checked again against
+// what they resolve to when a delivery connects.
+func CheckTarget(rawUrl string) error {
+	parsed, err := url.Parse(rawUrl)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return errors.New("Url must be an absolute http or https URL")
+	}
+	if AllowPrivateTargets {
+		return nil
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(parsed.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrPrivateTarget
+	}
+	if ip := net.ParseIP(host); ip != nil && privateIP(ip) {
+		return ErrPrivateTarget
+	}
+	return nil
+}
+
+// Refuse connections to private addresses once the host name is resolved,
+// so DNS can't point a registered webhook back inside
+func checkDial(network string, address string, _ syscall.RawConn) error {
+	if AllowPrivateTargets {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("Webhook dialed %q, not an IP address", address)
+	}
+	if privateIP(ip) {
+		return ErrPrivateTarget
+	}
+	return nil
+}
+
+// A client for delivering webhooks. It only connects to public addresses,
+// doesn't use a proxy and doesn't follow redirects, so a webhook can only
+// ever reach the host it was registered with. A redirect is a failed
+// delivery like any other non-2xx response.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control:   checkDial,
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,172 @@
+package webhooks_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericmcbride/go-dfw-testing/pkg/models"
+	"github.com/ericmcbride/go-dfw-testing/pkg/webhooks"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"id": 1}`)
+	signature := webhooks.Sign("0123456789abcdef", 1700000000, body)
+
+	if !webhooks.Verify("0123456789abcdef", 1700000000, body, signature) {
+		t.Errorf("Expected signature to verify")
+	}
+	if webhooks.Verify("fedcba9876543210", 1700000000, body, signature) {
+		t.Errorf("Expected a different secret not to verify")
+	}
+	if webhooks.Verify("0123456789abcdef", 1700000001, body, signature) {
+		t.Errorf("Expected a different timestamp not to verify")
+	}
+	if webhooks.Verify("0123456789abcdef", 1700000000, []byte(`{"id": 2}`), signature) {
+		t.Errorf("Expected a different body not to verify")
+	}
+}
+
+func TestSendSignsDelivery(t *testing.T) {
+	var (
+		got     []byte
+		headers http.Header
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = ioutil.ReadAll(r.Body)
+		headers = r.Header
+	}))
+	defer receiver.Close()
+
+	delivery := models.WebhookDelivery{
+		Id:        42,
+		EventType: "created",
+		Payload:   []byte(`{"id": 7, "type": "created"}`),
+		Url:       receiver.URL,
+		Secret:    "0123456789abcdef",
+	}
+	if err := webhooks.Send(receiver.Client(), delivery, time.Now()); err != nil {
+		t.Fatalf("Send failed: %s", err)
+	}
+
+	if string(got) != string(delivery.Payload) {
+		t.Errorf("Expected body %s, got %s", delivery.Payload, got)
+	}
+	if headers.Get(webhooks.EventHeader) != "created" || headers.Get(webhooks.DeliveryHeader) != "42" {
+		t.Errorf("Unexpected delivery headers: %v", headers)
+	}
+
+	timestamp, err := strconv.ParseInt(headers.Get(webhooks.TimestampHeader), 10, 64)
+	if err != nil {
+		t.Fatalf("Invalid timestamp header: %s", err)
+	}
+	if !webhooks.Verify(delivery.Secret, timestamp, got, headers.Get(webhooks.SignatureHeader)) {
+		t.Errorf("Signature didn't verify")
+	}
+}
+
+func TestSendFailsOnErrorStatus(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(503)
+	}))
+	defer receiver.Close()
+
+	delivery := models.WebhookDelivery{Payload: []byte(`{}`), Url: receiver.URL, Secret: "0123456789abcdef"}
+	if err := webhooks.Send(receiver.Client(), delivery, time.Now()); err == nil {
+		t.Errorf("Expected a 503 to fail the delivery")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	expected := map[int]time.Duration{
+		1:  webhooks.BaseBackoff,
+		2:  2 * webhooks.BaseBackoff,
+		3:  4 * webhooks.BaseBackoff,
+		20: webhooks.MaxBackoff,
+	}
+	for attempts, want := range expected {
+		if got := webhooks.Backoff(attempts); got != want {
+			t.Errorf("Backoff(%d): expected %s, got %s", attempts, want, got)
+		}
+	}
+}
+
+func TestCheckTarget(t *testing.T) {
+	allowed := []string{
+		"https://example.com/hook",
+		"http://93.184.216.34:8080/hook",
+		"https://[2606:2800:220:1:248:1893:25c8:1946]/hook",
+	}
+	for _, target := range allowed {
+		if err := webhooks.CheckTarget(target); err != nil {
+			t.Errorf("%s: expected it to be allowed, got %v", target, err)
+		}
+	}
+
+	refused := []string{
+		"http://localhost/hook",
+		"http://api.localhost./hook",
+		"http://127.0.0.1:9090/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.1/hook",
+		"http://172.16.5.4/hook",
+		"http://192.168.1.1/hook",
+		"http://0.0.0.0/hook",
+		"http://[::1]/hook",
+		"http://[fe80::1]/hook",
+		"http://[fd00::1]/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+	}
+	for _, target := range refused {
+		if err := webhooks.CheckTarget(target); err != webhooks.ErrPrivateTarget {
+			t.Errorf("%s: expected %v, got %v", target, webhooks.ErrPrivateTarget, err)
+		}
+	}
+
+	if err := webhooks.CheckTarget("ftp://example.com/hook"); err == nil {
+		t.Errorf("Expected a non http URL to be refused")
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	hit := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer receiver.Close()
+
+	// Registered by name, resolved to loopback when it connects
+	delivery := models.WebhookDelivery{Payload: []byte(`{}`), Url: strings.Replace(receiver.URL, "127.0.0.1", "localhost", 1), Secret: "0123456789abcdef"}
+	err := webhooks.Send(webhooks.NewClient(time.Second), delivery, time.Now())
+	if err == nil || !strings.Contains(err.Error(), webhooks.ErrPrivateTarget.Error()) {
+		t.Errorf("Expected %v, got %v", webhooks.ErrPrivateTarget, err)
+	}
+	if hit {
+		t.Errorf("Expected the receiver not to be reached")
+	}
+}
+
+func TestClientDoesNotFollowRedirects(t *testing.T) {
+	webhooks.AllowPrivateTargets = true
+	defer func() { webhooks.AllowPrivateTargets = false }()
+
+	followed := false
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		followed = true
+	}))
+	defer internal.Close()
+	receiver := httptest.NewServer(http.RedirectHandler(internal.URL, http.StatusTemporaryRedirect))
+	defer receiver.Close()
+
+	delivery := models.WebhookDelivery{Payload: []byte(`{}`), Url: receiver.URL, Secret: "0123456789abcdef"}
+	if err := webhooks.Send(webhooks.NewClient(time.Second), delivery, time.Now()); err == nil {
+		t.Errorf("Expected a redirect to fail the delivery")
+	}
+	if followed {
+		t.Errorf("Expected the redirect not to be followed")
+	}
+}
@@ -0,0 +1,106 @@
+-- Tenants, the change event trigger, the outbox and webhooks, for databases
+-- created from the original cars table. These came before the numbered
+-- migrations, so without this one a database that only ran them can't take
+-- writes: SaveCar inserts tenant and every write fires cars_notify. New
+-- databases get all of this from tables.sql.
+--
+-- The search column and its indexes are left to 001, which rebuilds them
+-- over the citext columns.
+BEGIN;
+
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS tenant text NOT NULL DEFAULT 'default';
+
+-- Every change to cars is published on the car_changes channel. Event ids
+-- come from a sequence so they're unique, but they're taken before commit so
+-- they aren't in delivery order; listeners number events as they arrive.
+CREATE SEQUENCE IF NOT EXISTS car_event_ids;
+
+-- Transactional outbox. The trigger writes every car change here in the same
+-- transaction as the change, and the relay hands them to the publishers.
+CREATE TABLE IF NOT EXISTS outbox (
+    id bigserial PRIMARY KEY,
+    event_id bigint NOT NULL,
+    event_type text NOT NULL,
+    tenant text NOT NULL,
+    payload jsonb NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    last_error text NOT NULL DEFAULT '',
+    published_at timestamptz,
+    dead_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS outbox_unpublished
+    ON outbox (id) WHERE published_at IS NULL AND dead_at IS NULL;
+
+-- Per tenant webhook subscriptions. An empty events list means every event.
+CREATE TABLE IF NOT EXISTS webhooks (
+    id uuid PRIMARY KEY,
+    tenant text NOT NULL,
+    url text NOT NULL,
+    secret text NOT NULL,
+    events text[] NOT NULL DEFAULT '{}',
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Webhook deliveries, queued by the relay for each matching webhook. Status
+-- is pending, delivered or dead.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id bigserial PRIMARY KEY,
+    webhook_id uuid NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_id bigint NOT NULL,
+    event_type text NOT NULL,
+    payload jsonb NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL DEFAULT now(),
+    last_error text NOT NULL DEFAULT '',
+    delivered_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (webhook_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due
+    ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+-- 001 replaces this with the same function once the search column exists
+CREATE OR REPLACE FUNCTION notify_car_change() RETURNS trigger AS $$
+DECLARE
+    car record;
+    event_type text;
+    event_id bigint;
+    payload jsonb;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        car := OLD;
+        event_type := 'deleted';
+    ELSIF TG_OP = 'UPDATE' THEN
+        car := NEW;
+        event_type := 'updated';
+    ELSE
+        car := NEW;
+        event_type := 'created';
+    END IF;
+
+    event_id := nextval('car_event_ids');
+    payload := jsonb_build_object(
+        'id', event_id,
+        'type', event_type,
+        'tenant', car.tenant,
+        'car', to_jsonb(car) - 'tenant' - 'search'
+    );
+
+    INSERT INTO outbox (event_id, event_type, tenant, payload)
+    VALUES (event_id, event_type, car.tenant, payload);
+
+    PERFORM pg_notify('car_changes', payload::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS cars_notify ON cars;
+CREATE TRIGGER cars_notify
+    AFTER INSERT OR UPDATE OR DELETE ON cars
+    FOR EACH ROW EXECUTE PROCEDURE notify_car_change();
+
+COMMIT;
@@ -0,0 +1,95 @@
+-- Move make, model and color off character(128), which pads every value
+-- with spaces to 128 characters, onto citext so values come back as written
+-- and compare without case. Existing rows are trimmed, inner whitespace is
+-- collapsed and makes are cased the same way the service now normalizes
+-- writes (models.NormalizeCar).
+--
+-- New databases get all of this from tables.sql. Existing ones run
+-- `make migrate`.
+--
+-- The columns are rewritten by ALTER ... USING rather than an UPDATE, so the
+-- backfill doesn't fire cars_notify and flood the outbox with updates.
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS citext;
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE FUNCTION pg_temp.normalize_car_text(value text) RETURNS text AS $$
+    SELECT btrim(regexp_replace(value, '\s+', ' ', 'g'))
+$$ LANGUAGE sql IMMUTABLE;
+
+-- Keep in step with models.CanonicalMakes
+CREATE FUNCTION pg_temp.canonical_make(value text) RETURNS text AS $$
+    SELECT CASE lower(pg_temp.normalize_car_text(value))
+        WHEN 'bmw' THEN 'BMW'
+        WHEN 'gmc' THEN 'GMC'
+        WHEN 'mini' THEN 'MINI'
+        WHEN 'mclaren' THEN 'McLaren'
+        ELSE initcap(pg_temp.normalize_car_text(value))
+    END
+$$ LANGUAGE sql IMMUTABLE;
+
+-- The search column and its trigram index are built over these columns, so
+-- they go while the types change and are rebuilt after
+DROP INDEX IF EXISTS cars_search_trigrams;
+ALTER TABLE cars DROP COLUMN IF EXISTS search;
+
+ALTER TABLE cars
+    ALTER COLUMN make TYPE citext USING pg_temp.canonical_make(make::text),
+    ALTER COLUMN model TYPE citext USING pg_temp.normalize_car_text(model::text),
+    ALTER COLUMN color TYPE citext USING pg_temp.normalize_car_text(color::text);
+
+ALTER TABLE cars
+    DROP CONSTRAINT IF EXISTS cars_make_length,
+    DROP CONSTRAINT IF EXISTS cars_model_length,
+    DROP CONSTRAINT IF EXISTS cars_color_length,
+    ADD CONSTRAINT cars_make_length CHECK (char_length(make) <= 128),
+    ADD CONSTRAINT cars_model_length CHECK (char_length(model) <= 128),
+    ADD CONSTRAINT cars_color_length CHECK (char_length(color) <= 128);
+
+ALTER TABLE cars ADD COLUMN search tsvector GENERATED ALWAYS AS (to_tsvector('simple',
+    coalesce(make::text, '') || ' ' || coalesce(model::text, '') || ' ' || coalesce(color::text, '')
+)) STORED;
+
+CREATE INDEX IF NOT EXISTS cars_search ON cars USING GIN (search);
+
+CREATE INDEX IF NOT EXISTS cars_search_trigrams ON cars USING GIN (
+    (coalesce(make::text, '') || ' ' || coalesce(model::text, '') || ' ' || coalesce(color::text, '')) gin_trgm_ops
+);
+
+-- Change events carry the car without its search column
+CREATE OR REPLACE FUNCTION notify_car_change() RETURNS trigger AS $$
+DECLARE
+    car record;
+    event_type text;
+    event_id bigint;
+    payload jsonb;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        car := OLD;
+        event_type := 'deleted';
+    ELSIF TG_OP = 'UPDATE' THEN
+        car := NEW;
+        event_type := 'updated';
+    ELSE
+        car := NEW;
+        event_type := 'created';
+    END IF;
+
+    event_id := nextval('car_event_ids');
+    payload := jsonb_build_object(
+        'id', event_id,
+        'type', event_type,
+        'tenant', car.tenant,
+        'car', to_jsonb(car) - 'tenant' - 'search'
+    );
+
+    INSERT INTO outbox (event_id, event_type, tenant, payload)
+    VALUES (event_id, event_type, car.tenant, payload);
+
+    PERFORM pg_notify('car_changes', payload::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMIT;
@@ -0,0 +1,42 @@
+-- Owners and their ownership history, for databases created before them.
+-- New databases get these from tables.sql.
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS citext;
+
+-- People and businesses that own cars
+CREATE TABLE IF NOT EXISTS owners (
+    id uuid PRIMARY KEY,
+    tenant text NOT NULL DEFAULT 'default',
+    name citext NOT NULL CONSTRAINT owners_name_length CHECK (char_length(name) <= 128),
+    email citext NOT NULL DEFAULT '' CONSTRAINT owners_email_length CHECK (char_length(email) <= 254),
+    phone text NOT NULL DEFAULT '' CONSTRAINT owners_phone_length CHECK (char_length(phone) <= 32),
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Who owned each car and when. An ownership runs from started_on up to but
+-- not including ended_on, and the current one has no ended_on. btree_gist
+-- lets the exclusion constraint compare car ids, so a car's ownerships
+-- can never overlap.
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+CREATE TABLE IF NOT EXISTS car_ownerships (
+    id bigserial PRIMARY KEY,
+    car_id uuid NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+    owner_id uuid NOT NULL REFERENCES owners (id),
+    tenant text NOT NULL,
+    started_on date NOT NULL,
+    ended_on date,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT car_ownerships_dates CHECK (ended_on > started_on),
+    CONSTRAINT car_ownerships_overlap EXCLUDE USING gist (
+        car_id WITH =, daterange(started_on, ended_on) WITH &&
+    )
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS car_ownerships_current
+    ON car_ownerships (car_id) WHERE ended_on IS NULL;
+
+CREATE INDEX IF NOT EXISTS car_ownerships_owner ON car_ownerships (owner_id);
+
+COMMIT;
@@ -0,0 +1,47 @@
+-- Dealerships, their locations and where each car sits, for databases
+-- created before them. New databases get these from tables.sql.
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS citext;
+
+-- Dealerships and the lots they keep their inventory on
+CREATE TABLE IF NOT EXISTS dealerships (
+    id uuid PRIMARY KEY,
+    tenant text NOT NULL DEFAULT 'default',
+    name citext NOT NULL CONSTRAINT dealerships_name_length CHECK (char_length(name) <= 128),
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS locations (
+    id uuid PRIMARY KEY,
+    dealership_id uuid NOT NULL REFERENCES dealerships (id),
+    tenant text NOT NULL DEFAULT 'default',
+    name citext NOT NULL CONSTRAINT locations_name_length CHECK (char_length(name) <= 128),
+    address text NOT NULL DEFAULT '' CONSTRAINT locations_address_length CHECK (char_length(address) <= 256),
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS locations_dealership ON locations (dealership_id);
+
+-- Where the car sits, null until it's first moved to a location. A location
+-- can't be deleted while it holds cars.
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS location_id uuid REFERENCES locations (id);
+
+CREATE INDEX IF NOT EXISTS cars_location ON cars (location_id);
+
+-- Every move of a car between locations, written in the same transaction as
+-- the change to cars.location_id. from_location_id is null for a car's first
+-- placement, and either side is nulled if its location is deleted.
+CREATE TABLE IF NOT EXISTS car_moves (
+    id bigserial PRIMARY KEY,
+    car_id uuid NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+    tenant text NOT NULL,
+    from_location_id uuid REFERENCES locations (id) ON DELETE SET NULL,
+    to_location_id uuid REFERENCES locations (id) ON DELETE SET NULL,
+    note text NOT NULL DEFAULT '' CONSTRAINT car_moves_note_length CHECK (char_length(note) <= 512),
+    moved_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS car_moves_car ON car_moves (car_id, moved_at);
+
+COMMIT;
@@ -0,0 +1,24 @@
+-- Car lifecycle status and transition history, for databases created
+-- before them. Existing cars start as drafts. New databases get these from
+-- tables.sql.
+BEGIN;
+
+-- Where the car is in its lifecycle, changed only through transitions
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'draft'
+    CONSTRAINT cars_status CHECK (status IN ('draft', 'listed', 'reserved', 'sold', 'retired'));
+
+-- Every lifecycle transition of a car, who made it and when
+CREATE TABLE IF NOT EXISTS car_transitions (
+    id bigserial PRIMARY KEY,
+    car_id uuid NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+    tenant text NOT NULL,
+    event text NOT NULL,
+    from_status text NOT NULL,
+    to_status text NOT NULL,
+    actor text NOT NULL CONSTRAINT car_transitions_actor_length CHECK (char_length(actor) <= 128),
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS car_transitions_car ON car_transitions (car_id, created_at);
+
+COMMIT;
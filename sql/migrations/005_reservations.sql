@@ -0,0 +1,26 @@
+-- Car reservations, for databases created before them. New databases get
+-- this from tables.sql.
+BEGIN;
+
+-- Time boxed holds on cars. A car has at most one active reservation; the
+-- sweeper expires active ones once expires_at passes.
+CREATE TABLE IF NOT EXISTS reservations (
+    id uuid PRIMARY KEY,
+    car_id uuid NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+    tenant text NOT NULL,
+    holder_name text NOT NULL CONSTRAINT reservations_holder_name_length CHECK (char_length(holder_name) <= 128),
+    holder_email text NOT NULL DEFAULT '' CONSTRAINT reservations_holder_email_length CHECK (char_length(holder_email) <= 254),
+    holder_phone text NOT NULL DEFAULT '' CONSTRAINT reservations_holder_phone_length CHECK (char_length(holder_phone) <= 32),
+    deposit_cents bigint NOT NULL DEFAULT 0 CONSTRAINT reservations_deposit CHECK (deposit_cents >= 0),
+    status text NOT NULL DEFAULT 'active'
+        CONSTRAINT reservations_status CHECK (status IN ('active', 'released', 'expired', 'fulfilled')),
+    expires_at timestamptz NOT NULL,
+    ended_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS reservations_one_active ON reservations (car_id) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS reservations_car ON reservations (car_id, created_at);
+CREATE INDEX IF NOT EXISTS reservations_expiry ON reservations (expires_at) WHERE status = 'active';
+
+COMMIT;
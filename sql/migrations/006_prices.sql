@@ -0,0 +1,25 @@
+-- List prices and their history, for databases created before them. New
+-- databases get these from tables.sql.
+BEGIN;
+
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS price_minor bigint CONSTRAINT cars_price CHECK (price_minor >= 0);
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS currency text CONSTRAINT cars_currency CHECK (currency ~ '^[A-Z]{3}$');
+ALTER TABLE cars DROP CONSTRAINT IF EXISTS cars_priced;
+ALTER TABLE cars ADD CONSTRAINT cars_priced CHECK ((price_minor IS NULL) = (currency IS NULL));
+CREATE INDEX IF NOT EXISTS cars_price ON cars (currency, price_minor);
+
+-- Every list price a car has had, who set it and why
+CREATE TABLE IF NOT EXISTS price_history (
+    id bigserial PRIMARY KEY,
+    car_id uuid NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+    tenant text NOT NULL,
+    price_minor bigint NOT NULL CONSTRAINT price_history_price CHECK (price_minor >= 0),
+    currency text NOT NULL CONSTRAINT price_history_currency CHECK (currency ~ '^[A-Z]{3}$'),
+    actor text NOT NULL CONSTRAINT price_history_actor_length CHECK (char_length(actor) <= 128),
+    reason text NOT NULL DEFAULT '' CONSTRAINT price_history_reason_length CHECK (char_length(reason) <= 512),
+    changed_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS price_history_car ON price_history (car_id, changed_at);
+
+COMMIT;
@@ -0,0 +1,25 @@
+-- Odometer readings and the car's latest mileage, for databases created
+-- before them. New databases get these from tables.sql.
+BEGIN;
+
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS mileage_km bigint CONSTRAINT cars_mileage CHECK (mileage_km >= 0);
+CREATE INDEX IF NOT EXISTS cars_mileage ON cars (mileage_km);
+
+-- Odometer readings, as read and in whole kilometers. suspected_rollback is
+-- set when a reading is lower than one taken on or before it.
+CREATE TABLE IF NOT EXISTS odometer_readings (
+    id bigserial PRIMARY KEY,
+    car_id uuid NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+    tenant text NOT NULL,
+    value bigint NOT NULL CONSTRAINT odometer_readings_value CHECK (value >= 0),
+    unit text NOT NULL CONSTRAINT odometer_readings_unit CHECK (unit IN ('miles', 'km')),
+    km bigint NOT NULL CONSTRAINT odometer_readings_km CHECK (km >= 0),
+    read_on date NOT NULL,
+    source text NOT NULL CONSTRAINT odometer_readings_source_length CHECK (char_length(source) <= 128),
+    suspected_rollback boolean NOT NULL DEFAULT false,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS odometer_readings_car ON odometer_readings (car_id, read_on);
+
+COMMIT;
@@ -0,0 +1,26 @@
+-- Service records, for databases created before them. New databases get
+-- this from tables.sql.
+BEGIN;
+
+-- Work done on cars. mileage_km is also logged as an odometer reading, and
+-- cost_minor is in minor units of currency, both null for free work.
+CREATE TABLE IF NOT EXISTS service_records (
+    id bigserial PRIMARY KEY,
+    car_id uuid NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+    tenant text NOT NULL,
+    type text NOT NULL CONSTRAINT service_records_type CHECK (
+        type IN ('oil_change', 'tire_rotation', 'brakes', 'inspection', 'repair', 'other')
+    ),
+    serviced_on date NOT NULL,
+    mileage_km bigint CONSTRAINT service_records_mileage CHECK (mileage_km >= 0),
+    cost_minor bigint CONSTRAINT service_records_cost CHECK (cost_minor >= 0),
+    currency text CONSTRAINT service_records_currency CHECK (currency ~ '^[A-Z]{3}$'),
+    CONSTRAINT service_records_costed CHECK ((cost_minor IS NULL) = (currency IS NULL)),
+    vendor text NOT NULL DEFAULT '' CONSTRAINT service_records_vendor_length CHECK (char_length(vendor) <= 128),
+    notes text NOT NULL DEFAULT '' CONSTRAINT service_records_notes_length CHECK (char_length(notes) <= 2000),
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS service_records_car ON service_records (car_id, type, serviced_on);
+
+COMMIT;
@@ -0,0 +1,8 @@
+-- Record the credential each transition was made with, for databases
+-- created before it. New databases get this from tables.sql. Earlier
+-- transitions only have the actor they claimed.
+BEGIN;
+
+ALTER TABLE car_transitions ADD COLUMN IF NOT EXISTS credential text NOT NULL DEFAULT '';
+
+COMMIT;
@@ -1,9 +1,296 @@
 CREATE DATABASE go_dfw_test;
 
+-- Make, model and color compare without case. The service trims and
+-- collapses whitespace before writing them.
+CREATE EXTENSION IF NOT EXISTS citext;
+
+-- Dealerships and the lots they keep their inventory on
+CREATE TABLE IF NOT EXISTS dealerships (
+    id uuid PRIMARY KEY,
+    tenant text NOT NULL DEFAULT 'default',
+    name citext NOT NULL CONSTRAINT dealerships_name_length CHECK (char_length(name) <= 128),
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS locations (
+    id uuid PRIMARY KEY,
+    dealership_id uuid NOT NULL REFERENCES dealerships (id),
+    tenant text NOT NULL DEFAULT 'default',
+    name citext NOT NULL CONSTRAINT locations_name_length CHECK (char_length(name) <= 128),
+    address text NOT NULL DEFAULT '' CONSTRAINT locations_address_length CHECK (char_length(address) <= 256),
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS locations_dealership ON locations (dealership_id);
+
 CREATE TABLE IF NOT EXISTS cars (
     id uuid PRIMARY KEY,
-    make character(128),
-    model character(128),
-    color character(128),
-    year integer
+    make citext CONSTRAINT cars_make_length CHECK (char_length(make) <= 128),
+    model citext CONSTRAINT cars_model_length CHECK (char_length(model) <= 128),
+    color citext CONSTRAINT cars_color_length CHECK (char_length(color) <= 128),
+    year integer,
+    tenant text NOT NULL DEFAULT 'default',
+    -- Where the car sits, null until it's first moved to a location. A
+    -- location can't be deleted while it holds cars.
+    location_id uuid REFERENCES locations (id),
+    -- Where the car is in its lifecycle, changed only through transitions
+    status text NOT NULL DEFAULT 'draft' CONSTRAINT cars_status CHECK (
+        status IN ('draft', 'listed', 'reserved', 'sold', 'retired')
+    ),
+    -- List price in minor units of an ISO 4217 currency, both null until the
+    -- car is first priced. Changed only through price_history.
+    price_minor bigint CONSTRAINT cars_price CHECK (price_minor >= 0),
+    currency text CONSTRAINT cars_currency CHECK (currency ~ '^[A-Z]{3}$'),
+    CONSTRAINT cars_priced CHECK ((price_minor IS NULL) = (currency IS NULL)),
+    -- Kilometers on the latest odometer reading, null until one is recorded
+    mileage_km bigint CONSTRAINT cars_mileage CHECK (mileage_km >= 0),
+    search tsvector GENERATED ALWAYS AS (to_tsvector('simple',
+        coalesce(make::text, '') || ' ' || coalesce(model::text, '') || ' ' || coalesce(color::text, '')
+    )) STORED
+);
+
+-- Free text search over make, model and color. The tsvector matches whole
+-- words, the trigram index on the same text catches typos.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS cars_search ON cars USING GIN (search);
+
+CREATE INDEX IF NOT EXISTS cars_search_trigrams ON cars USING GIN (
+    (coalesce(make::text, '') || ' ' || coalesce(model::text, '') || ' ' || coalesce(color::text, '')) gin_trgm_ops
+);
+
+-- Every change to cars is published on the car_changes channel. Event ids
+-- come from a sequence so they're unique, but they're taken before commit so
+-- they aren't in delivery order; listeners number events as they arrive.
+CREATE SEQUENCE IF NOT EXISTS car_event_ids;
+
+-- Transactional outbox. The trigger writes every car change here in the same
+-- transaction as the change, and the relay hands them to the publishers.
+CREATE TABLE IF NOT EXISTS outbox (
+    id bigserial PRIMARY KEY,
+    event_id bigint NOT NULL,
+    event_type text NOT NULL,
+    tenant text NOT NULL,
+    payload jsonb NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    last_error text NOT NULL DEFAULT '',
+    published_at timestamptz,
+    dead_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS outbox_unpublished
+    ON outbox (id) WHERE published_at IS NULL AND dead_at IS NULL;
+
+-- Per tenant webhook subscriptions. An empty events list means every event.
+CREATE TABLE IF NOT EXISTS webhooks (
+    id uuid PRIMARY KEY,
+    tenant text NOT NULL,
+    url text NOT NULL,
+    secret text NOT NULL,
+    events text[] NOT NULL DEFAULT '{}',
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Webhook deliveries, queued by the relay for each matching webhook. Status
+-- is pending, delivered or dead.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id bigserial PRIMARY KEY,
+    webhook_id uuid NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_id bigint NOT NULL,
+    event_type text NOT NULL,
+    payload jsonb NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL DEFAULT now(),
+    last_error text NOT NULL DEFAULT '',
+    delivered_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (webhook_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due
+    ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+-- People and businesses that own cars
+CREATE TABLE IF NOT EXISTS owners (
+    id uuid PRIMARY KEY,
+    tenant text NOT NULL DEFAULT 'default',
+    name citext NOT NULL CONSTRAINT owners_name_length CHECK (char_length(name) <= 128),
+    email citext NOT NULL DEFAULT '' CONSTRAINT owners_email_length CHECK (char_length(email) <= 254),
+    phone text NOT NULL DEFAULT '' CONSTRAINT owners_phone_length CHECK (char_length(phone) <= 32),
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Who owned each car and when. An ownership runs from started_on up to but
+-- not including ended_on, and the current one has no ended_on. btree_gist
+-- lets the exclusion constraint compare car ids, so a car's ownerships
+-- can never overlap.
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+CREATE TABLE IF NOT EXISTS car_ownerships (
+    id bigserial PRIMARY KEY,
+    car_id uuid NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+    owner_id uuid NOT NULL REFERENCES owners (id),
+    tenant text NOT NULL,
+    started_on date NOT NULL,
+    ended_on date,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT car_ownerships_dates CHECK (ended_on > started_on),
+    CONSTRAINT car_ownerships_overlap EXCLUDE USING gist (
+        car_id WITH =, daterange(started_on, ended_on) WITH &&
+    )
 );
+
+CREATE UNIQUE INDEX IF NOT EXISTS car_ownerships_current
+    ON car_ownerships (car_id) WHERE ended_on IS NULL;
+
+CREATE INDEX IF NOT EXISTS car_ownerships_owner ON car_ownerships (owner_id);
+
+CREATE INDEX IF NOT EXISTS cars_location ON cars (location_id);
+CREATE INDEX IF NOT EXISTS cars_price ON cars (currency, price_minor);
+CREATE INDEX IF NOT EXISTS cars_mileage ON cars (mileage_km);
+
+-- Every move of a car between locations, written in the same transaction as
+-- the change to cars.location_id. from_location_id is null for a car's first
+-- placement, and either side is nulled if its location is deleted.
+CREATE TABLE IF NOT EXISTS car_moves (
+    id bigserial PRIMARY KEY,
+    car_id uuid NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+    tenant text NOT NULL,
+    from_location_id uuid REFERENCES locations (id) ON DELETE SET NULL,
+    to_location_id uuid REFERENCES locations (id) ON DELETE SET NULL,
+    note text NOT NULL DEFAULT '' CONSTRAINT car_moves_note_length CHECK (char_length(note) <= 512),
+    moved_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS car_moves_car ON car_moves (car_id, moved_at);
+
+-- Every lifecycle transition of a car, who made it and when
+CREATE TABLE IF NOT EXISTS car_transitions (
+    id bigserial PRIMARY KEY,
+    car_id uuid NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+    tenant text NOT NULL,
+    event text NOT NULL,
+    from_status text NOT NULL,
+    to_status text NOT NULL,
+    actor text NOT NULL CONSTRAINT car_transitions_actor_length CHECK (char_length(actor) <= 128),
+    -- Fingerprint of the credential the transition was made with, as the
+    -- actor is only what the caller claims. Empty for the server's own.
+    credential text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS car_transitions_car ON car_transitions (car_id, created_at);
+
+-- Time boxed holds on cars. A car has at most one active reservation; the
+-- sweeper expires active ones once expires_at passes.
+CREATE TABLE IF NOT EXISTS reservations (
+    id uuid PRIMARY KEY,
+    car_id uuid NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+    tenant text NOT NULL,
+    holder_name text NOT NULL CONSTRAINT reservations_holder_name_length CHECK (char_length(holder_name) <= 128),
+    holder_email text NOT NULL DEFAULT '' CONSTRAINT reservations_holder_email_length CHECK (char_length(holder_email) <= 254),
+    holder_phone text NOT NULL DEFAULT '' CONSTRAINT reservations_holder_phone_length CHECK (char_length(holder_phone) <= 32),
+    deposit_cents bigint NOT NULL DEFAULT 0 CONSTRAINT reservations_deposit CHECK (deposit_cents >= 0),
+    status text NOT NULL DEFAULT 'active'
+        CONSTRAINT reservations_status CHECK (status IN ('active', 'released', 'expired', 'fulfilled')),
+    expires_at timestamptz NOT NULL,
+    ended_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS reservations_one_active ON reservations (car_id) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS reservations_car ON reservations (car_id, created_at);
+CREATE INDEX IF NOT EXISTS reservations_expiry ON reservations (expires_at) WHERE status = 'active';
+
+-- Every list price a car has had, who set it and why
+CREATE TABLE IF NOT EXISTS price_history (
+    id bigserial PRIMARY KEY,
+    car_id uuid NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+    tenant text NOT NULL,
+    price_minor bigint NOT NULL CONSTRAINT price_history_price CHECK (price_minor >= 0),
+    currency text NOT NULL CONSTRAINT price_history_currency CHECK (currency ~ '^[A-Z]{3}$'),
+    actor text NOT NULL CONSTRAINT price_history_actor_length CHECK (char_length(actor) <= 128),
+    reason text NOT NULL DEFAULT '' CONSTRAINT price_history_reason_length CHECK (char_length(reason) <= 512),
+    changed_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS price_history_car ON price_history (car_id, changed_at);
+
+-- Odometer readings, as read and in whole kilometers. suspected_rollback is
+-- set when a reading is lower than one taken on or before it.
+CREATE TABLE IF NOT EXISTS odometer_readings (
+    id bigserial PRIMARY KEY,
+    car_id uuid NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+    tenant text NOT NULL,
+    value bigint NOT NULL CONSTRAINT odometer_readings_value CHECK (value >= 0),
+    unit text NOT NULL CONSTRAINT odometer_readings_unit CHECK (unit IN ('miles', 'km')),
+    km bigint NOT NULL CONSTRAINT odometer_readings_km CHECK (km >= 0),
+    read_on date NOT NULL,
+    source text NOT NULL CONSTRAINT odometer_readings_source_length CHECK (char_length(source) <= 128),
+    suspected_rollback boolean NOT NULL DEFAULT false,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS odometer_readings_car ON odometer_readings (car_id, read_on);
+
+-- Work done on cars. mileage_km is also logged as an odometer reading, and
+-- cost_minor is in minor units of currency, both null for free work.
+CREATE TABLE IF NOT EXISTS service_records (
+    id bigserial PRIMARY KEY,
+    car_id uuid NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+    tenant text NOT NULL,
+    type text NOT NULL CONSTRAINT service_records_type CHECK (
+        type IN ('oil_change', 'tire_rotation', 'brakes', 'inspection', 'repair', 'other')
+    ),
+    serviced_on date NOT NULL,
+    mileage_km bigint CONSTRAINT service_records_mileage CHECK (mileage_km >= 0),
+    cost_minor bigint CONSTRAINT service_records_cost CHECK (cost_minor >= 0),
+    currency text CONSTRAINT service_records_currency CHECK (currency ~ '^[A-Z]{3}$'),
+    CONSTRAINT service_records_costed CHECK ((cost_minor IS NULL) = (currency IS NULL)),
+    vendor text NOT NULL DEFAULT '' CONSTRAINT service_records_vendor_length CHECK (char_length(vendor) <= 128),
+    notes text NOT NULL DEFAULT '' CONSTRAINT service_records_notes_length CHECK (char_length(notes) <= 2000),
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS service_records_car ON service_records (car_id, type, serviced_on);
+
+CREATE OR REPLACE FUNCTION notify_car_change() RETURNS trigger AS $$
+DECLARE
+    car record;
+    event_type text;
+    event_id bigint;
+    payload jsonb;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        car := OLD;
+        event_type := 'deleted';
+    ELSIF TG_OP = 'UPDATE' THEN
+        car := NEW;
+        event_type := 'updated';
+    ELSE
+        car := NEW;
+        event_type := 'created';
+    END IF;
+
+    event_id := nextval('car_event_ids');
+    payload := jsonb_build_object(
+        'id', event_id,
+        'type', event_type,
+        'tenant', car.tenant,
+        'car', to_jsonb(car) - 'tenant' - 'search'
+    );
+
+    INSERT INTO outbox (event_id, event_type, tenant, payload)
+    VALUES (event_id, event_type, car.tenant, payload);
+
+    PERFORM pg_notify('car_changes', payload::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS cars_notify ON cars;
+CREATE TRIGGER cars_notify
+    AFTER INSERT OR UPDATE OR DELETE ON cars
+    FOR EACH ROW EXECUTE PROCEDURE notify_car_change();